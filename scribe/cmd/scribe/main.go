@@ -2,13 +2,25 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/bibtex"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/config"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/llm"
 	"github.com/matsen/phylogenetic-compendium/scribe/internal/output"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/papers"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/qmd"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/quarto"
 	"github.com/matsen/phylogenetic-compendium/scribe/internal/queue"
 	"github.com/matsen/phylogenetic-compendium/scribe/internal/status"
 	"github.com/matsen/phylogenetic-compendium/scribe/internal/sweep"
@@ -32,22 +44,131 @@ func getOutputMode(cmd *cobra.Command, defaultToJSON bool) bool {
 	return jsonOutput
 }
 
+// getReportFormat determines the report format for verify and sweep.
+// --format takes precedence over --json and --human; the default is JSON.
+func getReportFormat(cmd *cobra.Command) (output.Format, error) {
+	if format, _ := cmd.Flags().GetString("format"); format != "" {
+		return output.ParseFormat(format)
+	}
+	if getOutputMode(cmd, true) {
+		return output.FormatJSON, nil
+	}
+	return output.FormatHuman, nil
+}
+
+// loadConfig loads the project configuration named by --config, $SCRIBE_CONFIG,
+// or the nearest .scribe.yaml.
+func loadConfig(cmd *cobra.Command) (*config.Config, error) {
+	path, _ := cmd.Flags().GetString("config")
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	return cfg, nil
+}
+
+// stringFlag returns a flag's value if it was set on the command line, else fallback.
+// Command-line flags take precedence over the config file and environment.
+func stringFlag(cmd *cobra.Command, name, fallback string) string {
+	if !cmd.Flags().Changed(name) {
+		return fallback
+	}
+	v, _ := cmd.Flags().GetString(name)
+	return v
+}
+
+// stringSliceFlag is stringFlag for repeatable string flags.
+func stringSliceFlag(cmd *cobra.Command, name string, fallback []string) []string {
+	if !cmd.Flags().Changed(name) {
+		return fallback
+	}
+	v, _ := cmd.Flags().GetStringSlice(name)
+	return v
+}
+
+// intFlag is stringFlag for int flags.
+func intFlag(cmd *cobra.Command, name string, fallback int) int {
+	if !cmd.Flags().Changed(name) {
+		return fallback
+	}
+	v, _ := cmd.Flags().GetInt(name)
+	return v
+}
+
+// durationFlag is stringFlag for duration flags.
+func durationFlag(cmd *cobra.Command, name string, fallback time.Duration) time.Duration {
+	if !cmd.Flags().Changed(name) {
+		return fallback
+	}
+	v, _ := cmd.Flags().GetDuration(name)
+	return v
+}
+
 // queueContext holds common dependencies for queue commands.
 type queueContext struct {
 	service   *queue.CandidateService
 	formatter *output.Formatter
 	jsonMode  bool
+	cfg       *config.Config
 }
 
 // newQueueContext creates common dependencies for queue commands.
-func newQueueContext(cmd *cobra.Command) *queueContext {
+func newQueueContext(cmd *cobra.Command) (*queueContext, error) {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
 	jsonMode := getOutputMode(cmd, true) // queue commands default to JSON
-	store := queue.NewStore("", "")
 	return &queueContext{
-		service:   queue.NewCandidateService(store),
+		service:   queue.NewCandidateService(cfg.QueueStore()),
 		formatter: output.NewFormatter(jsonMode),
 		jsonMode:  jsonMode,
+		cfg:       cfg,
+	}, nil
+}
+
+// resolveID expands a candidate ID prefix, listing the candidates it
+// matches if there are several.
+func (ctx *queueContext) resolveID(prefix string) (string, error) {
+	id, err := ctx.service.Resolve(prefix)
+	var ambiguous *queue.AmbiguousIDError
+	if errors.As(err, &ambiguous) {
+		return "", fmt.Errorf("ID prefix %s matches %d candidates; use a longer prefix:\n  %s",
+			prefix, len(ambiguous.Matches), strings.Join(ambiguous.Matches, "\n  "))
+	}
+	return id, err
+}
+
+// resolverLookup looks up paper metadata for the bibliography with a citation resolver.
+type resolverLookup struct {
+	resolver verify.CitationResolver
+}
+
+func (l resolverLookup) LookupPaper(s2ID string) (*queue.PaperMetadata, error) {
+	record, err := l.resolver.Resolve(s2ID)
+	if err != nil || record == nil {
+		return nil, err
+	}
+	return &queue.PaperMetadata{DOI: record.DOI, Title: record.Title, Authors: record.Authors, Year: record.Year}, nil
+}
+
+// setQueueBibliography makes the queue add approved papers to the first
+// bibliography file, with metadata from the paper library and bipartite.
+// It returns the resolver so the caller can flush its cache.
+func setQueueBibliography(cmd *cobra.Command, ctx *queueContext) (*verify.CachedResolver, error) {
+	paths, err := bibliographyPaths(cmd, ctx.cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no bibliography configured (set paths.bibliography or bibliography in %s)", quarto.ProjectFile)
+	}
+	resolver, err := newCitationResolver(cmd, ctx.cfg)
+	if err != nil {
+		return nil, err
 	}
+	ctx.service.SetBibliography(queue.NewBibSync(paths[0], resolverLookup{resolver}))
+	return resolver, nil
 }
 
 func main() {
@@ -55,8 +176,13 @@ func main() {
 		Use:   "scribe",
 		Short: "Compendium authoring toolkit",
 		Long: `scribe is a CLI for compendium authoring that implements
-discovery, verification, and human-review workflows.`,
+discovery, verification, and human-review workflows.
+
+Settings are read from the nearest .scribe.yaml above the working directory
+(or --config / $SCRIBE_CONFIG), then SCRIBE_* environment variables, then
+command-line flags.`,
 	}
+	rootCmd.PersistentFlags().String("config", "", "Path to the project config file (default: nearest "+config.FileName+")")
 
 	// Add version flag
 	rootCmd.Version = Version
@@ -67,6 +193,9 @@ discovery, verification, and human-review workflows.`,
 	rootCmd.AddCommand(queueCmd())
 	rootCmd.AddCommand(statusCmd())
 	rootCmd.AddCommand(sweepCmd())
+	rootCmd.AddCommand(cacheCmd())
+	rootCmd.AddCommand(bibCmd())
+	rootCmd.AddCommand(papersCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -82,35 +211,136 @@ func verifyCmd() *cobra.Command {
 
 Checks:
 - All paper IDs resolve in bipartite
+- All Pandoc citation keys (@key, [@key], [-@key]) are defined in the
+  bibliography or resolve in bipartite
+- Bibliography entries are cited and have unique keys
 - All repository URLs are accessible
 - All code location links are valid
 - Every factual claim has a citation
-- No TODO/FIXME markers in content`,
-		Args: cobra.MinimumNArgs(1),
+- No TODO/FIXME markers in content
+- With --project: every chapter, part, appendix, and included file in
+  _quarto.yml is verified in book order, and cross-references such as
+  @sec-foo must point at a label defined in some chapter
+
+Findings can be silenced in the source with HTML comments:
+  <!-- scribe-ignore claim -- reason -->  (claims in the enclosing paragraph)
+  <!-- scribe-ignore-next-line url -->    (URLs on the next line)
+or accepted in bulk with --write-baseline, after which only new findings fail.
+
+--fix rewrites mechanical problems in place: URLs that permanently redirect,
+code links to a branch (blob/main) pinned to its current commit, and paper
+IDs that are aliases of a canonical ID. Add --dry-run to print a diff instead.`,
+		Args: fileArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			humanOutput, _ := cmd.Flags().GetBool("human")
 			summaryOnly, _ := cmd.Flags().GetBool("summary")
-			jsonOutput, _ := cmd.Flags().GetBool("json")
+			format, err := getReportFormat(cmd)
+			if err != nil {
+				return err
+			}
+			fix, _ := cmd.Flags().GetBool("fix")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			if dryRun && !fix {
+				return fmt.Errorf("--dry-run requires --fix")
+			}
 
-			// Default to JSON output unless --human is specified
-			if !humanOutput && !jsonOutput {
-				jsonOutput = true
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			opts, err := cfg.VerifyOptions()
+			if err != nil {
+				return err
+			}
+			if opts.UseLLM && !llmBudgetLeft(cfg) {
+				opts.UseLLM = false
+			}
+			if offline, _ := cmd.Flags().GetBool("offline"); offline && opts.UseLLM {
+				opts.LLM.Offline = true
+				if _, err := llm.NewClientFromConfig(opts.LLM); err != nil {
+					fmt.Fprintf(os.Stderr, "warning: %v; checking claims with heuristics only\n", err)
+					opts.UseLLM = false
+				}
+			}
+
+			resolver, err := newCitationResolver(cmd, cfg)
+			if err != nil {
+				return err
 			}
 
-			opts := verify.DefaultOptions()
-			report, err := verify.VerifyFiles(args, opts)
+			bib, err := loadBibliography(cmd, cfg)
+			if err != nil {
+				return err
+			}
+
+			noCache, _ := cmd.Flags().GetBool("no-cache")
+
+			opts.Resolver = resolver
+			opts.Bibliography = bib
+			if !noCache {
+				opts.Cache = verify.NewResultCache(stringFlag(cmd, "result-cache", cfg.Paths.ResultCache), nil)
+			}
+			if opts.LLM.Cache, err = newLLMCache(cmd, cfg); err != nil {
+				return err
+			}
+			opts.Mirror = verify.NewRepoMirror(stringFlag(cmd, "repo-cache", cfg.Paths.RepoCache))
+			opts.Workers = intFlag(cmd, "workers", opts.Workers)
+			if err := setMetadataProvider(cmd, &opts); err != nil {
+				return err
+			}
+			files, project, err := projectFiles(cmd, args)
+			if err != nil {
+				return err
+			}
+			opts.Project = project
+			report, err := verify.VerifyFiles(files, opts)
 			if err != nil {
 				return fmt.Errorf("verification failed: %w", err)
 			}
 
-			formatter := output.NewFormatter(jsonOutput)
+			if fix {
+				fixes, err := report.PlanFixes()
+				if err != nil {
+					return err
+				}
+				if dryRun {
+					for _, f := range fixes {
+						fmt.Print(f.Diff())
+					}
+					flushVerifyCaches(resolver, opts.Cache, opts.LLM.Cache)
+					recordLLMUsage(cfg, report.LLMUsage)
+					os.Exit(report.ExitCode)
+				}
+				if err := report.ApplyFixes(fixes); err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "Applied %d fixes\n", report.Summary.Fixed)
+			}
+
+			// Accept known findings so only new ones fail
+			baselinePath := stringFlag(cmd, "baseline", cfg.Paths.Baseline)
+			writeBaseline, _ := cmd.Flags().GetBool("write-baseline")
+			var baseline *verify.Baseline
+			if writeBaseline {
+				baseline = verify.NewBaseline(report)
+				if err := baseline.Save(baselinePath); err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "Wrote %d findings to %s\n", len(baseline.Entries), baselinePath)
+			} else if baseline, err = verify.LoadBaseline(baselinePath); err != nil {
+				return err
+			}
+			report.ApplyBaseline(baseline)
+			flushVerifyCaches(resolver, opts.Cache, opts.LLM.Cache)
+			recordLLMUsage(cfg, report.LLMUsage)
+
+			formatter := output.NewFormatter(format == output.FormatJSON)
 
-			if jsonOutput {
+			switch format {
+			case output.FormatJSON:
 				if err := formatter.JSON(report); err != nil {
 					return fmt.Errorf("output error: %w", err)
 				}
-			} else {
-				// Human-readable output
+			case output.FormatHuman:
 				formatter.Header("Verification Report")
 				formatter.Println("Files: %d", len(report.ContentFiles))
 				formatter.Println("")
@@ -120,6 +350,17 @@ Checks:
 				formatter.Println("  Passed: %s %d", output.FormatStatus(output.StatusOK), report.Summary.Passed)
 				formatter.Println("  Failed: %s %d", output.FormatStatus(output.StatusError), report.Summary.Failed)
 				formatter.Println("  Warnings: %s %d", output.FormatStatus(output.StatusWarning), report.Summary.Warnings)
+				if report.Summary.Suppressed > 0 || report.Summary.Baselined > 0 {
+					formatter.Println("  Suppressed: %d", report.Summary.Suppressed)
+					formatter.Println("  Baselined: %d", report.Summary.Baselined)
+				}
+				if report.Summary.Fixed > 0 {
+					formatter.Println("  Fixed: %d", report.Summary.Fixed)
+				}
+				if u := report.LLMUsage; u != nil {
+					formatter.Println("  LLM: %s (%d cached), %d input / %d output tokens, %s",
+						output.FormatCount(u.Requests, "request", "requests"), u.Cached, u.InputTokens, u.OutputTokens, output.FormatCurrency(u.CostUSD))
+				}
 
 				if !summaryOnly && len(report.Results) > 0 {
 					// Show failures
@@ -139,9 +380,33 @@ Checks:
 						for _, r := range warnings {
 							formatter.Println("%s %s:%d", output.FormatStatus(output.StatusWarning), r.Target.File, r.Target.Line)
 							formatter.Println("   %s", r.Message)
+							if r.Fix != nil && !r.Fix.Applied {
+								formatter.Println("   fixable with --fix: %s", r.Fix.Reason)
+							}
+						}
+					}
+
+					// Show applied fixes
+					var fixed []verify.VerificationResult
+					for _, r := range report.Results {
+						if r.Fix != nil && r.Fix.Applied {
+							fixed = append(fixed, r)
+						}
+					}
+					if len(fixed) > 0 {
+						formatter.Header("Fixes")
+						for _, r := range fixed {
+							formatter.Println("%s %s:%d", output.FormatStatus(output.StatusOK), r.Target.File, r.Target.Line)
+							formatter.Println("   %s: %s -> %s", r.Fix.Reason, r.Fix.Old, r.Fix.New)
 						}
 					}
 				}
+			default:
+				findings := report.Findings()
+				findings.Version = Version
+				if err := output.WriteFindings(os.Stdout, format, findings); err != nil {
+					return fmt.Errorf("output error: %w", err)
+				}
 			}
 
 			// Exit with non-zero code if there are failures (per FR-007)
@@ -155,14 +420,212 @@ Checks:
 	cmd.Flags().Bool("human", false, "Human-readable output")
 	cmd.Flags().Bool("summary", false, "Show only summary")
 	cmd.Flags().Bool("json", false, "JSON output (default)")
+	cmd.Flags().String("format", "", "Output format: json, human, sarif, junit, or github")
+	cmd.Flags().StringSlice("library", nil, "Local paper library to resolve citations against (.jsonl or .bib, repeatable)")
+	cmd.Flags().Bool("offline", false, "Do not use the network: resolve citations from --library files only, skip metadata APIs, and use no LLM but a local Ollama server")
+	cmd.Flags().String("metadata-fixture", "", "JSON file of DOI, arXiv, and PubMed metadata to use instead of the web APIs")
+	cmd.Flags().String("paper-cache", verify.DefaultPaperCachePath, "Path to the resolved paper cache")
+	cmd.Flags().Duration("paper-cache-ttl", verify.DefaultPaperCacheTTL, "How long cached paper lookups stay valid")
+	cmd.Flags().String("repo-cache", verify.DefaultRepoCacheDir, "Directory for bare clones used to check code links")
+	cmd.Flags().Int("workers", verify.DefaultWorkers, "Number of checks to run in parallel")
+	cmd.Flags().Bool("project", false, "Verify every document of the enclosing Quarto project (_quarto.yml) instead of listed files")
+	cmd.Flags().Bool("fix", false, "Rewrite fixable issues in place (redirects, branch code links, paper ID aliases)")
+	cmd.Flags().Bool("dry-run", false, "With --fix, print a unified diff instead of writing files")
+	cmd.Flags().String("baseline", verify.DefaultBaselinePath, "Baseline file of accepted findings")
+	cmd.Flags().Bool("write-baseline", false, "Record current failures and warnings in the baseline file")
+	cmd.Flags().Bool("no-cache", false, "Re-run every check instead of reusing cached results")
+	cmd.Flags().String("result-cache", verify.DefaultResultCachePath, "Path to the verification result cache")
+	cmd.Flags().String("llm-cache", string(llm.CacheReadWrite), "Reuse LLM responses from earlier runs: off, read, or readwrite")
+	cmd.Flags().StringSlice("bibliography", nil, "BibTeX file defining citation keys (default: bibliography from _quarto.yml, repeatable)")
 	return cmd
 }
 
+// fileArgs requires file arguments unless --project is set, and none if it is.
+func fileArgs(cmd *cobra.Command, args []string) error {
+	if project, _ := cmd.Flags().GetBool("project"); project {
+		if len(args) > 0 {
+			return fmt.Errorf("--project takes no file arguments")
+		}
+		return nil
+	}
+	return cobra.MinimumNArgs(1)(cmd, args)
+}
+
+// projectFiles returns the files to check: the arguments, or with --project,
+// every document of the enclosing Quarto project in book order.
+func projectFiles(cmd *cobra.Command, args []string) ([]string, *quarto.Project, error) {
+	if project, _ := cmd.Flags().GetBool("project"); !project {
+		return args, nil, nil
+	}
+	project, err := enclosingProject()
+	if err != nil {
+		return nil, nil, err
+	}
+	if project == nil {
+		return nil, nil, fmt.Errorf("--project: no %s found in this directory or its parents", quarto.ProjectFile)
+	}
+	files, err := project.Files()
+	if err != nil {
+		return nil, nil, err
+	}
+	// Report paths relative to where scribe was run, like explicit arguments
+	if cwd, err := os.Getwd(); err == nil {
+		for i, file := range files {
+			if rel, err := filepath.Rel(cwd, file); err == nil {
+				files[i] = rel
+			}
+		}
+	}
+	return files, project, nil
+}
+
+// enclosingProject loads the Quarto project containing the working
+// directory. It returns nil if there is none.
+func enclosingProject() (*quarto.Project, error) {
+	dir, err := quarto.FindProject(".")
+	if err != nil {
+		return nil, fmt.Errorf("find quarto project: %w", err)
+	}
+	if dir == "" {
+		return nil, nil
+	}
+	return quarto.LoadProject(dir)
+}
+
+// bibliographyPaths returns the BibTeX files named by --bibliography or the
+// config, falling back to the bibliography of the enclosing Quarto project.
+func bibliographyPaths(cmd *cobra.Command, cfg *config.Config) ([]string, error) {
+	paths := stringSliceFlag(cmd, "bibliography", cfg.Paths.Bibliography)
+	if len(paths) > 0 {
+		return paths, nil
+	}
+	project, err := enclosingProject()
+	if err != nil || project == nil {
+		return nil, err
+	}
+	return project.Bibliography, nil
+}
+
+// loadBibliography loads the bibliography from bibliographyPaths.
+// It returns nil if none is set.
+func loadBibliography(cmd *cobra.Command, cfg *config.Config) (*verify.Bibliography, error) {
+	paths, err := bibliographyPaths(cmd, cfg)
+	if err != nil || len(paths) == 0 {
+		return nil, err
+	}
+	return verify.LoadBibliography(paths...)
+}
+
+// newCitationResolver builds the citation resolver chain from verify flags and config.
+// Local libraries are consulted before bipartite, and all lookups are cached on disk.
+func newCitationResolver(cmd *cobra.Command, cfg *config.Config) (*verify.CachedResolver, error) {
+	libraries := stringSliceFlag(cmd, "library", cfg.Paths.Library)
+	offline, _ := cmd.Flags().GetBool("offline")
+	cachePath := stringFlag(cmd, "paper-cache", cfg.Paths.PaperCache)
+	cacheTTL := durationFlag(cmd, "paper-cache-ttl", time.Duration(cfg.Verify.PaperCacheTTL))
+
+	var resolvers []verify.CitationResolver
+	if len(libraries) > 0 {
+		library, err := verify.NewLibraryResolver(libraries...)
+		if err != nil {
+			return nil, fmt.Errorf("load paper library: %w", err)
+		}
+		resolvers = append(resolvers, library)
+	}
+	if !offline {
+		resolvers = append(resolvers, verify.NewBipartiteResolver())
+	}
+	if len(resolvers) == 0 {
+		return nil, fmt.Errorf("--offline requires at least one --library")
+	}
+
+	return verify.NewCachedResolver(verify.NewChainResolver(resolvers...), cachePath, cacheTTL), nil
+}
+
+// setMetadataProvider configures identifier metadata lookups. A fixture
+// replaces the web APIs; offline runs without one skip identifier checks.
+func setMetadataProvider(cmd *cobra.Command, opts *verify.Options) error {
+	offline, _ := cmd.Flags().GetBool("offline")
+	fixture, _ := cmd.Flags().GetString("metadata-fixture")
+	switch {
+	case fixture != "":
+		provider, err := verify.NewFixtureMetadataProvider(fixture)
+		if err != nil {
+			return err
+		}
+		opts.Metadata = provider
+	case offline:
+		if opts.Severities == nil {
+			opts.Severities = make(map[verify.CheckType]verify.Severity)
+		}
+		opts.Severities[verify.CheckTypeIdentifier] = verify.SeverityOff
+	}
+	return nil
+}
+
+// flushVerifyCaches saves the paper, result, and LLM response caches,
+// warning on failure.
+func flushVerifyCaches(resolver *verify.CachedResolver, cache *verify.ResultCache, llmCache *llm.Cache) {
+	if err := resolver.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+	if cache != nil {
+		if err := cache.Flush(); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		}
+	}
+	if err := llmCache.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+	}
+}
+
+// llmBudgetLeft reports whether the active task, if any, has cost budget
+// left for LLM calls, warning if not.
+func llmBudgetLeft(cfg *config.Config) bool {
+	checkpoint, err := status.NewCheckpointStore(cfg.Paths.Checkpoint).Read()
+	if err != nil || checkpoint == nil || !status.NewBlockingDetector(checkpoint).OverBudget() {
+		return true
+	}
+	fmt.Fprintf(os.Stderr, "warning: task %s has spent its %s cost budget; skipping LLM analysis\n",
+		checkpoint.TaskID, output.FormatCurrency(*checkpoint.CostBudgetUSD))
+	return false
+}
+
+// recordLLMUsage adds a run's LLM usage to the active task's metrics,
+// warning once the task's cost budget is spent.
+func recordLLMUsage(cfg *config.Config, usage *llm.Usage) {
+	if usage == nil || usage.Requests == 0 {
+		return
+	}
+	checkpoint, err := status.NewCheckpointStore(cfg.Paths.Checkpoint).RecordLLMUsage(*usage)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: record LLM usage: %v\n", err)
+		return
+	}
+	if checkpoint != nil && status.NewBlockingDetector(checkpoint).OverBudget() {
+		fmt.Fprintf(os.Stderr, "warning: task %s has spent %s of its %s cost budget\n", checkpoint.TaskID,
+			output.FormatCurrency(checkpoint.Metrics.EstimatedCostUSD), output.FormatCurrency(*checkpoint.CostBudgetUSD))
+	}
+}
+
+// newLLMCache opens the LLM response cache in the mode given by --llm-cache
+// or the config. It returns nil if caching is off.
+func newLLMCache(cmd *cobra.Command, cfg *config.Config) (*llm.Cache, error) {
+	mode, err := llm.ParseCacheMode(stringFlag(cmd, "llm-cache", cfg.LLM.Cache))
+	if err != nil {
+		return nil, err
+	}
+	if mode == llm.CacheOff {
+		return nil, nil
+	}
+	return llm.NewCache(cfg.Paths.LLMCache, mode), nil
+}
+
 func queueCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "queue",
 		Short: "Manage candidate queue",
-		Long:  `Add, list, approve, or reject candidates in the review queue.`,
+		Long:  `Add, list, approve, reject, or reopen candidates in the review queue.`,
 	}
 
 	// Add subcommands
@@ -170,6 +633,8 @@ func queueCmd() *cobra.Command {
 	cmd.AddCommand(queueListCmd())
 	cmd.AddCommand(queueApproveCmd())
 	cmd.AddCommand(queueRejectCmd())
+	cmd.AddCommand(queueRestoreCmd("reopen", "Put a decided candidate back up for review", (*queue.CandidateService).Reopen))
+	cmd.AddCommand(queueRestoreCmd("revert", "Undo a mistaken approval or rejection", (*queue.CandidateService).Revert))
 	cmd.AddCommand(queueGetCmd())
 	cmd.AddCommand(queueStatsCmd())
 
@@ -182,12 +647,21 @@ func queueAddCmd() *cobra.Command {
 		Short: "Add a candidate to the queue",
 		Long: `Add a candidate to the review queue.
 
-Types: paper, repo, code-location, concept`,
+Types: paper, repo, code-location, concept
+
+A paper, repo, or code location that is already pending or approved is not
+queued twice: the new discovery is merged into the existing candidate. Papers
+match by S2 ID or DOI, repos by URL ignoring case, scheme, and a .git suffix,
+and code locations by file and commit with overlapping line ranges.`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			ctx := newQueueContext(cmd)
+			ctx, err := newQueueContext(cmd)
+			if err != nil {
+				return err
+			}
 			candidateType := queue.CandidateType(args[0])
 			s2ID, _ := cmd.Flags().GetString("s2-id")
+			doi, _ := cmd.Flags().GetString("doi")
 			repoURL, _ := cmd.Flags().GetString("repo")
 			filePath, _ := cmd.Flags().GetString("file")
 			lines, _ := cmd.Flags().GetString("lines")
@@ -205,11 +679,15 @@ Types: paper, repo, code-location, concept`,
 
 			switch candidateType {
 			case queue.CandidateTypePaper:
+				if s2ID == "" && doi == "" {
+					return fmt.Errorf("--s2-id or --doi is required for paper type")
+				}
 				if s2ID == "" {
-					return fmt.Errorf("--s2-id is required for paper type")
+					s2ID = "DOI:" + doi // Semantic Scholar accepts DOIs as paper IDs
 				}
 				candidate.PaperData = &queue.PaperData{
 					S2ID:           s2ID,
+					DOI:            doi,
 					RelevanceNotes: notes,
 				}
 			case queue.CandidateTypeRepo:
@@ -247,18 +725,28 @@ Types: paper, repo, code-location, concept`,
 				return fmt.Errorf("unknown type: %s", candidateType)
 			}
 
-			if err := ctx.service.Add(candidate); err != nil {
+			result, err := ctx.service.Add(candidate)
+			if err != nil {
 				return fmt.Errorf("failed to add candidate: %w", err)
 			}
 
 			if ctx.jsonMode {
-				return ctx.formatter.JSON(map[string]string{"status": "added", "id": candidate.ID})
+				status := "added"
+				if result.Merged {
+					status = "merged"
+				}
+				return ctx.formatter.JSON(map[string]string{"status": status, "id": result.ID})
 			}
-			ctx.formatter.Println("Added candidate: %s", candidate.ID)
+			if result.Merged {
+				ctx.formatter.Println("Already queued; merged into candidate: %s", result.ID)
+				return nil
+			}
+			ctx.formatter.Println("Added candidate: %s", result.ID)
 			return nil
 		},
 	}
 	cmd.Flags().String("s2-id", "", "Semantic Scholar ID (for paper type)")
+	cmd.Flags().String("doi", "", "DOI (for paper type)")
 	cmd.Flags().String("repo", "", "Repository URL")
 	cmd.Flags().String("file", "", "File path (for code-location type)")
 	cmd.Flags().String("lines", "", "Line range (e.g., 100-150)")
@@ -275,7 +763,10 @@ func queueListCmd() *cobra.Command {
 		Use:   "list",
 		Short: "List candidates in the queue",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			ctx := newQueueContext(cmd)
+			ctx, err := newQueueContext(cmd)
+			if err != nil {
+				return err
+			}
 			statusStr, _ := cmd.Flags().GetString("status")
 			typeStr, _ := cmd.Flags().GetString("type")
 
@@ -330,24 +821,55 @@ func queueApproveCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "approve [id]",
 		Short: "Approve a candidate",
+		Long:  "Approve a candidate. The ID may be abbreviated to any prefix that matches only one candidate.",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			ctx := newQueueContext(cmd)
-			id := args[0]
+			ctx, err := newQueueContext(cmd)
+			if err != nil {
+				return err
+			}
+			id, err := ctx.resolveID(args[0])
+			if err != nil {
+				return err
+			}
 			notes, _ := cmd.Flags().GetString("notes")
 
+			syncBib := ctx.cfg.Queue.SyncBibliography
+			if cmd.Flags().Changed("sync-bib") {
+				syncBib, _ = cmd.Flags().GetBool("sync-bib")
+			}
+			if syncBib {
+				resolver, err := setQueueBibliography(cmd, ctx)
+				if err != nil {
+					return err
+				}
+				defer func() {
+					if err := resolver.Flush(); err != nil {
+						fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+					}
+				}()
+			}
+
 			if err := ctx.service.Approve(id, "human", notes); err != nil {
 				return fmt.Errorf("failed to approve: %w", err)
 			}
 
+			result := map[string]string{"status": "approved", "id": id}
+			if candidate, err := ctx.service.Get(id); err == nil && candidate != nil && candidate.BibKey != nil {
+				result["bib_key"] = *candidate.BibKey
+			}
 			if ctx.jsonMode {
-				return ctx.formatter.JSON(map[string]string{"status": "approved", "id": id})
+				return ctx.formatter.JSON(result)
 			}
 			ctx.formatter.Println("Approved: %s", id)
+			if key, ok := result["bib_key"]; ok {
+				ctx.formatter.Println("Bibliography key: %s", key)
+			}
 			return nil
 		},
 	}
 	cmd.Flags().String("notes", "", "Approval notes")
+	cmd.Flags().Bool("sync-bib", false, "Add an approved paper to the bibliography (default: queue.sync_bibliography)")
 	cmd.Flags().Bool("json", false, "JSON output (default)")
 	cmd.Flags().Bool("human", false, "Human-readable output")
 	return cmd
@@ -357,10 +879,17 @@ func queueRejectCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "reject [id]",
 		Short: "Reject a candidate",
+		Long:  "Reject a candidate. The ID may be abbreviated to any prefix that matches only one candidate.",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			ctx := newQueueContext(cmd)
-			id := args[0]
+			ctx, err := newQueueContext(cmd)
+			if err != nil {
+				return err
+			}
+			id, err := ctx.resolveID(args[0])
+			if err != nil {
+				return err
+			}
 			reason, _ := cmd.Flags().GetString("reason")
 
 			if err := ctx.service.Reject(id, "human", reason); err != nil {
@@ -380,14 +909,64 @@ func queueRejectCmd() *cobra.Command {
 	return cmd
 }
 
+// queueRestoreCmd builds the reopen and revert commands, which make an
+// approved or rejected candidate pending again.
+func queueRestoreCmd(name, short string, restore func(*queue.CandidateService, string, string, string) error) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   name + " [id]",
+		Short: short,
+		Long: short + `.
+
+The candidate becomes pending and its review is cleared. A rejected candidate
+is also removed from the rejected file, so that it can be discovered again.
+Papers already added to the bibliography or bipartite are kept. The change is
+recorded in the candidate's history (see queue get).
+
+The ID may be abbreviated to any prefix that matches only one candidate.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, err := newQueueContext(cmd)
+			if err != nil {
+				return err
+			}
+			id, err := ctx.resolveID(args[0])
+			if err != nil {
+				return err
+			}
+			note, _ := cmd.Flags().GetString("note")
+
+			if err := restore(ctx.service, id, "human", note); err != nil {
+				return fmt.Errorf("failed to %s: %w", name, err)
+			}
+
+			if ctx.jsonMode {
+				return ctx.formatter.JSON(map[string]string{"status": string(queue.CandidateStatusPending), "id": id})
+			}
+			ctx.formatter.Println("Pending again: %s", id)
+			return nil
+		},
+	}
+	cmd.Flags().String("note", "", "Why the decision is reopened or reverted")
+	cmd.Flags().Bool("json", false, "JSON output (default)")
+	cmd.Flags().Bool("human", false, "Human-readable output")
+	return cmd
+}
+
 func queueGetCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "get [id]",
 		Short: "Get details of a candidate",
+		Long:  "Get details of a candidate. The ID may be abbreviated to any prefix that matches only one candidate, e.g. c-20261016.",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			ctx := newQueueContext(cmd)
-			id := args[0]
+			ctx, err := newQueueContext(cmd)
+			if err != nil {
+				return err
+			}
+			id, err := ctx.resolveID(args[0])
+			if err != nil {
+				return err
+			}
 
 			candidate, err := ctx.service.Get(id)
 			if err != nil {
@@ -397,14 +976,40 @@ func queueGetCmd() *cobra.Command {
 				return fmt.Errorf("candidate not found: %s", id)
 			}
 
+			history, err := ctx.service.History(id)
+			if err != nil {
+				return fmt.Errorf("failed to read history: %w", err)
+			}
+
 			if ctx.jsonMode {
-				return ctx.formatter.JSON(candidate)
+				return ctx.formatter.JSON(struct {
+					*queue.Candidate
+					History []queue.Event `json:"history"`
+				}{candidate, history})
 			}
 
 			ctx.formatter.Println("ID: %s", candidate.ID)
 			ctx.formatter.Println("Type: %s", candidate.Type)
 			ctx.formatter.Println("Status: %s", candidate.Status)
 			ctx.formatter.Println("Discovered: %s by %s", output.FormatTime(candidate.DiscoveredAt), candidate.DiscoveredBy)
+			for _, d := range candidate.Discoveries {
+				ctx.formatter.Println("Also discovered: %s by %s (%s)", output.FormatTime(d.DiscoveredAt), d.DiscoveredBy, d.DiscoveryContext)
+			}
+			if len(history) > 0 {
+				ctx.formatter.Println("")
+				ctx.formatter.Println("History:")
+			}
+			for _, e := range history {
+				line := fmt.Sprintf("  %s  %-7s %s", output.FormatTime(e.At), e.Action, e.To)
+				if e.From != "" {
+					line = fmt.Sprintf("  %s  %-7s %s -> %s", output.FormatTime(e.At), e.Action, e.From, e.To)
+				}
+				line += " by " + e.By
+				if e.Note != "" {
+					line += ": " + e.Note
+				}
+				ctx.formatter.Println("%s", line)
+			}
 			return nil
 		},
 	}
@@ -418,7 +1023,10 @@ func queueStatsCmd() *cobra.Command {
 		Use:   "stats",
 		Short: "Show queue statistics",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			ctx := newQueueContext(cmd)
+			ctx, err := newQueueContext(cmd)
+			if err != nil {
+				return err
+			}
 
 			stats, err := ctx.service.Stats()
 			if err != nil {
@@ -447,6 +1055,804 @@ func queueStatsCmd() *cobra.Command {
 	return cmd
 }
 
+func cacheCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the verification result cache",
+		Long: `Inspect or prune the results cached by scribe verify.
+
+Passing results are reused until their check type's TTL runs out: code links
+pinned to a commit SHA never expire, URLs expire after 24h, and citations and
+claims after 7 days. Editing a file invalidates every result for that file.`,
+	}
+	cmd.PersistentFlags().String("result-cache", verify.DefaultResultCachePath, "Path to the verification result cache")
+
+	cmd.AddCommand(cacheStatsCmd())
+	cmd.AddCommand(cachePruneCmd())
+
+	return cmd
+}
+
+func cacheStatsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Show result cache statistics",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			jsonMode := getOutputMode(cmd, true)
+			formatter := output.NewFormatter(jsonMode)
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			path := stringFlag(cmd, "result-cache", cfg.Paths.ResultCache)
+
+			stats, err := verify.NewResultCache(path, nil).Stats()
+			if err != nil {
+				return fmt.Errorf("failed to get cache stats: %w", err)
+			}
+
+			if jsonMode {
+				return formatter.JSON(stats)
+			}
+
+			formatter.Header("Result Cache")
+			formatter.Println("Path: %s (%d bytes)", stats.Path, stats.SizeBytes)
+			formatter.Println("Entries: %d", stats.Entries)
+			formatter.Println("Expired: %d", stats.Expired)
+			formatter.Println("")
+			formatter.Println("By Type:")
+			for checkType, count := range stats.ByType {
+				formatter.Println("  %s: %d", checkType, count)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().Bool("human", false, "Human-readable output")
+	cmd.Flags().Bool("json", false, "JSON output (default)")
+	return cmd
+}
+
+func cachePruneCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove expired results from the cache",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			jsonMode := getOutputMode(cmd, true)
+			formatter := output.NewFormatter(jsonMode)
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			path := stringFlag(cmd, "result-cache", cfg.Paths.ResultCache)
+			all, _ := cmd.Flags().GetBool("all")
+
+			removed, err := verify.NewResultCache(path, nil).Prune(all)
+			if err != nil {
+				return fmt.Errorf("failed to prune cache: %w", err)
+			}
+
+			if jsonMode {
+				return formatter.JSON(map[string]int{"removed": removed})
+			}
+			formatter.Println("Removed: %d cached results", removed)
+			return nil
+		},
+	}
+	cmd.Flags().Bool("all", false, "Remove every cached result, not just expired ones")
+	cmd.Flags().Bool("human", false, "Human-readable output")
+	cmd.Flags().Bool("json", false, "JSON output (default)")
+	return cmd
+}
+
+func bibCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bib",
+		Short: "Lint and normalize BibTeX bibliographies",
+		Long: `Lint, format, or deduplicate BibTeX files.
+
+Files default to the bibliography in the config, then the bibliography of the
+enclosing Quarto project. Renaming or removing an entry also rewrites its
+citations in the project's documents.`,
+	}
+
+	cmd.AddCommand(bibLintCmd())
+	cmd.AddCommand(bibFmtCmd())
+	cmd.AddCommand(bibDedupeCmd())
+	cmd.AddCommand(bibSyncCmd())
+
+	return cmd
+}
+
+// bibFilePaths returns the BibTeX files named by args, or the default
+// bibliography if there are none.
+func bibFilePaths(cmd *cobra.Command, args []string) ([]string, error) {
+	if len(args) > 0 {
+		return args, nil
+	}
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := bibliographyPaths(cmd, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no BibTeX files given and no bibliography configured")
+	}
+	if cwd, err := os.Getwd(); err == nil {
+		for i, path := range paths {
+			if rel, err := filepath.Rel(cwd, path); err == nil {
+				paths[i] = rel
+			}
+		}
+	}
+	return paths, nil
+}
+
+// loadBibFiles parses the BibTeX files named by args, or the default
+// bibliography if there are none.
+func loadBibFiles(cmd *cobra.Command, args []string) ([]*bibtex.File, error) {
+	paths, err := bibFilePaths(cmd, args)
+	if err != nil {
+		return nil, err
+	}
+	return parseBibFiles(paths)
+}
+
+// lockBibFiles locks the BibTeX files named by args, or the default
+// bibliography, and then parses them, so that they can be rewritten without
+// losing entries that scribe queue approve appends meanwhile. Files are
+// locked in sorted order so concurrent callers cannot deadlock. The caller
+// must call unlock.
+func lockBibFiles(cmd *cobra.Command, args []string, exclusive bool) (files []*bibtex.File, unlock func(), err error) {
+	paths, err := bibFilePaths(cmd, args)
+	if err != nil {
+		return nil, nil, err
+	}
+	sorted := make([]string, 0, len(paths))
+	for _, path := range paths {
+		// Do not leave lock files behind for mistyped paths
+		if _, err := os.Stat(path); err != nil {
+			return nil, nil, err
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		sorted = append(sorted, abs)
+	}
+	sort.Strings(sorted)
+	sorted = slices.Compact(sorted)
+
+	var unlocks []func()
+	unlock = func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
+	for _, path := range sorted {
+		u, err := queue.LockFile(path, exclusive)
+		if err != nil {
+			unlock()
+			return nil, nil, err
+		}
+		unlocks = append(unlocks, u)
+	}
+	if files, err = parseBibFiles(paths); err != nil {
+		unlock()
+		return nil, nil, err
+	}
+	return files, unlock, nil
+}
+
+// parseBibFiles parses BibTeX files.
+func parseBibFiles(paths []string) ([]*bibtex.File, error) {
+	files := make([]*bibtex.File, 0, len(paths))
+	for _, path := range paths {
+		f, err := bibtex.ParseFile(path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// replaceFile atomically replaces a file's content. The caller holds the
+// file's lock.
+func replaceFile(path string, content []byte) error {
+	return queue.WriteFileAtomic(path, func(w io.Writer) error {
+		_, err := w.Write(content)
+		return err
+	})
+}
+
+// renameProjectCitations rewrites citations of renamed keys in the documents
+// of the enclosing Quarto project. It returns the number of citations changed.
+func renameProjectCitations(project *quarto.Project, renames map[string]string) (int, error) {
+	files, err := project.Files()
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, file := range files {
+		n, err := renameCitations(file, renames)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// renameCitations rewrites citations of renamed keys in one document, holding
+// its lock from read to write.
+func renameCitations(file string, renames map[string]string) (int, error) {
+	unlock, err := queue.LockFile(file, true)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return 0, fmt.Errorf("read %s: %w", file, err)
+	}
+	updated, n := qmd.RenameCitations(file, content, renames)
+	if n == 0 {
+		return 0, nil
+	}
+	if err := replaceFile(file, updated); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// citingProject returns the enclosing Quarto project if its bibliography
+// lists every one of files, so that its citations refer to their keys.
+// Otherwise it warns, ending the warning with consequence, and returns nil.
+func citingProject(files []*bibtex.File, consequence string) (*quarto.Project, error) {
+	project, err := enclosingProject()
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		fmt.Fprintf(os.Stderr, "warning: no %s found; %s\n", quarto.ProjectFile, consequence)
+		return nil, nil
+	}
+	for _, f := range files {
+		if !project.UsesBibliography(f.Path) {
+			fmt.Fprintf(os.Stderr, "warning: %s is not in the bibliography of %s; %s\n",
+				f.Path, filepath.Join(project.Dir, quarto.ProjectFile), consequence)
+			return nil, nil
+		}
+	}
+	return project, nil
+}
+
+func bibLintCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lint [files...]",
+		Short: "Check BibTeX files for errors and style problems",
+		Long: `Check BibTeX files for unbalanced braces, duplicate keys, duplicate entries
+(same DOI or normalized title), missing required fields, malformed page
+ranges, and keys that do not follow the authorYEAR style.
+
+Exits 1 if any error-level issue is found.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := getReportFormat(cmd)
+			if err != nil {
+				return err
+			}
+			files, err := loadBibFiles(cmd, args)
+			if err != nil {
+				return err
+			}
+
+			issues := bibtex.Lint(files...)
+			errors := 0
+			for _, issue := range issues {
+				if issue.Severity == bibtex.SeverityError {
+					errors++
+				}
+			}
+
+			switch format {
+			case output.FormatJSON:
+				if issues == nil {
+					issues = []bibtex.Issue{}
+				}
+				if err := output.NewFormatter(true).JSON(map[string]any{
+					"files":  len(files),
+					"issues": issues,
+				}); err != nil {
+					return err
+				}
+			case output.FormatHuman:
+				formatter := output.NewFormatter(false)
+				formatter.Header("BibTeX Lint")
+				for _, issue := range issues {
+					status := output.StatusWarning
+					if issue.Severity == bibtex.SeverityError {
+						status = output.StatusError
+					}
+					formatter.Println("%s %s:%d [%s] %s", output.FormatStatus(status), issue.File, issue.Line, issue.Rule, issue.Message)
+				}
+				formatter.Println("")
+				formatter.Println("%s, %s, %s",
+					output.FormatCount(len(files), "file", "files"),
+					output.FormatCount(errors, "error", "errors"),
+					output.FormatCount(len(issues)-errors, "warning", "warnings"))
+			default:
+				findings := bibtex.Findings(issues)
+				findings.Version = Version
+				if err := output.WriteFindings(os.Stdout, format, findings); err != nil {
+					return fmt.Errorf("output error: %w", err)
+				}
+			}
+
+			if errors > 0 {
+				os.Exit(1)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringSlice("bibliography", nil, "BibTeX file to check when no files are given (repeatable)")
+	cmd.Flags().Bool("human", false, "Human-readable output")
+	cmd.Flags().Bool("json", false, "JSON output (default)")
+	cmd.Flags().String("format", "", "Output format: json, human, sarif, junit, or github")
+	return cmd
+}
+
+func bibFmtCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fmt [files...]",
+		Short: "Rewrite BibTeX files in canonical form",
+		Long: `Rewrite BibTeX entries with fields in canonical order, braces instead of
+quotes, and page ranges written first--last. With --rename-keys, keys are
+also renamed to the authorYEAR style and updated in the citations of the
+enclosing Quarto project; keys are kept unless that project's bibliography
+lists every file being formatted.
+
+Comments, @string definitions, and entries with syntax errors are kept as
+written, and formatting is idempotent, so diffs only show real changes.
+With --check, nothing is written and scribe exits 1 if any file would change.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			jsonMode := getOutputMode(cmd, true)
+			formatter := output.NewFormatter(jsonMode)
+			check, _ := cmd.Flags().GetBool("check")
+			renameKeys, _ := cmd.Flags().GetBool("rename-keys")
+
+			files, unlock, err := lockBibFiles(cmd, args, !check)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+			for _, f := range files {
+				for _, e := range f.Errors {
+					fmt.Fprintf(os.Stderr, "warning: %s:%d: %s (entry left unformatted)\n", f.Path, e.Line, e.Msg)
+				}
+			}
+
+			var renames map[string]string
+			var project *quarto.Project
+			if renameKeys {
+				renames = bibtex.CanonicalKeys(files...)
+				if len(renames) > 0 {
+					if project, err = citingProject(files, "keeping keys so citations stay valid"); err != nil {
+						return err
+					}
+					if project == nil {
+						renames = nil
+					}
+				}
+			}
+
+			changed := []string{}
+			for _, f := range files {
+				formatted := f.Format(renames)
+				if bytes.Equal(formatted, f.Source) {
+					continue
+				}
+				changed = append(changed, f.Path)
+				if !check {
+					if err := replaceFile(f.Path, formatted); err != nil {
+						return err
+					}
+				}
+			}
+
+			citations := 0
+			if !check && len(renames) > 0 {
+				if citations, err = renameProjectCitations(project, renames); err != nil {
+					return err
+				}
+			}
+
+			if jsonMode {
+				if err := formatter.JSON(map[string]any{
+					"changed":   changed,
+					"renamed":   renames,
+					"citations": citations,
+				}); err != nil {
+					return err
+				}
+			} else {
+				verb := "Formatted"
+				if check {
+					verb = "Would format"
+				}
+				for _, path := range changed {
+					formatter.Println("%s %s", verb, path)
+				}
+				olds := make([]string, 0, len(renames))
+				for old := range renames {
+					olds = append(olds, old)
+				}
+				sort.Strings(olds)
+				for _, old := range olds {
+					formatter.Println("  %s -> %s", old, renames[old])
+				}
+				if citations > 0 {
+					formatter.Println("Updated %s", output.FormatCount(citations, "citation", "citations"))
+				}
+				if len(changed) == 0 {
+					formatter.Println("%s already formatted", output.FormatCount(len(files), "file", "files"))
+				}
+			}
+
+			if check && len(changed) > 0 {
+				os.Exit(1)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().Bool("check", false, "List files that would change and exit 1 instead of writing")
+	cmd.Flags().Bool("rename-keys", false, "Rename keys to the authorYEAR style and update the project's citations")
+	cmd.Flags().StringSlice("bibliography", nil, "BibTeX file to format when no files are given (repeatable)")
+	cmd.Flags().Bool("human", false, "Human-readable output")
+	cmd.Flags().Bool("json", false, "JSON output (default)")
+	return cmd
+}
+
+func bibDedupeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dedupe [files...]",
+		Short: "Merge duplicate BibTeX entries",
+		Long: `Remove entries with the same DOI or normalized title as an earlier entry,
+copying any fields the kept entry lacks. Citations of removed keys in the
+enclosing Quarto project are pointed at the kept entry, if the project's
+bibliography lists every file being deduplicated.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			jsonMode := getOutputMode(cmd, true)
+			formatter := output.NewFormatter(jsonMode)
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+			files, unlock, err := lockBibFiles(cmd, args, !dryRun)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+
+			dups, changed := bibtex.Dedupe(files...)
+			renames := make(map[string]string)
+			type merge struct {
+				Key    string `json:"key"`
+				File   string `json:"file"`
+				Line   int    `json:"line"`
+				Into   string `json:"into"`
+				Reason string `json:"reason"`
+			}
+			merges := []merge{}
+			for _, dup := range dups {
+				merges = append(merges, merge{dup.Entry.Key, dup.Entry.File, dup.Entry.Line, dup.Of.Key, dup.Reason})
+				if dup.Entry.Key != dup.Of.Key {
+					renames[dup.Entry.Key] = dup.Of.Key
+				}
+			}
+
+			citations := 0
+			if !dryRun {
+				for _, f := range files {
+					if content, ok := changed[f.Path]; ok {
+						if err := replaceFile(f.Path, content); err != nil {
+							return err
+						}
+					}
+				}
+				if len(renames) > 0 {
+					project, err := citingProject(files, "update citations of removed keys by hand")
+					if err != nil {
+						return err
+					}
+					if project != nil {
+						if citations, err = renameProjectCitations(project, renames); err != nil {
+							return err
+						}
+					}
+				}
+			}
+
+			if jsonMode {
+				return formatter.JSON(map[string]any{
+					"merged":    merges,
+					"dry_run":   dryRun,
+					"citations": citations,
+				})
+			}
+			verb := "Merged"
+			if dryRun {
+				verb = "Would merge"
+			}
+			for _, m := range merges {
+				formatter.Println("%s %s (%s:%d) into %s: same %s", verb, m.Key, m.File, m.Line, m.Into, m.Reason)
+			}
+			if citations > 0 {
+				formatter.Println("Updated %s", output.FormatCount(citations, "citation", "citations"))
+			}
+			if len(merges) == 0 {
+				formatter.Println("No duplicate entries")
+			}
+			return nil
+		},
+	}
+	cmd.Flags().Bool("dry-run", false, "List duplicates without changing any file")
+	cmd.Flags().StringSlice("bibliography", nil, "BibTeX file to deduplicate when no files are given (repeatable)")
+	cmd.Flags().Bool("human", false, "Human-readable output")
+	cmd.Flags().Bool("json", false, "JSON output (default)")
+	return cmd
+}
+
+func bibSyncCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Add approved papers to the bibliography",
+		Long: `Reconcile approved paper candidates against the bibliography. Papers with
+no entry (matched by recorded key, DOI, or title) are appended with an
+authorYEAR key, using metadata from the paper library and bipartite when
+available. Each paper's key is recorded in the queue.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, err := newQueueContext(cmd)
+			if err != nil {
+				return err
+			}
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+			resolver, err := setQueueBibliography(cmd, ctx)
+			if err != nil {
+				return err
+			}
+			results, err := ctx.service.SyncBibliography(dryRun)
+			if err := resolver.Flush(); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to sync bibliography: %w", err)
+			}
+
+			if ctx.jsonMode {
+				if results == nil {
+					results = []queue.BibSyncResult{}
+				}
+				return ctx.formatter.JSON(map[string]any{"papers": results, "dry_run": dryRun})
+			}
+			added := 0
+			for _, r := range results {
+				if r.Added {
+					added++
+					verb := "Added"
+					if dryRun {
+						verb = "Would add"
+					}
+					ctx.formatter.Println("%s %s (%s)", verb, r.Key, r.CandidateID)
+				}
+			}
+			ctx.formatter.Println("%s, %d already in the bibliography",
+				output.FormatCount(len(results), "approved paper", "approved papers"), len(results)-added)
+			return nil
+		},
+	}
+	cmd.Flags().Bool("dry-run", false, "Show entries that would be added without writing")
+	cmd.Flags().StringSlice("bibliography", nil, "BibTeX file to add papers to (default: first configured bibliography)")
+	cmd.Flags().Bool("human", false, "Human-readable output")
+	cmd.Flags().Bool("json", false, "JSON output (default)")
+	return cmd
+}
+
+func papersCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "papers",
+		Short: "Store and search the full text of cited papers",
+		Long: `Keep the full text of cited papers in a local store (default .scribe/papers).
+
+PDFs and plain text files are split into pages and paragraphs and indexed,
+so passages can be searched and quoted offline. sweep's claim-consistency
+check uses the store when it exists.`,
+	}
+	cmd.PersistentFlags().String("papers", papers.DefaultDir, "Path to the paper store")
+
+	cmd.AddCommand(papersAddCmd())
+	cmd.AddCommand(papersSearchCmd())
+	cmd.AddCommand(papersShowCmd())
+
+	return cmd
+}
+
+// openPaperStore opens the store named by --papers or the config.
+func openPaperStore(cmd *cobra.Command) (*papers.Store, error) {
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return papers.NewStore(stringFlag(cmd, "papers", cfg.Paths.Papers)), nil
+}
+
+func papersAddCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "add <paper-id> <file>",
+		Short: "Add the full text of a paper",
+		Long: `Extract the text of a PDF or plain text file and store it under the paper's
+ID, replacing any text stored for it before. Form feeds split plain text
+into pages. Scanned PDFs without a text layer cannot be added.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			jsonMode := getOutputMode(cmd, true)
+			formatter := output.NewFormatter(jsonMode)
+			store, err := openPaperStore(cmd)
+			if err != nil {
+				return err
+			}
+
+			paper, err := store.Add(args[0], args[1])
+			if err != nil {
+				return fmt.Errorf("failed to add paper: %w", err)
+			}
+
+			if jsonMode {
+				return formatter.JSON(map[string]any{
+					"id":         paper.ID,
+					"format":     paper.Format,
+					"pages":      len(paper.Pages),
+					"paragraphs": paper.Paragraphs(),
+					"sha256":     paper.SHA256,
+				})
+			}
+			formatter.Println("Added %s: %s, %s", paper.ID,
+				output.FormatCount(len(paper.Pages), "page", "pages"),
+				output.FormatCount(paper.Paragraphs(), "paragraph", "paragraphs"))
+			return nil
+		},
+	}
+	cmd.Flags().Bool("human", false, "Human-readable output")
+	cmd.Flags().Bool("json", false, "JSON output (default)")
+	return cmd
+}
+
+func papersSearchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "search <query>...",
+		Short: "Search stored papers for passages",
+		Long:  `Find the paragraphs of stored papers best matching the query, ranked by BM25.`,
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			jsonMode := getOutputMode(cmd, true)
+			formatter := output.NewFormatter(jsonMode)
+			store, err := openPaperStore(cmd)
+			if err != nil {
+				return err
+			}
+			paperID, _ := cmd.Flags().GetString("paper")
+			limit, _ := cmd.Flags().GetInt("limit")
+
+			hits, err := store.Search(strings.Join(args, " "), paperID, limit)
+			if err != nil {
+				return fmt.Errorf("failed to search papers: %w", err)
+			}
+
+			if jsonMode {
+				if hits == nil {
+					hits = []papers.Hit{}
+				}
+				return formatter.JSON(hits)
+			}
+			if len(hits) == 0 {
+				formatter.Println("No matching passages")
+				return nil
+			}
+			for _, hit := range hits {
+				formatter.Println("%s, page %d, paragraph %d (%.2f)", hit.PaperID, hit.Page, hit.Paragraph, hit.Score)
+				formatter.Println("  %s", hit.Text)
+				formatter.Println("")
+			}
+			return nil
+		},
+	}
+	cmd.Flags().String("paper", "", "Only search this paper")
+	cmd.Flags().Int("limit", 10, "Maximum number of passages (0 for all)")
+	cmd.Flags().Bool("human", false, "Human-readable output")
+	cmd.Flags().Bool("json", false, "JSON output (default)")
+	return cmd
+}
+
+func papersShowCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show <paper-id>",
+		Short: "Show the stored text of a paper",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			jsonMode := getOutputMode(cmd, false)
+			formatter := output.NewFormatter(jsonMode)
+			store, err := openPaperStore(cmd)
+			if err != nil {
+				return err
+			}
+			pageNum, _ := cmd.Flags().GetInt("page")
+
+			paper, err := store.Get(args[0])
+			if err != nil {
+				return err
+			}
+			if paper == nil {
+				return fmt.Errorf("paper not found: %s", args[0])
+			}
+			if pageNum > 0 {
+				if pageNum > len(paper.Pages) {
+					return fmt.Errorf("%s has %s", paper.ID, output.FormatCount(len(paper.Pages), "page", "pages"))
+				}
+				paper.Pages = paper.Pages[pageNum-1 : pageNum]
+			}
+
+			if jsonMode {
+				return formatter.JSON(paper)
+			}
+			formatter.Header(paper.ID)
+			formatter.Println("Source: %s (%s)", paper.Source, paper.Format)
+			for _, page := range paper.Pages {
+				formatter.Println("")
+				formatter.Println("--- Page %d ---", page.Number)
+				for i, text := range page.Paragraphs {
+					formatter.Println("[%d] %s", i+1, text)
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().Int("page", 0, "Only show this page")
+	cmd.Flags().Bool("human", false, "Human-readable output (default)")
+	cmd.Flags().Bool("json", false, "JSON output")
+	return cmd
+}
+
+// setEvidenceSources configures claim-consistency checking: snippets of
+// cited papers from --snippets or the config and the paper store, judged by
+// the configured LLM. Without them, citations are flagged for manual review.
+func setEvidenceSources(cmd *cobra.Command, cfg *config.Config, cache *llm.Cache, opts *sweep.Options) error {
+	paths := stringSliceFlag(cmd, "snippets", cfg.Paths.Snippets)
+	if papers.IsStore(cfg.Paths.Papers) && !slices.Contains(paths, cfg.Paths.Papers) {
+		paths = append(paths, cfg.Paths.Papers)
+	}
+	if len(paths) > 0 {
+		source, err := sweep.NewSnippetSource(paths...)
+		if err != nil {
+			return err
+		}
+		opts.Snippets = source
+	}
+	if opts.Snippets != nil && cfg.LLMEnabled() && llmBudgetLeft(cfg) {
+		llmConfig := cfg.LLMClientConfig()
+		llmConfig.Cache = cache
+		client, err := llm.NewClientFromConfig(llmConfig)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v; claim-consistency needs manual review\n", err)
+			return nil
+		}
+		opts.Judge = client
+	}
+	return nil
+}
+
 func statusCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "status",
@@ -468,7 +1874,12 @@ Shows:
 				humanOutput = true
 			}
 
-			store := status.NewCheckpointStore("")
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+
+			store := status.NewCheckpointStore(cfg.Paths.Checkpoint)
 			checkpoint, err := store.Read()
 			if err != nil {
 				return fmt.Errorf("failed to read checkpoint: %w", err)
@@ -500,49 +1911,70 @@ Checks:
 - Claim-consistency: Do cited papers still support the claims?
 - Repo-freshness: Are referenced repos still maintained (< 2 years)?
 - Code-link validity: Do file paths and line ranges still exist?
-- Coverage gaps: Are there undocumented techniques?`,
-		Args: cobra.MinimumNArgs(1),
+- Coverage gaps: Are there undocumented techniques?
+
+Claim-consistency pairs each cited sentence with the papers it cites and asks
+the configured LLM whether their abstracts and passages (from --snippets or
+paths.snippets) support it, quoting the evidence. Without snippets or an LLM,
+citations are flagged for manual review.
+
+With --project, every chapter, part, appendix, and included file listed in
+_quarto.yml is swept in book order.`,
+		Args: fileArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			humanOutput, _ := cmd.Flags().GetBool("human")
-			jsonOutput, _ := cmd.Flags().GetBool("json")
 			checkStr, _ := cmd.Flags().GetString("check")
-
-			if !humanOutput && !jsonOutput {
-				jsonOutput = true
+			format, err := getReportFormat(cmd)
+			if err != nil {
+				return err
 			}
 
-			opts := sweep.DefaultOptions()
+			cfg, err := loadConfig(cmd)
+			if err != nil {
+				return err
+			}
+			opts, err := cfg.SweepOptions()
+			if err != nil {
+				return err
+			}
+			opts.Mirror = verify.NewRepoMirror(stringFlag(cmd, "repo-cache", cfg.Paths.RepoCache))
+			llmCache, err := newLLMCache(cmd, cfg)
+			if err != nil {
+				return err
+			}
+			if err := setEvidenceSources(cmd, cfg, llmCache, &opts); err != nil {
+				return err
+			}
 
 			// Filter by specific check if requested
 			if checkStr != "" {
-				var checkType sweep.CheckType
-				switch checkStr {
-				case "repo-freshness":
-					checkType = sweep.CheckTypeRepoFreshness
-				case "claim-consistency":
-					checkType = sweep.CheckTypeClaimConsistency
-				case "code-links":
-					checkType = sweep.CheckTypeCodeLinks
-				case "coverage":
-					checkType = sweep.CheckTypeCoverage
-				default:
-					return fmt.Errorf("unknown check type: %s", checkStr)
+				checkType, err := sweep.ParseCheckType(checkStr)
+				if err != nil {
+					return err
 				}
 				opts.Checks = []sweep.CheckType{checkType}
 			}
 
-			report, err := sweep.SweepFiles(args, opts)
+			files, _, err := projectFiles(cmd, args)
+			if err != nil {
+				return err
+			}
+			report, err := sweep.SweepFiles(files, opts)
 			if err != nil {
 				return fmt.Errorf("sweep failed: %w", err)
 			}
+			if err := llmCache.Flush(); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+			}
+			recordLLMUsage(cfg, report.LLMUsage)
 
-			formatter := output.NewFormatter(jsonOutput)
+			formatter := output.NewFormatter(format == output.FormatJSON)
 
-			if jsonOutput {
+			switch format {
+			case output.FormatJSON:
 				if err := formatter.JSON(report); err != nil {
 					return fmt.Errorf("output error: %w", err)
 				}
-			} else {
+			case output.FormatHuman:
 				formatter.Header("Sweep Report")
 				formatter.Println("Files: %d", len(report.ContentFiles))
 				formatter.Println("Checks: %v", report.ChecksRun)
@@ -553,6 +1985,10 @@ Checks:
 				formatter.Println("  OK: %s %d", output.FormatStatus(output.StatusOK), report.Summary.OK)
 				formatter.Println("  Issues: %s %d", output.FormatStatus(output.StatusError), report.Summary.Issues)
 				formatter.Println("  Warnings: %s %d", output.FormatStatus(output.StatusWarning), report.Summary.Warnings)
+				if u := report.LLMUsage; u != nil {
+					formatter.Println("  LLM: %s (%d cached), %d input / %d output tokens, %s",
+						output.FormatCount(u.Requests, "request", "requests"), u.Cached, u.InputTokens, u.OutputTokens, output.FormatCurrency(u.CostUSD))
+				}
 
 				// Show issues
 				issues := report.FilterByStatus(sweep.SweepStatusIssue)
@@ -576,13 +2012,24 @@ Checks:
 						formatter.Println("   %s", r.Message)
 					}
 				}
+			default:
+				findings := report.Findings()
+				findings.Version = Version
+				if err := output.WriteFindings(os.Stdout, format, findings); err != nil {
+					return fmt.Errorf("output error: %w", err)
+				}
 			}
 
 			return nil
 		},
 	}
 	cmd.Flags().String("check", "", "Run specific check (repo-freshness, claim-consistency, code-links, coverage)")
+	cmd.Flags().String("repo-cache", verify.DefaultRepoCacheDir, "Directory for bare clones used to check code links")
+	cmd.Flags().StringSlice("snippets", nil, "Paper store, PDF text cache directory, or bipartite export with abstracts for claim-consistency (repeatable)")
+	cmd.Flags().String("llm-cache", string(llm.CacheReadWrite), "Reuse LLM responses from earlier runs: off, read, or readwrite")
+	cmd.Flags().Bool("project", false, "Sweep every document of the enclosing Quarto project (_quarto.yml) instead of listed files")
 	cmd.Flags().Bool("human", false, "Human-readable output")
 	cmd.Flags().Bool("json", false, "JSON output (default)")
+	cmd.Flags().String("format", "", "Output format: json, human, sarif, junit, or github")
 	return cmd
 }
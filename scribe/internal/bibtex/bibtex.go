@@ -0,0 +1,441 @@
+// Package bibtex parses BibTeX bibliography files.
+package bibtex
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Field is a single "name = value" pair of an entry.
+type Field struct {
+	Name  string `json:"name"`  // lowercased
+	Value string `json:"value"` // raw value as written, e.g. {Inferring {P}hylogenies} or jmb # " 12"
+	Line  int    `json:"line"`
+}
+
+// Entry is a BibTeX entry such as @article{key, ...}.
+type Entry struct {
+	Type   string  `json:"type"` // lowercased
+	Key    string  `json:"key"`
+	Fields []Field `json:"fields"`
+	File   string  `json:"file"`
+	Line   int     `json:"line"` // line of the leading @
+	Start  int     `json:"-"`    // byte offset of the leading @
+	End    int     `json:"-"`    // byte offset just past the closing delimiter
+
+	macros map[string]string
+	broken bool // had a syntax error; rewrites leave it as written
+}
+
+// Error is a recoverable syntax problem.
+type Error struct {
+	Line int
+	Msg  string
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// File is a parsed BibTeX file.
+type File struct {
+	Path    string
+	Source  []byte
+	Entries []*Entry
+	Macros  map[string]string // @string definitions, by lowercased name
+	Errors  []Error
+}
+
+// ParseFile reads and parses a BibTeX file.
+func ParseFile(path string) (*File, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, src), nil
+}
+
+// Parse parses BibTeX source. Parsing never fails: text outside entries is
+// ignored as BibTeX does, and malformed entries are recorded in Errors.
+// An entry with unbalanced braces ends where the next line starting with @
+// begins, so one bad entry does not swallow the rest of the file.
+func Parse(path string, src []byte) *File {
+	p := &parser{src: string(src), line: 1}
+	f := &File{Path: path, Source: src, Macros: make(map[string]string)}
+	p.file = f
+
+	for p.skipTo('@') {
+		start, line := p.pos, p.line
+		p.advance(1)
+		typ := strings.ToLower(p.ident())
+		p.skipSpace()
+		if typ == "" || p.eof() || (p.peek() != '{' && p.peek() != '(') {
+			continue // a stray @, e.g. in an email address
+		}
+		closer := byte('}')
+		if p.peek() == '(' {
+			closer = ')'
+		}
+		p.advance(1)
+
+		switch typ {
+		case "comment", "preamble":
+			p.skipBalanced(closer, line)
+		case "string":
+			p.skipSpace()
+			name := strings.ToLower(p.ident())
+			p.skipSpace()
+			if p.peek() == '=' {
+				p.advance(1)
+				if value, ok := p.value(closer); ok {
+					f.Macros[name] = value
+				}
+			}
+			p.skipBalanced(closer, line)
+		default:
+			entry := &Entry{Type: typ, File: path, Line: line, Start: start, macros: f.Macros}
+			errors := len(f.Errors)
+			if !p.entry(entry, closer) {
+				f.Errors = append(f.Errors, Error{Line: line, Msg: fmt.Sprintf("unbalanced braces in entry %q", entry.Key)})
+			}
+			entry.End = p.pos
+			entry.broken = len(f.Errors) > errors
+			f.Entries = append(f.Entries, entry)
+		}
+	}
+	return f
+}
+
+// parser is a cursor over BibTeX source.
+type parser struct {
+	src  string
+	pos  int
+	line int
+	file *File
+}
+
+func (p *parser) eof() bool  { return p.pos >= len(p.src) }
+func (p *parser) peek() byte { return p.src[p.pos] }
+
+// advance moves forward n bytes, counting newlines.
+func (p *parser) advance(n int) {
+	for i := 0; i < n && p.pos < len(p.src); i++ {
+		if p.src[p.pos] == '\n' {
+			p.line++
+		}
+		p.pos++
+	}
+}
+
+// skipTo advances to the next occurrence of c and reports whether one was found.
+func (p *parser) skipTo(c byte) bool {
+	for !p.eof() && p.peek() != c {
+		p.advance(1)
+	}
+	return !p.eof()
+}
+
+func (p *parser) skipSpace() {
+	for !p.eof() && unicode.IsSpace(rune(p.peek())) {
+		p.advance(1)
+	}
+}
+
+// ident reads an entry type, field name, or macro name.
+func (p *parser) ident() string {
+	start := p.pos
+	for !p.eof() && isIdentByte(p.peek()) {
+		p.advance(1)
+	}
+	return p.src[start:p.pos]
+}
+
+// atEntryStart reports whether the cursor is at a newline followed by an @,
+// which marks where a runaway entry should be cut off.
+func (p *parser) atEntryStart() bool {
+	if p.peek() != '\n' {
+		return false
+	}
+	rest := strings.TrimLeft(p.src[p.pos+1:], " \t")
+	return strings.HasPrefix(rest, "@")
+}
+
+// skipBalanced skips to just past the closer that ends the current block.
+func (p *parser) skipBalanced(closer byte, line int) {
+	depth := 0
+	for !p.eof() {
+		c := p.peek()
+		switch {
+		case c == '{':
+			depth++
+		case c == '}' && depth > 0:
+			depth--
+		case c == closer && depth == 0:
+			p.advance(1)
+			return
+		}
+		p.advance(1)
+	}
+	p.file.Errors = append(p.file.Errors, Error{Line: line, Msg: "unterminated block"})
+}
+
+// entry parses the key and fields of an entry. It returns false if the
+// entry's braces are unbalanced.
+func (p *parser) entry(e *Entry, closer byte) bool {
+	p.skipSpace()
+	start := p.pos
+	for !p.eof() && p.peek() != ',' && p.peek() != closer && !p.atEntryStart() {
+		p.advance(1)
+	}
+	e.Key = strings.TrimSpace(p.src[start:p.pos])
+
+	for {
+		p.skipSpace()
+		for !p.eof() && p.peek() == ',' {
+			p.advance(1)
+			p.skipSpace()
+		}
+		if p.eof() || p.peek() == '@' {
+			return false
+		}
+		if p.peek() == closer {
+			p.advance(1)
+			return true
+		}
+
+		line := p.line
+		name := strings.ToLower(p.ident())
+		p.skipSpace()
+		if name == "" || p.eof() || p.peek() != '=' {
+			p.file.Errors = append(p.file.Errors, Error{Line: line, Msg: fmt.Sprintf("expected field name in entry %q", e.Key)})
+			// Skip to the next field or the end of the entry
+			for !p.eof() && p.peek() != ',' && p.peek() != closer && !p.atEntryStart() {
+				p.advance(1)
+			}
+			continue
+		}
+		p.advance(1)
+		value, ok := p.value(closer)
+		e.Fields = append(e.Fields, Field{Name: name, Value: value, Line: line})
+		if !ok {
+			return false
+		}
+	}
+}
+
+// value reads a field value: braced or quoted strings, numbers, and macro
+// names joined with #. It returns false if a brace group runs away.
+func (p *parser) value(closer byte) (string, bool) {
+	p.skipSpace()
+	start := p.pos
+	for !p.eof() {
+		switch c := p.peek(); {
+		case c == '{':
+			if !p.group('}') {
+				return strings.TrimSpace(p.src[start:p.pos]), false
+			}
+		case c == '"':
+			if !p.group('"') {
+				return strings.TrimSpace(p.src[start:p.pos]), false
+			}
+		case c == ',' || c == closer:
+			return strings.TrimSpace(p.src[start:p.pos]), true
+		case p.atEntryStart():
+			return strings.TrimSpace(p.src[start:p.pos]), false
+		default:
+			p.advance(1)
+		}
+	}
+	return strings.TrimSpace(p.src[start:p.pos]), false
+}
+
+// group skips a {...} or "..." group, honoring nested braces.
+func (p *parser) group(close byte) bool {
+	p.advance(1)
+	depth := 0
+	for !p.eof() {
+		c := p.peek()
+		switch {
+		case p.atEntryStart():
+			return false
+		case c == '\\' && p.pos+1 < len(p.src):
+			p.advance(2)
+			continue
+		case c == '{':
+			depth++
+		case c == '}' && depth > 0:
+			depth--
+		case c == close && depth == 0:
+			p.advance(1)
+			return true
+		}
+		p.advance(1)
+	}
+	return false
+}
+
+func isIdentByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		strings.IndexByte("_-:.+/'", c) >= 0
+}
+
+// Raw returns the raw value of a field.
+func (e *Entry) Raw(name string) (string, bool) {
+	name = strings.ToLower(name)
+	for _, f := range e.Fields {
+		if f.Name == name {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
+
+// Get returns a field as plain text: macros expanded, braces and simple
+// LaTeX escapes removed, and whitespace collapsed. Missing fields are "".
+func (e *Entry) Get(name string) string {
+	raw, ok := e.Raw(name)
+	if !ok {
+		return ""
+	}
+	return PlainText(expand(raw, e.macros))
+}
+
+// Names returns the author names as plain text, in order.
+func (e *Entry) Names() []string {
+	raw, ok := e.Raw("author")
+	if !ok {
+		return nil
+	}
+	var names []string
+	for _, name := range splitTopLevel(expand(raw, e.macros), " and ") {
+		if name = PlainText(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// FamilyNames returns the family names of the authors, in order.
+func (e *Entry) FamilyNames() []string {
+	raw, ok := e.Raw("author")
+	if !ok {
+		return nil
+	}
+	var families []string
+	for _, name := range splitTopLevel(expand(raw, e.macros), " and ") {
+		if family := FamilyName(name); family != "" {
+			families = append(families, family)
+		}
+	}
+	return families
+}
+
+// monthMacros are BibTeX's predefined month abbreviations.
+var monthMacros = map[string]string{
+	"jan": "January", "feb": "February", "mar": "March", "apr": "April",
+	"may": "May", "jun": "June", "jul": "July", "aug": "August",
+	"sep": "September", "oct": "October", "nov": "November", "dec": "December",
+}
+
+// expand joins the parts of a raw value, expanding macros and stripping the
+// outer delimiters of each part. Inner braces are kept.
+func expand(raw string, macros map[string]string) string {
+	var b strings.Builder
+	for _, part := range splitTopLevel(raw, "#") {
+		part = strings.TrimSpace(part)
+		switch {
+		case len(part) >= 2 && (part[0] == '{' && part[len(part)-1] == '}' || part[0] == '"' && part[len(part)-1] == '"'):
+			b.WriteString(part[1 : len(part)-1])
+		case macros[strings.ToLower(part)] != "":
+			b.WriteString(expand(macros[strings.ToLower(part)], nil))
+		case monthMacros[strings.ToLower(part)] != "":
+			b.WriteString(monthMacros[strings.ToLower(part)])
+		default:
+			b.WriteString(part)
+		}
+	}
+	return b.String()
+}
+
+// splitTopLevel splits s on sep outside braces and quotes. Matching is
+// case-insensitive, so " AND " also separates names.
+func splitTopLevel(s, sep string) []string {
+	var parts []string
+	depth, start := 0, 0
+	inQuote := false
+	lower := strings.ToLower(s)
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth > 0 {
+				depth--
+			}
+		case '"':
+			if depth == 0 {
+				inQuote = !inQuote
+			}
+		}
+		if depth == 0 && !inQuote && strings.HasPrefix(lower[i:], sep) {
+			parts = append(parts, s[start:i])
+			start = i + len(sep)
+			i += len(sep) - 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// FamilyName extracts the family name from a BibTeX name in "Last, First"
+// or "First Last" form. A fully braced name such as {World Health
+// Organization} is kept whole.
+func FamilyName(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ""
+	}
+	if parts := splitTopLevel(name, ","); len(parts) > 1 {
+		return PlainText(parts[0])
+	}
+	words := splitTopLevel(strings.Join(strings.Fields(name), " "), " ")
+	return PlainText(words[len(words)-1])
+}
+
+// latexAccents are the accent commands dropped from plain text, as in \"o or \'{e}.
+const latexAccents = "'\"^`~=.uvHc"
+
+// PlainText strips braces and simple LaTeX markup and collapses whitespace.
+func PlainText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '{' || c == '}':
+			continue
+		case c == '\\' && i+1 < len(s):
+			next := s[i+1]
+			if strings.IndexByte(latexAccents, next) >= 0 {
+				i++ // drop the accent, keep the letter
+				continue
+			}
+			if !isLetter(next) {
+				b.WriteByte(next) // escaped character such as \& or \%
+				i++
+				continue
+			}
+			// Drop a command name such as \textit, keeping its argument
+			for i+1 < len(s) && isLetter(s[i+1]) {
+				i++
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,105 @@
+package bibtex
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	src := `% a comment line
+@string{jmb = "J. Mol. Biol."}
+@comment{@article{ignored, title = {x}}}
+
+@Article{tavare1986,
+  author  = {Tavar\'e, Simon and {World Health Organization}},
+  title   = {Some {P}robabilistic and Statistical Problems},
+  journal = jmb # " 17",
+  year    = 1986,
+  month   = jan,
+}
+
+@book(felsenstein2004,
+  author = "Joseph Felsenstein",
+  title = "Inferring {Phylogenies}")
+`
+	f := Parse("refs.bib", []byte(src))
+	if len(f.Errors) != 0 {
+		t.Fatalf("unexpected errors: %v", f.Errors)
+	}
+	if len(f.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(f.Entries))
+	}
+
+	e := f.Entries[0]
+	if e.Type != "article" || e.Key != "tavare1986" || e.Line != 5 {
+		t.Errorf("entry = %s %s line %d, want article tavare1986 line 5", e.Type, e.Key, e.Line)
+	}
+	tests := []struct {
+		field, want string
+	}{
+		{"title", "Some Probabilistic and Statistical Problems"},
+		{"journal", "J. Mol. Biol. 17"},
+		{"year", "1986"},
+		{"month", "January"},
+		{"missing", ""},
+	}
+	for _, tt := range tests {
+		if got := e.Get(tt.field); got != tt.want {
+			t.Errorf("Get(%q) = %q, want %q", tt.field, got, tt.want)
+		}
+	}
+	if got, want := e.FamilyNames(), []string{"Tavare", "World Health Organization"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FamilyNames() = %q, want %q", got, want)
+	}
+	if raw, _ := e.Raw("title"); raw != "{Some {P}robabilistic and Statistical Problems}" {
+		t.Errorf("Raw(title) = %q", raw)
+	}
+
+	book := f.Entries[1]
+	if book.Key != "felsenstein2004" || book.Get("title") != "Inferring Phylogenies" {
+		t.Errorf("book = %s %q", book.Key, book.Get("title"))
+	}
+	if got := book.FamilyNames(); len(got) != 1 || got[0] != "Felsenstein" {
+		t.Errorf("FamilyNames() = %q, want [Felsenstein]", got)
+	}
+	if got := src[book.Start:book.End]; !strings.HasPrefix(got, "@book(") || !strings.HasSuffix(got, `}")`) {
+		t.Errorf("entry source = %q", got)
+	}
+}
+
+func TestParse_UnbalancedBraces(t *testing.T) {
+	src := `@article{broken,
+  title = {Missing {closing brace},
+  year = 2001,
+
+@misc{next,
+  title = {Still parsed},
+}
+`
+	f := Parse("refs.bib", []byte(src))
+	if len(f.Errors) != 1 || f.Errors[0].Line != 1 {
+		t.Errorf("Errors = %v, want one error at line 1", f.Errors)
+	}
+	if len(f.Entries) != 2 || f.Entries[1].Key != "next" || f.Entries[1].Get("title") != "Still parsed" {
+		t.Errorf("expected the entry after the broken one to be parsed, got %+v", f.Entries)
+	}
+}
+
+func TestFamilyName(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"Felsenstein, Joseph", "Felsenstein"},
+		{"Joseph Felsenstein", "Felsenstein"},
+		{"{van der Vaart}, A. W.", "van der Vaart"},
+		{"J. {de Bruijn}", "de Bruijn"},
+		{`Erd{\H{o}}s, Paul`, "Erdos"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := FamilyName(tt.name); got != tt.want {
+			t.Errorf("FamilyName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
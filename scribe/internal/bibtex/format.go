@@ -0,0 +1,223 @@
+package bibtex
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// fieldOrder is the canonical order of fields in a formatted entry.
+// Other fields follow in the order they were written.
+var fieldOrder = []string{
+	"author", "editor", "title", "subtitle", "booktitle", "journal", "journaltitle",
+	"series", "volume", "number", "issue", "chapter", "pages", "edition",
+	"publisher", "school", "institution", "organization", "address", "location",
+	"howpublished", "type", "month", "year", "date", "note",
+	"doi", "eprint", "archiveprefix", "eprinttype", "primaryclass", "pmid",
+	"isbn", "issn", "url", "urldate", "abstract", "keywords",
+}
+
+// fieldRank returns a field's position in the canonical order.
+func fieldRank(name string) int {
+	for i, n := range fieldOrder {
+		if n == name {
+			return i
+		}
+	}
+	return len(fieldOrder)
+}
+
+// Format returns the file's content with every entry in canonical form:
+// fields in canonical order, one per line with a trailing comma, braces
+// instead of quotes, and page ranges written with "--". Keys found in
+// renames are replaced. Text between entries and entries with syntax errors
+// are kept exactly as written, and formatting is idempotent, so a formatted
+// file only changes where entries change.
+func (f *File) Format(renames map[string]string) []byte {
+	return f.rewrite(func(e *Entry) (string, bool) {
+		return formatEntry(e, renames), true
+	})
+}
+
+// rewrite rebuilds the file, replacing each well-formed entry with the text
+// edit returns, or dropping it if edit returns false.
+func (f *File) rewrite(edit func(e *Entry) (string, bool)) []byte {
+	var b bytes.Buffer
+	pos := 0
+	for _, e := range f.Entries {
+		b.Write(f.Source[pos:e.Start])
+		pos = e.End
+		if e.broken {
+			b.Write(f.Source[e.Start:e.End])
+			continue
+		}
+		text, keep := edit(e)
+		if keep {
+			b.WriteString(text)
+			continue
+		}
+		// Drop the rest of the entry's line and one blank line around it
+		if i := bytes.IndexByte(f.Source[pos:], '\n'); i >= 0 && len(bytes.TrimSpace(f.Source[pos:pos+i])) == 0 {
+			pos += i + 1
+		}
+		if pos < len(f.Source) && f.Source[pos] == '\n' {
+			pos++
+		} else if out := b.Bytes(); bytes.HasSuffix(out, []byte("\n\n")) {
+			b.Truncate(len(out) - 1)
+		}
+	}
+	b.Write(f.Source[pos:])
+	return b.Bytes()
+}
+
+// formatEntry renders an entry in canonical form.
+func formatEntry(e *Entry, renames map[string]string) string {
+	key := e.Key
+	if renamed, ok := renames[key]; ok {
+		key = renamed
+	}
+	fields := append([]Field(nil), e.Fields...)
+	sort.SliceStable(fields, func(i, j int) bool { return fieldRank(fields[i].Name) < fieldRank(fields[j].Name) })
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "@%s{%s,\n", e.Type, key)
+	for _, field := range fields {
+		fmt.Fprintf(&b, "  %s = %s,\n", field.Name, formatValue(field))
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// formatValue normalizes a raw field value. Concatenations and macros are
+// kept as written.
+func formatValue(field Field) string {
+	raw := field.Value
+	if len(splitTopLevel(raw, "#")) != 1 {
+		return raw
+	}
+	inner, delimited := raw, false
+	if len(raw) >= 2 && (raw[0] == '{' && raw[len(raw)-1] == '}' || raw[0] == '"' && raw[len(raw)-1] == '"') {
+		inner, delimited = raw[1:len(raw)-1], true
+	}
+	if field.Name == "pages" {
+		if pages, ok := NormalizePages(inner); ok && pages != inner {
+			return "{" + pages + "}"
+		}
+	}
+	if delimited && raw[0] == '"' {
+		return "{" + inner + "}"
+	}
+	return raw
+}
+
+// AppendEntries returns src with entries added at the end in canonical
+// form, separated from the existing content by a blank line.
+func AppendEntries(src []byte, entries ...*Entry) []byte {
+	var b bytes.Buffer
+	b.Write(src)
+	for _, e := range entries {
+		switch {
+		case b.Len() == 0:
+		case bytes.HasSuffix(b.Bytes(), []byte("\n\n")):
+		case bytes.HasSuffix(b.Bytes(), []byte("\n")):
+			b.WriteString("\n")
+		default:
+			b.WriteString("\n\n")
+		}
+		b.WriteString(formatEntry(e, nil))
+		b.WriteString("\n")
+	}
+	return b.Bytes()
+}
+
+// Braced returns text as a braced field value. Unbalanced braces in text are
+// dropped so the value cannot end the entry early.
+func Braced(text string) string {
+	depth := 0
+	for _, r := range text {
+		switch r {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		}
+		if depth < 0 {
+			break
+		}
+	}
+	if depth != 0 {
+		text = strings.NewReplacer("{", "", "}", "").Replace(text)
+	}
+	return "{" + text + "}"
+}
+
+// Dedupe removes entries that duplicate an earlier entry (see
+// FindDuplicates), copying over any fields the kept entry lacks. It returns
+// the duplicates it removed and the new content of each file that changed,
+// by path. Kept entries only gain lines, so the diff is limited to the
+// merged fields and the removed entries.
+func Dedupe(files ...*File) ([]Duplicate, map[string][]byte) {
+	dups := FindDuplicates(files...)
+	if len(dups) == 0 {
+		return nil, nil
+	}
+	removed := make(map[*Entry]bool)
+	merged := make(map[*Entry][]Field)
+	for _, dup := range dups {
+		removed[dup.Entry] = true
+		for _, field := range dup.Entry.Fields {
+			if dup.Of.Get(field.Name) == "" && !hasField(merged[dup.Of], field.Name) {
+				merged[dup.Of] = append(merged[dup.Of], field)
+			}
+		}
+	}
+
+	changed := make(map[string][]byte)
+	for _, f := range files {
+		touched := false
+		for _, e := range f.Entries {
+			touched = touched || removed[e] || len(merged[e]) > 0
+		}
+		if !touched {
+			continue
+		}
+		changed[f.Path] = f.rewrite(func(e *Entry) (string, bool) {
+			if removed[e] {
+				return "", false
+			}
+			return appendFields(f.Source[e.Start:e.End], merged[e]), true
+		})
+	}
+	return dups, changed
+}
+
+// hasField reports whether fields includes one named name.
+func hasField(fields []Field, name string) bool {
+	for _, f := range fields {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// appendFields adds fields to the end of an entry's source text.
+func appendFields(src []byte, fields []Field) string {
+	text := string(src)
+	if len(fields) == 0 {
+		return text
+	}
+	closer := text[len(text)-1:]
+	body := strings.TrimRight(text[:len(text)-1], " \t\n")
+	if !strings.HasSuffix(body, ",") {
+		body += ","
+	}
+	var b strings.Builder
+	b.WriteString(body)
+	for _, field := range fields {
+		fmt.Fprintf(&b, "\n  %s = %s,", field.Name, formatValue(field))
+	}
+	b.WriteString("\n" + closer)
+	return b.String()
+}
@@ -0,0 +1,91 @@
+package bibtex
+
+import (
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	src := `% Project bibliography
+@string{cj = "Comput. J."}
+
+@Article{knuth84,
+  year = {1984},
+  journal = cj,
+  pages = {97–111},
+  title = "Literate {P}rogramming",
+  issue_date = {May 1984},
+  author = {Knuth, Donald E.}
+}
+
+@misc{broken,
+  title = {Unbalanced,
+
+@misc{last, title = {Last}}
+`
+	want := `% Project bibliography
+@string{cj = "Comput. J."}
+
+@article{knuth1984,
+  author = {Knuth, Donald E.},
+  title = {Literate {P}rogramming},
+  journal = cj,
+  pages = {97--111},
+  year = {1984},
+  issue_date = {May 1984},
+}
+
+@misc{broken,
+  title = {Unbalanced,
+
+@misc{last,
+  title = {Last},
+}
+`
+	f := Parse("refs.bib", []byte(src))
+	got := string(f.Format(CanonicalKeys(f)))
+	if got != want {
+		t.Errorf("Format() =\n%s\nwant\n%s", got, want)
+	}
+
+	// Formatting is idempotent
+	f = Parse("refs.bib", []byte(got))
+	if again := string(f.Format(CanonicalKeys(f))); again != got {
+		t.Errorf("second Format() changed the file:\n%s", again)
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	src := `@article{knuth1984,
+  author = {Knuth, Donald E.},
+  title = {Literate Programming},
+  year = 1984
+}
+
+@misc{knuth84,
+  title = {Literate programming},
+  doi = {10.1093/comjnl/27.2.97},
+}
+
+@book{felsenstein2004,
+  title = {Inferring Phylogenies},
+}
+`
+	want := `@article{knuth1984,
+  author = {Knuth, Donald E.},
+  title = {Literate Programming},
+  year = 1984,
+  doi = {10.1093/comjnl/27.2.97},
+}
+
+@book{felsenstein2004,
+  title = {Inferring Phylogenies},
+}
+`
+	dups, changed := Dedupe(Parse("refs.bib", []byte(src)))
+	if len(dups) != 1 || dups[0].Entry.Key != "knuth84" || dups[0].Of.Key != "knuth1984" || dups[0].Reason != "title" {
+		t.Fatalf("Dedupe() duplicates = %+v", dups)
+	}
+	if got := string(changed["refs.bib"]); got != want {
+		t.Errorf("Dedupe() =\n%s\nwant\n%s", got, want)
+	}
+}
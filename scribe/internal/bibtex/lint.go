@@ -0,0 +1,306 @@
+package bibtex
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/output"
+)
+
+// Severity is how serious a lint issue is.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Lint rule IDs.
+const (
+	RuleSyntax         = "syntax"          // unbalanced braces or malformed fields
+	RuleDuplicateKey   = "duplicate-key"   // two entries share a citation key
+	RuleDuplicateEntry = "duplicate-entry" // two entries share a DOI or title
+	RuleDuplicateField = "duplicate-field" // an entry sets a field twice
+	RuleMissingField   = "missing-field"   // a required field for the entry type is missing
+	RulePageRange      = "page-range"      // pages is not a page or an a--b range
+	RuleKeyStyle       = "key-style"       // the key is not authorYEAR
+)
+
+// Rules describes each lint rule for CI report formats.
+func Rules() []output.Rule {
+	return []output.Rule{
+		{ID: RuleSyntax, Description: "Entries have balanced braces and well-formed fields"},
+		{ID: RuleDuplicateKey, Description: "Citation keys are unique"},
+		{ID: RuleDuplicateEntry, Description: "No two entries have the same DOI or title"},
+		{ID: RuleDuplicateField, Description: "No entry sets a field twice"},
+		{ID: RuleMissingField, Description: "Entries have the fields their type requires"},
+		{ID: RulePageRange, Description: "Page ranges are written as first--last"},
+		{ID: RuleKeyStyle, Description: "Citation keys follow the authorYEAR style"},
+	}
+}
+
+// Findings converts lint issues into findings for CI report formats.
+func Findings(issues []Issue) output.FindingSet {
+	set := output.FindingSet{Tool: "scribe-bib", Rules: Rules()}
+	for _, issue := range issues {
+		level := output.LevelWarning
+		if issue.Severity == SeverityError {
+			level = output.LevelError
+		}
+		set.Findings = append(set.Findings, output.Finding{
+			RuleID:  issue.Rule,
+			Level:   level,
+			File:    issue.File,
+			Line:    issue.Line,
+			Target:  issue.Key,
+			Message: issue.Message,
+		})
+	}
+	return set
+}
+
+// Issue is a lint finding.
+type Issue struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	File     string   `json:"file"`
+	Line     int      `json:"line"`
+	Key      string   `json:"key,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// requiredFields lists the fields each standard entry type requires.
+// Alternatives are separated by "|", as in author|editor for books.
+var requiredFields = map[string][]string{
+	"article":       {"author", "title", "journal", "year"},
+	"book":          {"author|editor", "title", "publisher", "year"},
+	"booklet":       {"title"},
+	"inbook":        {"author|editor", "title", "chapter|pages", "publisher", "year"},
+	"incollection":  {"author", "title", "booktitle", "publisher", "year"},
+	"inproceedings": {"author", "title", "booktitle", "year"},
+	"conference":    {"author", "title", "booktitle", "year"},
+	"manual":        {"title"},
+	"mastersthesis": {"author", "title", "school", "year"},
+	"phdthesis":     {"author", "title", "school", "year"},
+	"proceedings":   {"title", "year"},
+	"techreport":    {"author", "title", "institution", "year"},
+	"unpublished":   {"author", "title", "note"},
+}
+
+// Lint checks BibTeX files for syntax errors, duplicate keys and entries,
+// missing required fields, malformed page ranges, and key style. Duplicates
+// are found across all the files.
+func Lint(files ...*File) []Issue {
+	var issues []Issue
+	add := func(rule string, severity Severity, e *Entry, line int, format string, args ...any) {
+		issues = append(issues, Issue{Rule: rule, Severity: severity, File: e.File, Line: line, Key: e.Key, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, f := range files {
+		for _, err := range f.Errors {
+			issues = append(issues, Issue{Rule: RuleSyntax, Severity: SeverityError, File: f.Path, Line: err.Line, Message: err.Msg})
+		}
+	}
+
+	renames := CanonicalKeys(files...)
+	firstByKey := make(map[string]*Entry)
+	for _, e := range entries(files) {
+		if first, ok := firstByKey[e.Key]; ok {
+			add(RuleDuplicateKey, SeverityError, e, e.Line, "Duplicate key %q (first defined at %s:%d)", e.Key, first.File, first.Line)
+		} else {
+			firstByKey[e.Key] = e
+		}
+
+		seen := make(map[string]bool)
+		for _, field := range e.Fields {
+			if seen[field.Name] {
+				add(RuleDuplicateField, SeverityWarning, e, field.Line, "Entry %q sets %s more than once", e.Key, field.Name)
+			}
+			seen[field.Name] = true
+		}
+
+		for _, required := range requiredFields[e.Type] {
+			if !hasAnyField(e, strings.Split(required, "|")) {
+				add(RuleMissingField, SeverityWarning, e, e.Line, "@%s %q is missing %s", e.Type, e.Key, strings.ReplaceAll(required, "|", " or "))
+			}
+		}
+
+		if pages := e.Get("pages"); pages != "" && !ValidPages(pages) {
+			line := fieldLine(e, "pages")
+			if fixed, ok := NormalizePages(pages); ok {
+				add(RulePageRange, SeverityWarning, e, line, "Page range %q should be written %q", pages, fixed)
+			} else {
+				add(RulePageRange, SeverityWarning, e, line, "Malformed page range %q", pages)
+			}
+		}
+		if first, last, ok := pageBounds(e.Get("pages")); ok && last < first {
+			add(RulePageRange, SeverityWarning, e, fieldLine(e, "pages"), "Page range %q ends before it starts", e.Get("pages"))
+		}
+
+		if key, ok := renames[e.Key]; ok {
+			add(RuleKeyStyle, SeverityWarning, e, e.Line, "Key %q does not follow the authorYEAR style (want %q)", e.Key, key)
+		}
+	}
+
+	for _, dup := range FindDuplicates(files...) {
+		add(RuleDuplicateEntry, SeverityError, dup.Entry, dup.Entry.Line, "Entry %q duplicates %q (%s:%d): same %s",
+			dup.Entry.Key, dup.Of.Key, dup.Of.File, dup.Of.Line, dup.Reason)
+	}
+
+	return issues
+}
+
+// entries returns the entries of files in order.
+func entries(files []*File) []*Entry {
+	var all []*Entry
+	for _, f := range files {
+		all = append(all, f.Entries...)
+	}
+	return all
+}
+
+// hasAnyField reports whether the entry has a non-empty value for any of names.
+func hasAnyField(e *Entry, names []string) bool {
+	for _, name := range names {
+		if e.Get(name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldLine returns the line of a field, or the entry's line if it is missing.
+func fieldLine(e *Entry, name string) int {
+	for _, f := range e.Fields {
+		if f.Name == name {
+			return f.Line
+		}
+	}
+	return e.Line
+}
+
+// pageBounds returns the first and last page of a numeric a--b range.
+func pageBounds(pages string) (int, int, bool) {
+	first, last, ok := strings.Cut(pages, "--")
+	if !ok {
+		return 0, 0, false
+	}
+	a, errA := strconv.Atoi(first)
+	b, errB := strconv.Atoi(last)
+	return a, b, errA == nil && errB == nil
+}
+
+// Duplicate is an entry that repeats an earlier one.
+type Duplicate struct {
+	Entry  *Entry
+	Of     *Entry
+	Reason string // "DOI" or "title"
+}
+
+// FindDuplicates finds entries with the DOI or normalized title of an
+// earlier entry. Entries with the same title but different DOIs are
+// distinct works, such as a preprint and its published version.
+func FindDuplicates(files ...*File) []Duplicate {
+	var dups []Duplicate
+	byDOI := make(map[string]*Entry)
+	byTitle := make(map[string]*Entry)
+	for _, e := range entries(files) {
+		doi := NormalizeDOI(e.Get("doi"))
+		title := NormalizeTitle(e.Get("title"))
+		if first, ok := byDOI[doi]; ok && doi != "" {
+			dups = append(dups, Duplicate{Entry: e, Of: first, Reason: "DOI"})
+			continue
+		}
+		if first, ok := byTitle[title]; ok && title != "" {
+			firstDOI := NormalizeDOI(first.Get("doi"))
+			if doi == "" || firstDOI == "" || doi == firstDOI {
+				dups = append(dups, Duplicate{Entry: e, Of: first, Reason: "title"})
+				continue
+			}
+		}
+		if doi != "" {
+			byDOI[doi] = e
+		}
+		if _, ok := byTitle[title]; !ok && title != "" {
+			byTitle[title] = e
+		}
+	}
+	return dups
+}
+
+// CanonicalKeys returns new keys for entries whose key does not follow the
+// authorYEAR style. Keys that already fit, such as tavare1986 or
+// tavare1986b, are kept, so adding an entry never renames existing ones.
+// Colliding keys get a letter suffix: tavare1986, tavare1986b, tavare1986c.
+// Entries without an author or year, and keys defined more than once, are
+// left alone.
+func CanonicalKeys(files ...*File) map[string]string {
+	all := entries(files)
+	count := make(map[string]int)
+	for _, e := range all {
+		count[e.Key]++
+	}
+
+	renames := make(map[string]string)
+	for _, e := range all {
+		base := KeyBase(e)
+		if base == "" || count[e.Key] > 1 || fitsKeyStyle(e.Key, base) {
+			continue
+		}
+		key := suffixKey(base, func(key string) bool { return count[key] > 0 })
+		if key == "" {
+			continue
+		}
+		count[key]++
+		renames[e.Key] = key
+	}
+	return renames
+}
+
+// NewKey returns an authorYEAR key for a new entry that no entry in files
+// uses. Entries without an author are keyed by the first word of their
+// title instead, and entries without either by "anon".
+func NewKey(e *Entry, files ...*File) string {
+	taken := make(map[string]bool)
+	for _, existing := range entries(files) {
+		taken[existing.Key] = true
+	}
+	base := KeyBase(e)
+	if base == "" {
+		base = "anon"
+		if words := strings.Fields(NormalizeTitle(e.Get("title"))); len(words) > 0 {
+			base = words[0]
+		}
+		if year := e.Get("year"); len(year) >= 4 && isDigits(year[:4]) {
+			base += year[:4]
+		}
+	}
+	if key := suffixKey(base, func(key string) bool { return taken[key] }); key != "" {
+		return key
+	}
+	for n := 2; ; n++ {
+		if key := fmt.Sprintf("%s-%d", base, n); !taken[key] {
+			return key
+		}
+	}
+}
+
+// suffixKey returns base, or base with the first letter suffix from b to z
+// that is not taken. It returns "" if every suffix is taken.
+func suffixKey(base string, taken func(string) bool) string {
+	key := base
+	for suffix := 'b'; taken(key) && suffix <= 'z'; suffix++ {
+		key = base + string(suffix)
+	}
+	if taken(key) {
+		return ""
+	}
+	return key
+}
+
+// fitsKeyStyle reports whether key is base or base with a letter suffix.
+func fitsKeyStyle(key, base string) bool {
+	rest, ok := strings.CutPrefix(key, base)
+	return ok && (rest == "" || len(rest) == 1 && rest[0] >= 'a' && rest[0] <= 'z')
+}
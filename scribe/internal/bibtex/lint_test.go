@@ -0,0 +1,96 @@
+package bibtex
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	src := `@article{knuth84,
+  author = {Knuth, Donald E.},
+  title = {Literate Programming},
+  journal = {Comput. J.},
+  year = {1984},
+  pages = {97–111},
+  doi = {10.1093/comjnl/27.2.97},
+}
+
+@book{felsenstein2004,
+  author = {Felsenstein, Joseph},
+  title = {Inferring Phylogenies},
+  year = 2004,
+  pages = {300--200},
+}
+
+@misc{knuth1984copy,
+  title = {Literate programming.},
+  doi = {https://doi.org/10.1093/COMJNL/27.2.97},
+}
+
+@misc{felsenstein2004,
+  title = {Another},
+  title = {Twice},
+}
+`
+	issues := Lint(Parse("refs.bib", []byte(src)))
+
+	type found struct {
+		rule string
+		line int
+		key  string
+	}
+	var got []found
+	for _, issue := range issues {
+		got = append(got, found{issue.Rule, issue.Line, issue.Key})
+	}
+	want := []found{
+		{RulePageRange, 6, "knuth84"},
+		{RuleKeyStyle, 1, "knuth84"},
+		{RuleMissingField, 10, "felsenstein2004"},
+		{RulePageRange, 14, "felsenstein2004"},
+		{RuleDuplicateKey, 22, "felsenstein2004"},
+		{RuleDuplicateField, 24, "felsenstein2004"},
+		{RuleDuplicateEntry, 17, "knuth1984copy"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Lint() found\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestCanonicalKeys(t *testing.T) {
+	src := `@article{Tavare_86, author = {Tavar\'e, Simon}, year = 1986}
+@article{tavare1986, author = {Simon Tavaré}, year = 1986}
+@article{other, author = {{van der Vaart}, A.}, year = 1998}
+@article{tavare1986b, author = {Tavaré, S.}, year = 1986}
+@misc{noyear, author = {Doe, Jane}}
+`
+	got := CanonicalKeys(Parse("refs.bib", []byte(src)))
+	want := map[string]string{
+		"Tavare_86": "tavare1986c",
+		"other":     "vandervaart1998",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CanonicalKeys() = %v, want %v", got, want)
+	}
+}
+
+func TestNormalizePages(t *testing.T) {
+	tests := []struct {
+		pages string
+		want  string
+		ok    bool
+	}{
+		{"97--111", "97--111", true},
+		{"97-111", "97--111", true},
+		{"97 – 111", "97--111", true},
+		{"e1000123", "e1000123", true},
+		{"S1-S10", "S1--S10", true},
+		{"97, 99", "97, 99", false},
+	}
+	for _, tt := range tests {
+		got, ok := NormalizePages(tt.pages)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("NormalizePages(%q) = %q, %v; want %q, %v", tt.pages, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package bibtex
+
+import (
+	"regexp"
+	"strings"
+)
+
+// NormalizeDOI strips resolver and doi: prefixes from a DOI and lowercases it.
+// DOIs are case-insensitive.
+func NormalizeDOI(doi string) string {
+	lower := strings.ToLower(strings.TrimSpace(doi))
+	for _, prefix := range []string{"https://doi.org/", "http://doi.org/", "https://dx.doi.org/", "http://dx.doi.org/", "doi:"} {
+		if strings.HasPrefix(lower, prefix) {
+			return strings.TrimSpace(lower[len(prefix):])
+		}
+	}
+	return lower
+}
+
+// NormalizeTitle lowercases a title, folds accents, and reduces it to
+// letters and digits separated by single spaces, so that titles differing
+// only in case, markup, or punctuation compare equal.
+func NormalizeTitle(title string) string {
+	words := strings.FieldsFunc(foldAccents(strings.ToLower(PlainText(title))), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	})
+	return strings.Join(words, " ")
+}
+
+// pagePart is a single page number such as 97, e1000123, or S12.
+const pagePart = `[A-Za-z]*\d+[A-Za-z]*`
+
+var (
+	pageSingle = regexp.MustCompile(`^` + pagePart + `$`)
+	pageRange  = regexp.MustCompile(`^(` + pagePart + `)--(` + pagePart + `)$`)
+	// pageLoose matches ranges written with a single hyphen, an en or em
+	// dash, or spaces around the separator.
+	pageLoose = regexp.MustCompile(`^(` + pagePart + `)\s*(?:-+|–|—)\s*(` + pagePart + `)$`)
+)
+
+// ValidPages reports whether a pages value is a single page or a range
+// written with BibTeX's "--" separator.
+func ValidPages(pages string) bool {
+	return pageSingle.MatchString(pages) || pageRange.MatchString(pages)
+}
+
+// NormalizePages rewrites a page range to use "--", as in 97--111. It
+// returns false if the value is not recognizable as a page or page range.
+func NormalizePages(pages string) (string, bool) {
+	pages = strings.TrimSpace(pages)
+	if ValidPages(pages) {
+		return pages, true
+	}
+	if m := pageLoose.FindStringSubmatch(pages); m != nil {
+		return m[1] + "--" + m[2], true
+	}
+	return pages, false
+}
+
+// KeyBase returns the canonical citation key for an entry: the first
+// author's family name in lowercase ASCII followed by the year, as in
+// tavare1986. It returns "" if the entry has no author or year.
+func KeyBase(e *Entry) string {
+	families := e.FamilyNames()
+	if len(families) == 0 {
+		return ""
+	}
+	year := e.Get("year")
+	if year == "" {
+		year = e.Get("date")
+	}
+	if len(year) < 4 || !isDigits(year[:4]) {
+		return ""
+	}
+	name := strings.ReplaceAll(NormalizeTitle(families[0]), " ", "")
+	if name == "" {
+		return ""
+	}
+	return name + year[:4]
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// accentFolder maps accented Latin letters to their base letters.
+var accentFolder = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ä", "a", "ã", "a", "å", "a", "ā", "a",
+	"ç", "c", "ć", "c", "č", "c",
+	"é", "e", "è", "e", "ê", "e", "ë", "e", "ē", "e", "ě", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ñ", "n", "ń", "n", "ň", "n",
+	"ó", "o", "ò", "o", "ô", "o", "ö", "o", "õ", "o", "ø", "o", "ő", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u", "ű", "u", "ů", "u",
+	"ý", "y", "ÿ", "y",
+	"ś", "s", "š", "s", "ž", "z", "ź", "z", "ż", "z", "ř", "r", "ł", "l", "ß", "ss",
+)
+
+// foldAccents replaces accented letters in lowercase text with their base letters.
+func foldAccents(s string) string {
+	return accentFolder.Replace(s)
+}
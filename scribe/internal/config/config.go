@@ -0,0 +1,509 @@
+// Package config loads the scribe project configuration file.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/llm"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/papers"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/queue"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/status"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/sweep"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/verify"
+)
+
+// FileName is the name of the project configuration file.
+const FileName = ".scribe.yaml"
+
+// EnvConfig names an environment variable holding the config file path.
+const EnvConfig = "SCRIBE_CONFIG"
+
+// Config is the scribe project configuration.
+type Config struct {
+	Paths  Paths        `yaml:"paths"`
+	Queue  QueueConfig  `yaml:"queue"`
+	Verify VerifyConfig `yaml:"verify"`
+	Sweep  SweepConfig  `yaml:"sweep"`
+	LLM    LLMConfig    `yaml:"llm"`
+
+	File string `yaml:"-"` // file the config was loaded from; "" if none
+}
+
+// Paths locates scribe's state files. Relative paths are resolved against
+// the directory containing the config file.
+type Paths struct {
+	Queue        string   `yaml:"queue"`
+	Rejected     string   `yaml:"rejected"`
+	Events       string   `yaml:"events"` // history of queue status changes; empty keeps it next to the queue
+	Checkpoint   string   `yaml:"checkpoint"`
+	PaperCache   string   `yaml:"paper_cache"`
+	ResultCache  string   `yaml:"result_cache"`
+	RepoCache    string   `yaml:"repo_cache"`
+	Baseline     string   `yaml:"baseline"`
+	Papers       string   `yaml:"papers"`       // full-text store of cited papers
+	LLMCache     string   `yaml:"llm_cache"`    // cached LLM responses
+	Bibliography []string `yaml:"bibliography"` // overrides the _quarto.yml bibliography
+	Library      []string `yaml:"library"`
+	Snippets     []string `yaml:"snippets"` // PDF text cache directories and bipartite exports with abstracts
+}
+
+// QueueConfig configures the candidate queue.
+type QueueConfig struct {
+	SyncBibliography bool `yaml:"sync_bibliography"` // add approved papers to the bibliography
+}
+
+// VerifyConfig configures scribe verify.
+type VerifyConfig struct {
+	Checks        []string          `yaml:"checks"`     // check types to run; empty runs all
+	Severities    map[string]string `yaml:"severities"` // check type -> error, warning, or off
+	Workers       int               `yaml:"workers"`
+	URLTimeout    Duration          `yaml:"url_timeout"`
+	PaperCacheTTL Duration          `yaml:"paper_cache_ttl"`
+	ClaimPatterns ClaimPatterns     `yaml:"claim_patterns"`
+	ClaimBatch    int               `yaml:"claim_batch"` // sentences per LLM request
+}
+
+// ClaimPatterns are extra regular expressions added to the built-in claim heuristics.
+type ClaimPatterns struct {
+	MustCite   []string `yaml:"must_cite"`
+	ShouldCite []string `yaml:"should_cite"`
+	Exempt     []string `yaml:"exempt"`
+}
+
+// SweepConfig configures scribe sweep.
+type SweepConfig struct {
+	Checks         []string          `yaml:"checks"`
+	Severities     map[string]string `yaml:"severities"`
+	StaleThreshold Duration          `yaml:"stale_threshold"`
+}
+
+// LLMConfig selects the LLM used for claim analysis.
+type LLMConfig struct {
+	Enabled  *bool               `yaml:"enabled"`  // nil means enabled
+	Provider string              `yaml:"provider"` // claude, ollama, or empty to auto-detect
+	Model    string              `yaml:"model"`
+	URL      string              `yaml:"url"`     // Ollama server
+	Timeout  Duration            `yaml:"timeout"` // per request
+	Retries  int                 `yaml:"retries"` // retries after a failed request
+	Cache    string              `yaml:"cache"`   // response cache: off, read, or readwrite
+	Prices   map[string]LLMPrice `yaml:"prices"`  // by model
+}
+
+// LLMPrice is a model's price in USD per million tokens.
+type LLMPrice struct {
+	Input  float64 `yaml:"input"`
+	Output float64 `yaml:"output"`
+}
+
+// Duration is a time.Duration that also accepts day ("d") and year ("y") units,
+// e.g. "36h", "7d", or "2y". A year is 365 days.
+type Duration time.Duration
+
+// UnmarshalYAML parses a duration string.
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	parsed, err := ParseDuration(node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// ParseDuration parses a Go duration or a whole number of days or years.
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "y": 365 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// Default returns the built-in configuration.
+func Default() *Config {
+	return &Config{
+		Paths: Paths{
+			Queue:       queue.DefaultQueuePath,
+			Rejected:    queue.DefaultRejectedPath,
+			Checkpoint:  status.DefaultCheckpointPath,
+			PaperCache:  verify.DefaultPaperCachePath,
+			ResultCache: verify.DefaultResultCachePath,
+			RepoCache:   verify.DefaultRepoCacheDir,
+			Baseline:    verify.DefaultBaselinePath,
+			Papers:      papers.DefaultDir,
+			LLMCache:    llm.DefaultCachePath,
+		},
+		Verify: VerifyConfig{
+			Workers:       verify.DefaultWorkers,
+			URLTimeout:    Duration(verify.DefaultURLTimeout),
+			PaperCacheTTL: Duration(verify.DefaultPaperCacheTTL),
+			ClaimBatch:    verify.DefaultClaimBatchSize,
+		},
+		Sweep: SweepConfig{
+			StaleThreshold: Duration(sweep.StaleThreshold),
+		},
+		LLM: LLMConfig{
+			Timeout: Duration(llm.DefaultTimeout),
+			Retries: llm.DefaultRetries,
+			Cache:   string(llm.CacheReadWrite),
+		},
+	}
+}
+
+// Find walks up from start looking for the config file.
+// It returns the file path, or "" if there is none.
+func Find(start string) (string, error) {
+	dir, err := filepath.Abs(start)
+	if err != nil {
+		return "", err
+	}
+	for {
+		path := filepath.Join(dir, FileName)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// Load reads the configuration. The file is path if given, else $SCRIBE_CONFIG,
+// else the nearest .scribe.yaml above the working directory. Environment
+// variable overrides are applied last. With no file, defaults are returned.
+func Load(path string) (*Config, error) {
+	if path == "" {
+		path = os.Getenv(EnvConfig)
+	}
+	if path == "" {
+		found, err := Find(".")
+		if err != nil {
+			return nil, fmt.Errorf("find %s: %w", FileName, err)
+		}
+		path = found
+	}
+
+	cfg := Default()
+	if path != "" {
+		if err := cfg.readFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.applyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// readFile merges a config file over the current values.
+func (c *Config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	c.File = path
+	c.resolvePaths(filepath.Dir(path))
+	return nil
+}
+
+// resolvePaths makes relative paths relative to dir.
+func (c *Config) resolvePaths(dir string) {
+	resolve := func(p *string) {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(dir, *p)
+		}
+	}
+	for _, p := range []*string{
+		&c.Paths.Queue, &c.Paths.Rejected, &c.Paths.Events, &c.Paths.Checkpoint,
+		&c.Paths.PaperCache, &c.Paths.ResultCache, &c.Paths.RepoCache, &c.Paths.Baseline,
+		&c.Paths.Papers, &c.Paths.LLMCache,
+	} {
+		resolve(p)
+	}
+	for i := range c.Paths.Bibliography {
+		resolve(&c.Paths.Bibliography[i])
+	}
+	for i := range c.Paths.Library {
+		resolve(&c.Paths.Library[i])
+	}
+	for i := range c.Paths.Snippets {
+		resolve(&c.Paths.Snippets[i])
+	}
+}
+
+// envOverrides maps environment variables onto config fields.
+var envOverrides = map[string]func(c *Config, v string) error{
+	"SCRIBE_QUEUE_PATH":      func(c *Config, v string) error { c.Paths.Queue = v; return nil },
+	"SCRIBE_REJECTED_PATH":   func(c *Config, v string) error { c.Paths.Rejected = v; return nil },
+	"SCRIBE_EVENTS_PATH":     func(c *Config, v string) error { c.Paths.Events = v; return nil },
+	"SCRIBE_CHECKPOINT_PATH": func(c *Config, v string) error { c.Paths.Checkpoint = v; return nil },
+	"SCRIBE_PAPER_CACHE":     func(c *Config, v string) error { c.Paths.PaperCache = v; return nil },
+	"SCRIBE_RESULT_CACHE":    func(c *Config, v string) error { c.Paths.ResultCache = v; return nil },
+	"SCRIBE_REPO_CACHE":      func(c *Config, v string) error { c.Paths.RepoCache = v; return nil },
+	"SCRIBE_BASELINE_PATH":   func(c *Config, v string) error { c.Paths.Baseline = v; return nil },
+	"SCRIBE_PAPERS_DIR":      func(c *Config, v string) error { c.Paths.Papers = v; return nil },
+	"SCRIBE_VERIFY_CHECKS":   func(c *Config, v string) error { c.Verify.Checks = splitList(v); return nil },
+	"SCRIBE_SWEEP_CHECKS":    func(c *Config, v string) error { c.Sweep.Checks = splitList(v); return nil },
+	"SCRIBE_LLM_PROVIDER":    func(c *Config, v string) error { c.LLM.Provider = v; return nil },
+	"SCRIBE_LLM_MODEL":       func(c *Config, v string) error { c.LLM.Model = v; return nil },
+	"SCRIBE_LLM_URL":         func(c *Config, v string) error { c.LLM.URL = v; return nil },
+	"SCRIBE_LLM_CACHE":       func(c *Config, v string) error { c.LLM.Cache = v; return nil },
+	"SCRIBE_WORKERS": func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		c.Verify.Workers = n
+		return err
+	},
+	"SCRIBE_URL_TIMEOUT": func(c *Config, v string) error {
+		d, err := ParseDuration(v)
+		c.Verify.URLTimeout = Duration(d)
+		return err
+	},
+	"SCRIBE_STALE_THRESHOLD": func(c *Config, v string) error {
+		d, err := ParseDuration(v)
+		c.Sweep.StaleThreshold = Duration(d)
+		return err
+	},
+	"SCRIBE_SYNC_BIBLIOGRAPHY": func(c *Config, v string) error {
+		enabled, err := strconv.ParseBool(v)
+		c.Queue.SyncBibliography = enabled
+		return err
+	},
+	"SCRIBE_LLM_TIMEOUT": func(c *Config, v string) error {
+		d, err := ParseDuration(v)
+		c.LLM.Timeout = Duration(d)
+		return err
+	},
+	"SCRIBE_LLM_ENABLED": func(c *Config, v string) error {
+		enabled, err := strconv.ParseBool(v)
+		c.LLM.Enabled = &enabled
+		return err
+	},
+}
+
+// applyEnv applies environment variable overrides.
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	for name, apply := range envOverrides {
+		if v, ok := lookup(name); ok {
+			if err := apply(c, v); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// splitList splits a comma-separated list, dropping empty items.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// validate checks names and patterns so mistakes surface when the config is loaded.
+func (c *Config) validate() error {
+	if _, err := c.verifyChecks(); err != nil {
+		return err
+	}
+	if _, err := c.verifySeverities(); err != nil {
+		return err
+	}
+	if _, err := c.claimPatterns(); err != nil {
+		return err
+	}
+	if _, err := c.sweepChecks(); err != nil {
+		return err
+	}
+	if _, err := c.sweepSeverities(); err != nil {
+		return err
+	}
+	switch c.LLM.Provider {
+	case "", "claude", "ollama":
+	default:
+		return fmt.Errorf("llm.provider: unknown provider %q (want claude or ollama)", c.LLM.Provider)
+	}
+	if c.Verify.ClaimBatch < 1 {
+		return fmt.Errorf("verify.claim_batch: must be at least 1, got %d", c.Verify.ClaimBatch)
+	}
+	if c.LLM.Retries < 0 {
+		return fmt.Errorf("llm.retries: must not be negative, got %d", c.LLM.Retries)
+	}
+	for model, price := range c.LLM.Prices {
+		if price.Input < 0 || price.Output < 0 {
+			return fmt.Errorf("llm.prices.%s: prices must not be negative", model)
+		}
+	}
+	if _, err := llm.ParseCacheMode(c.LLM.Cache); err != nil {
+		return fmt.Errorf("llm.cache: %w", err)
+	}
+	return nil
+}
+
+// LLMEnabled reports whether LLM claim analysis is enabled.
+func (c *Config) LLMEnabled() bool {
+	return c.LLM.Enabled == nil || *c.LLM.Enabled
+}
+
+// LLMClientConfig returns the settings for LLM clients.
+func (c *Config) LLMClientConfig() llm.Config {
+	cfg := llm.Config{
+		Provider: c.LLM.Provider,
+		Model:    c.LLM.Model,
+		URL:      c.LLM.URL,
+		Timeout:  time.Duration(c.LLM.Timeout),
+		Retries:  c.LLM.Retries,
+	}
+	if len(c.LLM.Prices) > 0 {
+		cfg.Prices = make(map[string]llm.Price, len(c.LLM.Prices))
+		for model, price := range c.LLM.Prices {
+			cfg.Prices[model] = llm.Price{Input: price.Input, Output: price.Output}
+		}
+	}
+	return cfg
+}
+
+// QueueStore opens the candidate queue at the configured paths. The event
+// log stays next to the queue file unless paths.events is set.
+func (c *Config) QueueStore() *queue.Store {
+	store := queue.NewStore(c.Paths.Queue, c.Paths.Rejected)
+	if c.Paths.Events != "" {
+		store.SetEventsPath(c.Paths.Events)
+	}
+	return store
+}
+
+// VerifyOptions converts the config into verification options.
+// Backends such as the resolver, mirror, and caches are left to the caller.
+func (c *Config) VerifyOptions() (verify.Options, error) {
+	opts := verify.DefaultOptions()
+	opts.UseLLM = c.LLMEnabled()
+	opts.Workers = c.Verify.Workers
+	opts.URLTimeout = time.Duration(c.Verify.URLTimeout)
+	opts.LLM = c.LLMClientConfig()
+	opts.ClaimBatch = c.Verify.ClaimBatch
+
+	var err error
+	if opts.Checks, err = c.verifyChecks(); err != nil {
+		return opts, err
+	}
+	if opts.Severities, err = c.verifySeverities(); err != nil {
+		return opts, err
+	}
+	if opts.ClaimPatterns, err = c.claimPatterns(); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// SweepOptions converts the config into sweep options.
+func (c *Config) SweepOptions() (sweep.Options, error) {
+	opts := sweep.DefaultOptions()
+	opts.StaleThreshold = time.Duration(c.Sweep.StaleThreshold)
+
+	checks, err := c.sweepChecks()
+	if err != nil {
+		return opts, err
+	}
+	if len(checks) > 0 {
+		opts.Checks = checks
+	}
+	if opts.Severities, err = c.sweepSeverities(); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+func (c *Config) verifyChecks() ([]verify.CheckType, error) {
+	var checks []verify.CheckType
+	for _, name := range c.Verify.Checks {
+		t, err := verify.ParseCheckType(name)
+		if err != nil {
+			return nil, fmt.Errorf("verify.checks: %w", err)
+		}
+		checks = append(checks, t)
+	}
+	return checks, nil
+}
+
+func (c *Config) verifySeverities() (map[verify.CheckType]verify.Severity, error) {
+	severities := make(map[verify.CheckType]verify.Severity)
+	for name, value := range c.Verify.Severities {
+		t, err := verify.ParseCheckType(name)
+		if err != nil {
+			return nil, fmt.Errorf("verify.severities: %w", err)
+		}
+		if severities[t], err = verify.ParseSeverity(value); err != nil {
+			return nil, fmt.Errorf("verify.severities.%s: %w", name, err)
+		}
+	}
+	return severities, nil
+}
+
+func (c *Config) claimPatterns() (*verify.ClaimPatterns, error) {
+	p := c.Verify.ClaimPatterns
+	patterns, err := verify.NewClaimPatterns(p.MustCite, p.ShouldCite, p.Exempt)
+	if err != nil {
+		return nil, fmt.Errorf("verify.claim_patterns: %w", err)
+	}
+	return patterns, nil
+}
+
+func (c *Config) sweepChecks() ([]sweep.CheckType, error) {
+	var checks []sweep.CheckType
+	for _, name := range c.Sweep.Checks {
+		t, err := sweep.ParseCheckType(name)
+		if err != nil {
+			return nil, fmt.Errorf("sweep.checks: %w", err)
+		}
+		checks = append(checks, t)
+	}
+	return checks, nil
+}
+
+func (c *Config) sweepSeverities() (map[sweep.CheckType]verify.Severity, error) {
+	severities := make(map[sweep.CheckType]verify.Severity)
+	for name, value := range c.Sweep.Severities {
+		t, err := sweep.ParseCheckType(name)
+		if err != nil {
+			return nil, fmt.Errorf("sweep.severities: %w", err)
+		}
+		if severities[t], err = verify.ParseSeverity(value); err != nil {
+			return nil, fmt.Errorf("sweep.severities.%s: %w", name, err)
+		}
+	}
+	return severities, nil
+}
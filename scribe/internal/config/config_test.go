@@ -0,0 +1,209 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/llm"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/queue"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/sweep"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/verify"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input string
+		want  time.Duration
+	}{
+		{"10s", 10 * time.Second},
+		{"36h", 36 * time.Hour},
+		{"7d", 7 * 24 * time.Hour},
+		{"2y", 2 * 365 * 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.input)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, %v; want %v", tt.input, got, err, tt.want)
+		}
+	}
+	if _, err := ParseDuration("soon"); err == nil {
+		t.Error("expected error for invalid duration")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, FileName)
+	content := `paths:
+  queue: state/queue.jsonl
+  bibliography: [refs.bib]
+verify:
+  checks: [citation, url]
+  severities:
+    url: warning
+  url_timeout: 3s
+  claim_patterns:
+    must_cite: ['(?i)\bstate of the art\b']
+sweep:
+  severities:
+    coverage: off
+  stale_threshold: 1y
+llm:
+  enabled: false
+  provider: ollama
+  model: mistral
+  timeout: 30s
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	t.Setenv("SCRIBE_WORKERS", "3")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if cfg.File != path {
+		t.Errorf("File = %q, want %q", cfg.File, path)
+	}
+	if want := filepath.Join(tmpDir, "state/queue.jsonl"); cfg.Paths.Queue != want {
+		t.Errorf("Paths.Queue = %q, want %q", cfg.Paths.Queue, want)
+	}
+	if want := filepath.Join(tmpDir, queue.DefaultRejectedPath); cfg.Paths.Rejected != want {
+		t.Errorf("default paths should resolve against the config dir, got %q", cfg.Paths.Rejected)
+	}
+	if cfg.Paths.Bibliography[0] != filepath.Join(tmpDir, "refs.bib") {
+		t.Errorf("Paths.Bibliography = %v", cfg.Paths.Bibliography)
+	}
+
+	opts, err := cfg.VerifyOptions()
+	if err != nil {
+		t.Fatalf("VerifyOptions failed: %v", err)
+	}
+	if opts.UseLLM || opts.LLM.Provider != "ollama" || opts.LLM.Model != "mistral" {
+		t.Errorf("unexpected LLM options: UseLLM=%v %+v", opts.UseLLM, opts.LLM)
+	}
+	if opts.LLM.Timeout != 30*time.Second || opts.LLM.Retries != llm.DefaultRetries {
+		t.Errorf("LLM timeout/retries = %v/%d", opts.LLM.Timeout, opts.LLM.Retries)
+	}
+	if opts.Workers != 3 {
+		t.Errorf("SCRIBE_WORKERS override: Workers = %d, want 3", opts.Workers)
+	}
+	if opts.URLTimeout != 3*time.Second {
+		t.Errorf("URLTimeout = %v, want 3s", opts.URLTimeout)
+	}
+	if len(opts.Checks) != 2 || opts.Severities[verify.CheckTypeURL] != verify.SeverityWarning {
+		t.Errorf("unexpected checks %v / severities %v", opts.Checks, opts.Severities)
+	}
+	if needs, _, _ := opts.ClaimPatterns.Analyze("This is the state of the art."); !needs {
+		t.Error("custom must_cite pattern was not applied")
+	}
+
+	sweepOpts, err := cfg.SweepOptions()
+	if err != nil {
+		t.Fatalf("SweepOptions failed: %v", err)
+	}
+	if sweepOpts.StaleThreshold != 365*24*time.Hour {
+		t.Errorf("StaleThreshold = %v, want 1y", sweepOpts.StaleThreshold)
+	}
+	if sweepOpts.Severities[sweep.CheckTypeCoverage] != verify.SeverityOff {
+		t.Errorf("unexpected sweep severities %v", sweepOpts.Severities)
+	}
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	t.Chdir(tmpDir)
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.File != "" || cfg.Paths.Queue != queue.DefaultQueuePath || !cfg.LLMEnabled() {
+		t.Errorf("expected defaults without a config file, got %+v", cfg)
+	}
+}
+
+func TestQueueStore_EventsFollowQueue(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	t.Chdir(tmpDir)
+
+	queueDir := filepath.Join(tmpDir, "elsewhere")
+	t.Setenv("SCRIBE_QUEUE_PATH", filepath.Join(queueDir, "queue.jsonl"))
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if err := cfg.QueueStore().AppendEvent(queue.Event{CandidateID: "c-1", Action: queue.EventAdd}); err != nil {
+		t.Fatalf("AppendEvent: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(queueDir, "events.jsonl")); err != nil {
+		t.Errorf("events should be next to the moved queue: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, queue.DefaultEventsPath)); err == nil {
+		t.Error("events were written to the default path despite the moved queue")
+	}
+
+	// An explicit events path still wins
+	t.Setenv("SCRIBE_EVENTS_PATH", filepath.Join(tmpDir, "log", "events.jsonl"))
+	if cfg, err = Load(""); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if err := cfg.QueueStore().AppendEvent(queue.Event{CandidateID: "c-1", Action: queue.EventAdd}); err != nil {
+		t.Fatalf("AppendEvent: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "log", "events.jsonl")); err != nil {
+		t.Errorf("events should be at SCRIBE_EVENTS_PATH: %v", err)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"unknown field", "verify:\n  timeout: 3s\n", "field timeout not found"},
+		{"unknown check", "verify:\n  checks: [spelling]\n", "unknown check type: spelling"},
+		{"bad severity", "sweep:\n  severities:\n    coverage: fatal\n", "unknown severity"},
+		{"bad pattern", "verify:\n  claim_patterns:\n    exempt: ['(']\n", "invalid claim pattern"},
+		{"bad duration", "sweep:\n  stale_threshold: forever\n", "invalid duration"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(tmpDir, FileName)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("write config: %v", err)
+			}
+			_, err := Load(path)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load error = %v, want containing %q", err, tt.want)
+			}
+		})
+	}
+}
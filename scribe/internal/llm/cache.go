@@ -0,0 +1,203 @@
+package llm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultCachePath is the default location of the LLM response cache.
+const DefaultCachePath = ".scribe/cache/llm.json"
+
+// Eviction defaults: entries unused for DefaultCacheTTL are dropped, then
+// the least recently used beyond DefaultCacheEntries.
+const (
+	DefaultCacheTTL     = 90 * 24 * time.Hour
+	DefaultCacheEntries = 10000
+)
+
+// CacheMode controls how the response cache is used.
+type CacheMode string
+
+const (
+	CacheOff       CacheMode = "off"       // never read or write
+	CacheRead      CacheMode = "read"      // reuse responses, but do not store new ones
+	CacheReadWrite CacheMode = "readwrite" // reuse and store responses
+)
+
+// ParseCacheMode parses a cache mode name.
+func ParseCacheMode(s string) (CacheMode, error) {
+	switch mode := CacheMode(s); mode {
+	case CacheOff, CacheRead, CacheReadWrite:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid LLM cache mode %q (want off, read, or readwrite)", s)
+	}
+}
+
+// cacheEntry is a cached response.
+type cacheEntry struct {
+	Provider string    `json:"provider"`
+	Model    string    `json:"model"`
+	Version  string    `json:"version,omitempty"`
+	Text     string    `json:"text"`
+	CachedAt time.Time `json:"cached_at"`
+	UsedAt   time.Time `json:"used_at"`
+}
+
+// Cache persists LLM responses between runs, so identical requests get
+// identical answers without another call. Entries are keyed by provider,
+// model, prompt version, and a hash of the prompt and schema. Only
+// responses that decoded successfully are stored. A nil Cache is off.
+type Cache struct {
+	path       string
+	mode       CacheMode
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	loaded  bool
+	dirty   bool
+}
+
+// NewCache creates a response cache backed by path. If path is empty,
+// DefaultCachePath is used.
+func NewCache(path string, mode CacheMode) *Cache {
+	if path == "" {
+		path = DefaultCachePath
+	}
+	return &Cache{
+		path:       path,
+		mode:       mode,
+		ttl:        DefaultCacheTTL,
+		maxEntries: DefaultCacheEntries,
+		entries:    make(map[string]cacheEntry),
+	}
+}
+
+// cacheKey derives the cache key of a request to a provider.
+func cacheKey(provider Provider, req Request) string {
+	schema, _ := json.Marshal(req.Schema)
+	sum := sha256.Sum256([]byte(provider.Name() + "\x00" + provider.Model() + "\x00" + req.Version + "\x00" + string(schema) + "\x00" + req.Prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+// get returns the cached response for key.
+func (c *Cache) get(key string) (string, bool) {
+	if c == nil || c.mode == CacheOff {
+		return "", false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.load(); err != nil {
+		return "", false
+	}
+	entry, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	if c.mode == CacheReadWrite {
+		entry.UsedAt = time.Now()
+		c.entries[key] = entry
+		c.dirty = true
+	}
+	return entry.Text, true
+}
+
+// put stores a response.
+func (c *Cache) put(key string, provider Provider, req Request, text string) {
+	if c == nil || c.mode != CacheReadWrite {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.load(); err != nil {
+		return
+	}
+	now := time.Now()
+	c.entries[key] = cacheEntry{
+		Provider: provider.Name(),
+		Model:    provider.Model(),
+		Version:  req.Version,
+		Text:     text,
+		CachedAt: now,
+		UsedAt:   now,
+	}
+	c.dirty = true
+}
+
+// Flush evicts stale entries and writes the cache to disk if it has changed.
+func (c *Cache) Flush() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty || c.mode != CacheReadWrite {
+		return nil
+	}
+	c.evict(time.Now())
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("create cache directory: %w", err)
+	}
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal LLM cache: %w", err)
+	}
+	if err := os.WriteFile(c.path, data, 0644); err != nil {
+		return fmt.Errorf("write LLM cache: %w", err)
+	}
+	c.dirty = false
+	return nil
+}
+
+// evict drops entries unused for longer than the TTL, then the least
+// recently used entries beyond the size limit. Callers must hold c.mu.
+func (c *Cache) evict(now time.Time) {
+	keys := make([]string, 0, len(c.entries))
+	for key, entry := range c.entries {
+		if now.Sub(entry.UsedAt) > c.ttl {
+			delete(c.entries, key)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) <= c.maxEntries {
+		return
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return c.entries[keys[i]].UsedAt.After(c.entries[keys[j]].UsedAt)
+	})
+	for _, key := range keys[c.maxEntries:] {
+		delete(c.entries, key)
+	}
+}
+
+// load reads the cache file once. Callers must hold c.mu.
+func (c *Cache) load() error {
+	if c.loaded {
+		return nil
+	}
+	c.loaded = true
+
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("read LLM cache: %w", err)
+	}
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		// A corrupt cache is not fatal; start over
+		c.entries = make(map[string]cacheEntry)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package llm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const cachedVerdict = `{"needs_citation": true, "confidence": "high", "reason": "number", "suggested_action": "add citation"}`
+
+func TestCache(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	path := filepath.Join(tmpDir, "llm.json")
+
+	// A first run asks the provider and stores the response
+	fake := NewFake(cachedVerdict)
+	cache := NewCache(path, CacheReadWrite)
+	client := NewClientWithProvider(fake, Config{Cache: cache})
+	if _, err := client.AnalyzeClaim(context.Background(), "About 90 percent of trees are binary."); err != nil {
+		t.Fatalf("AnalyzeClaim: %v", err)
+	}
+	if err := cache.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		mode         CacheMode
+		sentence     string
+		wantRequests int
+	}{
+		{"hit", CacheReadWrite, "About 90 percent of trees are binary.", 0},
+		{"read only hit", CacheRead, "About 90 percent of trees are binary.", 0},
+		{"different prompt", CacheReadWrite, "Most trees are binary.", 1},
+		{"off", CacheOff, "About 90 percent of trees are binary.", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := NewFake(cachedVerdict)
+			client := NewClientWithProvider(fake, Config{Cache: NewCache(path, tt.mode)})
+			result, err := client.AnalyzeClaim(context.Background(), tt.sentence)
+			if err != nil {
+				t.Fatalf("AnalyzeClaim: %v", err)
+			}
+			if !result.NeedsCitation || result.PromptVersion != ClaimPromptVersion {
+				t.Errorf("got %+v", result)
+			}
+			if n := len(fake.Requests()); n != tt.wantRequests {
+				t.Errorf("got %d requests, want %d", n, tt.wantRequests)
+			}
+			if usage := client.Usage(); usage.Cached != 1-tt.wantRequests {
+				t.Errorf("usage %+v, want %d cached", usage, 1-tt.wantRequests)
+			}
+		})
+	}
+
+	// The prompt version is part of the key
+	cache = NewCache(path, CacheReadWrite)
+	client = NewClientWithProvider(NewFake("new"), Config{Cache: cache})
+	req := Request{Prompt: "hi", Version: "greeting/1"}
+	if _, err := client.Complete(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	req.Version = "greeting/2"
+	if _, err := client.Complete(context.Background(), req); err == nil {
+		t.Error("a new prompt version should not reuse the cached response")
+	}
+}
+
+func TestCache_ReadOnlyAndInvalid(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	path := filepath.Join(tmpDir, "llm.json")
+
+	// Read mode never writes
+	cache := NewCache(path, CacheRead)
+	client := NewClientWithProvider(NewFake(cachedVerdict), Config{Cache: cache})
+	if _, err := client.AnalyzeClaim(context.Background(), "Trees are binary."); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("read-only cache wrote %s", path)
+	}
+
+	// A cached response that no longer matches the schema is asked again
+	fake := NewFake(cachedVerdict)
+	cache = NewCache(path, CacheReadWrite)
+	req := Request{Prompt: "Is this a claim?", Schema: claimAnalysisSchema}
+	cache.put(cacheKey(fake, req), fake, req, `{"needs_citation": "maybe"}`)
+	var result ClaimAnalysisResult
+	if _, err := NewClientWithProvider(fake, Config{Cache: cache}).Generate(context.Background(), req, &result); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if len(fake.Requests()) != 1 || !result.NeedsCitation {
+		t.Errorf("got %+v after %d requests", result, len(fake.Requests()))
+	}
+}
+
+func TestCache_Evict(t *testing.T) {
+	now := time.Now()
+	cache := NewCache("", CacheReadWrite)
+	cache.ttl = 24 * time.Hour
+	cache.maxEntries = 2
+	cache.loaded = true
+	cache.entries = map[string]cacheEntry{
+		"stale":  {UsedAt: now.Add(-48 * time.Hour)},
+		"old":    {UsedAt: now.Add(-3 * time.Hour)},
+		"recent": {UsedAt: now.Add(-2 * time.Hour)},
+		"new":    {UsedAt: now.Add(-time.Hour)},
+	}
+	cache.evict(now)
+	if len(cache.entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(cache.entries))
+	}
+	for _, key := range []string{"recent", "new"} {
+		if _, ok := cache.entries[key]; !ok {
+			t.Errorf("%s was evicted", key)
+		}
+	}
+}
@@ -2,39 +2,122 @@
 package llm
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"os"
 	"os/exec"
 	"strings"
+	"sync"
+	"time"
 )
 
-// Client provides access to LLM capabilities.
+// Default models for each provider.
+const (
+	DefaultClaudeModel = "claude-haiku-4-20250514"
+	DefaultOllamaModel = "llama3.2"
+)
+
+// Defaults for requests.
+const (
+	DefaultTimeout = 2 * time.Minute // per attempt
+	DefaultRetries = 2
+	DefaultBackoff = time.Second // doubled after each failed attempt
+)
+
+// Config selects an LLM provider and model.
+type Config struct {
+	Provider string           // "claude", "ollama", or "" to auto-detect
+	Model    string           // "" uses the provider's default model
+	URL      string           // Ollama server; "" uses $OLLAMA_HOST or DefaultOllamaURL
+	Timeout  time.Duration    // per attempt; 0 uses DefaultTimeout
+	Retries  int              // attempts after a failed one
+	Backoff  time.Duration    // wait before the first retry; 0 uses DefaultBackoff
+	Cache    *Cache           // response cache; nil disables caching
+	Prices   map[string]Price // by model; overrides the cost reported by the provider
+	Offline  bool             // only use a provider that needs no network: a local Ollama server
+}
+
+// Client provides access to LLM capabilities. Requests time out, and
+// failed requests and responses that do not match their schema are retried
+// with exponential backoff. With a cache, responses to requests seen
+// before are reused.
 type Client struct {
-	provider string // "claude" or "ollama"
-	model    string // model name for ollama
+	provider Provider
+	timeout  time.Duration
+	retries  int
+	backoff  time.Duration
+	cache    *Cache
+	price    *Price
+
+	mu    sync.Mutex
+	usage Usage // every attempt, including failed ones
 }
 
 // NewClient creates a new LLM client.
 // It prefers Claude CLI if available, falling back to local Ollama.
 func NewClient() (*Client, error) {
-	// Check if claude CLI is available
-	if _, err := exec.LookPath("claude"); err == nil {
-		return &Client{provider: "claude"}, nil
-	}
+	return NewClientFromConfig(Config{Retries: DefaultRetries})
+}
 
-	// Check if ollama is available
-	if _, err := exec.LookPath("ollama"); err == nil {
-		return &Client{provider: "ollama", model: "llama3.2"}, nil
+// NewClientFromConfig creates a client for the configured provider and model.
+// If no provider is configured, it prefers Claude CLI, falling back to Ollama
+// when it is installed or $OLLAMA_HOST is set. Offline, Claude CLI and
+// remote Ollama servers are not available.
+func NewClientFromConfig(cfg Config) (*Client, error) {
+	name := cfg.Provider
+	if name == "" {
+		if _, err := exec.LookPath("claude"); err == nil && !cfg.Offline {
+			name = "claude"
+		} else if _, err := exec.LookPath("ollama"); err == nil || os.Getenv("OLLAMA_HOST") != "" || cfg.URL != "" {
+			name = "ollama"
+		} else if cfg.Offline {
+			return nil, errors.New("no LLM provider available offline: run ollama locally")
+		} else {
+			return nil, errors.New("no LLM provider available: install claude CLI or ollama")
+		}
 	}
 
-	return nil, errors.New("no LLM provider available: install claude CLI or ollama")
+	var provider Provider
+	switch name {
+	case "claude":
+		if cfg.Offline {
+			return nil, errors.New("LLM provider claude needs the network and is not available offline")
+		}
+		if _, err := exec.LookPath("claude"); err != nil {
+			return nil, fmt.Errorf("LLM provider claude not available: %w", err)
+		}
+		provider = NewClaudeCLI(cfg.Model)
+	case "ollama":
+		ollama := NewOllama(cfg.URL, cfg.Model)
+		if cfg.Offline && !ollama.Local() {
+			return nil, fmt.Errorf("LLM provider ollama at %s is not local and is not available offline", ollama.BaseURL)
+		}
+		provider = ollama
+	default:
+		return nil, fmt.Errorf("unknown provider: %s", name)
+	}
+	return NewClientWithProvider(provider, cfg), nil
 }
 
-// NewClientWithProvider creates a client with a specific provider.
-func NewClientWithProvider(provider, model string) *Client {
-	return &Client{provider: provider, model: model}
+// NewClientWithProvider creates a client for a specific provider, using
+// the timeout and retry settings of cfg.
+func NewClientWithProvider(provider Provider, cfg Config) *Client {
+	c := &Client{provider: provider, timeout: cfg.Timeout, retries: cfg.Retries, backoff: cfg.Backoff, cache: cfg.Cache}
+	if c.timeout <= 0 {
+		c.timeout = DefaultTimeout
+	}
+	if c.retries < 0 {
+		c.retries = 0
+	}
+	if c.backoff <= 0 {
+		c.backoff = DefaultBackoff
+	}
+	if price, ok := cfg.Prices[provider.Model()]; ok {
+		c.price = &price
+	}
+	return c
 }
 
 // IsAvailable returns true if an LLM provider is available.
@@ -43,68 +126,144 @@ func IsAvailable() bool {
 	return err == nil
 }
 
+// Provider returns the client's provider.
+func (c *Client) Provider() Provider {
+	return c.provider
+}
+
+// Usage returns the usage of every request made by the client.
+func (c *Client) Usage() Usage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.usage
+}
+
 // Complete sends a prompt to the LLM and returns the response.
-func (c *Client) Complete(prompt string) (string, error) {
-	switch c.provider {
-	case "claude":
-		return c.completeClaude(prompt)
-	case "ollama":
-		return c.completeOllama(prompt)
-	default:
-		return "", fmt.Errorf("unknown provider: %s", c.provider)
-	}
+func (c *Client) Complete(ctx context.Context, req Request) (*Response, error) {
+	return c.do(ctx, req, nil)
 }
 
-// completeClaude uses the claude CLI to complete a prompt.
-func (c *Client) completeClaude(prompt string) (string, error) {
-	cmd := exec.Command("claude", "-p", prompt, "--model", "claude-haiku-4-20250514")
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+// Generate sends a request whose response must be JSON matching its schema
+// and decodes it into out.
+func (c *Client) Generate(ctx context.Context, req Request, out any) (*Response, error) {
+	return c.do(ctx, req, func(text string) error {
+		return Decode(text, req.Schema, out)
+	})
+}
 
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("claude CLI error: %w: %s", err, stderr.String())
+// do runs a request with retries. decode, if set, parses the response; a
+// response it rejects is retried like a failed request. Cached responses
+// that decode are returned without a request, and new ones are cached.
+func (c *Client) do(ctx context.Context, req Request, decode func(string) error) (*Response, error) {
+	var key string
+	if c.cache != nil {
+		key = cacheKey(c.provider, req)
+		if text, ok := c.cache.get(key); ok && (decode == nil || decode(text) == nil) {
+			c.mu.Lock()
+			c.usage.Cached++
+			c.mu.Unlock()
+			return &Response{Text: text, Usage: Usage{Cached: 1}}, nil
+		}
 	}
 
-	return strings.TrimSpace(stdout.String()), nil
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := c.attempt(ctx, req)
+		if err == nil && decode != nil {
+			err = decode(resp.Text)
+		}
+		if err == nil {
+			if c.cache != nil {
+				c.cache.put(key, c.provider, req, resp.Text)
+			}
+			return resp, nil
+		}
+		if attempt >= c.retries || !retryable(ctx, err) {
+			if attempt > 0 {
+				return nil, fmt.Errorf("%s: %w (after %d attempts)", c.provider.Name(), err, attempt+1)
+			}
+			return nil, fmt.Errorf("%s: %w", c.provider.Name(), err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
 }
 
-// completeOllama uses local Ollama to complete a prompt.
-func (c *Client) completeOllama(prompt string) (string, error) {
-	cmd := exec.Command("ollama", "run", c.model, prompt)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("ollama error: %w: %s", err, stderr.String())
+// attempt runs one request with the client's timeout.
+func (c *Client) attempt(ctx context.Context, req Request) (*Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	resp, err := c.provider.Complete(ctx, req)
+	if resp != nil && c.price != nil {
+		resp.Usage.CostUSD = c.price.Cost(resp.Usage.InputTokens, resp.Usage.OutputTokens)
+	}
+	c.mu.Lock()
+	if resp != nil {
+		c.usage.Add(resp.Usage)
+	} else {
+		c.usage.Requests++
 	}
+	c.mu.Unlock()
+	return resp, err
+}
 
-	return strings.TrimSpace(stdout.String()), nil
+// retryable reports whether a failed request is worth retrying: not if the
+// caller gave up, the provider is missing, or the request itself was bad.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, exec.ErrNotFound) {
+		return false
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		code := httpErr.StatusCode
+		return code >= 500 || code == http.StatusTooManyRequests || code == http.StatusRequestTimeout
+	}
+	return true
 }
 
+// Prompt template versions. Bump a version whenever its prompt or schema
+// changes, so cached responses to the old prompt are not reused and reports
+// show which prompt produced a verdict.
+const (
+	ClaimPromptVersion      = "claim/1"
+	ClaimBatchPromptVersion = "claim-batch/1"
+	EvidencePromptVersion   = "evidence/1"
+)
+
+// Confidence levels reported by the LLM.
+var confidenceSchema = &Schema{Type: "string", Enum: []string{"high", "medium", "low"}}
+
 // ClaimAnalysisResult represents the result of analyzing a sentence for citation need.
 type ClaimAnalysisResult struct {
 	NeedsCitation   bool   `json:"needs_citation"`
 	Confidence      string `json:"confidence"` // high, medium, low
 	Reason          string `json:"reason"`
 	SuggestedAction string `json:"suggested_action"`
+	PromptVersion   string `json:"-"` // prompt that produced the result
+}
+
+// claimAnalysisSchema constrains AnalyzeClaim responses.
+var claimAnalysisSchema = &Schema{
+	Type: "object",
+	Properties: map[string]*Schema{
+		"needs_citation":   {Type: "boolean"},
+		"confidence":       confidenceSchema,
+		"reason":           {Type: "string", Description: "brief explanation"},
+		"suggested_action": {Type: "string", Enum: []string{"add citation", "no action needed", "review manually"}},
+	},
+	Required: []string{"needs_citation", "confidence", "reason", "suggested_action"},
 }
 
 // AnalyzeClaim determines if a sentence is a factual claim that needs citation.
-func (c *Client) AnalyzeClaim(sentence string) (*ClaimAnalysisResult, error) {
+func (c *Client) AnalyzeClaim(ctx context.Context, sentence string) (*ClaimAnalysisResult, error) {
 	prompt := fmt.Sprintf(`Analyze if this sentence from a scientific document is a factual claim that requires a citation.
 
 Sentence: %q
 
-Respond with JSON only:
-{
-  "needs_citation": true/false,
-  "confidence": "high"/"medium"/"low",
-  "reason": "brief explanation",
-  "suggested_action": "add citation" or "no action needed" or "review manually"
-}
-
 Guidelines:
 - Performance comparisons ("X is faster than Y") NEED citations
 - Attribution of discoveries ("discovered by", "introduced by") NEED citations
@@ -113,38 +272,149 @@ Guidelines:
 - Examples ("for example", "e.g.") do NOT need citations
 - Transitional prose ("in this section") does NOT need citations`, sentence)
 
-	response, err := c.Complete(prompt)
-	if err != nil {
+	var result ClaimAnalysisResult
+	req := Request{Prompt: prompt, Schema: claimAnalysisSchema, Version: ClaimPromptVersion}
+	if _, err := c.Generate(ctx, req, &result); err != nil {
 		return nil, fmt.Errorf("LLM analysis failed: %w", err)
 	}
+	result.PromptVersion = ClaimPromptVersion
+	return &result, nil
+}
 
-	// Extract JSON from response (it might have markdown code fences)
-	jsonStr := extractJSON(response)
+// ClaimItem is a sentence to analyze in a batch, with the paragraph it
+// appears in for context.
+type ClaimItem struct {
+	ID       string
+	Sentence string
+	Context  string
+}
 
-	var result ClaimAnalysisResult
-	if err := json.Unmarshal([]byte(jsonStr), &result); err != nil {
-		return nil, fmt.Errorf("parse LLM response: %w", err)
-	}
+// claimVerdict is one element of a batched claim analysis response.
+type claimVerdict struct {
+	ID string `json:"id"`
+	ClaimAnalysisResult
+}
 
-	return &result, nil
+// claimBatchSchema constrains AnalyzeClaims responses.
+var claimBatchSchema = &Schema{
+	Type: "array",
+	Items: &Schema{
+		Type:       "object",
+		Properties: withID(claimAnalysisSchema.Properties),
+		Required:   append([]string{"id"}, claimAnalysisSchema.Required...),
+	},
+}
+
+// withID adds an id property to object properties.
+func withID(properties map[string]*Schema) map[string]*Schema {
+	out := map[string]*Schema{"id": {Type: "string", Description: "id of the sentence"}}
+	for name, s := range properties {
+		out[name] = s
+	}
+	return out
 }
 
-// extractJSON extracts JSON from a response that might have markdown fences.
-func extractJSON(s string) string {
-	s = strings.TrimSpace(s)
+// maxClaimContext truncates paragraph context in batched prompts, in bytes.
+const maxClaimContext = 600
 
-	// Remove markdown code fences if present
-	if strings.HasPrefix(s, "```json") {
-		s = strings.TrimPrefix(s, "```json")
-		if idx := strings.Index(s, "```"); idx != -1 {
-			s = s[:idx]
+// AnalyzeClaims analyzes several sentences in one request. The results are
+// keyed by item ID; items the response leaves out are missing.
+func (c *Client) AnalyzeClaims(ctx context.Context, items []ClaimItem) (map[string]*ClaimAnalysisResult, error) {
+	var list strings.Builder
+	for _, item := range items {
+		fmt.Fprintf(&list, "[%s] Sentence: %q\n", item.ID, item.Sentence)
+		if paragraph := strings.Join(strings.Fields(item.Context), " "); paragraph != "" && paragraph != item.Sentence {
+			if len(paragraph) > maxClaimContext {
+				paragraph = strings.ToValidUTF8(paragraph[:maxClaimContext], "") + "..."
+			}
+			fmt.Fprintf(&list, "Paragraph: %q\n", paragraph)
 		}
-	} else if strings.HasPrefix(s, "```") {
-		s = strings.TrimPrefix(s, "```")
-		if idx := strings.Index(s, "```"); idx != -1 {
-			s = s[:idx]
+		list.WriteString("\n")
+	}
+	prompt := fmt.Sprintf(`Analyze whether each sentence below, from a scientific document, is a factual claim that requires a citation. Each sentence is shown with the paragraph it appears in; judge only the sentence.
+
+%sGuidelines:
+- Performance comparisons ("X is faster than Y") NEED citations
+- Attribution of discoveries ("discovered by", "introduced by") NEED citations
+- Quantitative claims (numbers, percentages) NEED citations
+- Definitions ("is defined as") do NOT need citations
+- Examples ("for example", "e.g.") do NOT need citations
+- Transitional prose ("in this section") does NOT need citations
+
+Return a JSON array with one verdict per sentence, each with the sentence's id.`, list.String())
+
+	var verdicts []claimVerdict
+	req := Request{Prompt: prompt, Schema: claimBatchSchema, Version: ClaimBatchPromptVersion}
+	if _, err := c.Generate(ctx, req, &verdicts); err != nil {
+		return nil, fmt.Errorf("LLM batch analysis failed: %w", err)
+	}
+	known := make(map[string]bool, len(items))
+	for _, item := range items {
+		known[item.ID] = true
+	}
+	results := make(map[string]*ClaimAnalysisResult, len(verdicts))
+	for _, v := range verdicts {
+		if known[v.ID] {
+			result := v.ClaimAnalysisResult
+			result.PromptVersion = ClaimBatchPromptVersion
+			results[v.ID] = &result
 		}
 	}
+	return results, nil
+}
+
+// Evidence verdicts.
+const (
+	VerdictSupports    = "supports"
+	VerdictContradicts = "contradicts"
+	VerdictUnrelated   = "unrelated"
+)
+
+// EvidenceResult is the LLM's judgment of whether passages from a paper
+// support a claim that cites it.
+type EvidenceResult struct {
+	Verdict       string `json:"verdict"`    // supports, contradicts, or unrelated
+	Evidence      string `json:"evidence"`   // quoted span from the passages; empty if unrelated
+	Confidence    string `json:"confidence"` // high, medium, low
+	Reason        string `json:"reason"`
+	PromptVersion string `json:"-"` // prompt that produced the result
+}
 
-	return strings.TrimSpace(s)
+// evidenceSchema constrains CheckEvidence responses.
+var evidenceSchema = &Schema{
+	Type: "object",
+	Properties: map[string]*Schema{
+		"verdict":    {Type: "string", Enum: []string{VerdictSupports, VerdictContradicts, VerdictUnrelated}},
+		"evidence":   {Type: "string", Description: "exact quote from the passages that supports or contradicts the sentence, or empty"},
+		"confidence": confidenceSchema,
+		"reason":     {Type: "string", Description: "brief explanation"},
+	},
+	Required: []string{"verdict", "evidence", "confidence", "reason"},
+}
+
+// CheckEvidence asks whether passages from a cited paper support a claim.
+func (c *Client) CheckEvidence(ctx context.Context, claim string, passages []string) (*EvidenceResult, error) {
+	var numbered strings.Builder
+	for i, p := range passages {
+		fmt.Fprintf(&numbered, "[%d] %s\n\n", i+1, p)
+	}
+	prompt := fmt.Sprintf(`A sentence in a scientific document cites a paper. Decide whether the passages from the cited paper support the sentence.
+
+Sentence: %q
+
+Passages from the cited paper:
+%s
+Guidelines:
+- "supports": the passages state or directly imply the sentence's claim about the paper
+- "contradicts": the passages state something incompatible with the claim
+- "unrelated": the passages do not address the claim; this is not evidence against it
+- Copy the evidence word for word from one passage; do not paraphrase`, claim, numbered.String())
+
+	var result EvidenceResult
+	req := Request{Prompt: prompt, Schema: evidenceSchema, Version: EvidencePromptVersion}
+	if _, err := c.Generate(ctx, req, &result); err != nil {
+		return nil, fmt.Errorf("LLM evidence check failed: %w", err)
+	}
+	result.PromptVersion = EvidencePromptVersion
+	return &result, nil
 }
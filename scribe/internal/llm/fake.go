@@ -0,0 +1,57 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Fake is a deterministic Provider for tests. It answers with Respond if
+// set, otherwise with Responses in order, and records every request.
+type Fake struct {
+	Responses []string
+	Respond   func(Request) (string, error)
+
+	mu       sync.Mutex
+	requests []Request
+}
+
+// NewFake creates a Fake that returns responses in order.
+func NewFake(responses ...string) *Fake {
+	return &Fake{Responses: responses}
+}
+
+// Name returns "fake".
+func (f *Fake) Name() string { return "fake" }
+
+// Model returns "fake".
+func (f *Fake) Model() string { return "fake" }
+
+// Complete returns the next canned response.
+func (f *Fake) Complete(ctx context.Context, req Request) (*Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := len(f.requests)
+	f.requests = append(f.requests, req)
+	if f.Respond != nil {
+		text, err := f.Respond(req)
+		if err != nil {
+			return nil, err
+		}
+		return &Response{Text: text, Usage: Usage{Requests: 1}}, nil
+	}
+	if n >= len(f.Responses) {
+		return nil, errors.New("fake provider: no response left")
+	}
+	return &Response{Text: f.Responses[n], Usage: Usage{Requests: 1}}, nil
+}
+
+// Requests returns the requests received so far.
+func (f *Fake) Requests() []Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Request(nil), f.requests...)
+}
@@ -0,0 +1,228 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSchemaValidate(t *testing.T) {
+	tests := []struct {
+		json    string
+		wantErr string
+	}{
+		{`{"verdict": "supports", "evidence": "", "confidence": "high", "reason": "r"}`, ""},
+		{`{"verdict": "supports", "evidence": null, "confidence": "high", "reason": "r"}`, ""},
+		{`{"verdict": "maybe", "evidence": "", "confidence": "high", "reason": "r"}`, `$.verdict: "maybe" is not one of`},
+		{`{"verdict": "supports", "confidence": "high", "reason": "r"}`, `missing "evidence"`},
+		{`{"verdict": "supports", "evidence": 3, "confidence": "high", "reason": "r"}`, "$.evidence: want a string"},
+		{`["supports"]`, "want an object"},
+	}
+	for _, tt := range tests {
+		var v any
+		if err := json.Unmarshal([]byte(tt.json), &v); err != nil {
+			t.Fatal(err)
+		}
+		err := evidenceSchema.Validate(v)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("Validate(%s) = %v", tt.json, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("Validate(%s) = %v, want %q", tt.json, err, tt.wantErr)
+		}
+	}
+
+	integer := &Schema{Type: "array", Items: &Schema{Type: "integer"}}
+	if err := integer.Validate([]any{1.0, 2.5}); err == nil || !strings.Contains(err.Error(), "$[1]") {
+		t.Errorf("integer items: got %v", err)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		text string
+		ok   bool
+	}{
+		{`{"needs_citation": true, "confidence": "low", "reason": "x", "suggested_action": "add citation"}`, true},
+		{"```json\n{\"needs_citation\": false, \"confidence\": \"high\", \"reason\": \"x\", \"suggested_action\": \"no action needed\"}\n```", true},
+		{`Sure! {"needs_citation": true, "confidence": "medium", "reason": "x", "suggested_action": "review manually"} Hope that helps.`, true},
+		{`{"needs_citation": "yes", "confidence": "low", "reason": "x", "suggested_action": "add citation"}`, false},
+		{`not json`, false},
+	}
+	for _, tt := range tests {
+		var result ClaimAnalysisResult
+		err := Decode(tt.text, claimAnalysisSchema, &result)
+		if tt.ok && err != nil {
+			t.Errorf("Decode(%q) = %v", tt.text, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrInvalidResponse) {
+			t.Errorf("Decode(%q) = %v, want ErrInvalidResponse", tt.text, err)
+		}
+	}
+}
+
+func TestClient_Retries(t *testing.T) {
+	valid := `{"verdict": "supports", "evidence": "quote", "confidence": "high", "reason": "r"}`
+	cfg := Config{Retries: 2, Backoff: time.Millisecond}
+
+	// Invalid responses are retried until one matches the schema
+	fake := NewFake(`{"verdict": "probably"}`, "garbage", valid)
+	result, err := NewClientWithProvider(fake, cfg).CheckEvidence(context.Background(), "claim", []string{"passage"})
+	if err != nil {
+		t.Fatalf("CheckEvidence: %v", err)
+	}
+	if result.Verdict != VerdictSupports || len(fake.Requests()) != 3 {
+		t.Errorf("got %+v after %d requests", result, len(fake.Requests()))
+	}
+	if req := fake.Requests()[0]; req.Schema != evidenceSchema || !strings.Contains(req.Prompt, "[1] passage") {
+		t.Errorf("unexpected request %+v", req)
+	}
+
+	// Retries run out
+	fake = NewFake("garbage", "garbage", "garbage", valid)
+	if _, err := NewClientWithProvider(fake, cfg).CheckEvidence(context.Background(), "claim", nil); !errors.Is(err, ErrInvalidResponse) {
+		t.Errorf("expected ErrInvalidResponse, got %v", err)
+	}
+
+	// Client errors are not retried
+	calls := 0
+	fake = &Fake{Respond: func(Request) (string, error) {
+		calls++
+		return "", &HTTPError{StatusCode: http.StatusNotFound, Body: "model not found"}
+	}}
+	if _, err := NewClientWithProvider(fake, cfg).Complete(context.Background(), Request{Prompt: "hi"}); err == nil || calls != 1 {
+		t.Errorf("404: err = %v after %d calls, want one failed call", err, calls)
+	}
+
+	// Cancellation stops retrying
+	ctx, cancel := context.WithCancel(context.Background())
+	fake = &Fake{Respond: func(Request) (string, error) {
+		cancel()
+		return "", errors.New("boom")
+	}}
+	if _, err := NewClientWithProvider(fake, Config{Retries: 5, Backoff: time.Hour}).Complete(ctx, Request{}); err == nil || len(fake.Requests()) != 1 {
+		t.Errorf("cancelled: err = %v after %d requests", err, len(fake.Requests()))
+	}
+}
+
+func TestClient_Timeout(t *testing.T) {
+	provider := providerFunc(func(ctx context.Context, req Request) (*Response, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	client := NewClientWithProvider(provider, Config{Timeout: 10 * time.Millisecond, Retries: 1, Backoff: time.Millisecond})
+	start := time.Now()
+	_, err := client.Complete(context.Background(), Request{Prompt: "hi"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a deadline error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "after 2 attempts") {
+		t.Errorf("timed out attempts should be retried: %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("timeout not applied")
+	}
+}
+
+// providerFunc adapts a function to Provider.
+type providerFunc func(ctx context.Context, req Request) (*Response, error)
+
+func (f providerFunc) Name() string { return "func" }
+
+func (f providerFunc) Model() string { return "func" }
+
+func (f providerFunc) Complete(ctx context.Context, req Request) (*Response, error) {
+	return f(ctx, req)
+}
+
+func TestOllama(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/generate" || r.Method != http.MethodPost {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		if body["model"] != "mistral" || body["stream"] != false {
+			t.Errorf("unexpected body %v", body)
+		}
+		if format, ok := body["format"].(map[string]any); !ok || format["type"] != "object" {
+			t.Errorf("schema not sent as format: %v", body["format"])
+		}
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error": "loading model"}`))
+			return
+		}
+		w.Write([]byte(`{"response": "{\"needs_citation\": true, \"confidence\": \"high\", \"reason\": \"number\", \"suggested_action\": \"add citation\"}", "prompt_eval_count": 120, "eval_count": 30}`))
+	}))
+	defer server.Close()
+
+	provider := NewOllama(server.URL, "mistral")
+	client := NewClientWithProvider(provider, Config{Retries: 1, Backoff: time.Millisecond})
+	var result ClaimAnalysisResult
+	resp, err := client.Generate(context.Background(), Request{Prompt: "Is 90% of trees binary?", Schema: claimAnalysisSchema}, &result)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if !result.NeedsCitation || resp.Usage != (Usage{Requests: 1, InputTokens: 120, OutputTokens: 30}) || calls.Load() != 2 {
+		t.Errorf("got %+v usage %+v after %d calls", result, resp.Usage, calls.Load())
+	}
+}
+
+func TestNewClientFromConfig_Offline(t *testing.T) {
+	tests := []struct {
+		cfg     Config
+		wantErr bool
+	}{
+		{Config{Provider: "claude", Offline: true}, true},
+		{Config{Provider: "ollama", URL: "http://gpu.example.org:11434", Offline: true}, true},
+		{Config{Provider: "ollama", URL: "localhost:11434", Offline: true}, false},
+		{Config{Provider: "ollama", URL: "http://127.0.0.1:11434", Offline: true}, false},
+		{Config{Provider: "ollama", URL: "http://gpu.example.org:11434"}, false},
+	}
+	for _, tt := range tests {
+		_, err := NewClientFromConfig(tt.cfg)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NewClientFromConfig(%+v) error = %v, want error %t", tt.cfg, err, tt.wantErr)
+		}
+	}
+}
+
+func TestClient_Prices(t *testing.T) {
+	provider := providerFunc(func(ctx context.Context, req Request) (*Response, error) {
+		return &Response{Text: "ok", Usage: Usage{Requests: 1, InputTokens: 2000, OutputTokens: 500, CostUSD: 0.5}}, nil
+	})
+	tests := []struct {
+		name     string
+		prices   map[string]Price
+		wantCost float64
+	}{
+		{"reported cost", nil, 1.0},
+		{"other model", map[string]Price{"haiku": {Input: 1, Output: 5}}, 1.0},
+		{"priced", map[string]Price{"func": {Input: 1, Output: 5}}, 2 * (0.002 + 0.0025)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClientWithProvider(provider, Config{Prices: tt.prices})
+			for range 2 {
+				if _, err := client.Complete(context.Background(), Request{Prompt: "hi"}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			usage := client.Usage()
+			if usage.Requests != 2 || usage.InputTokens != 4000 || math.Abs(usage.CostUSD-tt.wantCost) > 1e-9 {
+				t.Errorf("usage = %+v, want cost %v", usage, tt.wantCost)
+			}
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Request is a prompt sent to a provider.
+type Request struct {
+	Prompt  string
+	Schema  *Schema // constrains the response to JSON matching the schema; nil for free text
+	Version string  // version of the prompt template, part of the cache key
+}
+
+// Response is a provider's completion of a request.
+type Response struct {
+	Text  string
+	Usage Usage
+}
+
+// Usage counts the requests, tokens, and cost of LLM calls. Providers that
+// do not report tokens or cost leave them zero.
+type Usage struct {
+	Requests     int     `json:"requests"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+	Cached       int     `json:"cached"` // requests answered from the response cache
+}
+
+// Add adds other to u.
+func (u *Usage) Add(other Usage) {
+	u.Requests += other.Requests
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.CostUSD += other.CostUSD
+	u.Cached += other.Cached
+}
+
+// Price is what a model charges, in USD per million tokens.
+type Price struct {
+	Input  float64
+	Output float64
+}
+
+// Cost returns the cost of a request's tokens.
+func (p Price) Cost(inputTokens, outputTokens int) float64 {
+	return (float64(inputTokens)*p.Input + float64(outputTokens)*p.Output) / 1e6
+}
+
+// Provider completes prompts with a language model.
+type Provider interface {
+	Name() string
+	Model() string
+	Complete(ctx context.Context, req Request) (*Response, error)
+}
+
+// HTTPError is a failed HTTP request to a provider.
+type HTTPError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Body)
+}
+
+// withSchema appends the schema to the prompt for providers that cannot
+// constrain their output natively.
+func (r Request) withSchema() string {
+	if r.Schema == nil {
+		return r.Prompt
+	}
+	schema, _ := json.MarshalIndent(r.Schema, "", "  ")
+	return fmt.Sprintf("%s\n\nRespond with JSON only, matching this JSON schema:\n%s", r.Prompt, schema)
+}
+
+// ClaudeCLI runs prompts through the claude command-line tool.
+type ClaudeCLI struct {
+	Command string // "" runs claude from PATH
+
+	model string
+}
+
+// NewClaudeCLI creates a ClaudeCLI provider for model.
+func NewClaudeCLI(model string) *ClaudeCLI {
+	if model == "" {
+		model = DefaultClaudeModel
+	}
+	return &ClaudeCLI{model: model}
+}
+
+// Name returns "claude".
+func (p *ClaudeCLI) Name() string { return "claude" }
+
+// Model returns the model prompts are sent to.
+func (p *ClaudeCLI) Model() string { return p.model }
+
+// claudeResult is the JSON printed by claude -p --output-format json.
+type claudeResult struct {
+	Result       string  `json:"result"`
+	IsError      bool    `json:"is_error"`
+	TotalCostUSD float64 `json:"total_cost_usd"`
+	Usage        struct {
+		InputTokens              int `json:"input_tokens"`
+		CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+		CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+		OutputTokens             int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// Complete sends the prompt on stdin, so long prompts are not limited by
+// the size of the argument list.
+func (p *ClaudeCLI) Complete(ctx context.Context, req Request) (*Response, error) {
+	command := p.Command
+	if command == "" {
+		command = "claude"
+	}
+	cmd := exec.CommandContext(ctx, command, "-p", "--model", p.model, "--output-format", "json")
+	cmd.Stdin = strings.NewReader(req.withSchema())
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("claude CLI error: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var result claudeResult
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		// Older versions print plain text
+		return &Response{Text: strings.TrimSpace(stdout.String()), Usage: Usage{Requests: 1}}, nil
+	}
+	if result.IsError {
+		return nil, fmt.Errorf("claude CLI error: %s", result.Result)
+	}
+	return &Response{
+		Text: strings.TrimSpace(result.Result),
+		Usage: Usage{
+			Requests:     1,
+			InputTokens:  result.Usage.InputTokens + result.Usage.CacheCreationInputTokens + result.Usage.CacheReadInputTokens,
+			OutputTokens: result.Usage.OutputTokens,
+			CostUSD:      result.TotalCostUSD,
+		},
+	}, nil
+}
+
+// DefaultOllamaURL is the address of a local Ollama server.
+const DefaultOllamaURL = "http://localhost:11434"
+
+// Ollama sends prompts to an Ollama server's HTTP API.
+type Ollama struct {
+	BaseURL string
+	Client  *http.Client
+
+	model string
+}
+
+// NewOllama creates an Ollama provider. An empty baseURL uses $OLLAMA_HOST,
+// then DefaultOllamaURL.
+func NewOllama(baseURL, model string) *Ollama {
+	if baseURL == "" {
+		baseURL = os.Getenv("OLLAMA_HOST")
+	}
+	if baseURL == "" {
+		baseURL = DefaultOllamaURL
+	}
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "http://" + baseURL
+	}
+	if model == "" {
+		model = DefaultOllamaModel
+	}
+	return &Ollama{BaseURL: strings.TrimSuffix(baseURL, "/"), Client: http.DefaultClient, model: model}
+}
+
+// Name returns "ollama".
+func (p *Ollama) Name() string { return "ollama" }
+
+// Local reports whether the server runs on this machine, so that it can be
+// used without network access.
+func (p *Ollama) Local() bool {
+	u, err := url.Parse(p.BaseURL)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Model returns the model prompts are sent to.
+func (p *Ollama) Model() string { return p.model }
+
+// ollamaRequest is the body of a POST to /api/generate.
+type ollamaRequest struct {
+	Model   string         `json:"model"`
+	Prompt  string         `json:"prompt"`
+	Stream  bool           `json:"stream"`
+	Format  *Schema        `json:"format,omitempty"`
+	Options map[string]any `json:"options,omitempty"`
+}
+
+// ollamaResponse is a non-streamed /api/generate response.
+type ollamaResponse struct {
+	Response        string `json:"response"`
+	Error           string `json:"error"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+}
+
+// Complete generates a response. Schemas are passed as Ollama's format
+// parameter, which constrains decoding to matching JSON.
+func (p *Ollama) Complete(ctx context.Context, req Request) (*Response, error) {
+	body, err := json.Marshal(ollamaRequest{
+		Model:   p.model,
+		Prompt:  req.withSchema(),
+		Format:  req.Schema,
+		Options: map[string]any{"temperature": 0},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encode ollama request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.BaseURL+"/api/generate", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("ollama request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("ollama request: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return nil, fmt.Errorf("read ollama response: %w", err)
+	}
+
+	var result ollamaResponse
+	decodeErr := json.Unmarshal(data, &result)
+	if resp.StatusCode != http.StatusOK {
+		message := strings.TrimSpace(string(data))
+		if decodeErr == nil && result.Error != "" {
+			message = result.Error
+		}
+		return nil, fmt.Errorf("ollama: %w", &HTTPError{StatusCode: resp.StatusCode, Body: message})
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("decode ollama response: %w", decodeErr)
+	}
+	if result.Error != "" {
+		return nil, errors.New("ollama: " + result.Error)
+	}
+	return &Response{
+		Text:  strings.TrimSpace(result.Response),
+		Usage: Usage{Requests: 1, InputTokens: result.PromptEvalCount, OutputTokens: result.EvalCount},
+	}, nil
+}
@@ -0,0 +1,135 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Schema is the subset of JSON Schema used to constrain LLM responses:
+// objects with typed properties, arrays, and string enums.
+type Schema struct {
+	Type        string             `json:"type"` // object, array, string, number, integer, or boolean
+	Description string             `json:"description,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+}
+
+// ErrInvalidResponse is returned when a response is not JSON matching the
+// request's schema.
+var ErrInvalidResponse = errors.New("invalid LLM response")
+
+// Validate checks a decoded JSON value against the schema.
+func (s *Schema) Validate(v any) error {
+	return s.validate(v, "$")
+}
+
+func (s *Schema) validate(v any, path string) error {
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: want an object", path)
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				return fmt.Errorf("%s: missing %q", path, name)
+			}
+		}
+		names := make([]string, 0, len(s.Properties))
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if value, ok := obj[name]; ok && value != nil {
+				if err := s.Properties[name].validate(value, path+"."+name); err != nil {
+					return err
+				}
+			}
+		}
+	case "array":
+		items, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("%s: want an array", path)
+		}
+		if s.Items != nil {
+			for i, item := range items {
+				if err := s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s: want a string", path)
+		}
+		if len(s.Enum) > 0 && !slices.Contains(s.Enum, str) {
+			return fmt.Errorf("%s: %q is not one of %s", path, str, strings.Join(s.Enum, ", "))
+		}
+	case "number", "integer":
+		n, ok := v.(float64)
+		if !ok {
+			return fmt.Errorf("%s: want a number", path)
+		}
+		if s.Type == "integer" && n != math.Trunc(n) {
+			return fmt.Errorf("%s: want an integer", path)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s: want a boolean", path)
+		}
+	}
+	return nil
+}
+
+// Decode parses a JSON response, checks it against schema, and stores it
+// in out. Markdown code fences and prose around the JSON are ignored.
+func Decode(text string, schema *Schema, out any) error {
+	raw := extractJSON(text)
+	var value any
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	if schema != nil {
+		if err := schema.Validate(value); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+		}
+	}
+	if err := json.Unmarshal([]byte(raw), out); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	return nil
+}
+
+// extractJSON extracts the JSON value from a response that might be wrapped
+// in markdown fences or prose.
+func extractJSON(s string) string {
+	s = strings.TrimSpace(s)
+	if rest, ok := strings.CutPrefix(s, "```"); ok {
+		rest = strings.TrimPrefix(rest, "json")
+		if idx := strings.Index(rest, "```"); idx != -1 {
+			rest = rest[:idx]
+		}
+		s = strings.TrimSpace(rest)
+	}
+	if strings.HasPrefix(s, "{") || strings.HasPrefix(s, "[") {
+		return s
+	}
+	opening, closing := "{", "}"
+	if i, j := strings.Index(s, "["), strings.Index(s, "{"); i >= 0 && (j < 0 || i < j) {
+		opening, closing = "[", "]"
+	}
+	start, end := strings.Index(s, opening), strings.LastIndex(s, closing)
+	if start >= 0 && end > start {
+		return s[start : end+1]
+	}
+	return s
+}
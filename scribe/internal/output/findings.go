@@ -0,0 +1,335 @@
+package output
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Format is a report output format.
+type Format string
+
+const (
+	FormatJSON   Format = "json"
+	FormatHuman  Format = "human"
+	FormatSARIF  Format = "sarif"
+	FormatJUnit  Format = "junit"
+	FormatGitHub Format = "github"
+)
+
+// ParseFormat validates a --format flag value.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatJSON, FormatHuman, FormatSARIF, FormatJUnit, FormatGitHub:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown format %q (want json, human, sarif, junit, or github)", s)
+	}
+}
+
+// Level is the severity of a finding, using SARIF's level names.
+type Level string
+
+const (
+	LevelError   Level = "error"
+	LevelWarning Level = "warning"
+	LevelNote    Level = "note"
+	LevelNone    Level = "none" // the check passed
+)
+
+// Rule describes a kind of check, such as a verify or sweep check type.
+type Rule struct {
+	ID          string
+	Description string
+}
+
+// Finding is the outcome of one check at a source location.
+type Finding struct {
+	RuleID  string
+	Level   Level
+	File    string
+	Line    int // 0 if the finding is not tied to a line
+	Target  string
+	Message string
+}
+
+// FindingSet is a tool's findings for a CI report.
+type FindingSet struct {
+	Tool     string
+	Version  string
+	Rules    []Rule
+	Findings []Finding
+}
+
+// WriteFindings writes findings in a machine-readable CI format.
+func WriteFindings(w io.Writer, format Format, set FindingSet) error {
+	switch format {
+	case FormatSARIF:
+		return WriteSARIF(w, set)
+	case FormatJUnit:
+		return WriteJUnit(w, set)
+	case FormatGitHub:
+		return WriteGitHub(w, set)
+	default:
+		return fmt.Errorf("format %q is not a findings format", format)
+	}
+}
+
+// SARIF 2.1.0 document structure (only the fields scribe emits).
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name    string      `json:"name"`
+	Version string      `json:"version,omitempty"`
+	Rules   []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     Level           `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// WriteSARIF writes failed and warning findings as a SARIF 2.1.0 log.
+// Each rule becomes a reportingDescriptor so viewers can group results by check type.
+func WriteSARIF(w io.Writer, set FindingSet) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:    set.Tool,
+			Version: set.Version,
+			Rules:   []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+
+	ruleIndex := make(map[string]int)
+	addRule := func(id, description string) int {
+		if i, ok := ruleIndex[id]; ok {
+			return i
+		}
+		if description == "" {
+			description = id
+		}
+		ruleIndex[id] = len(run.Tool.Driver.Rules)
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+			ID:               id,
+			ShortDescription: sarifMessage{Text: description},
+		})
+		return ruleIndex[id]
+	}
+	for _, rule := range set.Rules {
+		addRule(rule.ID, rule.Description)
+	}
+
+	for _, f := range set.Findings {
+		if f.Level == LevelNone {
+			continue
+		}
+		result := sarifResult{
+			RuleID:    f.RuleID,
+			RuleIndex: addRule(f.RuleID, ""),
+			Level:     f.Level,
+			Message:   sarifMessage{Text: f.Message},
+		}
+		if f.File != "" {
+			loc := sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(f.File)},
+			}}
+			if f.Line > 0 {
+				loc.PhysicalLocation.Region = &sarifRegion{StartLine: f.Line}
+			}
+			result.Locations = []sarifLocation{loc}
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// JUnit XML document structure.
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// maxTestNameLen caps the target text used in a JUnit test case name.
+const maxTestNameLen = 80
+
+// WriteJUnit writes one JUnit test case per finding, grouped into a suite per file.
+// Errors become failures; warnings and notes pass with the message in system-out.
+func WriteJUnit(w io.Writer, set FindingSet) error {
+	doc := junitTestSuites{Name: set.Tool}
+	suiteIndex := make(map[string]int)
+
+	for _, f := range set.Findings {
+		i, ok := suiteIndex[f.File]
+		if !ok {
+			i = len(doc.Suites)
+			suiteIndex[f.File] = i
+			doc.Suites = append(doc.Suites, junitTestSuite{Name: f.File})
+		}
+		suite := &doc.Suites[i]
+
+		tc := junitTestCase{
+			Name:      testCaseName(f),
+			ClassName: set.Tool + "." + f.RuleID,
+		}
+		switch f.Level {
+		case LevelError:
+			tc.Failure = &junitFailure{
+				Message: f.Message,
+				Type:    f.RuleID,
+				Text:    fmt.Sprintf("%s:%d: %s", f.File, f.Line, f.Message),
+			}
+			suite.Failures++
+			doc.Failures++
+		case LevelWarning, LevelNote:
+			tc.SystemOut = fmt.Sprintf("%s: %s", f.Level, f.Message)
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+		suite.Tests++
+		doc.Tests++
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// testCaseName names a JUnit test case after its line and target.
+func testCaseName(f Finding) string {
+	target := strings.Join(strings.Fields(f.Target), " ")
+	if len(target) > maxTestNameLen {
+		target = target[:maxTestNameLen-3] + "..."
+	}
+	if f.Line > 0 {
+		return fmt.Sprintf("line %d: %s", f.Line, target)
+	}
+	return target
+}
+
+// WriteGitHub writes failed and warning findings as GitHub Actions workflow
+// commands, which show up as inline annotations on pull requests.
+func WriteGitHub(w io.Writer, set FindingSet) error {
+	for _, f := range set.Findings {
+		var command string
+		switch f.Level {
+		case LevelError:
+			command = "error"
+		case LevelWarning:
+			command = "warning"
+		case LevelNote:
+			command = "notice"
+		default:
+			continue
+		}
+
+		props := []string{}
+		if f.File != "" {
+			props = append(props, "file="+escapeGitHubProperty(filepath.ToSlash(f.File)))
+			if f.Line > 0 {
+				props = append(props, fmt.Sprintf("line=%d", f.Line))
+			}
+		}
+		props = append(props, "title="+escapeGitHubProperty(set.Tool+" "+f.RuleID))
+
+		if _, err := fmt.Fprintf(w, "::%s %s::%s\n", command, strings.Join(props, ","), escapeGitHubData(f.Message)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// escapeGitHubData escapes a workflow command message.
+func escapeGitHubData(s string) string {
+	s = strings.ReplaceAll(s, "%", "%25")
+	s = strings.ReplaceAll(s, "\r", "%0D")
+	return strings.ReplaceAll(s, "\n", "%0A")
+}
+
+// escapeGitHubProperty escapes a workflow command property value.
+func escapeGitHubProperty(s string) string {
+	s = escapeGitHubData(s)
+	s = strings.ReplaceAll(s, ":", "%3A")
+	return strings.ReplaceAll(s, ",", "%2C")
+}
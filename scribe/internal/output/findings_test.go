@@ -0,0 +1,107 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func testFindings() FindingSet {
+	return FindingSet{
+		Tool:    "scribe-verify",
+		Version: "dev",
+		Rules:   []Rule{{ID: "url", Description: "URLs are reachable"}},
+		Findings: []Finding{
+			{RuleID: "url", Level: LevelNone, File: "intro.qmd", Line: 3, Target: "https://example.com", Message: "ok"},
+			{RuleID: "url", Level: LevelError, File: "intro.qmd", Line: 7, Target: "https://example.com/gone", Message: "HTTP 404: Not Found"},
+			{RuleID: "claim", Level: LevelWarning, File: "dir/a,b.qmd", Line: 2, Target: "A claim.", Message: "50% sure\nmaybe"},
+		},
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, s := range []string{"json", "human", "SARIF", "junit", "github"} {
+		if _, err := ParseFormat(s); err != nil {
+			t.Errorf("ParseFormat(%q) failed: %v", s, err)
+		}
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, testFindings()); err != nil {
+		t.Fatalf("WriteSARIF failed: %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("invalid SARIF JSON: %v", err)
+	}
+	run := log.Runs[0]
+	if log.Version != "2.1.0" || run.Tool.Driver.Name != "scribe-verify" {
+		t.Errorf("unexpected log header: %+v", log)
+	}
+	if len(run.Tool.Driver.Rules) != 2 {
+		t.Errorf("expected declared and discovered rules, got %+v", run.Tool.Driver.Rules)
+	}
+	if len(run.Results) != 2 {
+		t.Fatalf("expected passing findings to be omitted, got %d results", len(run.Results))
+	}
+
+	r := run.Results[0]
+	if r.RuleID != "url" || r.RuleIndex != 0 || r.Level != LevelError {
+		t.Errorf("unexpected result: %+v", r)
+	}
+	loc := r.Locations[0].PhysicalLocation
+	if loc.ArtifactLocation.URI != "intro.qmd" || loc.Region.StartLine != 7 {
+		t.Errorf("unexpected location: %+v", loc)
+	}
+	if run.Results[1].RuleIndex != 1 {
+		t.Errorf("claim rule index = %d, want 1", run.Results[1].RuleIndex)
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, testFindings()); err != nil {
+		t.Fatalf("WriteJUnit failed: %v", err)
+	}
+
+	var doc junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JUnit XML: %v", err)
+	}
+	if doc.Tests != 3 || doc.Failures != 1 || len(doc.Suites) != 2 {
+		t.Errorf("tests=%d failures=%d suites=%d, want 3, 1, 2", doc.Tests, doc.Failures, len(doc.Suites))
+	}
+	failed := doc.Suites[0].TestCases[1]
+	if failed.Failure == nil || failed.Failure.Type != "url" || failed.Name != "line 7: https://example.com/gone" {
+		t.Errorf("unexpected failed test case: %+v", failed)
+	}
+}
+
+func TestWriteGitHub(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteGitHub(&buf, testFindings()); err != nil {
+		t.Fatalf("WriteGitHub failed: %v", err)
+	}
+
+	want := []string{
+		"::error file=intro.qmd,line=7,title=scribe-verify url::HTTP 404: Not Found",
+		"::warning file=dir/a%2Cb.qmd,line=2,title=scribe-verify claim::50%25 sure%0Amaybe",
+	}
+	got := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(got) != len(want) {
+		t.Fatalf("got %d annotations, want %d:\n%s", len(got), len(want), buf.String())
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("annotation %d:\n got %s\nwant %s", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package papers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// index is the inverted index of a store: for each term, the paragraphs
+// containing it.
+type index struct {
+	Papers map[string][]int     `json:"papers"` // paper ID -> length in terms of each paragraph
+	Terms  map[string][]posting `json:"terms"`
+}
+
+// posting is an occurrence of a term in a paragraph. Para counts
+// paragraphs across all pages of the paper.
+type posting struct {
+	Paper string `json:"paper"`
+	Para  int    `json:"para"`
+	Count int    `json:"count"`
+}
+
+// loadIndex reads the store's index, or returns an empty one.
+func (s *Store) loadIndex() (*index, error) {
+	idx := &index{Papers: make(map[string][]int), Terms: make(map[string][]posting)}
+	data, err := os.ReadFile(filepath.Join(s.dir, indexFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read paper index: %w", err)
+	}
+	if err := json.Unmarshal(data, idx); err != nil {
+		return nil, fmt.Errorf("parse paper index: %w", err)
+	}
+	return idx, nil
+}
+
+// add indexes a paper, replacing its previous postings.
+func (idx *index) add(paper *Paper) {
+	idx.remove(paper.ID)
+	var lengths []int
+	para := 0
+	for _, page := range paper.Pages {
+		for _, text := range page.Paragraphs {
+			counts := make(map[string]int)
+			terms := Terms(text)
+			for _, term := range terms {
+				counts[term]++
+			}
+			for term, count := range counts {
+				idx.Terms[term] = append(idx.Terms[term], posting{Paper: paper.ID, Para: para, Count: count})
+			}
+			lengths = append(lengths, len(terms))
+			para++
+		}
+	}
+	idx.Papers[paper.ID] = lengths
+}
+
+// remove drops a paper's postings.
+func (idx *index) remove(id string) {
+	if _, ok := idx.Papers[id]; !ok {
+		return
+	}
+	delete(idx.Papers, id)
+	for term, postings := range idx.Terms {
+		kept := postings[:0]
+		for _, p := range postings {
+			if p.Paper != id {
+				kept = append(kept, p)
+			}
+		}
+		if len(kept) == 0 {
+			delete(idx.Terms, term)
+		} else {
+			idx.Terms[term] = kept
+		}
+	}
+}
+
+// stopWords are too common to be useful search terms.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"by": true, "for": true, "from": true, "in": true, "is": true, "it": true, "of": true,
+	"on": true, "or": true, "that": true, "the": true, "this": true, "to": true, "we": true,
+	"was": true, "were": true, "with": true,
+}
+
+// Terms splits text into lowercase index terms, dropping stop words.
+func Terms(text string) []string {
+	var terms []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if !stopWords[word] {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// Hit is a paragraph matching a search.
+type Hit struct {
+	PaperID   string  `json:"paper_id"`
+	Page      int     `json:"page"`
+	Paragraph int     `json:"paragraph"` // 1-based within the page
+	Text      string  `json:"text"`
+	Score     float64 `json:"score"`
+}
+
+// BM25 parameters.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Search returns the paragraphs best matching query, ranked by BM25. If
+// paperID is set, only that paper is searched. At most limit hits are
+// returned (all if limit <= 0).
+func (s *Store) Search(query, paperID string, limit int) ([]Hit, error) {
+	idx, err := s.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	total, length := 0, 0
+	for _, lengths := range idx.Papers {
+		for _, n := range lengths {
+			length += n
+		}
+		total += len(lengths)
+	}
+	if total == 0 {
+		return nil, nil
+	}
+	avgLength := float64(length) / float64(total)
+
+	type key struct {
+		paper string
+		para  int
+	}
+	scores := make(map[key]float64)
+	seen := make(map[string]bool)
+	for _, term := range Terms(query) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		postings := idx.Terms[term]
+		idf := math.Log(1 + (float64(total)-float64(len(postings))+0.5)/(float64(len(postings))+0.5))
+		for _, p := range postings {
+			if paperID != "" && p.Paper != paperID {
+				continue
+			}
+			lengths := idx.Papers[p.Paper]
+			if p.Para >= len(lengths) {
+				continue
+			}
+			tf := float64(p.Count)
+			norm := 1 - bm25B + bm25B*float64(lengths[p.Para])/avgLength
+			scores[key{p.Paper, p.Para}] += idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+		}
+	}
+
+	keys := make([]key, 0, len(scores))
+	for k := range scores {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if scores[keys[i]] != scores[keys[j]] {
+			return scores[keys[i]] > scores[keys[j]]
+		}
+		if keys[i].paper != keys[j].paper {
+			return keys[i].paper < keys[j].paper
+		}
+		return keys[i].para < keys[j].para
+	})
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+
+	hits := make([]Hit, 0, len(keys))
+	papers := make(map[string]*Paper)
+	for _, k := range keys {
+		paper, ok := papers[k.paper]
+		if !ok {
+			if paper, err = s.Get(k.paper); err != nil {
+				return nil, err
+			}
+			papers[k.paper] = paper
+		}
+		if paper == nil {
+			continue
+		}
+		if page, n, text, ok := paper.paragraph(k.para); ok {
+			hits = append(hits, Hit{PaperID: k.paper, Page: page, Paragraph: n, Text: text, Score: scores[k]})
+		}
+	}
+	return hits, nil
+}
+
+// paragraph finds the i-th paragraph across pages, returning its page
+// number, 1-based position within the page, and text.
+func (p *Paper) paragraph(i int) (int, int, string, bool) {
+	for _, page := range p.Pages {
+		if i < len(page.Paragraphs) {
+			return page.Number, i + 1, page.Paragraphs[i], true
+		}
+		i -= len(page.Paragraphs)
+	}
+	return 0, 0, "", false
+}
+
+// List returns the IDs of the stored papers, sorted.
+func (s *Store) List() ([]string, error) {
+	idx, err := s.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(idx.Papers))
+	for id := range idx.Papers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
@@ -0,0 +1,949 @@
+package papers
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// This file is a small PDF text extractor. It reads classic and compressed
+// object streams, walks the page tree, decodes Flate, ASCIIHex, and ASCII85
+// content, maps glyph codes through ToUnicode CMaps, and lays out text
+// operators into lines and paragraphs. It does not render, so text drawn
+// as images (scanned PDFs) is not recovered.
+
+// PDF object types. Dictionaries are map[string]any keyed by name without
+// the slash, arrays are []any, and numbers are float64.
+type (
+	pdfName   string
+	pdfString []byte
+	pdfRef    struct{ num, gen int }
+	pdfStream struct {
+		dict map[string]any
+		data []byte // raw, still encoded
+	}
+	pdfKeyword string // an operator or unknown keyword
+)
+
+// lexer reads PDF tokens.
+type lexer struct {
+	data []byte
+	pos  int
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+// skipSpace skips whitespace and comments.
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isPDFSpace(c) {
+			l.pos++
+		} else if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		} else {
+			return
+		}
+	}
+}
+
+// errEnd is returned when the lexer runs out of input.
+var errEnd = errors.New("unexpected end of PDF data")
+
+// next returns the next token: a value, or a delimiter such as "[", "]",
+// "<<", or ">>" as a pdfKeyword.
+func (l *lexer) next() (any, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, errEnd
+	}
+	c := l.data[l.pos]
+	switch {
+	case c == '/':
+		l.pos++
+		start := l.pos
+		for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+			l.pos++
+		}
+		return pdfName(decodeName(l.data[start:l.pos])), nil
+	case c == '(':
+		return l.literalString()
+	case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+		l.pos += 2
+		return pdfKeyword("<<"), nil
+	case c == '>' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '>':
+		l.pos += 2
+		return pdfKeyword(">>"), nil
+	case c == '<':
+		end := bytes.IndexByte(l.data[l.pos:], '>')
+		if end < 0 {
+			return nil, errEnd
+		}
+		s := hexString(l.data[l.pos+1 : l.pos+end])
+		l.pos += end + 1
+		return s, nil
+	case c == '[' || c == ']' || c == '{' || c == '}':
+		l.pos++
+		return pdfKeyword(string(c)), nil
+	}
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	if l.pos == start { // stray delimiter such as ")" or ">"
+		l.pos++
+	}
+	word := string(l.data[start:l.pos])
+	if n, err := strconv.ParseFloat(word, 64); err == nil && (word[0] == '-' || word[0] == '+' || word[0] == '.' || word[0] >= '0' && word[0] <= '9') {
+		return n, nil
+	}
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	return pdfKeyword(word), nil
+}
+
+// decodeName expands #xx escapes in a name.
+func decodeName(b []byte) string {
+	if bytes.IndexByte(b, '#') < 0 {
+		return string(b)
+	}
+	var out []byte
+	for i := 0; i < len(b); i++ {
+		if b[i] == '#' && i+2 < len(b) {
+			if v, err := strconv.ParseUint(string(b[i+1:i+3]), 16, 8); err == nil {
+				out = append(out, byte(v))
+				i += 2
+				continue
+			}
+		}
+		out = append(out, b[i])
+	}
+	return string(out)
+}
+
+// literalString reads a (...) string, handling nesting and escapes.
+func (l *lexer) literalString() (pdfString, error) {
+	l.pos++ // (
+	var out []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return out, nil
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return out, nil
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for k := 0; k < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; k++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					out = append(out, byte(v))
+				} else {
+					out = append(out, e)
+				}
+			}
+			continue
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// hexString decodes the body of a <...> string.
+func hexString(b []byte) pdfString {
+	digits := make([]byte, 0, len(b)+1)
+	for _, c := range b {
+		if !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	if _, err := hex.Decode(out, digits); err != nil {
+		return nil
+	}
+	return out
+}
+
+// object reads a complete object, combining "n g R" into a reference and
+// reading arrays and dictionaries.
+func (l *lexer) object() (any, error) {
+	tok, err := l.next()
+	if err != nil {
+		return nil, err
+	}
+	return l.objectFrom(tok)
+}
+
+func (l *lexer) objectFrom(tok any) (any, error) {
+	switch t := tok.(type) {
+	case pdfKeyword:
+		switch t {
+		case "[":
+			var arr []any
+			for {
+				item, err := l.next()
+				if err != nil {
+					return arr, err
+				}
+				if item == pdfKeyword("]") {
+					return arr, nil
+				}
+				v, err := l.objectFrom(item)
+				if err != nil {
+					return arr, err
+				}
+				arr = append(arr, v)
+			}
+		case "<<":
+			dict := make(map[string]any)
+			for {
+				key, err := l.next()
+				if err != nil {
+					return dict, err
+				}
+				if key == pdfKeyword(">>") {
+					return dict, nil
+				}
+				name, ok := key.(pdfName)
+				if !ok {
+					continue
+				}
+				v, err := l.object()
+				if err != nil {
+					return dict, err
+				}
+				dict[string(name)] = v
+			}
+		}
+	case float64:
+		// Look ahead for "gen R"
+		save := l.pos
+		if gen, err := l.next(); err == nil {
+			if g, ok := gen.(float64); ok {
+				if r, err := l.next(); err == nil && r == pdfKeyword("R") {
+					return pdfRef{int(t), int(g)}, nil
+				}
+			}
+		}
+		l.pos = save
+	}
+	return tok, nil
+}
+
+// pdfDoc is a parsed PDF file.
+type pdfDoc struct {
+	objects map[int]any
+	trailer map[string]any
+}
+
+// objPattern finds "n g obj" headers.
+var objPattern = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// parsePDF reads every object in the file. Objects are found by scanning
+// rather than through the cross-reference table, which also recovers
+// damaged files; later definitions win, as with incremental updates.
+func parsePDF(data []byte) (*pdfDoc, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("%PDF")) {
+		return nil, fmt.Errorf("not a PDF file")
+	}
+	doc := &pdfDoc{objects: make(map[int]any), trailer: make(map[string]any)}
+	for _, m := range objPattern.FindAllSubmatchIndex(data, -1) {
+		num, _ := strconv.Atoi(string(data[m[2]:m[3]]))
+		l := &lexer{data: data, pos: m[1]}
+		obj, err := l.object()
+		if err != nil {
+			continue
+		}
+		if dict, ok := obj.(map[string]any); ok {
+			if stream, ok := readStream(l, dict); ok {
+				obj = stream
+			}
+		}
+		doc.objects[num] = obj
+	}
+
+	// Trailer dictionaries, or the dictionaries of cross-reference streams
+	for _, i := range allIndexes(data, []byte("trailer")) {
+		l := &lexer{data: data, pos: i + len("trailer")}
+		if dict, err := l.object(); err == nil {
+			if d, ok := dict.(map[string]any); ok {
+				for k, v := range d {
+					doc.trailer[k] = v
+				}
+			}
+		}
+	}
+	var objStreams []int
+	for num, obj := range doc.objects {
+		if s, ok := obj.(*pdfStream); ok {
+			switch s.dict["Type"] {
+			case pdfName("XRef"):
+				if root, ok := s.dict["Root"]; ok {
+					doc.trailer["Root"] = root
+				}
+			case pdfName("ObjStm"):
+				objStreams = append(objStreams, num)
+			}
+		}
+	}
+	sort.Ints(objStreams)
+	for _, num := range objStreams {
+		doc.loadObjectStream(doc.objects[num].(*pdfStream))
+	}
+	return doc, nil
+}
+
+// allIndexes returns the offsets of every occurrence of sep in data.
+func allIndexes(data, sep []byte) []int {
+	var out []int
+	for i := 0; ; {
+		j := bytes.Index(data[i:], sep)
+		if j < 0 {
+			return out
+		}
+		out = append(out, i+j)
+		i += j + len(sep)
+	}
+}
+
+// readStream reads the stream body following a dictionary, if there is one.
+func readStream(l *lexer, dict map[string]any) (*pdfStream, bool) {
+	l.skipSpace()
+	if !bytes.HasPrefix(l.data[l.pos:], []byte("stream")) {
+		return nil, false
+	}
+	start := l.pos + len("stream")
+	if start < len(l.data) && l.data[start] == '\r' {
+		start++
+	}
+	if start < len(l.data) && l.data[start] == '\n' {
+		start++
+	}
+	end := -1
+	if n, ok := dict["Length"].(float64); ok && n >= 0 && n <= float64(len(l.data)-start) {
+		if e := start + int(n); bytes.Contains(l.data[e:min(e+20, len(l.data))], []byte("endstream")) {
+			end = e
+		}
+	}
+	if end < 0 { // indirect or wrong length: search for the end marker
+		i := bytes.Index(l.data[start:], []byte("endstream"))
+		if i < 0 {
+			return nil, false
+		}
+		end = start + i
+		for end > start && (l.data[end-1] == '\n' || l.data[end-1] == '\r') {
+			end--
+		}
+	}
+	l.pos = end
+	return &pdfStream{dict: dict, data: l.data[start:end]}, true
+}
+
+// loadObjectStream adds the objects packed in an object stream.
+// Objects defined directly in the file take precedence.
+func (d *pdfDoc) loadObjectStream(s *pdfStream) {
+	data, err := d.decode(s)
+	if err != nil {
+		return
+	}
+	n, _ := d.resolve(s.dict["N"]).(float64)
+	first, _ := d.resolve(s.dict["First"]).(float64)
+	if n < 0 || n > float64(len(data)) || first < 0 || first > float64(len(data)) {
+		return
+	}
+	header := &lexer{data: data}
+	for i := 0; i < int(n); i++ {
+		num, err1 := header.next()
+		off, err2 := header.next()
+		numF, ok1 := num.(float64)
+		offF, ok2 := off.(float64)
+		if err1 != nil || err2 != nil || !ok1 || !ok2 {
+			return
+		}
+		if _, exists := d.objects[int(numF)]; exists {
+			continue
+		}
+		if offF < 0 || offF >= float64(len(data))-first {
+			continue
+		}
+		l := &lexer{data: data, pos: int(first) + int(offF)}
+		if obj, err := l.object(); err == nil {
+			d.objects[int(numF)] = obj
+		}
+	}
+}
+
+// resolve follows references.
+func (d *pdfDoc) resolve(v any) any {
+	for i := 0; i < 32; i++ {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = d.objects[ref.num]
+	}
+	return nil
+}
+
+// dict resolves v to a dictionary, including a stream's dictionary.
+func (d *pdfDoc) dict(v any) map[string]any {
+	switch t := d.resolve(v).(type) {
+	case map[string]any:
+		return t
+	case *pdfStream:
+		return t.dict
+	}
+	return nil
+}
+
+// decode applies a stream's filters.
+func (d *pdfDoc) decode(s *pdfStream) ([]byte, error) {
+	var filters []any
+	switch f := d.resolve(s.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []any{f}
+	case []any:
+		filters = f
+	}
+	data := s.data
+	for _, f := range filters {
+		var err error
+		switch d.resolve(f) {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			data, err = inflate(data)
+		case pdfName("ASCIIHexDecode"), pdfName("AHx"):
+			if end := bytes.IndexByte(data, '>'); end >= 0 {
+				data = data[:end]
+			}
+			data = hexString(data)
+		case pdfName("ASCII85Decode"), pdfName("A85"):
+			data, err = decodeASCII85(data)
+		default:
+			return nil, fmt.Errorf("unsupported filter %v", f)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// inflate decompresses zlib data, keeping whatever was read before an error
+// since truncated streams are common.
+func inflate(data []byte) ([]byte, error) {
+	var r io.ReadCloser
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		r = flate.NewReader(bytes.NewReader(data))
+	}
+	defer r.Close()
+	out, err := io.ReadAll(r)
+	if len(out) > 0 {
+		return out, nil
+	}
+	return out, err
+}
+
+func decodeASCII85(data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+	if end := bytes.Index(data, []byte("~>")); end >= 0 {
+		data = data[:end]
+	}
+	out := make([]byte, 4*len(data)/5+4)
+	n, _, err := ascii85.Decode(out, data, true)
+	return out[:n], err
+}
+
+// pages returns the page dictionaries in order, each with its inherited
+// resources.
+func (d *pdfDoc) pages() []map[string]any {
+	var pages []map[string]any
+	var walk func(node map[string]any, resources any, depth int)
+	walk = func(node map[string]any, resources any, depth int) {
+		if node == nil || depth > 64 { // the depth limit also breaks cycles
+			return
+		}
+		if r, ok := node["Resources"]; ok {
+			resources = r
+		}
+		kids, isTree := d.resolve(node["Kids"]).([]any)
+		if !isTree {
+			page := make(map[string]any, len(node)+1)
+			for k, v := range node {
+				page[k] = v
+			}
+			page["Resources"] = resources
+			pages = append(pages, page)
+			return
+		}
+		for _, kid := range kids {
+			walk(d.dict(kid), resources, depth+1)
+		}
+	}
+	if root := d.dict(d.trailer["Root"]); root != nil {
+		walk(d.dict(root["Pages"]), nil, 0)
+	}
+	if len(pages) > 0 {
+		return pages
+	}
+
+	// No usable page tree: take page objects in object order
+	var nums []int
+	for num, obj := range d.objects {
+		if dict, ok := obj.(map[string]any); ok && dict["Type"] == pdfName("Page") {
+			nums = append(nums, num)
+		}
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		pages = append(pages, d.objects[num].(map[string]any))
+	}
+	return pages
+}
+
+// ExtractPDF returns the text of each page of a PDF. Lines are separated by
+// "\n" and paragraphs by blank lines. A malformed file is an error, never
+// a panic.
+func ExtractPDF(data []byte) (texts []string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			texts, err = nil, fmt.Errorf("malformed PDF: %v", r)
+		}
+	}()
+	return extractPDF(data)
+}
+
+// extractPDF does the work of ExtractPDF without recovering from panics, so
+// that fuzzing finds them.
+func extractPDF(data []byte) ([]string, error) {
+	doc, err := parsePDF(data)
+	if err != nil {
+		return nil, err
+	}
+	if _, encrypted := doc.trailer["Encrypt"]; encrypted {
+		return nil, fmt.Errorf("encrypted PDFs are not supported")
+	}
+	pages := doc.pages()
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("no pages found")
+	}
+	var texts []string
+	for _, page := range pages {
+		var content []byte
+		switch c := doc.resolve(page["Contents"]).(type) {
+		case *pdfStream:
+			content, _ = doc.decode(c)
+		case []any:
+			for _, part := range c {
+				if s, ok := doc.resolve(part).(*pdfStream); ok {
+					data, _ := doc.decode(s)
+					content = append(append(content, data...), '\n')
+				}
+			}
+		}
+		w := &textWriter{}
+		doc.runContent(content, doc.dict(page["Resources"]), w, 0)
+		texts = append(texts, w.String())
+	}
+	return texts, nil
+}
+
+// font decodes the strings shown in a font.
+type font struct {
+	cmap     map[string]string // code bytes -> text, from ToUnicode
+	codeLen  int               // bytes per code in the CMap (1 or 2)
+	twoBytes bool              // composite (Type0) font without a CMap
+}
+
+// loadFont reads a font's ToUnicode CMap.
+func (d *pdfDoc) loadFont(v any) *font {
+	dict := d.dict(v)
+	f := &font{codeLen: 1}
+	if dict == nil {
+		return f
+	}
+	f.twoBytes = dict["Subtype"] == pdfName("Type0")
+	if s, ok := d.resolve(dict["ToUnicode"]).(*pdfStream); ok {
+		if data, err := d.decode(s); err == nil {
+			f.cmap, f.codeLen = parseCMap(data)
+		}
+	}
+	return f
+}
+
+// parseCMap reads the bfchar and bfrange mappings of a ToUnicode CMap.
+func parseCMap(data []byte) (map[string]string, int) {
+	cmap := make(map[string]string)
+	codeLen := 1
+	l := &lexer{data: data}
+	var operands []any
+	for {
+		tok, err := l.next()
+		if err != nil {
+			break
+		}
+		if kw, ok := tok.(pdfKeyword); ok && kw != "[" {
+			switch kw {
+			case "endcodespacerange":
+				if len(operands) >= 1 {
+					if s, ok := operands[0].(pdfString); ok && len(s) > 0 {
+						codeLen = len(s)
+					}
+				}
+			case "endbfchar":
+				for i := 0; i+1 < len(operands); i += 2 {
+					src, ok1 := operands[i].(pdfString)
+					dst, ok2 := operands[i+1].(pdfString)
+					if ok1 && ok2 {
+						cmap[string(src)] = utf16BE(dst)
+					}
+				}
+			case "endbfrange":
+				for i := 0; i+2 < len(operands); i += 3 {
+					lo, ok1 := operands[i].(pdfString)
+					hi, ok2 := operands[i+1].(pdfString)
+					if !ok1 || !ok2 || len(lo) != len(hi) {
+						continue
+					}
+					addRange(cmap, lo, hi, operands[i+2])
+				}
+			}
+			operands = operands[:0]
+			continue
+		}
+		v, err := l.objectFrom(tok)
+		if err != nil {
+			break
+		}
+		operands = append(operands, v)
+	}
+	return cmap, codeLen
+}
+
+// addRange maps the codes lo..hi of a bfrange to consecutive characters
+// starting at dst, or to the strings of a dst array.
+func addRange(cmap map[string]string, lo, hi pdfString, dst any) {
+	start, end := codeValue(lo), codeValue(hi)
+	if end < start || end-start > 0xFFFF {
+		return
+	}
+	for code := start; code <= end; code++ {
+		key := make([]byte, len(lo))
+		for i, v := len(key)-1, code; i >= 0; i, v = i-1, v>>8 {
+			key[i] = byte(v)
+		}
+		switch t := dst.(type) {
+		case pdfString:
+			if len(t) == 0 {
+				continue
+			}
+			next := append(pdfString(nil), t...)
+			next[len(next)-1] += byte(code - start)
+			cmap[string(key)] = utf16BE(next)
+		case []any:
+			if code-start >= len(t) {
+				return
+			}
+			if s, ok := t[code-start].(pdfString); ok {
+				cmap[string(key)] = utf16BE(s)
+			}
+		}
+	}
+}
+
+func codeValue(b []byte) int {
+	v := 0
+	for _, c := range b {
+		v = v<<8 | int(c)
+	}
+	return v
+}
+
+// utf16BE decodes UTF-16BE text.
+func utf16BE(b []byte) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	return string(utf16.Decode(units))
+}
+
+// text decodes a shown string.
+func (f *font) text(s pdfString) string {
+	if f.cmap != nil {
+		var b strings.Builder
+		for i := 0; i < len(s); {
+			n := f.codeLen
+			if i+n > len(s) {
+				n = len(s) - i
+			}
+			if t, ok := f.cmap[string(s[i:i+n])]; ok {
+				b.WriteString(t)
+			} else if n == 1 {
+				b.WriteRune(winAnsi(s[i]))
+			}
+			i += n
+		}
+		return b.String()
+	}
+	if f.twoBytes {
+		return utf16BE(s) // a guess for Identity-H fonts without a CMap
+	}
+	if len(s) >= 2 && s[0] == 0xFE && s[1] == 0xFF {
+		return utf16BE(s[2:])
+	}
+	var b strings.Builder
+	for _, c := range s {
+		b.WriteRune(winAnsi(c))
+	}
+	return b.String()
+}
+
+// winAnsiHigh maps the WinAnsiEncoding bytes 0x80-0x9F that differ from Latin-1.
+var winAnsiHigh = map[byte]rune{
+	0x80: '€', 0x82: '‚', 0x83: 'ƒ', 0x84: '„', 0x85: '…', 0x86: '†', 0x87: '‡',
+	0x88: 'ˆ', 0x89: '‰', 0x8A: 'Š', 0x8B: '‹', 0x8C: 'Œ', 0x8E: 'Ž', 0x91: '‘',
+	0x92: '’', 0x93: '“', 0x94: '”', 0x95: '•', 0x96: '–', 0x97: '—', 0x98: '˜',
+	0x99: '™', 0x9A: 'š', 0x9B: '›', 0x9C: 'œ', 0x9E: 'ž', 0x9F: 'Ÿ',
+}
+
+func winAnsi(c byte) rune {
+	if r, ok := winAnsiHigh[c]; ok {
+		return r
+	}
+	return rune(c)
+}
+
+// textWriter lays out shown text into lines and paragraphs by position.
+type textWriter struct {
+	b        strings.Builder
+	started  bool
+	y        float64 // baseline of the current line
+	gap      float64 // usual distance between lines
+	fontSize float64
+}
+
+// moveTo starts text at baseline y, breaking the line or paragraph if it moved.
+func (w *textWriter) moveTo(y float64) {
+	if !w.started {
+		w.y = y
+		return
+	}
+	dy := w.y - y
+	size := math.Max(w.fontSize, 1)
+	switch {
+	case math.Abs(dy) < size*0.5: // same line
+		w.space()
+		return
+	case dy < 0 || w.gap > 0 && dy > w.gap*1.4 || dy > size*2: // up a column, or a wide gap
+		w.paragraph()
+	default:
+		w.newline()
+		w.gap = dy
+	}
+	w.y = y
+}
+
+func (w *textWriter) write(s string) {
+	if s == "" {
+		return
+	}
+	w.b.WriteString(s)
+	w.started = true
+}
+
+func (w *textWriter) space() {
+	if s := w.b.String(); s != "" && !strings.HasSuffix(s, " ") && !strings.HasSuffix(s, "\n") {
+		w.b.WriteString(" ")
+	}
+}
+
+func (w *textWriter) newline() {
+	if s := w.b.String(); s != "" && !strings.HasSuffix(s, "\n") {
+		w.b.WriteString("\n")
+	}
+}
+
+func (w *textWriter) paragraph() {
+	if s := w.b.String(); s != "" && !strings.HasSuffix(s, "\n\n") {
+		w.newline()
+		w.b.WriteString("\n")
+	}
+}
+
+func (w *textWriter) String() string {
+	return strings.TrimSpace(w.b.String())
+}
+
+// runContent interprets the text operators of a content stream.
+func (d *pdfDoc) runContent(content []byte, resources map[string]any, w *textWriter, depth int) {
+	fonts := d.dict(resources["Font"])
+	loaded := make(map[string]*font)
+	current := &font{codeLen: 1}
+	var leading, lineY, size float64
+	scale := 1.0 // vertical scale of the text matrix
+	var operands []any
+
+	l := &lexer{data: content}
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return
+		}
+		op, isOp := tok.(pdfKeyword)
+		if !isOp || op == "[" || op == "<<" {
+			if v, err := l.objectFrom(tok); err == nil {
+				operands = append(operands, v)
+			}
+			continue
+		}
+		num := func(i int) float64 {
+			if i < len(operands) {
+				if f, ok := operands[i].(float64); ok {
+					return f
+				}
+			}
+			return 0
+		}
+		show := func(v any) {
+			switch t := v.(type) {
+			case pdfString:
+				w.write(current.text(t))
+			case []any:
+				for _, item := range t {
+					switch it := item.(type) {
+					case pdfString:
+						w.write(current.text(it))
+					case float64:
+						if it <= -150 { // a kerning gap wide enough to be a space
+							w.space()
+						}
+					}
+				}
+			}
+		}
+		switch op {
+		case "BT":
+			lineY = 0
+		case "Tf":
+			if len(operands) >= 2 {
+				if name, ok := operands[0].(pdfName); ok {
+					if loaded[string(name)] == nil {
+						loaded[string(name)] = d.loadFont(fonts[string(name)])
+					}
+					current = loaded[string(name)]
+					size = math.Abs(num(1))
+					w.fontSize = size * scale
+				}
+			}
+		case "TL":
+			leading = num(0)
+		case "Td", "TD":
+			if op == "TD" {
+				leading = -num(1)
+			}
+			lineY += num(1) * scale
+			if num(1) == 0 && num(0) > 0 {
+				w.space()
+			} else {
+				w.moveTo(lineY)
+			}
+		case "Tm":
+			lineY = num(5)
+			if scale = math.Abs(num(3)); scale == 0 {
+				scale = 1
+			}
+			w.fontSize = size * scale
+			w.moveTo(lineY)
+		case "T*":
+			lineY -= leading * scale
+			w.moveTo(lineY)
+		case "Tj", "TJ":
+			if len(operands) > 0 {
+				show(operands[len(operands)-1])
+			}
+		case "'", "\"":
+			lineY -= leading * scale
+			w.moveTo(lineY)
+			if len(operands) > 0 {
+				show(operands[len(operands)-1])
+			}
+		case "Do":
+			if depth < 8 && len(operands) > 0 {
+				if name, ok := operands[0].(pdfName); ok {
+					xobjects := d.dict(resources["XObject"])
+					if form, ok := d.resolve(xobjects[string(name)]).(*pdfStream); ok && form.dict["Subtype"] == pdfName("Form") {
+						if data, err := d.decode(form); err == nil {
+							inner := d.dict(form.dict["Resources"])
+							if inner == nil {
+								inner = resources
+							}
+							d.runContent(data, inner, w, depth+1)
+						}
+					}
+				}
+			}
+		case "BI": // inline image: skip its data
+			if end := bytes.Index(l.data[l.pos:], []byte("EI")); end >= 0 {
+				l.pos += end + 2
+			}
+		}
+		operands = operands[:0]
+	}
+}
@@ -0,0 +1,182 @@
+package papers
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildPDF assembles a PDF from object bodies, numbered from 1, with a
+// valid cross-reference table. Object 1 must be the catalog.
+func buildPDF(objects ...string) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes()
+}
+
+// stream returns a stream object holding data, Flate-compressed if flate is set.
+func stream(data string, flate bool) string {
+	if !flate {
+		return fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(data), data)
+	}
+	var z bytes.Buffer
+	w := zlib.NewWriter(&z)
+	w.Write([]byte(data))
+	w.Close()
+	return fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", z.Len(), z.String())
+}
+
+func TestExtractPDF(t *testing.T) {
+	page1 := `BT /F1 12 Tf 72 720 Td (Phylogenetic likelihoods are) Tj
+0 -14 Td [(com) 20 (puted by) -600 (pruning.)] TJ
+0 -40 Td (A second paragraph \(with parentheses\).) Tj ET`
+	page2 := `BT /F2 12 Tf 1 0 0 1 72 720 Tm <00480049> Tj T* <0048> Tj ET`
+	cmap := `/CIDInit /ProcSet findresource begin
+begincmap
+1 begincodespacerange <0000> <FFFF> endcodespacerange
+1 beginbfchar <0048> <0054> endbfchar
+1 beginbfrange <0049> <004A> <0072> endbfrange
+endcmap end`
+
+	data := buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 /Resources << /Font << /F1 5 0 R /F2 6 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 7 0 R >>",
+		"<< /Type /Page /Parent 2 0 R /Contents [8 0 R] >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Times-Roman /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type0 /BaseFont /Custom /ToUnicode 9 0 R >>",
+		stream(page1, true),
+		stream(page2, false),
+		stream(cmap, true),
+	)
+
+	pages, err := ExtractPDF(data)
+	if err != nil {
+		t.Fatalf("ExtractPDF: %v", err)
+	}
+	if len(pages) != 2 {
+		t.Fatalf("got %d pages, want 2: %q", len(pages), pages)
+	}
+
+	paragraphs := SplitParagraphs(pages[0])
+	want := []string{
+		"Phylogenetic likelihoods are computed by pruning.",
+		"A second paragraph (with parentheses).",
+	}
+	if strings.Join(paragraphs, "|") != strings.Join(want, "|") {
+		t.Errorf("page 1 paragraphs = %q, want %q", paragraphs, want)
+	}
+	if got := strings.Fields(pages[1]); strings.Join(got, " ") != "Tr T" {
+		t.Errorf("page 2 = %q, want the ToUnicode mapping \"Tr\" then \"T\"", pages[1])
+	}
+}
+
+func TestExtractPDF_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not a PDF", []byte("hello")},
+		{"no pages", buildPDF("<< /Type /Catalog /Pages 2 0 R >>", "<< /Type /Pages /Kids [] /Count 0 >>")},
+	}
+	for _, tt := range tests {
+		if _, err := ExtractPDF(tt.data); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestSplitParagraphs(t *testing.T) {
+	text := "Maximum like-\nlihood trees are\nconsistent.\n\n\nSelf-\nContained   words keep hyphens.\n"
+	got := SplitParagraphs(text)
+	want := []string{
+		"Maximum likelihood trees are consistent.",
+		"Self- Contained words keep hyphens.",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("SplitParagraphs = %q, want %q", got, want)
+	}
+}
+
+// objectStream returns an object stream holding body, with the given /N and
+// /First entries.
+func objectStream(n, first int, body string) string {
+	return fmt.Sprintf("<< /Type /ObjStm /N %d /First %d /Length %d >>\nstream\n%s\nendstream", n, first, len(body), body)
+}
+
+// malformedPDFs are files whose offsets and lengths point outside their data.
+var malformedPDFs = []struct {
+	name string
+	data []byte
+}{
+	{"negative stream length", buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+		"<< /Length -1000 >>\nstream\nBT (text) Tj ET\nendstream",
+	)},
+	{"huge stream length", buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+		"<< /Length 1e300 >>\nstream\nBT (text) Tj ET\nendstream",
+	)},
+	{"negative object stream first", buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [] /Count 0 >>",
+		objectStream(1, -100, "10 0 (x)"),
+	)},
+	{"negative object offset", buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [] /Count 0 >>",
+		objectStream(1, 5, "10 -40 (x)"),
+	)},
+	{"object offset past the end", buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [] /Count 0 >>",
+		objectStream(1000000, 1e9, "10 99999999999 (x)"),
+	)},
+}
+
+func TestExtractPDF_Malformed(t *testing.T) {
+	for _, tt := range malformedPDFs {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Fatalf("extractPDF panicked: %v", r)
+				}
+			}()
+			extractPDF(tt.data)
+		})
+	}
+}
+
+func FuzzExtractPDF(f *testing.F) {
+	f.Add(buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		stream("BT /F1 12 Tf 72 720 Td (Phylogenetics) Tj ET", true),
+		"<< /Type /Font /Subtype /Type0 /ToUnicode 6 0 R >>",
+		stream("1 beginbfchar <0048> <0054> endbfchar", false),
+	))
+	for _, tt := range malformedPDFs {
+		f.Add(tt.data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		extractPDF(data)
+	})
+}
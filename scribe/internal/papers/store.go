@@ -0,0 +1,249 @@
+// Package papers keeps the full text of cited papers and searches it.
+package papers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/queue"
+)
+
+// DefaultDir is the default location of the paper store.
+const DefaultDir = ".scribe/papers"
+
+// indexFile is the name of the inverted index inside the store.
+const indexFile = "index.json"
+
+// Format is the format of an ingested file.
+type Format string
+
+const (
+	FormatPDF  Format = "pdf"
+	FormatText Format = "text"
+)
+
+// Paper is the stored text of a paper, split into pages and paragraphs.
+type Paper struct {
+	ID      string    `json:"id"`
+	Source  string    `json:"source"` // name of the ingested file
+	Format  Format    `json:"format"`
+	SHA256  string    `json:"sha256"`
+	AddedAt time.Time `json:"added_at"`
+	Pages   []Page    `json:"pages"`
+}
+
+// Page is a page of a paper. Plain text files are split into pages at form feeds.
+type Page struct {
+	Number     int      `json:"number"`
+	Paragraphs []string `json:"paragraphs"`
+}
+
+// Paragraphs returns the number of paragraphs in the paper.
+func (p *Paper) Paragraphs() int {
+	n := 0
+	for _, page := range p.Pages {
+		n += len(page.Paragraphs)
+	}
+	return n
+}
+
+// Store is a directory of paper texts with an inverted index.
+type Store struct {
+	dir string
+}
+
+// NewStore creates a Store in dir. If dir is empty, DefaultDir is used.
+func NewStore(dir string) *Store {
+	if dir == "" {
+		dir = DefaultDir
+	}
+	return &Store{dir: dir}
+}
+
+// IsStore reports whether dir holds a paper store.
+func IsStore(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, indexFile))
+	return err == nil
+}
+
+// Dir returns the store directory.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// paperDir returns the directory holding a paper. IDs are escaped so that
+// DOIs and other IDs with slashes map to a single directory. Escaping leaves
+// "." and ".." alone, so they are rejected, as are IDs that would collide
+// with the index.
+func (s *Store) paperDir(id string) (string, error) {
+	name := url.PathEscape(id)
+	switch {
+	case id == "":
+		return "", fmt.Errorf("paper ID is required")
+	case name == "." || name == ".." || name == indexFile || strings.HasPrefix(name, indexFile+"."):
+		return "", fmt.Errorf("invalid paper ID %q", id)
+	}
+	return filepath.Join(s.dir, name), nil
+}
+
+// Add ingests a PDF or plain text file as the text of the paper with the
+// given ID, replacing any text stored for it before. The store is locked
+// while the paper and the index are written, so concurrent adds keep each
+// other's index entries.
+func (s *Store) Add(id, path string) (*Paper, error) {
+	dir, err := s.paperDir(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	var pages []string
+	format := FormatText
+	if bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("%PDF")) {
+		format = FormatPDF
+		if pages, err = ExtractPDF(data); err != nil {
+			return nil, fmt.Errorf("extract text from %s: %w", path, err)
+		}
+	} else {
+		pages = strings.Split(strings.ToValidUTF8(string(data), "�"), "\f")
+	}
+
+	sum := sha256.Sum256(data)
+	paper := &Paper{
+		ID:      id,
+		Source:  filepath.Base(path),
+		Format:  format,
+		SHA256:  hex.EncodeToString(sum[:]),
+		AddedAt: time.Now(),
+	}
+	for i, text := range pages {
+		paper.Pages = append(paper.Pages, Page{Number: i + 1, Paragraphs: SplitParagraphs(text)})
+	}
+	if paper.Paragraphs() == 0 {
+		return nil, fmt.Errorf("no text found in %s (is it a scanned PDF?)", path)
+	}
+
+	indexPath := filepath.Join(s.dir, indexFile)
+	unlock, err := queue.LockFile(indexPath, true)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	source := "source.txt"
+	if format == FormatPDF {
+		source = "source.pdf"
+	}
+	write := func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	}
+	if err := queue.WriteFileAtomic(filepath.Join(dir, source), write); err != nil {
+		return nil, fmt.Errorf("save source: %w", err)
+	}
+	if err := writeJSON(filepath.Join(dir, "paper.json"), paper); err != nil {
+		return nil, err
+	}
+
+	index, err := s.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+	index.add(paper)
+	if err := writeJSON(indexPath, index); err != nil {
+		return nil, err
+	}
+	return paper, nil
+}
+
+// Get returns a stored paper, or nil if there is none.
+func (s *Store) Get(id string) (*Paper, error) {
+	dir, err := s.paperDir(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "paper.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read paper %s: %w", id, err)
+	}
+	var paper Paper
+	if err := json.Unmarshal(data, &paper); err != nil {
+		return nil, fmt.Errorf("parse paper %s: %w", id, err)
+	}
+	return &paper, nil
+}
+
+// writeJSON writes v as indented JSON through a temporary file, so readers
+// never see a partial file.
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal %s: %w", filepath.Base(path), err)
+	}
+	return queue.WriteFileAtomic(path, func(w io.Writer) error {
+		_, err := w.Write(append(data, '\n'))
+		return err
+	})
+}
+
+// SplitParagraphs splits page text into paragraphs at blank lines. Lines
+// within a paragraph are joined with spaces, and words hyphenated across a
+// line break are rejoined.
+func SplitParagraphs(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	var paragraphs []string
+	for _, block := range strings.Split(text, "\n\n") {
+		var b strings.Builder
+		for _, line := range strings.Split(block, "\n") {
+			line = strings.Join(strings.Fields(line), " ")
+			if line == "" {
+				continue
+			}
+			current := b.String()
+			switch {
+			case current == "":
+			case hyphenated(current, line):
+				b.Reset()
+				b.WriteString(strings.TrimSuffix(current, "-"))
+			default:
+				b.WriteString(" ")
+			}
+			b.WriteString(line)
+		}
+		if p := ligatures.Replace(b.String()); p != "" {
+			paragraphs = append(paragraphs, p)
+		}
+	}
+	return paragraphs
+}
+
+// hyphenated reports whether a line ends with a word broken by a hyphen
+// that continues in lowercase on the next line.
+func hyphenated(line, next string) bool {
+	if len(line) < 2 || !strings.HasSuffix(line, "-") {
+		return false
+	}
+	before := []rune(line[:len(line)-1])
+	first := []rune(next)
+	return unicode.IsLetter(before[len(before)-1]) && unicode.IsLower(first[0])
+}
+
+// ligatures expands typographic ligatures common in PDF text.
+var ligatures = strings.NewReplacer("ﬀ", "ff", "ﬁ", "fi", "ﬂ", "fl", "ﬃ", "ffi", "ﬄ", "ffl", "­", "")
@@ -0,0 +1,166 @@
+package papers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestStore_AddAndSearch(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	write := func(name string, data []byte) string {
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	text := write("felsenstein.txt", []byte("Abstract. We describe the pruning algorithm.\n\n"+
+		"The pruning algorithm computes the likelihood of a tree\nin time linear in the number of sequences.\f"+
+		"Acknowledgements to the funding agencies."))
+	pdf := write("tavare.pdf", buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		stream("BT /F1 10 Tf 72 700 Td (Substitution rates vary across sites, so likelihood models) Tj 0 -12 Td (need rate heterogeneity.) Tj ET", true),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	))
+	empty := write("scanned.txt", []byte(" \n\n \f "))
+
+	store := NewStore(filepath.Join(tmpDir, "papers"))
+	if IsStore(store.Dir()) {
+		t.Error("empty directory reported as a store")
+	}
+	paper, err := store.Add("10.1007/BF01734359", text)
+	if err != nil {
+		t.Fatalf("Add text: %v", err)
+	}
+	if paper.Format != FormatText || len(paper.Pages) != 2 || paper.Paragraphs() != 3 {
+		t.Errorf("text paper = %s with %d pages and %d paragraphs", paper.Format, len(paper.Pages), paper.Paragraphs())
+	}
+	if paper, err = store.Add("tavare1986", pdf); err != nil {
+		t.Fatalf("Add PDF: %v", err)
+	}
+	if paper.Format != FormatPDF || paper.Pages[0].Paragraphs[0] != "Substitution rates vary across sites, so likelihood models need rate heterogeneity." {
+		t.Errorf("PDF paper = %s %q", paper.Format, paper.Pages)
+	}
+	if _, err := store.Add("scanned", empty); err == nil {
+		t.Error("expected an error for a file without text")
+	}
+	if !IsStore(store.Dir()) {
+		t.Error("store not detected after adding papers")
+	}
+
+	tests := []struct {
+		query     string
+		paper     string
+		wantFirst string
+		wantPage  int
+		wantCount int
+	}{
+		{"pruning likelihood linear", "", "10.1007/BF01734359", 1, 3},
+		{"rate heterogeneity likelihood", "", "tavare1986", 1, 2},
+		{"likelihood", "tavare1986", "tavare1986", 1, 1},
+		{"funding", "", "10.1007/BF01734359", 2, 1},
+		{"the of", "", "", 0, 0},
+	}
+	for _, tt := range tests {
+		hits, err := store.Search(tt.query, tt.paper, 0)
+		if err != nil {
+			t.Fatalf("Search(%q): %v", tt.query, err)
+		}
+		if len(hits) != tt.wantCount {
+			t.Errorf("Search(%q) returned %d hits, want %d: %v", tt.query, len(hits), tt.wantCount, hits)
+			continue
+		}
+		if len(hits) > 0 && (hits[0].PaperID != tt.wantFirst || hits[0].Page != tt.wantPage) {
+			t.Errorf("Search(%q) top hit = %s page %d, want %s page %d", tt.query, hits[0].PaperID, hits[0].Page, tt.wantFirst, tt.wantPage)
+		}
+	}
+	if hits, _ := store.Search("pruning computes", "", 1); len(hits) != 1 || hits[0].Paragraph != 2 {
+		t.Errorf("limited search = %v, want the second paragraph only", hits)
+	}
+
+	// Re-adding a paper replaces its text in the index
+	if _, err := store.Add("tavare1986", text); err != nil {
+		t.Fatalf("re-Add: %v", err)
+	}
+	if hits, _ := store.Search("heterogeneity", "", 0); len(hits) != 0 {
+		t.Errorf("stale postings after re-adding: %v", hits)
+	}
+	ids, err := store.List()
+	if err != nil || len(ids) != 2 {
+		t.Errorf("List = %v, %v", ids, err)
+	}
+	if got, err := store.Get("missing"); got != nil || err != nil {
+		t.Errorf("Get(missing) = %v, %v", got, err)
+	}
+}
+
+func TestStore_AddRejectsEscapingIDs(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	text := filepath.Join(tmpDir, "paper.txt")
+	if err := os.WriteFile(text, []byte("Some text about trees."), 0644); err != nil {
+		t.Fatal(err)
+	}
+	store := NewStore(filepath.Join(tmpDir, "store", "papers"))
+	for _, id := range []string{"", ".", "..", "index.json"} {
+		if _, err := store.Add(id, text); err == nil {
+			t.Errorf("Add(%q) should fail", id)
+		}
+		if _, err := store.Get(id); err == nil {
+			t.Errorf("Get(%q) should fail", id)
+		}
+	}
+	for _, name := range []string{"source.txt", "paper.json"} {
+		if _, err := os.Stat(filepath.Join(tmpDir, "store", name)); err == nil {
+			t.Errorf("%s written outside the store", name)
+		}
+	}
+}
+
+func TestStore_ConcurrentAdds(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	text := filepath.Join(tmpDir, "paper.txt")
+	if err := os.WriteFile(text, []byte("Some text about trees."), 0644); err != nil {
+		t.Fatal(err)
+	}
+	store := NewStore(filepath.Join(tmpDir, "papers"))
+	const n = 16
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := store.Add(fmt.Sprintf("paper%d", i), text); err != nil {
+				t.Errorf("Add: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	ids, err := store.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(ids) != n {
+		t.Errorf("index lists %d papers, want %d", len(ids), n)
+	}
+}
@@ -0,0 +1,180 @@
+package qmd
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// inlineLinkPattern matches [text](url) and ![alt](src), with an optional title.
+	inlineLinkPattern = regexp.MustCompile(`(!?)\[([^\]]*)\]\(\s*<?([^)\s>]+)>?(?:\s+"[^"]*")?\s*\)`)
+	// autolinkPattern matches <https://...>.
+	autolinkPattern = regexp.MustCompile(`<(https?://[^>\s]+)>`)
+	// bareURLPattern matches URLs in running text.
+	bareURLPattern = regexp.MustCompile(`https?://[^\s\)\]>"']+`)
+	// citationKeyPattern matches Pandoc citation keys, including the @{...} form.
+	// Bare keys may contain internal punctuation but not end with it, so trailing
+	// punctuation is trimmed separately.
+	citationKeyPattern = regexp.MustCompile(`@(?:\{([^{}\s]+)\}|([A-Za-z0-9_][A-Za-z0-9_:.#$%&\-+?<>~/]*))`)
+	// citationGroupPattern matches a bracketed citation group such as [see @a, p. 3; -@b].
+	// Link text is excluded by the negative check on the following character in code.
+	citationGroupPattern = regexp.MustCompile(`\[([^\[\]]*@[^\[\]]*)\]`)
+)
+
+// crossRefPrefixes are the Quarto cross-reference label prefixes.
+var crossRefPrefixes = []string{
+	"sec", "fig", "tbl", "eq", "lst", "thm", "lem", "cor", "prp", "cnj",
+	"def", "exm", "exr", "sol", "rem", "alg",
+}
+
+// IsCrossRef reports whether a citation key is a Quarto cross-reference label.
+func IsCrossRef(key string) bool {
+	prefix, _, ok := strings.Cut(key, "-")
+	if !ok {
+		return false
+	}
+	for _, p := range crossRefPrefixes {
+		if prefix == p {
+			return true
+		}
+	}
+	return false
+}
+
+// scanInline extracts links and citations from a block of prose.
+func (p *parser) scanInline(para *Paragraph) {
+	text := []byte(para.Text)
+
+	spanOf := func(start, end int) Span {
+		return Span{Start: para.PositionOf(start), End: para.PositionOf(end)}
+	}
+
+	for _, m := range inlineLinkPattern.FindAllStringSubmatchIndex(para.Text, -1) {
+		p.doc.Links = append(p.doc.Links, Link{
+			Kind:  LinkInline,
+			Text:  para.Text[m[4]:m[5]],
+			URL:   para.Text[m[6]:m[7]],
+			Image: m[3] > m[2],
+			Span:  spanOf(m[6], m[7]),
+		})
+		// The link text may still hold citations; only the target is consumed
+		blank(text, m[6], m[7])
+	}
+
+	masked := string(text)
+	for _, m := range autolinkPattern.FindAllStringSubmatchIndex(masked, -1) {
+		p.doc.Links = append(p.doc.Links, Link{
+			Kind: LinkAutolink,
+			URL:  masked[m[2]:m[3]],
+			Span: spanOf(m[2], m[3]),
+		})
+		blank(text, m[0], m[1])
+	}
+
+	masked = string(text)
+	for _, m := range bareURLPattern.FindAllStringIndex(masked, -1) {
+		url := strings.TrimRight(masked[m[0]:m[1]], ".,;:!?")
+		end := m[0] + len(url)
+		p.doc.Links = append(p.doc.Links, Link{
+			Kind: LinkBare,
+			URL:  url,
+			Span: spanOf(m[0], end),
+		})
+		blank(text, m[0], end)
+	}
+
+	masked = string(text)
+	for _, g := range citationGroupPattern.FindAllStringSubmatchIndex(masked, -1) {
+		// [text](url) and [text]{attrs} are links and spans, not citations
+		if g[1] < len(masked) && (masked[g[1]] == '(' || masked[g[1]] == '{') {
+			continue
+		}
+		items := p.parseCitationGroup(masked, g[2], g[3], spanOf)
+		if len(items) == 0 {
+			continue
+		}
+		p.groups++
+		for _, c := range items {
+			c.Group = p.groups
+			p.addCitation(c)
+		}
+		blank(text, g[0], g[1])
+	}
+
+	masked = string(text)
+	for _, m := range citationKeyPattern.FindAllStringSubmatchIndex(masked, -1) {
+		key, end, ok := citationKeyAt(masked, m)
+		if !ok {
+			continue
+		}
+		p.addCitation(Citation{
+			Key:  key,
+			Mode: CitationInText,
+			Span: spanOf(m[0], end),
+		})
+	}
+}
+
+// parseCitationGroup parses the items of a bracketed citation group in
+// text[start:end]. It returns nil if any item lacks a citation key, since
+// then the brackets are ordinary text.
+func (p *parser) parseCitationGroup(text string, start, end int, spanOf func(int, int) Span) []Citation {
+	var items []Citation
+	itemStart := start
+	for i := start; i <= end; i++ {
+		if i < end && text[i] != ';' {
+			continue
+		}
+		item := text[itemStart:i]
+		m := citationKeyPattern.FindStringSubmatchIndex(item)
+		if m == nil {
+			return nil
+		}
+		key, keyEnd, ok := citationKeyAt(item, m)
+		if !ok {
+			return nil
+		}
+		c := Citation{
+			Key:    key,
+			Mode:   CitationNormal,
+			Prefix: strings.TrimSpace(item[:m[0]]),
+			Suffix: strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(item[keyEnd:]), ",")),
+			Span:   spanOf(itemStart+m[0], itemStart+keyEnd),
+		}
+		if strings.HasSuffix(c.Prefix, "-") {
+			c.Mode = CitationSuppressAuthor
+			c.Prefix = strings.TrimSpace(strings.TrimSuffix(c.Prefix, "-"))
+		}
+		items = append(items, c)
+		itemStart = i + 1
+	}
+	return items
+}
+
+// citationKeyAt extracts the key from a citationKeyPattern match in text.
+// It returns the key, the end offset of the key, and false for email addresses.
+func citationKeyAt(text string, m []int) (string, int, bool) {
+	// An @ preceded by a word character is an email address, not a citation
+	if m[0] > 0 && isWordByte(text[m[0]-1]) {
+		return "", 0, false
+	}
+	if m[2] >= 0 {
+		return text[m[2]:m[3]], m[1], true
+	}
+	key := strings.TrimRight(text[m[4]:m[5]], ":.#$%&-+?<>~/")
+	return key, m[4] + len(key), true
+}
+
+// addCitation records a citation or cross-reference.
+func (p *parser) addCitation(c Citation) {
+	if IsCrossRef(c.Key) {
+		p.doc.CrossRefs = append(p.doc.CrossRefs, c)
+	} else {
+		p.doc.Citations = append(p.doc.Citations, c)
+	}
+}
+
+// isWordByte reports whether b is an ASCII letter, digit, or underscore.
+func isWordByte(b byte) bool {
+	return b == '_' || (b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
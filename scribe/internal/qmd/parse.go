@@ -0,0 +1,440 @@
+package qmd
+
+import (
+	"os"
+	"regexp"
+	"strings"
+)
+
+var (
+	headingPattern  = regexp.MustCompile(`^(#{1,6})(?:\s+(.*?))?\s*$`)
+	attrPattern     = regexp.MustCompile(`\s*\{([^{}]*)\}\s*$`)
+	codeFence       = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})\\s*(.*)$")
+	divFence        = regexp.MustCompile(`^ {0,3}(:{3,})\s*(.*)$`)
+	listItemPattern = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+`)
+	titleAttr       = regexp.MustCompile(`title\s*=\s*"([^"]*)"`)
+	includePattern  = regexp.MustCompile(`^\{\{<\s*include\s+(\S+)\s*>\}\}$`)
+	labelAttr       = regexp.MustCompile(`\{#([A-Za-z][\w:.-]*)[^{}]*\}`)
+	chunkLabel      = regexp.MustCompile(`^#\|\s*label:\s*["']?([^"'\s]+)["']?\s*$`)
+)
+
+// ParseFile reads and parses a QMD file.
+func ParseFile(path string) (*Document, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, content), nil
+}
+
+// divFrame tracks an open fenced div.
+type divFrame struct {
+	callout int // index into Document.Callouts, or -1 for a plain div
+}
+
+// parser holds line-level parsing state.
+type parser struct {
+	doc *Document
+
+	para      []string // pending paragraph lines
+	paraStart int      // 1-based line where the pending paragraph starts
+
+	divs []divFrame
+
+	inComment    bool
+	commentText  strings.Builder
+	commentStart Position
+
+	groups int // bracketed citation groups seen so far
+}
+
+// Parse parses QMD content. Parsing never fails; malformed constructs such as
+// unclosed fences extend to the end of the file.
+func Parse(path string, content []byte) *Document {
+	text := strings.ReplaceAll(string(content), "\r\n", "\n")
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if text == "" {
+		lines = nil
+	}
+
+	doc := &Document{
+		Path:  path,
+		Lines: lines,
+		kinds: make([]LineKind, len(lines)),
+	}
+	p := &parser{doc: doc}
+
+	start := p.parseFrontMatter()
+
+	for i := start; i < len(lines); i++ {
+		lineNum := i + 1
+		line := lines[i]
+
+		// Fenced code blocks are opaque: no comments, links, or citations inside
+		if !p.inComment {
+			if m := codeFence.FindStringSubmatch(line); m != nil {
+				p.flushParagraph()
+				i = p.parseCodeChunk(i, m[1], m[2])
+				continue
+			}
+		}
+
+		masked, onlyComment := p.maskComments(line, lineNum)
+		trimmed := strings.TrimSpace(masked)
+
+		switch {
+		case onlyComment:
+			p.flushParagraph()
+			doc.kinds[i] = LineComment
+		case trimmed == "":
+			p.flushParagraph()
+			doc.kinds[i] = LineBlank
+		case divFence.MatchString(masked):
+			p.flushParagraph()
+			doc.kinds[i] = LineDivFence
+			p.parseDivFence(lineNum, divFence.FindStringSubmatch(masked)[2])
+		case headingPattern.MatchString(masked):
+			p.flushParagraph()
+			doc.kinds[i] = LineHeading
+			p.parseHeading(lineNum, masked)
+		case includePattern.MatchString(trimmed):
+			p.flushParagraph()
+			doc.kinds[i] = LineShortcode
+			doc.Includes = append(doc.Includes, Include{
+				Path: includePattern.FindStringSubmatch(trimmed)[1],
+				Span: p.lineSpan(lineNum),
+			})
+		default:
+			if listItemPattern.MatchString(masked) {
+				p.flushParagraph()
+			}
+			doc.kinds[i] = LineText
+			if len(p.para) == 0 {
+				p.paraStart = lineNum
+			}
+			p.para = append(p.para, maskInlineCode(masked))
+		}
+	}
+	p.flushParagraph()
+
+	// Close anything left open at end of file
+	end := Position{Line: len(lines), Col: len(doc.Line(len(lines))) + 1}
+	if p.inComment {
+		doc.Comments = append(doc.Comments, Comment{
+			Text: p.commentText.String(),
+			Span: Span{Start: p.commentStart, End: end},
+		})
+	}
+	for len(p.divs) > 0 {
+		p.closeDiv(end)
+	}
+
+	return doc
+}
+
+// parseFrontMatter consumes a leading YAML block and returns the first body line index.
+func (p *parser) parseFrontMatter() int {
+	lines := p.doc.Lines
+	if len(lines) == 0 || strings.TrimRight(lines[0], " \t") != "---" {
+		return 0
+	}
+	for i := 1; i < len(lines); i++ {
+		l := strings.TrimRight(lines[i], " \t")
+		if l == "---" || l == "..." {
+			for j := 0; j <= i; j++ {
+				p.doc.kinds[j] = LineFrontMatter
+			}
+			p.doc.FrontMatter = &FrontMatter{
+				Raw: strings.Join(lines[1:i], "\n"),
+				Span: Span{
+					Start: Position{Line: 1, Col: 1},
+					End:   Position{Line: i + 1, Col: len(lines[i]) + 1},
+				},
+			}
+			return i + 1
+		}
+	}
+	// No closing delimiter: treat the opening line as a thematic break
+	return 0
+}
+
+// parseCodeChunk consumes a fenced code block starting at index start and
+// returns the index of its closing fence.
+func (p *parser) parseCodeChunk(start int, fence, info string) int {
+	lines := p.doc.Lines
+	chunk := CodeChunk{}
+	info = strings.TrimSpace(info)
+	if strings.HasPrefix(info, "{") {
+		inner := strings.Trim(info, "{}")
+		fields := strings.FieldsFunc(inner, func(r rune) bool { return r == ' ' || r == ',' })
+		if len(fields) > 0 {
+			chunk.Lang = strings.TrimPrefix(fields[0], ".")
+			chunk.Executable = !strings.HasPrefix(fields[0], ".")
+		}
+	} else if fields := strings.Fields(info); len(fields) > 0 {
+		chunk.Lang = fields[0]
+	}
+
+	end := len(lines) - 1
+	closed := false
+	for i := start + 1; i < len(lines); i++ {
+		if isClosingFence(lines[i], fence) {
+			end, closed = i, true
+			break
+		}
+	}
+	for i := start; i <= end; i++ {
+		p.doc.kinds[i] = LineCode
+	}
+
+	bodyEnd := end
+	if !closed {
+		bodyEnd = end + 1 // unclosed: body runs to end of file
+	}
+	if start+1 < bodyEnd {
+		chunk.Content = strings.Join(lines[start+1:bodyEnd], "\n")
+	}
+	for i := start + 1; i < bodyEnd; i++ {
+		if m := chunkLabel.FindStringSubmatch(strings.TrimSpace(lines[i])); m != nil {
+			chunk.Label = m[1]
+			p.addLabel(m[1], i+1)
+			break
+		}
+	}
+	chunk.Span = Span{
+		Start: Position{Line: start + 1, Col: 1},
+		End:   Position{Line: end + 1, Col: len(lines[end]) + 1},
+	}
+	p.doc.CodeChunks = append(p.doc.CodeChunks, chunk)
+	return end
+}
+
+// isClosingFence reports whether line closes a code fence opened with fence:
+// the same character, at least as many times, and nothing else.
+func isClosingFence(line, fence string) bool {
+	l := strings.TrimSpace(line)
+	return len(l) >= len(fence) && strings.Trim(l, fence[:1]) == ""
+}
+
+// maskComments blanks out HTML comments in a line, recording them as it goes.
+// It returns the masked line and whether the line held nothing but comments.
+func (p *parser) maskComments(line string, lineNum int) (string, bool) {
+	if !p.inComment && !strings.Contains(line, "<!--") {
+		return line, false
+	}
+
+	masked := []byte(line)
+	sawComment := p.inComment
+	pos := 0
+	for pos < len(line) {
+		if p.inComment {
+			end := strings.Index(line[pos:], "-->")
+			if end < 0 {
+				p.commentText.WriteString(line[pos:])
+				p.commentText.WriteByte('\n')
+				blank(masked, pos, len(line))
+				pos = len(line)
+				break
+			}
+			p.commentText.WriteString(line[pos : pos+end])
+			blank(masked, pos, pos+end+3)
+			pos += end + 3
+			p.doc.Comments = append(p.doc.Comments, Comment{
+				Text: strings.TrimSpace(p.commentText.String()),
+				Span: Span{Start: p.commentStart, End: Position{Line: lineNum, Col: pos + 1}},
+			})
+			p.commentText.Reset()
+			p.inComment = false
+			continue
+		}
+		start := strings.Index(line[pos:], "<!--")
+		if start < 0 {
+			break
+		}
+		sawComment = true
+		blank(masked, pos+start, pos+start+4)
+		p.inComment = true
+		p.commentStart = Position{Line: lineNum, Col: pos + start + 1}
+		pos += start + 4
+	}
+
+	result := string(masked)
+	return result, sawComment && strings.TrimSpace(result) == ""
+}
+
+// blank replaces bytes in [from, to) with spaces.
+func blank(b []byte, from, to int) {
+	for i := from; i < to && i < len(b); i++ {
+		b[i] = ' '
+	}
+}
+
+// maskInlineCode blanks out `inline code` spans so they are not scanned for links.
+func maskInlineCode(line string) string {
+	if !strings.Contains(line, "`") {
+		return line
+	}
+	b := []byte(line)
+	for i := 0; i < len(b); {
+		if b[i] != '`' {
+			i++
+			continue
+		}
+		run := 1
+		for i+run < len(b) && b[i+run] == '`' {
+			run++
+		}
+		closeAt := strings.Index(line[i+run:], strings.Repeat("`", run))
+		if closeAt < 0 {
+			break
+		}
+		end := i + run + closeAt + run
+		blank(b, i, end)
+		i = end
+	}
+	return string(b)
+}
+
+// parseHeading records an ATX heading and scans it for links and citations.
+func (p *parser) parseHeading(lineNum int, line string) {
+	m := headingPattern.FindStringSubmatch(line)
+	text := strings.TrimRight(m[2], "# ")
+	h := Heading{
+		Level: len(m[1]),
+		Span: Span{
+			Start: Position{Line: lineNum, Col: 1},
+			End:   Position{Line: lineNum, Col: len(line) + 1},
+		},
+	}
+	if am := attrPattern.FindStringSubmatchIndex(text); am != nil {
+		h.ID, h.Classes, _ = parseAttributes(text[am[2]:am[3]])
+		text = text[:am[0]]
+	}
+	if h.ID != "" {
+		p.addLabel(h.ID, lineNum)
+	}
+	h.Text = strings.TrimSpace(text)
+	p.doc.Headings = append(p.doc.Headings, h)
+
+	para := Paragraph{
+		Text:       maskInlineCode(line),
+		Span:       h.Span,
+		lineStarts: []int{0},
+	}
+	p.scanInline(&para)
+}
+
+// parseDivFence opens or closes a fenced div. Callout divs are recorded.
+func (p *parser) parseDivFence(lineNum int, attrs string) {
+	attrs = strings.TrimSpace(attrs)
+	if attrs == "" {
+		p.closeDiv(Position{Line: lineNum, Col: len(p.doc.Line(lineNum)) + 1})
+		return
+	}
+
+	frame := divFrame{callout: -1}
+	var classes []string
+	if strings.HasPrefix(attrs, "{") {
+		var id string
+		id, classes, _ = parseAttributes(strings.Trim(attrs, "{}"))
+		if id != "" {
+			p.addLabel(id, lineNum)
+		}
+	} else {
+		classes = strings.Fields(attrs)[:1]
+	}
+	for _, class := range classes {
+		if typ, ok := strings.CutPrefix(class, "callout-"); ok {
+			callout := Callout{
+				Type: typ,
+				Span: Span{Start: Position{Line: lineNum, Col: 1}},
+			}
+			if tm := titleAttr.FindStringSubmatch(attrs); tm != nil {
+				callout.Title = tm[1]
+			}
+			p.doc.Callouts = append(p.doc.Callouts, callout)
+			frame.callout = len(p.doc.Callouts) - 1
+			break
+		}
+	}
+	p.divs = append(p.divs, frame)
+}
+
+// closeDiv closes the innermost open div.
+func (p *parser) closeDiv(end Position) {
+	if len(p.divs) == 0 {
+		return
+	}
+	frame := p.divs[len(p.divs)-1]
+	p.divs = p.divs[:len(p.divs)-1]
+	if frame.callout >= 0 {
+		p.doc.Callouts[frame.callout].Span.End = end
+	}
+}
+
+// currentCallout returns the type of the innermost open callout, if any.
+func (p *parser) currentCallout() string {
+	for i := len(p.divs) - 1; i >= 0; i-- {
+		if p.divs[i].callout >= 0 {
+			return p.doc.Callouts[p.divs[i].callout].Type
+		}
+	}
+	return ""
+}
+
+// flushParagraph records the pending paragraph, if any.
+func (p *parser) flushParagraph() {
+	if len(p.para) == 0 {
+		return
+	}
+	para := Paragraph{
+		Callout: p.currentCallout(),
+		Span: Span{
+			Start: Position{Line: p.paraStart, Col: 1},
+			End:   Position{Line: p.paraStart + len(p.para) - 1, Col: len(p.para[len(p.para)-1]) + 1},
+		},
+	}
+	offset := 0
+	for _, l := range p.para {
+		para.lineStarts = append(para.lineStarts, offset)
+		offset += len(l) + 1
+	}
+	para.Text = strings.Join(p.para, "\n")
+	p.scanInline(&para)
+	// Attributes such as ![..](a.png){#fig-a}, ": Caption {#tbl-b}", or "$$ {#eq-c}"
+	for _, m := range labelAttr.FindAllStringSubmatchIndex(para.Text, -1) {
+		p.addLabel(para.Text[m[2]:m[3]], para.PositionOf(m[0]).Line)
+	}
+	p.doc.Paragraphs = append(p.doc.Paragraphs, para)
+	p.para = nil
+}
+
+// addLabel records a label defined on a 1-based line.
+func (p *parser) addLabel(id string, lineNum int) {
+	p.doc.Labels = append(p.doc.Labels, Label{ID: id, Span: p.lineSpan(lineNum)})
+}
+
+// lineSpan returns the span of a whole 1-based line.
+func (p *parser) lineSpan(lineNum int) Span {
+	return Span{
+		Start: Position{Line: lineNum, Col: 1},
+		End:   Position{Line: lineNum, Col: len(p.doc.Line(lineNum)) + 1},
+	}
+}
+
+// parseAttributes parses a Pandoc attribute list such as `#sec-foo .unnumbered key="v"`.
+func parseAttributes(attrs string) (id string, classes []string, kv map[string]string) {
+	kv = make(map[string]string)
+	for _, field := range strings.Fields(attrs) {
+		switch {
+		case strings.HasPrefix(field, "#"):
+			id = field[1:]
+		case strings.HasPrefix(field, "."):
+			classes = append(classes, field[1:])
+		case strings.Contains(field, "="):
+			k, v, _ := strings.Cut(field, "=")
+			kv[k] = strings.Trim(v, `"`)
+		}
+	}
+	return id, classes, kv
+}
@@ -0,0 +1,265 @@
+package qmd
+
+import (
+	"strings"
+	"testing"
+)
+
+const sample = `---
+title: "Test https://front.matter/url"
+---
+
+# Rate Matrices {#sec-rates}
+
+The GTR model [@paper:tavare1986] generalizes JC69.
+See <https://auto.link/x> and https://bare.link/y.
+
+<!-- hidden https://comment.url/z @paper:commented -->
+
+` + "```{python}" + `
+# https://code.url @paper:incode
+print("hi")
+` + "```" + `
+
+::: {.callout-note title="Aside"}
+Callout text with [a link](https://callout.link) and @sec-rates.
+:::
+
+Text with ` + "`https://inline.code`" + ` and mail me@example.com.
+- list item one
+- list item two
+`
+
+func TestParse_Structure(t *testing.T) {
+	doc := Parse("test.qmd", []byte(sample))
+
+	if doc.FrontMatter == nil || doc.FrontMatter.Span.End.Line != 3 {
+		t.Fatalf("expected front matter on lines 1-3, got %+v", doc.FrontMatter)
+	}
+
+	if len(doc.Headings) != 1 {
+		t.Fatalf("got %d headings, want 1", len(doc.Headings))
+	}
+	if h := doc.Headings[0]; h.Text != "Rate Matrices" || h.ID != "sec-rates" || h.Level != 1 {
+		t.Errorf("unexpected heading: %+v", h)
+	}
+
+	if len(doc.CodeChunks) != 1 {
+		t.Fatalf("got %d code chunks, want 1", len(doc.CodeChunks))
+	}
+	if c := doc.CodeChunks[0]; c.Lang != "python" || !c.Executable || c.Span.Start.Line != 12 || c.Span.End.Line != 15 {
+		t.Errorf("unexpected code chunk: %+v", c)
+	}
+
+	if len(doc.Comments) != 1 || doc.Comments[0].Span.Start.Line != 10 {
+		t.Errorf("unexpected comments: %+v", doc.Comments)
+	}
+
+	if len(doc.Callouts) != 1 {
+		t.Fatalf("got %d callouts, want 1", len(doc.Callouts))
+	}
+	if c := doc.Callouts[0]; c.Type != "note" || c.Title != "Aside" || c.Span.Start.Line != 17 || c.Span.End.Line != 19 {
+		t.Errorf("unexpected callout: %+v", c)
+	}
+
+	// 1 body paragraph, 1 callout paragraph, 1 trailing paragraph, 2 list items
+	if len(doc.Paragraphs) != 5 {
+		t.Fatalf("got %d paragraphs, want 5", len(doc.Paragraphs))
+	}
+	if doc.Paragraphs[1].Callout != "note" {
+		t.Errorf("expected second paragraph inside note callout, got %q", doc.Paragraphs[1].Callout)
+	}
+
+	for line, want := range map[int]LineKind{1: LineFrontMatter, 4: LineBlank, 5: LineHeading, 7: LineText, 10: LineComment, 13: LineCode, 17: LineDivFence} {
+		if got := doc.Kind(line); got != want {
+			t.Errorf("line %d: got kind %d, want %d", line, got, want)
+		}
+	}
+}
+
+func TestParse_LinksAndCitations(t *testing.T) {
+	doc := Parse("test.qmd", []byte(sample))
+
+	wantLinks := []struct {
+		url  string
+		kind LinkKind
+		line int
+		col  int
+	}{
+		{"https://auto.link/x", LinkAutolink, 8, 6},
+		{"https://bare.link/y", LinkBare, 8, 31},
+		{"https://callout.link", LinkInline, 18, 28},
+	}
+	if len(doc.Links) != len(wantLinks) {
+		t.Fatalf("got %d links, want %d: %+v", len(doc.Links), len(wantLinks), doc.Links)
+	}
+	for _, want := range wantLinks {
+		found := false
+		for _, l := range doc.Links {
+			if l.URL == want.url {
+				found = true
+				if l.Kind != want.kind || l.Span.Start.Line != want.line || l.Span.Start.Col != want.col {
+					t.Errorf("link %s: got %s at %d:%d, want %s at %d:%d",
+						want.url, l.Kind, l.Span.Start.Line, l.Span.Start.Col, want.kind, want.line, want.col)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("missing link %s", want.url)
+		}
+	}
+
+	if len(doc.Citations) != 1 {
+		t.Fatalf("got %d citations, want 1: %+v", len(doc.Citations), doc.Citations)
+	}
+	if c := doc.Citations[0]; c.Key != "paper:tavare1986" || c.Span.Start.Line != 7 || c.Span.Start.Col != 16 {
+		t.Errorf("unexpected citation: %+v", c)
+	}
+
+	if len(doc.CrossRefs) != 1 || doc.CrossRefs[0].Key != "sec-rates" {
+		t.Errorf("unexpected cross refs: %+v", doc.CrossRefs)
+	}
+}
+
+func TestParse_MultilineComment(t *testing.T) {
+	content := "Before <!-- start\nhttps://hidden.url\nend --> after https://shown.url\n"
+	doc := Parse("test.qmd", []byte(content))
+
+	if len(doc.Comments) != 1 {
+		t.Fatalf("got %d comments, want 1", len(doc.Comments))
+	}
+	if c := doc.Comments[0]; c.Span.Start.Line != 1 || c.Span.End.Line != 3 {
+		t.Errorf("unexpected comment span: %+v", c.Span)
+	}
+	if len(doc.Links) != 1 || doc.Links[0].URL != "https://shown.url" {
+		t.Errorf("unexpected links: %+v", doc.Links)
+	}
+}
+
+func TestParse_UnclosedFence(t *testing.T) {
+	content := "Intro.\n\n```\ncode https://in.code\n"
+	doc := Parse("test.qmd", []byte(content))
+
+	if len(doc.CodeChunks) != 1 || doc.CodeChunks[0].Content != "code https://in.code" {
+		t.Errorf("unexpected code chunks: %+v", doc.CodeChunks)
+	}
+	if len(doc.Links) != 0 {
+		t.Errorf("expected no links, got %+v", doc.Links)
+	}
+}
+
+func TestIsCrossRef(t *testing.T) {
+	tests := map[string]bool{
+		"sec-intro":        true,
+		"fig-tree":         true,
+		"tbl-rates":        true,
+		"knuth84":          false,
+		"paper:tavare1986": false,
+		"smith-jones2020":  false,
+	}
+	for key, want := range tests {
+		if got := IsCrossRef(key); got != want {
+			t.Errorf("IsCrossRef(%q): got %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestParse_PandocCitations(t *testing.T) {
+	content := "As @knuth84 says [see @a; -@b, p. 3; @{odd:key}] and [@sec-intro].\n" +
+		"Not a group: [plain text] or [link @c](https://x.org) or me@example.com.\n"
+	doc := Parse("test.qmd", []byte(content))
+
+	want := []Citation{
+		{Key: "a", Mode: CitationNormal, Prefix: "see", Group: 1},
+		{Key: "b", Mode: CitationSuppressAuthor, Suffix: "p. 3", Group: 1},
+		{Key: "odd:key", Mode: CitationNormal, Group: 1},
+		{Key: "knuth84", Mode: CitationInText},
+		{Key: "c", Mode: CitationInText},
+	}
+	if len(doc.Citations) != len(want) {
+		t.Fatalf("got %d citations, want %d: %+v", len(doc.Citations), len(want), doc.Citations)
+	}
+	for i, w := range want {
+		got := doc.Citations[i]
+		if got.Key != w.Key || got.Mode != w.Mode || got.Prefix != w.Prefix || got.Suffix != w.Suffix || got.Group != w.Group {
+			t.Errorf("citation %d: got %+v, want %+v", i, got, w)
+		}
+	}
+	if c := doc.Citations[1]; c.Span.Start.Col != 28 || c.Span.End.Col != 30 {
+		t.Errorf("unexpected span for -@b: %+v", c.Span)
+	}
+
+	if len(doc.CrossRefs) != 1 || doc.CrossRefs[0].Group != 2 {
+		t.Errorf("unexpected cross refs: %+v", doc.CrossRefs)
+	}
+}
+
+func TestParse_LabelsAndIncludes(t *testing.T) {
+	content := `# Trees {#sec-trees}
+
+![A tree](tree.png){#fig-tree width=50%}
+
+{{< include _details.qmd >}}
+
+::: {#thm-bound}
+A bound.
+:::
+
+` + "```{r}" + `
+#| label: fig-plot
+plot(1)
+` + "```" + `
+
+| a | b |
+: Counts {#tbl-counts}
+`
+	doc := Parse("test.qmd", []byte(content))
+
+	want := map[string]int{"sec-trees": 1, "fig-tree": 3, "thm-bound": 7, "fig-plot": 12, "tbl-counts": 17}
+	if len(doc.Labels) != len(want) {
+		t.Fatalf("got labels %+v, want %v", doc.Labels, want)
+	}
+	for _, label := range doc.Labels {
+		if line, ok := want[label.ID]; !ok || label.Span.Start.Line != line {
+			t.Errorf("unexpected label %s on line %d", label.ID, label.Span.Start.Line)
+		}
+	}
+
+	if len(doc.Includes) != 1 || doc.Includes[0].Path != "_details.qmd" || doc.Includes[0].Span.Start.Line != 5 {
+		t.Errorf("unexpected includes: %+v", doc.Includes)
+	}
+	if doc.Kind(5) != LineShortcode {
+		t.Errorf("include line kind = %d, want LineShortcode", doc.Kind(5))
+	}
+	if doc.CodeChunks[0].Label != "fig-plot" {
+		t.Errorf("chunk label = %q, want fig-plot", doc.CodeChunks[0].Label)
+	}
+}
+
+func TestRenameCitations(t *testing.T) {
+	content := "See @knuth84 and [-@knuth84, p. 3; @other].\n\n" +
+		"Mail knuth84@example.com or read `@knuth84`.\n\n" +
+		"<!-- @knuth84 -->\n\nAlso @knuth84a and @{knuth84}.\n"
+	want := "See @knuth1984 and [-@knuth1984, p. 3; @other].\n\n" +
+		"Mail knuth84@example.com or read `@knuth84`.\n\n" +
+		"<!-- @knuth84 -->\n\nAlso @knuth84a and @{knuth1984}.\n"
+
+	got, n := RenameCitations("test.qmd", []byte(content), map[string]string{"knuth84": "knuth1984"})
+	if string(got) != want || n != 3 {
+		t.Errorf("RenameCitations() = %q, %d; want %q, 3", got, n, want)
+	}
+}
+
+func TestSplitSentences(t *testing.T) {
+	text := "First sentence spans\ntwo lines. Second one?  Third"
+	sentences := SplitSentences(text)
+	if len(sentences) != 3 {
+		t.Fatalf("got %d sentences, want 3", len(sentences))
+	}
+	if sentences[0].Text != "First sentence spans two lines." {
+		t.Errorf("got %q", sentences[0].Text)
+	}
+	if sentences[1].Start != strings.Index(text, "Second") || sentences[1].End != strings.Index(text, "?")+1 {
+		t.Errorf("got offsets %d-%d for second sentence", sentences[1].Start, sentences[1].End)
+	}
+}
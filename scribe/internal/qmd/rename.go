@@ -0,0 +1,44 @@
+package qmd
+
+import (
+	"sort"
+	"strings"
+)
+
+// RenameCitations replaces citation keys in a document's source, returning
+// the new content and the number of citations renamed. Only citations are
+// touched; the same text in code, comments, URLs, or email addresses is not.
+func RenameCitations(path string, content []byte, renames map[string]string) ([]byte, int) {
+	doc := Parse(path, content)
+	lines := strings.Split(string(content), "\n")
+
+	// Replace from the end of each line so earlier columns stay valid
+	citations := append([]Citation(nil), doc.Citations...)
+	sort.Slice(citations, func(i, j int) bool {
+		a, b := citations[i].Span.Start, citations[j].Span.Start
+		return a.Line > b.Line || a.Line == b.Line && a.Col > b.Col
+	})
+
+	renamed := 0
+	for _, c := range citations {
+		key, ok := renames[c.Key]
+		if !ok || c.Span.Start.Line != c.Span.End.Line || c.Span.Start.Line > len(lines) {
+			continue
+		}
+		line := lines[c.Span.Start.Line-1]
+		start, end := c.Span.Start.Col-1, c.Span.End.Col-1
+		if start < 0 || end > len(line) {
+			continue
+		}
+		i := strings.Index(line[start:end], c.Key)
+		if i < 0 {
+			continue
+		}
+		lines[c.Span.Start.Line-1] = line[:start+i] + key + line[start+i+len(c.Key):]
+		renamed++
+	}
+	if renamed == 0 {
+		return content, 0
+	}
+	return []byte(strings.Join(lines, "\n")), renamed
+}
@@ -0,0 +1,41 @@
+package qmd
+
+import "strings"
+
+// Sentence is a sentence of a paragraph.
+type Sentence struct {
+	Text  string // whitespace-normalized text
+	Start int    // byte offset of the first character in the paragraph's Text
+	End   int    // byte offset just past the sentence, including its terminator
+}
+
+// Sentences splits the paragraph into sentences (simple heuristic: a
+// sentence ends at ".", "?", or "!"). Line breaks are treated as spaces.
+func (p *Paragraph) Sentences() []Sentence {
+	return SplitSentences(p.Text)
+}
+
+// SplitSentences splits text into sentences as Paragraph.Sentences does.
+func SplitSentences(text string) []Sentence {
+	var sentences []Sentence
+	start := 0
+	emit := func(end int) {
+		raw := text[start:end]
+		trimmed := strings.TrimSpace(raw)
+		if trimmed != "" {
+			sentences = append(sentences, Sentence{
+				Text:  strings.Join(strings.Fields(trimmed), " "),
+				Start: start + strings.Index(raw, trimmed),
+				End:   end,
+			})
+		}
+		start = end
+	}
+	for i := 0; i < len(text); i++ {
+		if c := text[i]; c == '.' || c == '?' || c == '!' {
+			emit(i + 1)
+		}
+	}
+	emit(len(text))
+	return sentences
+}
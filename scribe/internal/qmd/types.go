@@ -0,0 +1,219 @@
+// Package qmd parses Quarto markdown (QMD) documents into a structural model.
+package qmd
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Position is a 1-based line and column in a source file.
+// Columns count bytes, so they match what editors report for ASCII text.
+type Position struct {
+	Line int `json:"line"`
+	Col  int `json:"col"`
+}
+
+// Span is a range in a source file. End is exclusive.
+type Span struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// LineKind classifies a source line by the block it belongs to.
+type LineKind int
+
+const (
+	LineBlank LineKind = iota
+	LineText
+	LineHeading
+	LineFrontMatter
+	LineCode
+	LineComment
+	LineDivFence
+	LineShortcode // a block shortcode such as {{< include _intro.qmd >}}
+)
+
+// FrontMatter is the YAML block at the top of a document.
+type FrontMatter struct {
+	Raw  string `json:"raw"`
+	Span Span   `json:"span"`
+}
+
+// Heading is an ATX heading, with any Pandoc attributes parsed out.
+type Heading struct {
+	Level   int      `json:"level"`
+	Text    string   `json:"text"`
+	ID      string   `json:"id,omitempty"` // from {#sec-foo}
+	Classes []string `json:"classes,omitempty"`
+	Span    Span     `json:"span"`
+}
+
+// Paragraph is a run of prose lines. HTML comments and inline code are
+// blanked out of Text so that columns still line up with the source.
+type Paragraph struct {
+	Text    string `json:"text"`
+	Span    Span   `json:"span"`
+	Callout string `json:"callout,omitempty"` // callout type if inside a callout, e.g. "note"
+
+	lineStarts []int // offset in Text where each source line begins
+}
+
+// CodeChunk is a fenced code block. Executable chunks use Quarto's {lang} syntax.
+type CodeChunk struct {
+	Lang       string `json:"lang,omitempty"`
+	Executable bool   `json:"executable"`
+	Label      string `json:"label,omitempty"` // from a "#| label: fig-foo" option line
+	Content    string `json:"content"`
+	Span       Span   `json:"span"`
+}
+
+// Callout is a Quarto callout block (::: {.callout-note}).
+type Callout struct {
+	Type  string `json:"type"` // note, tip, warning, caution, important
+	Title string `json:"title,omitempty"`
+	Span  Span   `json:"span"`
+}
+
+// Comment is an HTML comment.
+type Comment struct {
+	Text string `json:"text"`
+	Span Span   `json:"span"`
+}
+
+// Label is an identifier defined in the document: a heading or div ID, a
+// {#fig-foo} attribute on an image, table caption, or equation, or a code
+// chunk label. Quarto cross-references (@sec-foo) point at labels.
+type Label struct {
+	ID   string `json:"id"`
+	Span Span   `json:"span"` // the line defining the label
+}
+
+// Include is a {{< include path >}} shortcode.
+type Include struct {
+	Path string `json:"path"` // as written, relative to the including file
+	Span Span   `json:"span"`
+}
+
+// LinkKind distinguishes how a link was written.
+type LinkKind string
+
+const (
+	LinkInline   LinkKind = "inline"   // [text](url)
+	LinkAutolink LinkKind = "autolink" // <url>
+	LinkBare     LinkKind = "bare"     // url in running text
+)
+
+// Link is a hyperlink or image reference in prose or a heading.
+type Link struct {
+	Kind  LinkKind `json:"kind"`
+	Text  string   `json:"text,omitempty"`
+	URL   string   `json:"url"`
+	Image bool     `json:"image,omitempty"`
+	Span  Span     `json:"span"` // span of the URL itself
+}
+
+// CitationMode is how a citation is rendered, following Pandoc's citation syntax.
+type CitationMode string
+
+const (
+	CitationNormal         CitationMode = "normal"          // [@key]
+	CitationSuppressAuthor CitationMode = "suppress-author" // [-@key]
+	CitationInText         CitationMode = "in-text"         // @key
+)
+
+// Citation is a Pandoc citation key such as @knuth84 or @paper:tavare1986.
+type Citation struct {
+	Key    string       `json:"key"`
+	Mode   CitationMode `json:"mode"`
+	Prefix string       `json:"prefix,omitempty"` // text before the key inside brackets
+	Suffix string       `json:"suffix,omitempty"` // locator and text after the key, e.g. "p. 3"
+	Group  int          `json:"group,omitempty"`  // 1-based bracketed group in the document; 0 for in-text
+	Span   Span         `json:"span"`             // span of @key
+}
+
+// Document is a parsed QMD file.
+type Document struct {
+	Path        string       `json:"path"`
+	Lines       []string     `json:"-"`
+	FrontMatter *FrontMatter `json:"front_matter,omitempty"`
+	Headings    []Heading    `json:"headings"`
+	Paragraphs  []Paragraph  `json:"paragraphs"`
+	CodeChunks  []CodeChunk  `json:"code_chunks"`
+	Callouts    []Callout    `json:"callouts"`
+	Comments    []Comment    `json:"comments"`
+	Links       []Link       `json:"links"`
+	Citations   []Citation   `json:"citations"`
+	CrossRefs   []Citation   `json:"cross_refs"` // @sec-foo, @fig-bar, ...
+	Labels      []Label      `json:"labels"`
+	Includes    []Include    `json:"includes"`
+
+	kinds []LineKind
+}
+
+// Kind returns the kind of a 1-based source line.
+func (d *Document) Kind(line int) LineKind {
+	if line < 1 || line > len(d.kinds) {
+		return LineBlank
+	}
+	return d.kinds[line-1]
+}
+
+// Line returns the text of a 1-based source line, or "" if out of range.
+func (d *Document) Line(line int) string {
+	if line < 1 || line > len(d.Lines) {
+		return ""
+	}
+	return d.Lines[line-1]
+}
+
+// Anchors returns the IDs a link can target with #anchor: explicit labels
+// and the automatic identifiers of headings without one.
+func (d *Document) Anchors() map[string]bool {
+	anchors := make(map[string]bool)
+	for _, label := range d.Labels {
+		anchors[label.ID] = true
+	}
+	for _, h := range d.Headings {
+		if h.ID == "" {
+			anchors[AutoID(h.Text)] = true
+		}
+	}
+	return anchors
+}
+
+// AutoID derives a heading identifier the way Pandoc does: keep letters,
+// digits, "_", "-", and ".", turn spaces into hyphens, lowercase, and drop
+// everything before the first letter.
+func AutoID(text string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(text) {
+		switch {
+		case unicode.IsLetter(r):
+			b.WriteRune(unicode.ToLower(r))
+		case unicode.IsDigit(r) || r == '_' || r == '-' || r == '.':
+			if b.Len() > 0 {
+				b.WriteRune(r)
+			}
+		case unicode.IsSpace(r):
+			if b.Len() > 0 {
+				b.WriteRune('-')
+			}
+		}
+	}
+	if b.Len() == 0 {
+		return "section"
+	}
+	return b.String()
+}
+
+// PositionOf maps a byte offset in Text to a source position.
+func (p *Paragraph) PositionOf(offset int) Position {
+	i := len(p.lineStarts) - 1
+	for i > 0 && p.lineStarts[i] > offset {
+		i--
+	}
+	return Position{
+		Line: p.Span.Start.Line + i,
+		Col:  offset - p.lineStarts[i] + 1,
+	}
+}
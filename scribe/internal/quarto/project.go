@@ -0,0 +1,352 @@
+// Package quarto reads Quarto project and document configuration.
+package quarto
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/qmd"
+)
+
+// ProjectFile is the name of the Quarto project configuration file.
+const ProjectFile = "_quarto.yml"
+
+// stringList is a YAML value that may be a single string or a list of strings.
+type stringList []string
+
+// UnmarshalYAML accepts both `key: value` and `key: [a, b]` forms.
+func (l *stringList) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		*l = stringList{node.Value}
+		return nil
+	case yaml.SequenceNode:
+		var items []string
+		if err := node.Decode(&items); err != nil {
+			return err
+		}
+		*l = items
+		return nil
+	default:
+		return fmt.Errorf("line %d: expected string or list of strings", node.Line)
+	}
+}
+
+// chapterEntry is one item of book.chapters or book.appendices: a file name,
+// a {href: file} link, or a {part: file-or-title, chapters: [...]} group.
+type chapterEntry struct {
+	Href     string
+	Part     string
+	Chapters []chapterEntry
+}
+
+// UnmarshalYAML accepts the scalar and mapping forms of a chapter entry.
+func (e *chapterEntry) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		e.Href = node.Value
+		return nil
+	}
+	var m struct {
+		Href     string         `yaml:"href"`
+		File     string         `yaml:"file"`
+		Part     string         `yaml:"part"`
+		Chapters []chapterEntry `yaml:"chapters"`
+	}
+	if err := node.Decode(&m); err != nil {
+		return err
+	}
+	e.Href, e.Part, e.Chapters = m.Href, m.Part, m.Chapters
+	if e.Href == "" {
+		e.Href = m.File
+	}
+	return nil
+}
+
+// config is the subset of _quarto.yml that scribe reads.
+type config struct {
+	Bibliography stringList `yaml:"bibliography"`
+	Nocite       string     `yaml:"nocite"`
+	Project      struct {
+		Type   string   `yaml:"type"`
+		Render []string `yaml:"render"`
+	} `yaml:"project"`
+	Book struct {
+		Chapters   []chapterEntry `yaml:"chapters"`
+		Appendices []chapterEntry `yaml:"appendices"`
+	} `yaml:"book"`
+}
+
+// Project is a loaded Quarto project.
+type Project struct {
+	Dir          string   // directory containing _quarto.yml
+	Type         string   // project type, e.g. "book"
+	Bibliography []string // bibliography files, resolved against Dir
+	Nocite       []string // keys listed under nocite, or "*" for every entry
+	// Chapters lists the project's documents in book order: book chapters,
+	// parts, and appendices; else project.render; else every .qmd file.
+	Chapters []string
+}
+
+// FindProject walks up from start looking for _quarto.yml.
+// It returns the project directory, or "" if there is none.
+func FindProject(start string) (string, error) {
+	dir, err := filepath.Abs(start)
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ProjectFile)); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// LoadProject reads _quarto.yml from dir.
+func LoadProject(dir string) (*Project, error) {
+	path := filepath.Join(dir, ProjectFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	var cfg config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	project := &Project{
+		Dir:          dir,
+		Type:         cfg.Project.Type,
+		Bibliography: resolvePaths(dir, cfg.Bibliography),
+		Nocite:       NociteKeys(cfg.Nocite),
+	}
+	switch {
+	case len(cfg.Book.Chapters) > 0 || len(cfg.Book.Appendices) > 0:
+		project.Chapters = resolvePaths(dir, flattenChapters(append(cfg.Book.Chapters, cfg.Book.Appendices...)))
+	case len(cfg.Project.Render) > 0:
+		project.Chapters, err = globDocuments(dir, cfg.Project.Render)
+	default:
+		project.Chapters, err = globDocuments(dir, []string{"**.qmd"})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+// flattenChapters lists chapter files in order. Part entries contribute their
+// own file, if they name one, followed by their chapters.
+func flattenChapters(entries []chapterEntry) []string {
+	var files []string
+	for _, e := range entries {
+		if e.Href != "" && isDocument(e.Href) {
+			files = append(files, e.Href)
+		}
+		if e.Part != "" && isDocument(e.Part) {
+			files = append(files, e.Part)
+		}
+		files = append(files, flattenChapters(e.Chapters)...)
+	}
+	return files
+}
+
+// isDocument reports whether a path names a Markdown source scribe can read.
+func isDocument(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".qmd" || ext == ".md"
+}
+
+// globDocuments expands project.render patterns into documents in path order.
+// "**" matches across directories and a leading "!" excludes matches. Files
+// and directories starting with "_" or "." are skipped, as Quarto does.
+func globDocuments(dir string, patterns []string) ([]string, error) {
+	var include, exclude []*regexp.Regexp
+	for _, pattern := range patterns {
+		if negated, ok := strings.CutPrefix(pattern, "!"); ok {
+			exclude = append(exclude, globRegexp(negated))
+		} else {
+			include = append(include, globRegexp(pattern))
+		}
+	}
+	matches := func(res []*regexp.Regexp, name string) bool {
+		for _, re := range res {
+			if re.MatchString(name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if path != dir && (strings.HasPrefix(name, "_") || strings.HasPrefix(name, ".")) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !isDocument(path) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if matches(include, rel) && !matches(exclude, rel) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list project documents: %w", err)
+	}
+	return files, nil
+}
+
+// globRegexp compiles a slash-separated glob: "**" matches anything, "*" and
+// "?" match within one path segment.
+func globRegexp(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '*' && i+1 < len(pattern) && pattern[i+1] == '*':
+			b.WriteString(".*")
+			i++
+			if i+1 < len(pattern) && pattern[i+1] == '/' {
+				b.WriteString("/?")
+				i++
+			}
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// Files returns every document in the project in book order, with files
+// pulled in by {{< include >}} following the document that includes them.
+// Include paths are relative to the including file, or to the project
+// directory if they start with "/".
+func (p *Project) Files() ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	var visit func(path, from string) error
+	visit = func(path, from string) error {
+		if seen[path] {
+			return nil
+		}
+		seen[path] = true
+		doc, err := qmd.ParseFile(path)
+		if err != nil {
+			if from != "" {
+				return fmt.Errorf("%s: include %w", from, err)
+			}
+			return fmt.Errorf("chapter listed in %s: %w", ProjectFile, err)
+		}
+		files = append(files, path)
+		for _, inc := range doc.Includes {
+			target := filepath.Join(filepath.Dir(path), inc.Path)
+			if strings.HasPrefix(inc.Path, "/") {
+				target = filepath.Join(p.Dir, inc.Path)
+			}
+			if err := visit(target, fmt.Sprintf("%s:%d", path, inc.Span.Start.Line)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, chapter := range p.Chapters {
+		if err := visit(chapter, ""); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// UsesBibliography reports whether the project's bibliography lists the
+// BibTeX file at path.
+func (p *Project) UsesBibliography(path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(abs)
+	for _, bib := range p.Bibliography {
+		if filepath.Clean(bib) == abs {
+			return true
+		}
+		if err == nil {
+			if other, err := os.Stat(bib); err == nil && os.SameFile(info, other) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FrontMatterBibliography returns the bibliography files declared in a
+// document's YAML front matter, resolved against the document's directory.
+func FrontMatterBibliography(docPath, frontMatter string) ([]string, error) {
+	var cfg config
+	if err := yaml.Unmarshal([]byte(frontMatter), &cfg); err != nil {
+		return nil, fmt.Errorf("parse front matter of %s: %w", docPath, err)
+	}
+	return resolvePaths(filepath.Dir(docPath), cfg.Bibliography), nil
+}
+
+// FrontMatterNocite returns the citation keys listed under nocite in a
+// document's YAML front matter.
+func FrontMatterNocite(docPath, frontMatter string) ([]string, error) {
+	var cfg config
+	if err := yaml.Unmarshal([]byte(frontMatter), &cfg); err != nil {
+		return nil, fmt.Errorf("parse front matter of %s: %w", docPath, err)
+	}
+	return NociteKeys(cfg.Nocite), nil
+}
+
+// nocitePattern matches the citations in a nocite value, e.g. "@a, @b" or "@*".
+var nocitePattern = regexp.MustCompile(`@(\*|[\p{L}\p{N}_][\p{L}\p{N}_:.#$%&+?<>~/-]*)`)
+
+// NociteKeys returns the keys cited in a nocite value. The wildcard "@*",
+// which includes every bibliography entry, is returned as "*".
+func NociteKeys(nocite string) []string {
+	var keys []string
+	for _, m := range nocitePattern.FindAllStringSubmatch(nocite, -1) {
+		keys = append(keys, strings.TrimRight(m[1], ".:;,"))
+	}
+	return keys
+}
+
+// resolvePaths joins relative paths onto dir.
+func resolvePaths(dir string, paths []string) []string {
+	var resolved []string
+	for _, p := range paths {
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(dir, p)
+		}
+		resolved = append(resolved, p)
+	}
+	return resolved
+}
@@ -0,0 +1,189 @@
+package quarto
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadProject(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	cfg := "project:\n  type: book\nbibliography: references.bib\nnocite: |\n  @felsenstein1981, @tavare1986\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, ProjectFile), []byte(cfg), 0644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	sub := filepath.Join(tmpDir, "chapters", "part1")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	dir, err := FindProject(sub)
+	if err != nil {
+		t.Fatalf("FindProject: %v", err)
+	}
+	if dir != tmpDir {
+		t.Errorf("got project dir %s, want %s", dir, tmpDir)
+	}
+
+	project, err := LoadProject(dir)
+	if err != nil {
+		t.Fatalf("LoadProject: %v", err)
+	}
+	if len(project.Bibliography) != 1 || project.Bibliography[0] != filepath.Join(tmpDir, "references.bib") {
+		t.Errorf("unexpected bibliography: %v", project.Bibliography)
+	}
+	if len(project.Nocite) != 2 || project.Nocite[1] != "tavare1986" {
+		t.Errorf("unexpected nocite: %v", project.Nocite)
+	}
+	if !project.UsesBibliography(filepath.Join(sub, "..", "..", "references.bib")) {
+		t.Error("UsesBibliography should match the project bibliography by its resolved path")
+	}
+	if project.UsesBibliography(filepath.Join(sub, "references.bib")) {
+		t.Error("UsesBibliography should not match another file with the same name")
+	}
+}
+
+func TestNociteKeys(t *testing.T) {
+	tests := []struct {
+		nocite string
+		want   []string
+	}{
+		{"@a, @b2020", []string{"a", "b2020"}},
+		{"@*", []string{"*"}},
+		{"@paper:abc123.", []string{"paper:abc123"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		got := NociteKeys(tt.nocite)
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("NociteKeys(%q) = %q, want %q", tt.nocite, got, tt.want)
+		}
+	}
+}
+
+func TestFrontMatterBibliography(t *testing.T) {
+	paths, err := FrontMatterBibliography("book/ch1.qmd", "title: x\nbibliography:\n  - a.bib\n  - /abs/b.bib\n")
+	if err != nil {
+		t.Fatalf("FrontMatterBibliography: %v", err)
+	}
+	want := []string{filepath.Join("book", "a.bib"), "/abs/b.bib"}
+	if len(paths) != 2 || paths[0] != want[0] || paths[1] != want[1] {
+		t.Errorf("got %v, want %v", paths, want)
+	}
+
+	paths, err = FrontMatterBibliography("ch1.qmd", "title: x\n")
+	if err != nil || len(paths) != 0 {
+		t.Errorf("expected no bibliography, got %v, %v", paths, err)
+	}
+}
+
+func TestProjectFiles(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	cfg := `project:
+  type: book
+book:
+  chapters:
+    - index.qmd
+    - part: part1.qmd
+      chapters:
+        - chapters/trees.qmd
+    - part: "Methods"
+      chapters:
+        - href: chapters/methods.qmd
+  appendices:
+    - appendix.qmd
+`
+	files := map[string]string{
+		ProjectFile:                 cfg,
+		"index.qmd":                 "# Preface\n",
+		"part1.qmd":                 "# Part 1\n",
+		"chapters/trees.qmd":        "# Trees\n\n{{< include _shared/note.qmd >}}\n",
+		"_shared/note.qmd":          "A note.\n\n{{< include /_shared/footer.qmd >}}\n",
+		"_shared/footer.qmd":        "Footer.\n",
+		"chapters/_shared/note.qmd": "Chapter-local note.\n",
+		"chapters/methods.qmd":      "# Methods\n",
+		"appendix.qmd":              "# Appendix\n",
+		"unlisted.qmd":              "# Not in the book\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	project, err := LoadProject(tmpDir)
+	if err != nil {
+		t.Fatalf("LoadProject: %v", err)
+	}
+	got, err := project.Files()
+	if err != nil {
+		t.Fatalf("Files: %v", err)
+	}
+	want := []string{"index.qmd", "part1.qmd", "chapters/trees.qmd", "chapters/_shared/note.qmd", "chapters/methods.qmd", "appendix.qmd"}
+	if len(got) != len(want) {
+		t.Fatalf("got files %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != filepath.Join(tmpDir, want[i]) {
+			t.Errorf("file %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+
+	// A missing include is an error
+	if err := os.WriteFile(filepath.Join(tmpDir, "appendix.qmd"), []byte("{{< include missing.qmd >}}\n"), 0644); err != nil {
+		t.Fatalf("write appendix: %v", err)
+	}
+	if _, err := project.Files(); err == nil {
+		t.Error("expected error for a missing include")
+	}
+}
+
+func TestLoadProject_Render(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	cfg := "project:\n  render:\n    - \"**/*.qmd\"\n    - \"!drafts/**\"\n"
+	for name, content := range map[string]string{
+		ProjectFile:       cfg,
+		"a.qmd":           "",
+		"sub/b.qmd":       "",
+		"drafts/c.qmd":    "",
+		"_partials/d.qmd": "",
+		"notes.txt":       "",
+	} {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	project, err := LoadProject(tmpDir)
+	if err != nil {
+		t.Fatalf("LoadProject: %v", err)
+	}
+	want := []string{filepath.Join(tmpDir, "a.qmd"), filepath.Join(tmpDir, "sub/b.qmd")}
+	if len(project.Chapters) != 2 || project.Chapters[0] != want[0] || project.Chapters[1] != want[1] {
+		t.Errorf("Chapters = %v, want %v", project.Chapters, want)
+	}
+}
@@ -0,0 +1,148 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/bibtex"
+)
+
+// PaperMetadata is what a paper library such as bipartite knows about a paper.
+type PaperMetadata struct {
+	DOI     string
+	Title   string
+	Authors []string
+	Year    int
+}
+
+// PaperLookup looks up paper metadata by Semantic Scholar ID.
+// LookupPaper returns (nil, nil) if the paper is unknown.
+type PaperLookup interface {
+	LookupPaper(s2ID string) (*PaperMetadata, error)
+}
+
+// BibSync adds approved papers to a BibTeX file.
+type BibSync struct {
+	Path   string
+	Lookup PaperLookup // optional; fills in the DOI and corrects PaperData
+}
+
+// NewBibSync creates a BibSync for the BibTeX file at path.
+func NewBibSync(path string, lookup PaperLookup) *BibSync {
+	return &BibSync{Path: path, Lookup: lookup}
+}
+
+// BibSyncResult is the bibliography entry for an approved paper.
+type BibSyncResult struct {
+	CandidateID string `json:"candidate_id"`
+	Key         string `json:"key"`
+	Added       bool   `json:"added"` // false if the paper was already in the file
+}
+
+// Sync makes sure every approved paper among candidates has an entry in the
+// bibliography, appending entries for the ones that do not. A paper is
+// already present if its recorded BibKey, DOI, or normalized title matches
+// an entry. New keys are authorYEAR keys that do not collide with existing
+// ones, so the same queue and file always produce the same keys. With
+// dryRun, the file is not written. The file is locked while it is read and
+// rewritten, so concurrent approvals do not lose each other's entries.
+func (b *BibSync) Sync(candidates []Candidate, dryRun bool) ([]BibSyncResult, error) {
+	unlock, err := LockFile(b.Path, !dryRun)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	src, err := os.ReadFile(b.Path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("read bibliography: %w", err)
+	}
+	file := bibtex.Parse(b.Path, src)
+
+	var results []BibSyncResult
+	var added []*bibtex.Entry
+	for _, c := range candidates {
+		if c.Type != CandidateTypePaper || c.Status != CandidateStatusApproved || c.PaperData == nil {
+			continue
+		}
+		entry := b.paperEntry(*c.PaperData)
+		if key := findEntry(file, added, c, entry); key != "" {
+			results = append(results, BibSyncResult{CandidateID: c.ID, Key: key})
+			continue
+		}
+		entry.Key = bibtex.NewKey(entry, file, &bibtex.File{Entries: added})
+		added = append(added, entry)
+		results = append(results, BibSyncResult{CandidateID: c.ID, Key: entry.Key, Added: true})
+	}
+
+	if len(added) > 0 && !dryRun {
+		write := func(w io.Writer) error {
+			_, err := w.Write(bibtex.AppendEntries(src, added...))
+			return err
+		}
+		if err := WriteFileAtomic(b.Path, write); err != nil {
+			return nil, fmt.Errorf("write bibliography: %w", err)
+		}
+	}
+	return results, nil
+}
+
+// paperEntry builds a BibTeX entry for a paper from its candidate data and
+// library metadata. Lookups are best effort: if the library does not know
+// the paper, the candidate data alone is used.
+func (b *BibSync) paperEntry(paper PaperData) *bibtex.Entry {
+	title, authors, year, doi := paper.Title, paper.Authors, paper.Year, ""
+	if b.Lookup != nil && paper.S2ID != "" {
+		if meta, err := b.Lookup.LookupPaper(paper.S2ID); err == nil && meta != nil {
+			if meta.Title != "" {
+				title = meta.Title
+			}
+			if len(meta.Authors) > 0 {
+				authors = meta.Authors
+			}
+			if meta.Year != 0 {
+				year = meta.Year
+			}
+			doi = bibtex.NormalizeDOI(meta.DOI)
+		}
+	}
+
+	entry := &bibtex.Entry{Type: "misc", File: b.Path}
+	add := func(name, value string) {
+		if value != "" {
+			entry.Fields = append(entry.Fields, bibtex.Field{Name: name, Value: bibtex.Braced(value)})
+		}
+	}
+	add("author", strings.Join(authors, " and "))
+	add("title", title)
+	if year != 0 {
+		add("year", strconv.Itoa(year))
+	}
+	add("doi", doi)
+	return entry
+}
+
+// findEntry returns the key of the entry for a candidate's paper in file or
+// among entries added earlier in the same sync, or "" if there is none.
+func findEntry(file *bibtex.File, added []*bibtex.Entry, c Candidate, paper *bibtex.Entry) string {
+	all := append(append([]*bibtex.Entry(nil), file.Entries...), added...)
+	if c.BibKey != nil {
+		for _, e := range all {
+			if e.Key == *c.BibKey {
+				return e.Key
+			}
+		}
+	}
+	doi := bibtex.NormalizeDOI(paper.Get("doi"))
+	title := bibtex.NormalizeTitle(paper.Get("title"))
+	for _, e := range all {
+		if doi != "" && bibtex.NormalizeDOI(e.Get("doi")) == doi || title != "" && bibtex.NormalizeTitle(e.Get("title")) == title {
+			return e.Key
+		}
+	}
+	return ""
+}
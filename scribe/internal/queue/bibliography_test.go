@@ -0,0 +1,203 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/bibtex"
+)
+
+// fakeLookup is a PaperLookup backed by a map.
+type fakeLookup map[string]*PaperMetadata
+
+func (f fakeLookup) LookupPaper(s2ID string) (*PaperMetadata, error) {
+	return f[s2ID], nil
+}
+
+func paperCandidate(id, s2ID, title string, authors []string, year int) Candidate {
+	return Candidate{
+		ID:           id,
+		Type:         CandidateTypePaper,
+		Status:       CandidateStatusPending,
+		DiscoveredAt: time.Now(),
+		DiscoveredBy: "test",
+		PaperData:    &PaperData{S2ID: s2ID, Title: title, Authors: authors, Year: year},
+	}
+}
+
+func TestCandidateService_ApproveAddsBibEntry(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	bibPath := filepath.Join(tmpDir, "references.bib")
+	existing := "@book{felsenstein2004,\n  author = {Felsenstein, Joseph},\n  title = {Inferring Phylogenies},\n  year = {2004},\n}\n"
+	if err := os.WriteFile(bibPath, []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewStore(filepath.Join(tmpDir, "queue.jsonl"), filepath.Join(tmpDir, "rejected.jsonl"))
+	svc := NewCandidateService(store)
+	svc.bipartite = func(Candidate) error { return nil }
+	svc.SetBibliography(NewBibSync(bibPath, fakeLookup{
+		"s2-tavare": {DOI: "https://doi.org/10.1000/ABC", Title: "Some probabilistic and statistical problems in the analysis of DNA sequences"},
+	}))
+
+	svc.Add(paperCandidate("c-tavare", "s2-tavare", "Some problems", []string{"Simon Tavaré"}, 1986))
+	svc.Add(paperCandidate("c-felsenstein", "s2-felsenstein", "Inferring phylogenies.", []string{"Joseph Felsenstein"}, 2004))
+	svc.Add(paperCandidate("c-tavare2", "s2-tavare2", "Line-of-descent and genealogical processes", []string{"S. Tavaré"}, 1986))
+
+	for _, id := range []string{"c-tavare", "c-felsenstein", "c-tavare2"} {
+		if err := svc.Approve(id, "reviewer", ""); err != nil {
+			t.Fatalf("Approve(%s): %v", id, err)
+		}
+	}
+
+	data, err := os.ReadFile(bibPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := existing + `
+@misc{tavare1986,
+  author = {Simon Tavaré},
+  title = {Some probabilistic and statistical problems in the analysis of DNA sequences},
+  year = {1986},
+  doi = {10.1000/abc},
+}
+
+@misc{tavare1986b,
+  author = {S. Tavaré},
+  title = {Line-of-descent and genealogical processes},
+  year = {1986},
+}
+`
+	if string(data) != want {
+		t.Errorf("bibliography =\n%s\nwant\n%s", data, want)
+	}
+
+	for id, key := range map[string]string{"c-tavare": "tavare1986", "c-felsenstein": "felsenstein2004", "c-tavare2": "tavare1986b"} {
+		c, _ := svc.Get(id)
+		if c.BibKey == nil || *c.BibKey != key {
+			t.Errorf("%s: BibKey = %v, want %s", id, c.BibKey, key)
+		}
+	}
+
+	// Syncing again finds every paper and changes nothing
+	results, err := svc.SyncBibliography(false)
+	if err != nil {
+		t.Fatalf("SyncBibliography: %v", err)
+	}
+	for _, r := range results {
+		if r.Added {
+			t.Errorf("SyncBibliography re-added %s as %s", r.CandidateID, r.Key)
+		}
+	}
+	if again, _ := os.ReadFile(bibPath); string(again) != want {
+		t.Errorf("SyncBibliography changed the file:\n%s", again)
+	}
+}
+
+func TestBibSync_DryRun(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	bibPath := filepath.Join(tmpDir, "refs", "references.bib")
+	c := paperCandidate("c-1", "s2-1", "A {broken title", nil, 0)
+	c.Status = CandidateStatusApproved
+
+	sync := NewBibSync(bibPath, nil)
+	results, err := sync.Sync([]Candidate{c}, true)
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if len(results) != 1 || results[0].Key != "a" || !results[0].Added {
+		t.Errorf("Sync() = %+v", results)
+	}
+	if _, err := os.Stat(bibPath); !os.IsNotExist(err) {
+		t.Errorf("dry run wrote the bibliography")
+	}
+
+	if _, err := sync.Sync([]Candidate{c}, false); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	data, _ := os.ReadFile(bibPath)
+	if !strings.Contains(string(data), "title = {A broken title},") {
+		t.Errorf("bibliography =\n%s", data)
+	}
+}
+
+func TestCandidateService_ApproveReportsEveryFailure(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// A directory where the bibliography should be cannot be updated
+	bibPath := filepath.Join(tmpDir, "references.bib")
+	if err := os.Mkdir(bibPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	store := NewStore(filepath.Join(tmpDir, "queue.jsonl"), filepath.Join(tmpDir, "rejected.jsonl"))
+	svc := NewCandidateService(store)
+	svc.bipartite = func(Candidate) error { return errors.New("bip CLI not found") }
+	svc.SetBibliography(NewBibSync(bibPath, nil))
+
+	c := paperCandidate("c-1", "abc123", "Inferring Phylogenies", []string{"Joseph Felsenstein"}, 2004)
+	if _, err := svc.Add(c); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	err = svc.Approve("c-1", "human", "")
+	if err == nil || !strings.Contains(err.Error(), "bipartite integration failed") || !strings.Contains(err.Error(), "bibliography update failed") {
+		t.Errorf("Approve error should report both failures, got %v", err)
+	}
+}
+
+func TestBibSync_Concurrent(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	bibPath := filepath.Join(tmpDir, "references.bib")
+	const papers = 16
+	var wg sync.WaitGroup
+	errs := make(chan error, papers)
+	for i := range papers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := paperCandidate(fmt.Sprintf("c-%d", i), fmt.Sprintf("s2-%d", i), fmt.Sprintf("Paper number %d", i), []string{"Ann Author"}, 2000+i)
+			c.Status = CandidateStatusApproved
+			_, err := NewBibSync(bibPath, nil).Sync([]Candidate{c}, false)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	file, err := bibtex.ParseFile(bibPath)
+	if err != nil {
+		t.Fatalf("ParseFile: %v", err)
+	}
+	if len(file.Entries) != papers {
+		t.Errorf("got %d entries, want %d", len(file.Entries), papers)
+	}
+}
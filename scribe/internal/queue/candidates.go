@@ -2,29 +2,100 @@ package queue
 
 import (
 	"bytes"
+	"crypto/rand"
+	"errors"
 	"fmt"
 	"os/exec"
+	"strings"
 	"time"
 )
 
 // CandidateService provides candidate management operations.
 type CandidateService struct {
-	store *Store
+	store     *Store
+	bib       *BibSync
+	bipartite func(Candidate) error
 }
 
 // NewCandidateService creates a new CandidateService.
 func NewCandidateService(store *Store) *CandidateService {
-	return &CandidateService{store: store}
+	return &CandidateService{store: store, bipartite: triggerBipartiteAdd}
 }
 
-// GenerateID generates a new candidate ID in c-YYYYMMDDHHMM format.
+// SetBibliography makes Approve add approved papers to a BibTeX file.
+func (s *CandidateService) SetBibliography(bib *BibSync) {
+	s.bib = bib
+}
+
+// idAlphabet is Crockford's base32 in lower case, which sorts like the
+// values it encodes and avoids the ambiguous letters i, l, o, and u.
+const idAlphabet = "0123456789abcdefghjkmnpqrstvwxyz"
+
+// idSuffixLen is the number of random characters in an ID.
+const idSuffixLen = 6
+
+// GenerateID generates a new candidate ID in c-YYYYMMDDHHMMSS-xxxxxx
+// format: the UTC time to the second, which makes IDs sort by when they
+// were added, and a random suffix that keeps IDs from the same second
+// apart. Older c-YYYYMMDDHHMM IDs remain valid.
 func GenerateID() string {
-	return fmt.Sprintf("c-%s", time.Now().Format("200601021504"))
+	suffix := make([]byte, idSuffixLen)
+	rand.Read(suffix)
+	for i, b := range suffix {
+		suffix[i] = idAlphabet[b%byte(len(idAlphabet))]
+	}
+	return fmt.Sprintf("c-%s-%s", time.Now().UTC().Format("20060102150405"), suffix)
+}
+
+// AmbiguousIDError is returned when an ID prefix matches several candidates.
+type AmbiguousIDError struct {
+	Prefix  string
+	Matches []string
+}
+
+func (e *AmbiguousIDError) Error() string {
+	return fmt.Sprintf("ID prefix %s is ambiguous; it matches %s", e.Prefix, strings.Join(e.Matches, ", "))
+}
+
+// Resolve returns the ID of the candidate whose ID is id or, failing that,
+// starts with id. A prefix matching several candidates is an
+// *AmbiguousIDError.
+func (s *CandidateService) Resolve(id string) (string, error) {
+	candidates, err := s.store.ReadAll()
+	if err != nil {
+		return "", err
+	}
+	var matches []string
+	for _, c := range candidates {
+		if c.ID == id {
+			return id, nil
+		}
+		if strings.HasPrefix(c.ID, id) {
+			matches = append(matches, c.ID)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("candidate not found: %s", id)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", &AmbiguousIDError{Prefix: id, Matches: matches}
+	}
+}
+
+// AddResult reports how Add stored a candidate.
+type AddResult struct {
+	ID     string `json:"id"`
+	Merged bool   `json:"merged"` // the candidate was already queued, and was merged into candidate ID
 }
 
-// Add adds a new candidate to the queue.
-func (s *CandidateService) Add(candidate Candidate) error {
-	if candidate.ID == "" {
+// Add adds a new candidate to the queue. A candidate without an ID gets a
+// generated one. A candidate for a paper, repo, or code location that is
+// already pending or approved is merged into the existing candidate instead.
+func (s *CandidateService) Add(candidate Candidate) (AddResult, error) {
+	generated := candidate.ID == ""
+	if generated {
 		candidate.ID = GenerateID()
 	}
 	if candidate.Status == "" {
@@ -34,28 +105,62 @@ func (s *CandidateService) Add(candidate Candidate) error {
 		candidate.DiscoveredAt = time.Now()
 	}
 
-	// Check for duplicates in queue
-	existing, err := s.store.FindByID(candidate.ID)
+	// Check if previously rejected (FR-012)
+	rejected, err := s.store.ReadRejected()
 	if err != nil {
-		return fmt.Errorf("check existing: %w", err)
+		return AddResult{}, fmt.Errorf("check rejected: %w", err)
 	}
-	if existing != nil {
-		return fmt.Errorf("candidate with ID %s already exists", candidate.ID)
+	for _, r := range rejected {
+		if sameItem(r, candidate) {
+			return AddResult{}, fmt.Errorf("candidate was previously rejected (external ID: %s)", getExternalID(r))
+		}
 	}
 
-	// Check if previously rejected (FR-012)
-	externalID := getExternalID(candidate)
-	if externalID != "" {
-		rejected, err := s.store.IsRejected(externalID, candidate.Type)
-		if err != nil {
-			return fmt.Errorf("check rejected: %w", err)
+	// Check for duplicates and add under one lock, so concurrent adds of
+	// the same item cannot both succeed
+	result := AddResult{ID: candidate.ID}
+	err = s.store.Modify(func(candidates []Candidate) ([]Candidate, error) {
+		for taken(candidates, result.ID) {
+			if !generated {
+				return nil, fmt.Errorf("candidate with ID %s already exists", result.ID)
+			}
+			result.ID = GenerateID()
+		}
+		if i := findDuplicate(candidates, candidate); i >= 0 {
+			merge(&candidates[i], candidate)
+			result = AddResult{ID: candidates[i].ID, Merged: true}
+			return candidates, nil
+		}
+		candidate.ID = result.ID
+		return append(candidates, candidate), nil
+	})
+	if err != nil {
+		return AddResult{}, err
+	}
+	if !result.Merged {
+		event := Event{
+			CandidateID: result.ID,
+			Action:      EventAdd,
+			To:          candidate.Status,
+			By:          candidate.DiscoveredBy,
+			At:          candidate.DiscoveredAt,
+			Note:        candidate.DiscoveryContext,
 		}
-		if rejected {
-			return fmt.Errorf("candidate was previously rejected (external ID: %s)", externalID)
+		if err := s.store.AppendEvent(event); err != nil {
+			return result, fmt.Errorf("candidate %s added but not logged: %w", result.ID, err)
 		}
 	}
+	return result, nil
+}
 
-	return s.store.Append(candidate)
+// taken reports whether a candidate has the given ID.
+func taken(candidates []Candidate, id string) bool {
+	for _, c := range candidates {
+		if c.ID == id {
+			return true
+		}
+	}
+	return false
 }
 
 // List returns all candidates, optionally filtered.
@@ -87,66 +192,193 @@ func (s *CandidateService) Get(id string) (*Candidate, error) {
 	return s.store.FindByID(id)
 }
 
+// transition changes a candidate's status under the queue lock, so that two
+// reviewers cannot both change it, logs the change, and returns the updated
+// candidate. change checks the current status and applies the new one.
+func (s *CandidateService) transition(id string, action EventAction, by, note string, change func(*Candidate) error) (*Candidate, error) {
+	var updated Candidate
+	var from CandidateStatus
+	err := s.store.Modify(func(candidates []Candidate) ([]Candidate, error) {
+		for i := range candidates {
+			if candidates[i].ID != id {
+				continue
+			}
+			from = candidates[i].Status
+			if err := change(&candidates[i]); err != nil {
+				return nil, err
+			}
+			updated = candidates[i]
+			return candidates, nil
+		}
+		return nil, fmt.Errorf("candidate not found: %s", id)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	event := Event{CandidateID: id, Action: action, From: from, To: updated.Status, By: by, At: time.Now(), Note: note}
+	if err := s.store.AppendEvent(event); err != nil {
+		return &updated, fmt.Errorf("candidate %s updated but not logged: %w", id, err)
+	}
+	return &updated, nil
+}
+
+// review decides on a pending candidate.
+func (s *CandidateService) review(id string, action EventAction, by, note string, decide func(*Candidate)) (*Candidate, error) {
+	return s.transition(id, action, by, note, func(c *Candidate) error {
+		if c.Status != CandidateStatusPending {
+			return fmt.Errorf("candidate %s is not pending (status: %s)", id, c.Status)
+		}
+		decide(c)
+		return nil
+	})
+}
+
 // Approve approves a candidate and triggers appropriate actions.
 func (s *CandidateService) Approve(id string, reviewedBy string, notes string) error {
-	candidate, err := s.store.FindByID(id)
+	candidate, err := s.review(id, EventApprove, reviewedBy, notes, func(c *Candidate) {
+		now := time.Now()
+		c.Status = CandidateStatusApproved
+		c.ReviewedAt = &now
+		c.ReviewedBy = &reviewedBy
+		c.ReviewNotes = &notes
+	})
 	if err != nil {
 		return err
 	}
-	if candidate == nil {
-		return fmt.Errorf("candidate not found: %s", id)
+
+	// Approval succeeded even if an integration below fails. The candidate
+	// status is already saved, so a retry will find it already approved;
+	// report every failure so that none is fixed by hand without the others.
+	var errs []error
+
+	// Trigger bipartite add for papers and repos
+	if err := s.bipartite(*candidate); err != nil {
+		errs = append(errs, fmt.Errorf("candidate %s approved but bipartite integration failed: %w", id, err))
 	}
-	if candidate.Status != CandidateStatusPending {
-		return fmt.Errorf("candidate %s is not pending (status: %s)", id, candidate.Status)
+
+	// Add papers to the bibliography after bipartite, which supplies their metadata
+	if s.bib != nil && candidate.Type == CandidateTypePaper {
+		if err := s.syncBibliography([]Candidate{*candidate}); err != nil {
+			errs = append(errs, fmt.Errorf("candidate %s approved but bibliography update failed: %w", id, err))
+		}
 	}
 
-	now := time.Now()
-	candidate.Status = CandidateStatusApproved
-	candidate.ReviewedAt = &now
-	candidate.ReviewedBy = &reviewedBy
-	candidate.ReviewNotes = &notes
+	return errors.Join(errs...)
+}
 
-	// Update the store
-	if err := s.store.Update(*candidate); err != nil {
-		return err
+// SyncBibliography adds every approved paper missing from the bibliography
+// set with SetBibliography and records each paper's citation key. With
+// dryRun, neither the bibliography nor the queue is changed.
+func (s *CandidateService) SyncBibliography(dryRun bool) ([]BibSyncResult, error) {
+	if s.bib == nil {
+		return nil, fmt.Errorf("no bibliography configured")
+	}
+	candidates, err := s.store.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	results, err := s.bib.Sync(candidates, dryRun)
+	if err != nil || dryRun {
+		return results, err
 	}
+	return results, s.recordBibKeys(candidates, results)
+}
 
-	// Trigger bipartite add for papers and repos
-	if err := triggerBipartiteAdd(*candidate); err != nil {
-		// Approval succeeded but bipartite integration failed - propagate error
-		// The candidate status is already saved, so a retry will find it already approved
-		return fmt.Errorf("candidate %s approved but bipartite integration failed: %w", id, err)
+// syncBibliography adds candidates to the bibliography and records their keys.
+func (s *CandidateService) syncBibliography(candidates []Candidate) error {
+	results, err := s.bib.Sync(candidates, false)
+	if err != nil {
+		return err
 	}
+	return s.recordBibKeys(candidates, results)
+}
 
+// recordBibKeys stores each candidate's citation key in one locked rewrite,
+// if any of the candidates' keys changed.
+func (s *CandidateService) recordBibKeys(candidates []Candidate, results []BibSyncResult) error {
+	keys := make(map[string]string, len(results))
+	for _, r := range results {
+		keys[r.CandidateID] = r.Key
+	}
+	changed := false
+	for _, c := range candidates {
+		if key, ok := keys[c.ID]; ok && (c.BibKey == nil || *c.BibKey != key) {
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	err := s.store.Modify(func(candidates []Candidate) ([]Candidate, error) {
+		for i := range candidates {
+			if key, ok := keys[candidates[i].ID]; ok {
+				candidates[i].BibKey = &key
+			}
+		}
+		return candidates, nil
+	})
+	if err != nil {
+		return fmt.Errorf("record citation keys: %w", err)
+	}
 	return nil
 }
 
 // Reject rejects a candidate.
 func (s *CandidateService) Reject(id string, reviewedBy string, reason string) error {
-	candidate, err := s.store.FindByID(id)
+	candidate, err := s.review(id, EventReject, reviewedBy, reason, func(c *Candidate) {
+		now := time.Now()
+		c.Status = CandidateStatusRejected
+		c.ReviewedAt = &now
+		c.ReviewedBy = &reviewedBy
+		c.RejectionReason = &reason
+	})
 	if err != nil {
 		return err
 	}
-	if candidate == nil {
-		return fmt.Errorf("candidate not found: %s", id)
-	}
-	if candidate.Status != CandidateStatusPending {
-		return fmt.Errorf("candidate %s is not pending (status: %s)", id, candidate.Status)
-	}
 
-	now := time.Now()
-	candidate.Status = CandidateStatusRejected
-	candidate.ReviewedAt = &now
-	candidate.ReviewedBy = &reviewedBy
-	candidate.RejectionReason = &reason
+	// Also add to rejected.jsonl for re-discovery prevention (FR-012)
+	return s.store.AppendRejected(*candidate)
+}
 
-	// Update the store
-	if err := s.store.Update(*candidate); err != nil {
+// Reopen puts an approved or rejected candidate back up for review.
+func (s *CandidateService) Reopen(id, by, note string) error {
+	return s.restore(id, EventReopen, by, note)
+}
+
+// Revert undoes a mistaken approval or rejection. Like Reopen, it makes the
+// candidate pending again; the history records the decision as a mistake.
+func (s *CandidateService) Revert(id, by, note string) error {
+	return s.restore(id, EventRevert, by, note)
+}
+
+// restore makes a decided candidate pending, clearing its review, and
+// removes it from the rejected file so that it is no longer blocked.
+// Papers already added to bipartite or the bibliography stay there.
+func (s *CandidateService) restore(id string, action EventAction, by, note string) error {
+	_, err := s.transition(id, action, by, note, func(c *Candidate) error {
+		if c.Status == CandidateStatusPending {
+			return fmt.Errorf("candidate %s is already pending", id)
+		}
+		c.Status = CandidateStatusPending
+		c.ReviewedAt = nil
+		c.ReviewedBy = nil
+		c.ReviewNotes = nil
+		c.RejectionReason = nil
+		return nil
+	})
+	if err != nil {
 		return err
 	}
+	if _, err := s.store.RemoveRejected(id); err != nil {
+		return fmt.Errorf("candidate %s is pending but still in the rejected file: %w", id, err)
+	}
+	return nil
+}
 
-	// Also add to rejected.jsonl for re-discovery prevention (FR-012)
-	return s.store.AppendRejected(*candidate)
+// History returns a candidate's status changes, oldest first.
+func (s *CandidateService) History(id string) ([]Event, error) {
+	return s.store.ReadEvents(id)
 }
 
 // Stats returns queue statistics.
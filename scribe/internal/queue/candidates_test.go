@@ -1,19 +1,88 @@
 package queue
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"regexp"
 	"testing"
 	"time"
 )
 
 func TestGenerateID(t *testing.T) {
 	id := GenerateID()
-	if len(id) != 14 { // "c-" + 12 digits
-		t.Errorf("unexpected ID length: %d", len(id))
+	if !regexp.MustCompile(`^c-\d{14}-[0-9a-hjkmnp-tv-z]{6}$`).MatchString(id) {
+		t.Errorf("unexpected ID format: %s", id)
 	}
-	if id[0:2] != "c-" {
-		t.Errorf("ID should start with 'c-': %s", id)
+
+	// IDs generated in the same second differ
+	seen := make(map[string]bool)
+	for range 1000 {
+		id := GenerateID()
+		if seen[id] {
+			t.Fatalf("duplicate ID %s", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestCandidateService_Resolve(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	store := NewStore(filepath.Join(tmpDir, "queue.jsonl"), filepath.Join(tmpDir, "rejected.jsonl"))
+	for _, id := range []string{"c-202610151200", "c-20261016093000-ab12cd", "c-20261016093000-ab34ef", "c-20261017110000-zz99xx"} {
+		if err := store.Append(Candidate{ID: id, Type: CandidateTypeConcept, Status: CandidateStatusPending}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	svc := NewCandidateService(store)
+
+	tests := []struct {
+		id        string
+		want      string
+		ambiguous int // number of matches listed, if ambiguous
+	}{
+		{id: "c-202610151200", want: "c-202610151200"}, // old format, exact
+		{id: "c-20261017", want: "c-20261017110000-zz99xx"},
+		{id: "c-20261016093000-ab3", want: "c-20261016093000-ab34ef"},
+		{id: "c-20261016", ambiguous: 2},
+		{id: "c-2026101", ambiguous: 4},
+		{id: "c-2025"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			got, err := svc.Resolve(tt.id)
+			var ambiguous *AmbiguousIDError
+			switch {
+			case tt.ambiguous > 0:
+				if !errors.As(err, &ambiguous) || len(ambiguous.Matches) != tt.ambiguous {
+					t.Errorf("Resolve = %q, %v; want %d matches", got, err, tt.ambiguous)
+				}
+			case tt.want == "":
+				if err == nil {
+					t.Errorf("Resolve = %q, want an error", got)
+				}
+			case err != nil || got != tt.want:
+				t.Errorf("Resolve = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	// Generated IDs never collide with existing ones
+	seen := make(map[string]bool)
+	for range 20 {
+		result, err := svc.Add(Candidate{Type: CandidateTypeConcept})
+		if err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+		if seen[result.ID] {
+			t.Fatalf("duplicate ID %s", result.ID)
+		}
+		seen[result.ID] = true
 	}
 }
 
@@ -217,7 +286,7 @@ func TestCandidateService_AddAndList(t *testing.T) {
 		},
 	}
 
-	if err := svc.Add(c); err != nil {
+	if _, err := svc.Add(c); err != nil {
 		t.Fatalf("Add: %v", err)
 	}
 
@@ -250,6 +319,7 @@ func TestCandidateService_ApproveReject(t *testing.T) {
 
 	store := NewStore(filepath.Join(tmpDir, "queue.jsonl"), filepath.Join(tmpDir, "rejected.jsonl"))
 	svc := NewCandidateService(store)
+	svc.bipartite = func(Candidate) error { return nil } // bip may not be installed
 
 	// Add two candidates
 	c1 := Candidate{
@@ -342,7 +412,7 @@ func TestCandidateService_RejectPreventsReAdd(t *testing.T) {
 		},
 	}
 
-	err = svc.Add(c2)
+	_, err = svc.Add(c2)
 	if err == nil {
 		t.Error("expected error when adding previously rejected candidate")
 	}
@@ -0,0 +1,150 @@
+package queue
+
+import (
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/bibtex"
+)
+
+// minSHALen is the shortest commit SHA prefix compared with a full SHA.
+const minSHALen = 7
+
+// sameItem reports whether two candidates refer to the same paper, repo, or
+// code: papers with a common S2 ID or DOI, repos whose URLs differ only in
+// case, scheme, or a .git suffix, and code locations at the same file and
+// commit with overlapping line ranges.
+func sameItem(a, b Candidate) bool {
+	if a.Type != b.Type {
+		return false
+	}
+	switch a.Type {
+	case CandidateTypePaper:
+		if a.PaperData == nil || b.PaperData == nil {
+			return false
+		}
+		keys := paperKeys(a.PaperData)
+		for _, key := range paperKeys(b.PaperData) {
+			if slices.Contains(keys, key) {
+				return true
+			}
+		}
+	case CandidateTypeRepo:
+		if a.RepoData == nil || b.RepoData == nil {
+			return false
+		}
+		return a.RepoData.URL != "" && normalizeRepoURL(a.RepoData.URL) == normalizeRepoURL(b.RepoData.URL)
+	case CandidateTypeCodeLocation:
+		if a.CodeLocationData == nil || b.CodeLocationData == nil {
+			return false
+		}
+		return sameCode(a.CodeLocationData, b.CodeLocationData)
+	}
+	return false
+}
+
+// paperKeys returns a paper's normalized identifiers: "doi:" keys for its
+// DOI, including S2 IDs of the form DOI:10.x, and "s2:" keys for other S2 IDs.
+func paperKeys(p *PaperData) []string {
+	var keys []string
+	if p.DOI != "" {
+		keys = append(keys, "doi:"+bibtex.NormalizeDOI(p.DOI))
+	}
+	id := strings.ToLower(strings.TrimSpace(p.S2ID))
+	switch {
+	case id == "":
+	case strings.HasPrefix(id, "doi:") || strings.HasPrefix(id, "10.") || strings.Contains(id, "doi.org/"):
+		keys = append(keys, "doi:"+bibtex.NormalizeDOI(id))
+	default:
+		keys = append(keys, "s2:"+strings.TrimPrefix(id, "s2:"))
+	}
+	return keys
+}
+
+// normalizeRepoURL reduces a repository URL to host/owner/name in lower
+// case, so that https, ssh, and .git forms of the same URL compare equal.
+func normalizeRepoURL(url string) string {
+	s := strings.ToLower(strings.TrimSpace(url))
+	if rest, ok := strings.CutPrefix(s, "git@"); ok {
+		s = strings.Replace(rest, ":", "/", 1)
+	}
+	if _, rest, ok := strings.Cut(s, "://"); ok {
+		s = rest
+	}
+	s = strings.TrimPrefix(s, "git@")
+	s = strings.TrimPrefix(s, "www.")
+	s = strings.TrimSuffix(s, "/")
+	s = strings.TrimSuffix(s, ".git")
+	return strings.TrimSuffix(s, "/")
+}
+
+// sameCode reports whether two code locations overlap in the same file at
+// the same commit. A short SHA matches a full SHA it abbreviates.
+func sameCode(a, b *CodeLocationData) bool {
+	if a.RepoURL == "" || b.RepoURL == "" {
+		return a.PermalinkURL != "" && a.PermalinkURL == b.PermalinkURL
+	}
+	if normalizeRepoURL(a.RepoURL) != normalizeRepoURL(b.RepoURL) {
+		return false
+	}
+	if path.Clean("/"+a.FilePath) != path.Clean("/"+b.FilePath) {
+		return false
+	}
+	shaA, shaB := strings.ToLower(a.CommitSHA), strings.ToLower(b.CommitSHA)
+	if len(shaA) > len(shaB) {
+		shaA, shaB = shaB, shaA
+	}
+	if shaA != shaB && (len(shaA) < minSHALen || !strings.HasPrefix(shaB, shaA)) {
+		return false
+	}
+	return a.StartLine <= b.EndLine && b.StartLine <= a.EndLine
+}
+
+// findDuplicate returns the index of the pending or approved candidate that
+// refers to the same item as c, or -1.
+func findDuplicate(candidates []Candidate, c Candidate) int {
+	for i, existing := range candidates {
+		if existing.Status != CandidateStatusPending && existing.Status != CandidateStatusApproved {
+			continue
+		}
+		if sameItem(existing, c) {
+			return i
+		}
+	}
+	return -1
+}
+
+// merge records a rediscovery of existing as c: who found it and in what
+// context, and any details existing lacks.
+func merge(existing *Candidate, c Candidate) {
+	existing.Discoveries = append(existing.Discoveries, Discovery{
+		DiscoveredAt:     c.DiscoveredAt,
+		DiscoveredBy:     c.DiscoveredBy,
+		DiscoveryContext: c.DiscoveryContext,
+	})
+	fill := func(dst *string, src string) {
+		if *dst == "" {
+			*dst = src
+		}
+	}
+	switch {
+	case existing.PaperData != nil && c.PaperData != nil:
+		p, q := existing.PaperData, c.PaperData
+		fill(&p.DOI, q.DOI)
+		fill(&p.Title, q.Title)
+		fill(&p.RelevanceNotes, q.RelevanceNotes)
+		if len(p.Authors) == 0 {
+			p.Authors = q.Authors
+		}
+		if p.Year == 0 {
+			p.Year = q.Year
+		}
+	case existing.RepoData != nil && c.RepoData != nil:
+		fill(&existing.RepoData.Name, c.RepoData.Name)
+		fill(&existing.RepoData.Description, c.RepoData.Description)
+		fill(&existing.RepoData.RelevanceNotes, c.RepoData.RelevanceNotes)
+	case existing.CodeLocationData != nil && c.CodeLocationData != nil:
+		fill(&existing.CodeLocationData.Description, c.CodeLocationData.Description)
+	}
+}
@@ -0,0 +1,117 @@
+package queue
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSameItem(t *testing.T) {
+	paper := func(s2ID, doi string) Candidate {
+		return Candidate{Type: CandidateTypePaper, PaperData: &PaperData{S2ID: s2ID, DOI: doi}}
+	}
+	repo := func(url string) Candidate {
+		return Candidate{Type: CandidateTypeRepo, RepoData: &RepoData{URL: url}}
+	}
+	code := func(repo, file, sha string, start, end int) Candidate {
+		return Candidate{Type: CandidateTypeCodeLocation, CodeLocationData: &CodeLocationData{
+			RepoURL: repo, FilePath: file, CommitSHA: sha, StartLine: start, EndLine: end,
+		}}
+	}
+	const sha = "3f2a9c1d8e7b6a5f4c3d2e1f0a9b8c7d6e5f4a3b"
+
+	tests := []struct {
+		name string
+		a, b Candidate
+		want bool
+	}{
+		{"same S2 ID", paper("S2:abc123", ""), paper("abc123", ""), true},
+		{"different S2 IDs", paper("abc123", ""), paper("def456", ""), false},
+		{"S2 DOI form and DOI", paper("DOI:10.1093/SysBio/syy032", ""), paper("abc123", "https://doi.org/10.1093/sysbio/syy032"), true},
+		{"DOIs differ", paper("abc123", "10.1/a"), paper("def456", "10.1/b"), false},
+		{"repo URL forms", repo("https://github.com/Matsen/PhyloTools.git"), repo("git@github.com:matsen/phylotools"), true},
+		{"repo with www and slash", repo("http://www.github.com/matsen/phylotools/"), repo("https://github.com/matsen/phylotools"), true},
+		{"different repos", repo("https://github.com/matsen/phylotools"), repo("https://github.com/matsen/phylotools2"), false},
+		{"paper and repo", paper("abc123", ""), repo("abc123"), false},
+		{"overlapping lines", code("https://github.com/a/b", "src/tree.go", sha, 10, 40), code("https://github.com/A/B.git", "./src/tree.go", sha[:8], 35, 60), true},
+		{"adjacent lines", code("https://github.com/a/b", "src/tree.go", sha, 10, 40), code("https://github.com/a/b", "src/tree.go", sha, 41, 60), false},
+		{"other commit", code("https://github.com/a/b", "src/tree.go", sha, 10, 40), code("https://github.com/a/b", "src/tree.go", "9d8c7b6", 10, 40), false},
+		{"other file", code("https://github.com/a/b", "src/tree.go", sha, 10, 40), code("https://github.com/a/b", "src/node.go", sha, 10, 40), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameItem(tt.a, tt.b); got != tt.want {
+				t.Errorf("sameItem = %v, want %v", got, tt.want)
+			}
+			if got := sameItem(tt.b, tt.a); got != tt.want {
+				t.Errorf("sameItem reversed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCandidateService_AddMergesDuplicates(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	store := NewStore(filepath.Join(tmpDir, "queue.jsonl"), filepath.Join(tmpDir, "rejected.jsonl"))
+	svc := NewCandidateService(store)
+	svc.bipartite = func(Candidate) error { return nil }
+
+	first, err := svc.Add(Candidate{
+		Type:             CandidateTypePaper,
+		DiscoveredBy:     "exploration",
+		DiscoveryContext: "cited by the RAxML paper",
+		PaperData:        &PaperData{S2ID: "DOI:10.1093/sysbio/syy032"},
+	})
+	if err != nil || first.Merged {
+		t.Fatalf("first Add = %+v, %v", first, err)
+	}
+
+	// The same paper by S2 ID with a DOI, from another agent
+	second, err := svc.Add(Candidate{
+		Type:             CandidateTypePaper,
+		DiscoveredAt:     time.Now(),
+		DiscoveredBy:     "survey",
+		DiscoveryContext: "search for tree rearrangements",
+		PaperData:        &PaperData{S2ID: "abc123", DOI: "10.1093/SYSBIO/SYY032", Title: "Tree rearrangements"},
+	})
+	if err != nil {
+		t.Fatalf("second Add: %v", err)
+	}
+	if !second.Merged || second.ID != first.ID {
+		t.Errorf("second Add = %+v, want merged into %s", second, first.ID)
+	}
+
+	candidates, err := store.ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if len(candidates) != 1 {
+		t.Fatalf("got %d candidates, want 1", len(candidates))
+	}
+	c := candidates[0]
+	if len(c.Discoveries) != 1 || c.Discoveries[0].DiscoveredBy != "survey" || !strings.Contains(c.Discoveries[0].DiscoveryContext, "rearrangements") {
+		t.Errorf("discoveries not merged: %+v", c.Discoveries)
+	}
+	if c.DiscoveredBy != "exploration" || c.PaperData.Title != "Tree rearrangements" || c.PaperData.S2ID != "DOI:10.1093/sysbio/syy032" {
+		t.Errorf("unexpected merged candidate %+v, paper %+v", c, c.PaperData)
+	}
+
+	// A repo rejected under one spelling of its URL stays rejected under another
+	repo, err := svc.Add(Candidate{Type: CandidateTypeRepo, RepoData: &RepoData{URL: "https://github.com/matsen/phylotools"}})
+	if err != nil {
+		t.Fatalf("Add repo: %v", err)
+	}
+	if err := svc.Reject(repo.ID, "human", "out of scope"); err != nil {
+		t.Fatalf("Reject: %v", err)
+	}
+	if _, err := svc.Add(Candidate{Type: CandidateTypeRepo, RepoData: &RepoData{URL: "git@github.com:Matsen/phylotools.git"}}); err == nil || !strings.Contains(err.Error(), "previously rejected") {
+		t.Errorf("expected rejection, got %v", err)
+	}
+}
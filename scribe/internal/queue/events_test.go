@@ -0,0 +1,96 @@
+package queue
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCandidateService_ReopenAndRevert(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	store := NewStore(filepath.Join(tmpDir, "queue.jsonl"), filepath.Join(tmpDir, "rejected.jsonl"))
+	svc := NewCandidateService(store)
+	svc.bipartite = func(Candidate) error { return nil }
+
+	repo := func(url string) Candidate {
+		return Candidate{Type: CandidateTypeRepo, DiscoveredBy: "survey", RepoData: &RepoData{URL: url}}
+	}
+	added, err := svc.Add(repo("https://github.com/matsen/phylotools"))
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	id := added.ID
+
+	if err := svc.Reopen(id, "human", ""); err == nil || !strings.Contains(err.Error(), "already pending") {
+		t.Errorf("Reopen of pending candidate: got %v", err)
+	}
+
+	// Reverting a rejection unblocks the candidate
+	if err := svc.Reject(id, "human", "out of scope"); err != nil {
+		t.Fatalf("Reject: %v", err)
+	}
+	if err := svc.Revert(id, "human", "wrong candidate"); err != nil {
+		t.Fatalf("Revert: %v", err)
+	}
+	rejected, err := store.ReadRejected()
+	if err != nil {
+		t.Fatalf("ReadRejected: %v", err)
+	}
+	if len(rejected) != 0 {
+		t.Errorf("rejected file still has %d entries", len(rejected))
+	}
+	c, err := svc.Get(id)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if c.Status != CandidateStatusPending || c.ReviewedAt != nil || c.ReviewedBy != nil || c.RejectionReason != nil {
+		t.Errorf("review not cleared: %+v", c)
+	}
+	if merged, err := svc.Add(repo("git@github.com:matsen/phylotools.git")); err != nil || !merged.Merged {
+		t.Errorf("rediscovery after revert = %+v, %v; want merged", merged, err)
+	}
+
+	// Reopening an approval
+	if err := svc.Approve(id, "human", "core tool"); err != nil {
+		t.Fatalf("Approve: %v", err)
+	}
+	if err := svc.Reopen(id, "editor", "check the license"); err != nil {
+		t.Fatalf("Reopen: %v", err)
+	}
+	if err := svc.Revert("c-missing", "human", ""); err == nil {
+		t.Error("Revert of missing candidate should fail")
+	}
+
+	history, err := svc.History(id)
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	want := []Event{
+		{Action: EventAdd, To: CandidateStatusPending, By: "survey"},
+		{Action: EventReject, From: CandidateStatusPending, To: CandidateStatusRejected, By: "human", Note: "out of scope"},
+		{Action: EventRevert, From: CandidateStatusRejected, To: CandidateStatusPending, By: "human", Note: "wrong candidate"},
+		{Action: EventApprove, From: CandidateStatusPending, To: CandidateStatusApproved, By: "human", Note: "core tool"},
+		{Action: EventReopen, From: CandidateStatusApproved, To: CandidateStatusPending, By: "editor", Note: "check the license"},
+	}
+	if len(history) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(history), len(want), history)
+	}
+	for i, e := range history {
+		if e.CandidateID != id || e.At.IsZero() {
+			t.Errorf("event %d: candidate %q at %v", i, e.CandidateID, e.At)
+		}
+		e.CandidateID, e.At = "", want[i].At
+		if e != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, e, want[i])
+		}
+		if i > 0 && history[i].At.Before(history[i-1].At) {
+			t.Errorf("event %d is out of order", i)
+		}
+	}
+}
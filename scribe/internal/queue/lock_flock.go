@@ -0,0 +1,41 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package queue
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// LockFile takes an advisory flock on path+".lock", waiting for other
+// holders. The lock is on a separate file because rewrites replace the
+// data file. Locks conflict between processes and between goroutines, as
+// each call opens the lock file anew.
+func LockFile(path string, exclusive bool) (unlock func(), err error) {
+	if err := ensureDir(path); err != nil {
+		return nil, fmt.Errorf("create directory for %s: %w", path, err)
+	}
+	file, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open lock for %s: %w", path, err)
+	}
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	for {
+		err = syscall.Flock(int(file.Fd()), how)
+		if err != syscall.EINTR {
+			break
+		}
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("lock %s: %w", path, err)
+	}
+	return func() {
+		syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		file.Close()
+	}, nil
+}
@@ -0,0 +1,21 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package queue
+
+import "sync"
+
+// fileLocks serializes access within the process where flock is unavailable.
+var fileLocks sync.Map // path -> *sync.RWMutex
+
+// LockFile locks path within this process only; other processes are not
+// excluded on this platform.
+func LockFile(path string, exclusive bool) (unlock func(), err error) {
+	value, _ := fileLocks.LoadOrStore(path, &sync.RWMutex{})
+	mu := value.(*sync.RWMutex)
+	if exclusive {
+		mu.Lock()
+		return mu.Unlock, nil
+	}
+	mu.RLock()
+	return mu.RUnlock, nil
+}
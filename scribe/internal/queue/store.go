@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 )
@@ -15,10 +16,18 @@ const DefaultQueuePath = ".candidates/queue.jsonl"
 // DefaultRejectedPath is the default path for rejected candidates.
 const DefaultRejectedPath = ".candidates/rejected.jsonl"
 
-// Store provides JSONL storage operations for candidates.
+// DefaultEventsPath is the default path for the log of status changes.
+// Unless set with SetEventsPath, the log is kept next to the queue file.
+const DefaultEventsPath = ".candidates/events.jsonl"
+
+// Store provides JSONL storage operations for candidates. It is safe for
+// concurrent use by goroutines and processes: reads, appends, and
+// read-modify-write updates hold an advisory lock on the file, and rewrites
+// replace it atomically, so a crash never leaves a half-written queue.
 type Store struct {
 	queuePath    string
 	rejectedPath string
+	eventsPath   string
 }
 
 // NewStore creates a new Store with the given paths.
@@ -33,6 +42,14 @@ func NewStore(queuePath, rejectedPath string) *Store {
 	return &Store{
 		queuePath:    queuePath,
 		rejectedPath: rejectedPath,
+		eventsPath:   filepath.Join(filepath.Dir(queuePath), filepath.Base(DefaultEventsPath)),
+	}
+}
+
+// SetEventsPath moves the event log from its default place next to the queue.
+func (s *Store) SetEventsPath(path string) {
+	if path != "" {
+		s.eventsPath = path
 	}
 }
 
@@ -44,26 +61,97 @@ func ensureDir(path string) error {
 
 // ReadAll reads all candidates from the queue file.
 func (s *Store) ReadAll() ([]Candidate, error) {
-	return readJSONL[Candidate](s.queuePath)
+	return lockedRead[Candidate](s.queuePath)
 }
 
 // ReadRejected reads all rejected candidates.
 func (s *Store) ReadRejected() ([]Candidate, error) {
-	return readJSONL[Candidate](s.rejectedPath)
+	return lockedRead[Candidate](s.rejectedPath)
 }
 
 // Append appends a candidate to the queue file.
 func (s *Store) Append(c Candidate) error {
-	return appendJSONL(s.queuePath, c)
+	return lockedAppend(s.queuePath, c)
 }
 
 // AppendRejected appends a candidate to the rejected file.
 func (s *Store) AppendRejected(c Candidate) error {
-	return appendJSONL(s.rejectedPath, c)
+	return lockedAppend(s.rejectedPath, c)
+}
+
+// RemoveRejected removes a candidate's entries from the rejected file, so
+// that it can be queued again. It reports whether there were any.
+func (s *Store) RemoveRejected(id string) (bool, error) {
+	unlock, err := LockFile(s.rejectedPath, true)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	rejected, err := readJSONL[Candidate](s.rejectedPath)
+	if err != nil {
+		return false, err
+	}
+	kept := make([]Candidate, 0, len(rejected))
+	for _, c := range rejected {
+		if c.ID != id {
+			kept = append(kept, c)
+		}
+	}
+	if len(kept) == len(rejected) {
+		return false, nil
+	}
+	return true, writeJSONL(s.rejectedPath, kept)
+}
+
+// AppendEvent appends a status change to the event log.
+func (s *Store) AppendEvent(e Event) error {
+	return lockedAppend(s.eventsPath, e)
+}
+
+// ReadEvents returns the status changes of a candidate, oldest first.
+func (s *Store) ReadEvents(id string) ([]Event, error) {
+	events, err := lockedRead[Event](s.eventsPath)
+	if err != nil {
+		return nil, err
+	}
+	var history []Event
+	for _, e := range events {
+		if e.CandidateID == id {
+			history = append(history, e)
+		}
+	}
+	return history, nil
 }
 
 // WriteAll writes all candidates to the queue file, overwriting existing content.
 func (s *Store) WriteAll(candidates []Candidate) error {
+	unlock, err := LockFile(s.queuePath, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return writeJSONL(s.queuePath, candidates)
+}
+
+// Modify reads the queue, passes the candidates to fn, and writes back the
+// slice fn returns, holding the queue lock throughout so that no other
+// writer interleaves. If fn fails, the queue is left unchanged.
+func (s *Store) Modify(fn func([]Candidate) ([]Candidate, error)) error {
+	unlock, err := LockFile(s.queuePath, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	candidates, err := readJSONL[Candidate](s.queuePath)
+	if err != nil {
+		return err
+	}
+	candidates, err = fn(candidates)
+	if err != nil {
+		return err
+	}
 	return writeJSONL(s.queuePath, candidates)
 }
 
@@ -85,26 +173,16 @@ func (s *Store) FindByID(id string) (*Candidate, error) {
 // This reads all candidates, replaces the matching one, and rewrites the file.
 // JSONL format requires full rewrite for updates - this is expected behavior.
 func (s *Store) Update(candidate Candidate) error {
-	candidates, err := s.ReadAll()
-	if err != nil {
-		return err
-	}
-
-	// Find and replace the candidate by ID
-	index := -1
-	for i, existing := range candidates {
-		if existing.ID == candidate.ID {
-			index = i
-			break
+	return s.Modify(func(candidates []Candidate) ([]Candidate, error) {
+		// Find and replace the candidate by ID
+		for i, existing := range candidates {
+			if existing.ID == candidate.ID {
+				candidates[i] = candidate
+				return candidates, nil
+			}
 		}
-	}
-
-	if index == -1 {
-		return fmt.Errorf("candidate not found: %s", candidate.ID)
-	}
-
-	candidates[index] = candidate
-	return s.WriteAll(candidates)
+		return nil, fmt.Errorf("candidate not found: %s", candidate.ID)
+	})
 }
 
 // IsRejected checks if a candidate with the given external ID has been previously rejected.
@@ -171,6 +249,28 @@ func (s *Store) GetStats() (*QueueStats, error) {
 	return stats, nil
 }
 
+// lockedRead reads a JSONL file under a shared lock, so that it never sees
+// a partly appended line.
+func lockedRead[T any](path string) ([]T, error) {
+	unlock, err := LockFile(path, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return readJSONL[T](path)
+}
+
+// lockedAppend appends an item to a JSONL file under an exclusive lock, so
+// that it cannot land in a file that is being replaced.
+func lockedAppend[T any](path string, item T) error {
+	unlock, err := LockFile(path, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return appendJSONL(path, item)
+}
+
 // readJSONL reads a JSONL file and returns a slice of items.
 func readJSONL[T any](path string) ([]T, error) {
 	file, err := os.Open(path)
@@ -225,31 +325,73 @@ func appendJSONL[T any](path string, item T) error {
 	if _, err := file.Write(append(data, '\n')); err != nil {
 		return fmt.Errorf("write to %s: %w", path, err)
 	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("sync %s: %w", path, err)
+	}
 
 	return nil
 }
 
-// writeJSONL writes a slice of items to a JSONL file, overwriting existing content.
+// writeJSONL replaces a JSONL file with items, atomically.
 func writeJSONL[T any](path string, items []T) error {
+	return WriteFileAtomic(path, func(w io.Writer) error {
+		bw := bufio.NewWriter(w)
+		for i, item := range items {
+			data, err := json.Marshal(item)
+			if err != nil {
+				return fmt.Errorf("marshal item %d: %w", i, err)
+			}
+			bw.Write(append(data, '\n'))
+		}
+		return bw.Flush()
+	})
+}
+
+// WriteFileAtomic replaces a file with what write produces. The content is
+// written to a temporary file in the same directory, synced, and renamed
+// over path, so readers see either the old or the new contents.
+func WriteFileAtomic(path string, write func(io.Writer) error) error {
 	if err := ensureDir(path); err != nil {
 		return fmt.Errorf("create directory for %s: %w", path, err)
 	}
 
-	file, err := os.Create(path)
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
 	if err != nil {
-		return fmt.Errorf("create %s: %w", path, err)
+		return fmt.Errorf("create temporary file for %s: %w", path, err)
 	}
-	defer file.Close()
+	tmpPath := file.Name()
+	defer os.Remove(tmpPath) // no-op after a successful rename
 
-	for i, item := range items {
-		data, err := json.Marshal(item)
-		if err != nil {
-			return fmt.Errorf("marshal item %d: %w", i, err)
-		}
-		if _, err := file.Write(append(data, '\n')); err != nil {
-			return fmt.Errorf("write item %d to %s: %w", i, path, err)
-		}
+	if err := write(file); err != nil {
+		file.Close()
+		return fmt.Errorf("write %s: %w", tmpPath, err)
+	}
+	if err := file.Chmod(0644); err != nil {
+		file.Close()
+		return fmt.Errorf("chmod %s: %w", tmpPath, err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("sync %s: %w", tmpPath, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("close %s: %w", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("replace %s: %w", path, err)
 	}
+	return syncDir(filepath.Dir(path))
+}
 
+// syncDir flushes a directory entry change, such as a rename, to disk.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", dir, err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil && !errors.Is(err, os.ErrInvalid) {
+		return fmt.Errorf("sync %s: %w", dir, err)
+	}
 	return nil
 }
@@ -0,0 +1,124 @@
+package queue
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// stressEnv names the queue directory for helper processes of TestStore_Concurrent.
+const stressEnv = "SCRIBE_QUEUE_STRESS_DIR"
+
+// stressAdds is how many candidates each goroutine and process adds.
+const stressAdds = 20
+
+// addAndReview adds candidates with IDs prefix-0.. and approves or rejects
+// each one after adding it.
+func addAndReview(dir, prefix string) error {
+	svc := NewCandidateService(NewStore(filepath.Join(dir, "queue.jsonl"), filepath.Join(dir, "rejected.jsonl")))
+	svc.bipartite = func(Candidate) error { return nil }
+	for i := range stressAdds {
+		id := fmt.Sprintf("%s-%d", prefix, i)
+		c := Candidate{
+			ID:        id,
+			Type:      CandidateTypePaper,
+			PaperData: &PaperData{S2ID: "S2:" + id, Title: "Paper " + id},
+		}
+		if _, err := svc.Add(c); err != nil {
+			return fmt.Errorf("add %s: %w", id, err)
+		}
+		review := svc.Approve
+		if i%2 == 1 {
+			review = svc.Reject
+		}
+		if err := review(id, prefix, "stress"); err != nil {
+			return fmt.Errorf("review %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// TestStoreStressHelper runs in a child process of TestStore_Concurrent.
+func TestStoreStressHelper(t *testing.T) {
+	dir := os.Getenv(stressEnv)
+	if dir == "" {
+		t.Skip("helper process for TestStore_Concurrent")
+	}
+	if err := addAndReview(dir, "p"+os.Getenv(stressEnv+"_ID")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStore_Concurrent(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	const goroutines, processes = 8, 4
+	errs := make(chan error, goroutines+processes)
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- addAndReview(tmpDir, "g"+strconv.Itoa(g))
+		}()
+	}
+	for p := range processes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cmd := exec.Command(os.Args[0], "-test.run=^TestStoreStressHelper$")
+			cmd.Env = append(os.Environ(), stressEnv+"="+tmpDir, stressEnv+"_ID="+strconv.Itoa(p))
+			if out, err := cmd.CombinedOutput(); err != nil {
+				errs <- fmt.Errorf("helper process %d: %v\n%s", p, err, out)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	store := NewStore(filepath.Join(tmpDir, "queue.jsonl"), filepath.Join(tmpDir, "rejected.jsonl"))
+	candidates, err := store.ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	want := (goroutines + processes) * stressAdds
+	seen := make(map[string]bool)
+	for _, c := range candidates {
+		if seen[c.ID] {
+			t.Errorf("duplicate candidate %s", c.ID)
+		}
+		seen[c.ID] = true
+		if c.Status == CandidateStatusPending {
+			t.Errorf("review of %s was lost", c.ID)
+		}
+	}
+	if len(seen) != want {
+		t.Errorf("got %d candidates, want %d", len(seen), want)
+	}
+	rejected, err := store.ReadRejected()
+	if err != nil {
+		t.Fatalf("ReadRejected: %v", err)
+	}
+	if len(rejected) != want/2 {
+		t.Errorf("got %d rejected, want %d", len(rejected), want/2)
+	}
+
+	// Rewrites leave no temporary files behind
+	leftovers, _ := filepath.Glob(filepath.Join(tmpDir, "*.tmp-*"))
+	if len(leftovers) > 0 {
+		t.Errorf("temporary files left: %v", leftovers)
+	}
+}
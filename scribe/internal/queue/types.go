@@ -30,6 +30,7 @@ type Candidate struct {
 	DiscoveredAt     time.Time       `json:"discovered_at"`
 	DiscoveredBy     string          `json:"discovered_by"`
 	DiscoveryContext string          `json:"discovery_context"`
+	Discoveries      []Discovery     `json:"discoveries,omitempty"` // later discoveries merged into this candidate
 
 	// Type-specific data (one of these based on type)
 	PaperData        *PaperData        `json:"paper_data,omitempty"`
@@ -42,11 +43,22 @@ type Candidate struct {
 	ReviewedBy      *string    `json:"reviewed_by,omitempty"`
 	ReviewNotes     *string    `json:"review_notes,omitempty"`
 	RejectionReason *string    `json:"rejection_reason,omitempty"`
+
+	// Citation key of the paper's bibliography entry (set by bibliography sync)
+	BibKey *string `json:"bib_key,omitempty"`
+}
+
+// Discovery records who else found a candidate, and in what context.
+type Discovery struct {
+	DiscoveredAt     time.Time `json:"discovered_at"`
+	DiscoveredBy     string    `json:"discovered_by"`
+	DiscoveryContext string    `json:"discovery_context"`
 }
 
 // PaperData contains paper-specific candidate data.
 type PaperData struct {
-	S2ID           string   `json:"s2_id"`
+	S2ID           string   `json:"s2_id"` // S2 paper ID, or DOI:10.x
+	DOI            string   `json:"doi,omitempty"`
 	Title          string   `json:"title"`
 	Authors        []string `json:"authors"`
 	Year           int      `json:"year"`
@@ -82,6 +94,29 @@ type CodeLocationData struct {
 	SurroundingContext string  `json:"surrounding_context"` // ~10 lines around the location
 }
 
+// EventAction names a change to a candidate's status.
+type EventAction string
+
+const (
+	EventAdd     EventAction = "add"
+	EventApprove EventAction = "approve"
+	EventReject  EventAction = "reject"
+	EventReopen  EventAction = "reopen" // a decided candidate is put back up for review
+	EventRevert  EventAction = "revert" // a mistaken decision is undone
+)
+
+// Event records a change to a candidate's status. Events are only ever
+// appended to the event log, so they form the candidate's history.
+type Event struct {
+	CandidateID string          `json:"candidate_id"`
+	Action      EventAction     `json:"action"`
+	From        CandidateStatus `json:"from,omitempty"` // empty for add
+	To          CandidateStatus `json:"to"`
+	By          string          `json:"by"`
+	At          time.Time       `json:"at"`
+	Note        string          `json:"note,omitempty"`
+}
+
 // QueueStats contains statistics about the candidate queue.
 type QueueStats struct {
 	Total    int `json:"total"`
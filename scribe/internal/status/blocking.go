@@ -64,11 +64,15 @@ func (d *BlockingDetector) ShouldQueueForReview() (bool, string) {
 	}
 
 	// Check cost budget
-	if d.checkpoint.CostBudgetUSD != nil {
-		if d.checkpoint.Metrics.EstimatedCostUSD >= *d.checkpoint.CostBudgetUSD {
-			return true, "Reached cost budget"
-		}
+	if d.OverBudget() {
+		return true, "Reached cost budget"
 	}
 
 	return false, ""
 }
+
+// OverBudget returns true if the task has spent its cost budget.
+func (d *BlockingDetector) OverBudget() bool {
+	budget := d.checkpoint.CostBudgetUSD
+	return budget != nil && d.checkpoint.Metrics.EstimatedCostUSD >= *budget
+}
@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/llm"
 )
 
 // DefaultCheckpointPath is the default path for task checkpoints.
@@ -60,30 +62,33 @@ func (s *CheckpointStore) Write(checkpoint *TaskCheckpoint) error {
 
 	// Update last checkpoint time
 	checkpoint.LastCheckpoint = time.Now()
-
-	// Ensure directory exists
-	dir := filepath.Dir(s.path)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("create checkpoint directory: %w", err)
-	}
-
-	data, err := json.MarshalIndent(checkpoint, "", "  ")
-	if err != nil {
-		return fmt.Errorf("marshal checkpoint: %w", err)
-	}
-
-	if err := os.WriteFile(s.path, data, 0644); err != nil {
-		return fmt.Errorf("write checkpoint: %w", err)
-	}
-
-	return nil
+	return s.save(checkpoint)
 }
 
 // WriteForced writes a checkpoint without checking the interval.
 // Use this only for initial checkpoint creation.
 func (s *CheckpointStore) WriteForced(checkpoint *TaskCheckpoint) error {
 	checkpoint.LastCheckpoint = time.Now()
+	return s.save(checkpoint)
+}
+
+// RecordLLMUsage adds LLM usage to the metrics of the active task. It is
+// not a checkpoint, so the interval is not enforced and LastCheckpoint is
+// kept. It returns the updated checkpoint, or nil if no task is active.
+func (s *CheckpointStore) RecordLLMUsage(usage llm.Usage) (*TaskCheckpoint, error) {
+	checkpoint, err := s.Read()
+	if err != nil || checkpoint == nil {
+		return nil, err
+	}
+	checkpoint.Metrics.AddLLMUsage(usage)
+	if err := s.save(checkpoint); err != nil {
+		return nil, err
+	}
+	return checkpoint, nil
+}
 
+// save writes a checkpoint to disk.
+func (s *CheckpointStore) save(checkpoint *TaskCheckpoint) error {
 	dir := filepath.Dir(s.path)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return fmt.Errorf("create checkpoint directory: %w", err)
@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"testing"
 	"time"
+
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/llm"
 )
 
 func TestCheckpointStore_ReadWrite(t *testing.T) {
@@ -251,3 +253,47 @@ func TestNewTaskCheckpoint(t *testing.T) {
 		t.Errorf("unexpected iteration count: %d", cp.IterationCount)
 	}
 }
+
+func TestCheckpointStore_RecordLLMUsage(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	store := NewCheckpointStore(filepath.Join(tmpDir, "checkpoint.json"))
+
+	// Without an active task, usage is not recorded
+	usage := llm.Usage{Requests: 3, InputTokens: 1200, OutputTokens: 300, CostUSD: 0.6}
+	if cp, err := store.RecordLLMUsage(usage); err != nil || cp != nil {
+		t.Fatalf("RecordLLMUsage without a task = %v, %v", cp, err)
+	}
+
+	budget := 1.0
+	if err := store.WriteForced(NewTaskCheckpoint(TaskTypeSurvey, "Survey", "PROMPT.md", 50, &budget)); err != nil {
+		t.Fatalf("WriteForced: %v", err)
+	}
+
+	// Usage accumulates without tripping the checkpoint interval
+	for _, wantOver := range []bool{false, true} {
+		cp, err := store.RecordLLMUsage(usage)
+		if err != nil {
+			t.Fatalf("RecordLLMUsage: %v", err)
+		}
+		if over := NewBlockingDetector(cp).OverBudget(); over != wantOver {
+			t.Errorf("OverBudget at %.2f = %v, want %v", cp.Metrics.EstimatedCostUSD, over, wantOver)
+		}
+	}
+
+	read, err := store.Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	m := read.Metrics
+	if m.LLMRequests != 6 || m.InputTokens != 2400 || m.OutputTokens != 600 || m.EstimatedCostUSD < 1.19 {
+		t.Errorf("unexpected metrics %+v", m)
+	}
+	if queue, reason := NewBlockingDetector(read).ShouldQueueForReview(); !queue || reason != "Reached cost budget" {
+		t.Errorf("ShouldQueueForReview = %v, %q", queue, reason)
+	}
+}
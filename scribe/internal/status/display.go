@@ -67,6 +67,11 @@ func (d *Display) ShowCheckpoint(checkpoint *TaskCheckpoint) {
 		checkpoint.Metrics.PapersFound,
 		checkpoint.Metrics.CodeLocationsFound)
 	d.formatter.Println("Repos searched: %d", checkpoint.Metrics.ReposSearched)
+	if checkpoint.Metrics.LLMRequests > 0 {
+		d.formatter.Println("LLM usage: %s, %d input / %d output tokens",
+			output.FormatCount(checkpoint.Metrics.LLMRequests, "request", "requests"),
+			checkpoint.Metrics.InputTokens, checkpoint.Metrics.OutputTokens)
+	}
 	d.formatter.Println("Estimated cost: %s", output.FormatCurrency(checkpoint.Metrics.EstimatedCostUSD))
 
 	if checkpoint.CostBudgetUSD != nil {
@@ -1,7 +1,11 @@
 // Package status implements task checkpoint and status display for the scribe CLI.
 package status
 
-import "time"
+import (
+	"time"
+
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/llm"
+)
 
 // TaskType represents the type of autonomous task.
 type TaskType string
@@ -34,6 +38,17 @@ type TaskMetrics struct {
 	CodeLocationsFound int     `json:"code_locations_found"`
 	ReposSearched      int     `json:"repos_searched"`
 	EstimatedCostUSD   float64 `json:"estimated_cost_usd"`
+	LLMRequests        int     `json:"llm_requests"`
+	InputTokens        int     `json:"input_tokens"`
+	OutputTokens       int     `json:"output_tokens"`
+}
+
+// AddLLMUsage adds the requests, tokens, and cost of LLM calls.
+func (m *TaskMetrics) AddLLMUsage(usage llm.Usage) {
+	m.LLMRequests += usage.Requests
+	m.InputTokens += usage.InputTokens
+	m.OutputTokens += usage.OutputTokens
+	m.EstimatedCostUSD += usage.CostUSD
 }
 
 // TaskCheckpoint represents the progress state for autonomous operation.
@@ -4,24 +4,29 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/qmd"
 	"github.com/matsen/phylogenetic-compendium/scribe/internal/verify"
 )
 
 // CheckCodeLinks verifies that code location links are still valid (FR-035).
-// Checks that files exist and line ranges are valid at HEAD.
-func CheckCodeLinks(content string, file string) []SweepResult {
+// Checks that the pinned commit and file exist and that line ranges are valid.
+func CheckCodeLinks(mirror *verify.RepoMirror, doc *qmd.Document) []SweepResult {
 	var results []SweepResult
 
-	links := verify.ExtractCodeLinks(content)
-	for _, link := range links {
-		result := checkSingleCodeLink(link, file)
+	for _, docLink := range doc.Links {
+		link, ok := verify.ParseCodeLink(docLink.URL)
+		if !ok {
+			continue
+		}
+		result := checkSingleCodeLink(mirror, link, doc.Path)
+		result.Line = docLink.Span.Start.Line
 		results = append(results, result)
 	}
 
 	return results
 }
 
-func checkSingleCodeLink(link verify.CodeLinkMatch, file string) SweepResult {
+func checkSingleCodeLink(mirror *verify.RepoMirror, link verify.CodeLinkMatch, file string) SweepResult {
 	result := SweepResult{
 		CheckType: CheckTypeCodeLinks,
 		Target:    link.FullURL,
@@ -38,7 +43,11 @@ func checkSingleCodeLink(link verify.CodeLinkMatch, file string) SweepResult {
 	}
 
 	// Use the verify package's code link checker
-	verifyResult := verify.VerifyCodeLink(link, file, 0, "")
+	verifyResult := verify.VerifyCodeLink(mirror, link, file, 0, "")
+	if cl := verifyResult.Details.CodeLink; cl != nil {
+		result.Details["commit_exists"] = cl.CommitExists
+		result.Details["line_count"] = cl.LineCount
+	}
 
 	switch verifyResult.Status {
 	case verify.CheckStatusPass:
@@ -58,15 +67,19 @@ func checkSingleCodeLink(link verify.CodeLinkMatch, file string) SweepResult {
 
 // CheckCodeLinksAtHead verifies code links against the HEAD of the repository.
 // This catches cases where files have been moved or deleted since the permalink was created.
-func CheckCodeLinksAtHead(content string, file string) []SweepResult {
+func CheckCodeLinksAtHead(doc *qmd.Document) []SweepResult {
 	var results []SweepResult
 
-	links := verify.ExtractCodeLinks(content)
-	for _, link := range links {
+	for _, docLink := range doc.Links {
+		link, ok := verify.ParseCodeLink(docLink.URL)
+		if !ok {
+			continue
+		}
 		result := SweepResult{
 			CheckType: CheckTypeCodeLinks,
 			Target:    link.FullURL,
-			File:      file,
+			File:      doc.Path,
+			Line:      docLink.Span.Start.Line,
 			CheckedAt: time.Now(),
 		}
 
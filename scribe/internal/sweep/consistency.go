@@ -1,40 +1,276 @@
 package sweep
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
+	"unicode/utf8"
 
-	"github.com/matsen/phylogenetic-compendium/scribe/internal/verify"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/llm"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/qmd"
 )
 
-// CheckClaimConsistency checks if cited papers still support the claims (FR-033).
-// Uses Asta snippet search to verify claim-source alignment.
-func CheckClaimConsistency(content string, file string) []SweepResult {
+// EvidenceJudge decides whether passages from a cited paper support a claim.
+// llm.Client implements it.
+type EvidenceJudge interface {
+	CheckEvidence(ctx context.Context, claim string, passages []string) (*llm.EvidenceResult, error)
+}
+
+// maxPassages is how many snippets of a paper are shown to the judge.
+const maxPassages = 4
+
+// maxPassageLen truncates long snippets, in bytes.
+const maxPassageLen = 2000
+
+// claimPair is a cited sentence and one of the papers it cites.
+type claimPair struct {
+	claim   string
+	line    int
+	paperID string
+}
+
+// CheckClaimConsistency checks that cited papers support the sentences that
+// cite them (FR-033). Each cited sentence is paired with each paper it
+// cites; the judge compares the sentence with the paper's abstract and most
+// relevant passages from snippets and quotes its evidence. Contradicted
+// claims are issues and claims the passages do not address are warnings.
+// Without a snippet source or judge, pairs are marked for manual review.
+func CheckClaimConsistency(doc *qmd.Document, snippets SnippetSource, judge EvidenceJudge) []SweepResult {
 	var results []SweepResult
+	for _, pair := range claimPairs(doc) {
+		results = append(results, checkClaimPair(doc.Path, pair, snippets, judge))
+	}
+	return results
+}
+
+// claimPairs pairs each sentence with the papers it cites. Citations after
+// the sentence's period, as in "... trees. [@a]", belong to the sentence
+// before.
+func claimPairs(doc *qmd.Document) []claimPair {
+	var pairs []claimPair
+	seen := make(map[string]bool)
+	for i := range doc.Paragraphs {
+		para := &doc.Paragraphs[i]
+		sentences := para.Sentences()
+
+		// Each sentence's claim leaves out a leading citation group, which
+		// supports the previous sentence
+		claims := make([]claimPair, len(sentences))
+		leads := make([]qmd.Position, len(sentences))
+		for j, sent := range sentences {
+			start := sent.Start + leadingCitations(para.Text[sent.Start:sent.End])
+			claims[j] = claimPair{
+				claim: strings.Join(strings.Fields(para.Text[start:sent.End]), " "),
+				line:  para.PositionOf(start).Line,
+			}
+			leads[j] = para.PositionOf(start)
+		}
+
+		claim := -1 // the last sentence long enough to be a claim
+		for j, sent := range sentences {
+			previous := claim
+			if len(claims[j].claim) >= 20 || claim < 0 {
+				claim = j
+			}
+			start, end := para.PositionOf(sent.Start), para.PositionOf(sent.End)
+			for _, c := range doc.Citations {
+				if before(c.Span.Start, start) || !before(c.Span.Start, end) {
+					continue
+				}
+				pair := claims[claim]
+				if previous >= 0 && before(c.Span.Start, leads[j]) {
+					pair = claims[previous]
+				}
+				pair.paperID = strings.TrimPrefix(c.Key, "paper:")
+				if key := pair.paperID + "\x00" + pair.claim; !seen[key] {
+					seen[key] = true
+					pairs = append(pairs, pair)
+				}
+			}
+		}
+	}
+	return pairs
+}
 
-	// Extract citations
-	citations := verify.ExtractCitations(content)
-	if len(citations) == 0 {
-		return results
-	}
-
-	// For each citation, we would ideally use Asta to verify the claim still holds.
-	// This is a placeholder that marks for manual review.
-	for _, citation := range citations {
-		results = append(results, SweepResult{
-			CheckType: CheckTypeClaimConsistency,
-			Status:    SweepStatusOK,
-			Target:    citation,
-			File:      file,
-			Message:   fmt.Sprintf("Citation %s exists - manual consistency check recommended", citation),
-			Details: map[string]any{
-				"citation_id":  citation,
-				"needs_review": true,
-			},
-			SuggestedFix: "Use Asta snippet search to verify the cited paper still supports the claim",
-			CheckedAt:    time.Now(),
-		})
+// leadingCitations returns the length of the bracketed citation groups at
+// the start of text, including surrounding whitespace.
+func leadingCitations(text string) int {
+	n := 0
+	for {
+		rest := strings.TrimLeft(text[n:], " \t\n")
+		end := strings.IndexByte(rest, ']')
+		if !strings.HasPrefix(rest, "[") || end < 0 || !strings.Contains(rest[:end], "@") {
+			if n == 0 {
+				return 0
+			}
+			return len(text) - len(rest)
+		}
+		n = len(text) - len(rest) + end + 1
 	}
+}
 
-	return results
+// before reports whether position a comes before b.
+func before(a, b qmd.Position) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Col < b.Col
+}
+
+// checkClaimPair judges one sentence against one cited paper.
+func checkClaimPair(file string, pair claimPair, snippets SnippetSource, judge EvidenceJudge) SweepResult {
+	result := SweepResult{
+		CheckType: CheckTypeClaimConsistency,
+		Status:    SweepStatusOK,
+		Target:    pair.paperID,
+		File:      file,
+		Line:      pair.line,
+		Details: map[string]any{
+			"citation_id": pair.paperID,
+			"claim":       pair.claim,
+		},
+		CheckedAt: time.Now(),
+	}
+	review := func(message string) SweepResult {
+		result.Message = message
+		result.Details["needs_review"] = true
+		result.SuggestedFix = "Check that the cited paper supports the claim"
+		return result
+	}
+
+	if snippets == nil {
+		return review(fmt.Sprintf("Citation %s - no snippet source configured, manual consistency check recommended", pair.paperID))
+	}
+	found, err := snippets.Snippets(pair.paperID)
+	if err != nil {
+		result.Status = SweepStatusWarning
+		result.Message = fmt.Sprintf("Could not load passages of %s: %v", pair.paperID, err)
+		return result
+	}
+	passages := selectSnippets(pair.claim, found)
+	if len(passages) == 0 {
+		return review(fmt.Sprintf("No abstract or passages available for %s - manual consistency check recommended", pair.paperID))
+	}
+	var texts, sources []string
+	for _, p := range passages {
+		texts = append(texts, p.Text)
+		sources = append(sources, p.Source)
+	}
+	result.Details["passages"] = sources
+	if judge == nil {
+		return review(fmt.Sprintf("Citation %s - LLM unavailable, manual consistency check recommended", pair.paperID))
+	}
+
+	verdict, err := judge.CheckEvidence(context.Background(), pair.claim, texts)
+	if err != nil {
+		result.Status = SweepStatusWarning
+		result.Message = fmt.Sprintf("Could not check %s against the claim: %v", pair.paperID, err)
+		return result
+	}
+	result.Details["verdict"] = verdict.Verdict
+	result.Details["confidence"] = verdict.Confidence
+	result.Details["reason"] = verdict.Reason
+	if verdict.PromptVersion != "" {
+		result.Details["prompt_version"] = verdict.PromptVersion
+	}
+	if verdict.Evidence != "" {
+		result.Details["evidence"] = verdict.Evidence
+	}
+
+	quoted := verdict.Evidence == "" || quotes(verdict.Evidence, texts)
+	switch {
+	case verdict.Verdict == llm.VerdictContradicts:
+		result.Status = SweepStatusIssue
+		result.Message = fmt.Sprintf("%s appears to contradict the claim (%s confidence)", pair.paperID, verdict.Confidence)
+		result.SuggestedFix = "Revise the claim or cite a paper that supports it"
+	case verdict.Verdict == llm.VerdictUnrelated:
+		result.Status = SweepStatusWarning
+		result.Message = fmt.Sprintf("Passages of %s do not address the claim (%s confidence)", pair.paperID, verdict.Confidence)
+		result.SuggestedFix = "Check the full text, or cite a more specific source"
+	case !quoted:
+		result.Status = SweepStatusWarning
+		result.Message = fmt.Sprintf("%s reportedly supports the claim, but the quoted evidence is not in its passages", pair.paperID)
+		result.SuggestedFix = "Check that the cited paper supports the claim"
+	default:
+		result.Message = fmt.Sprintf("%s supports the claim (%s confidence)", pair.paperID, verdict.Confidence)
+	}
+	if !quoted {
+		result.Details["evidence_verified"] = false
+	}
+	return result
+}
+
+// selectSnippets picks the passages most relevant to a claim: the abstract,
+// then the passages sharing the most words with the claim.
+func selectSnippets(claim string, snippets []Snippet) []Snippet {
+	words := make(map[string]bool)
+	for _, w := range contentWords(claim) {
+		words[w] = true
+	}
+	type scored struct {
+		snippet Snippet
+		score   int
+	}
+	var candidates []scored
+	for _, s := range snippets {
+		score := 0
+		for _, w := range contentWords(s.Text) {
+			if words[w] {
+				score++
+			}
+		}
+		if s.Source == "abstract" {
+			score = 1 << 30
+		}
+		if score > 0 {
+			candidates = append(candidates, scored{s, score})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+
+	var selected []Snippet
+	for _, c := range candidates {
+		if len(selected) == maxPassages {
+			break
+		}
+		if text := c.snippet.Text; len(text) > maxPassageLen {
+			cut := maxPassageLen
+			for cut > 0 && !utf8.RuneStart(text[cut]) {
+				cut--
+			}
+			c.snippet.Text = text[:cut]
+		}
+		selected = append(selected, c.snippet)
+	}
+	return selected
+}
+
+// contentWords returns the lowercased words of text with at least four letters.
+func contentWords(text string) []string {
+	var words []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !('a' <= r && r <= 'z' || '0' <= r && r <= '9' || r > 127)
+	}) {
+		if len(w) >= 4 {
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+// quotes reports whether evidence appears in the passages, ignoring case,
+// whitespace, and surrounding quotation marks. Quotes elided with "..."
+// must have every part present.
+func quotes(evidence string, passages []string) bool {
+	normalize := func(s string) string {
+		return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+	}
+	text := normalize(strings.Join(passages, "\n"))
+	evidence = strings.NewReplacer("…", "...", "“", "", "”", "").Replace(evidence)
+	for _, part := range strings.Split(evidence, "...") {
+		part = normalize(strings.Trim(strings.TrimSpace(part), `"'`))
+		if part != "" && !strings.Contains(text, part) {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,171 @@
+package sweep
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/llm"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/papers"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/qmd"
+)
+
+// fakeJudge returns canned verdicts by paper, keyed on a word in the passages.
+type fakeJudge struct {
+	verdicts map[string]llm.EvidenceResult // by passage substring
+	claims   []string
+}
+
+func (j *fakeJudge) CheckEvidence(ctx context.Context, claim string, passages []string) (*llm.EvidenceResult, error) {
+	j.claims = append(j.claims, claim)
+	text := strings.Join(passages, " ")
+	for marker, verdict := range j.verdicts {
+		if strings.Contains(text, marker) {
+			return &verdict, nil
+		}
+	}
+	return &llm.EvidenceResult{Verdict: llm.VerdictUnrelated, Confidence: "low"}, nil
+}
+
+func TestCheckClaimConsistency(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	textDir := filepath.Join(tmpDir, "fulltext")
+	if err := os.Mkdir(textDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	fulltext := "Abstract. We introduce a pruning algorithm.\n\nThe pruning algorithm computes\nlikelihoods in linear time.\n\nUnrelated acknowledgements."
+	if err := os.WriteFile(filepath.Join(textDir, "felsenstein1981.txt"), []byte(fulltext), 0644); err != nil {
+		t.Fatal(err)
+	}
+	export := filepath.Join(tmpDir, "library.jsonl")
+	lines := `{"id": "tavare1986", "abstract": "Substitution rates vary\nacross sites."}
+{"id": "knuth84", "doi": "10.1093/comjnl/27.2.97", "abstract": "Literate programming combines code and prose."}
+`
+	if err := os.WriteFile(export, []byte(lines), 0644); err != nil {
+		t.Fatal(err)
+	}
+	source, err := NewSnippetSource(textDir, export)
+	if err != nil {
+		t.Fatalf("NewSnippetSource: %v", err)
+	}
+
+	content := `# Methods
+
+Likelihoods can be computed in linear time with pruning [@paper:felsenstein1981].
+Rates are constant across sites. [@paper:tavare1986; @knuth84]
+Nothing is known about @missing2020 here.
+`
+	doc := qmd.Parse("ch.qmd", []byte(content))
+	judge := &fakeJudge{verdicts: map[string]llm.EvidenceResult{
+		"pruning":     {Verdict: llm.VerdictSupports, Evidence: "computes likelihoods in linear time", Confidence: "high"},
+		"vary":        {Verdict: llm.VerdictContradicts, Evidence: "Substitution rates vary across sites", Confidence: "medium"},
+		"programming": {Verdict: llm.VerdictSupports, Evidence: "literate programming is fast", Confidence: "low"},
+	}}
+
+	results := CheckClaimConsistency(doc, source, judge)
+	type got struct {
+		target string
+		line   int
+		status SweepResultStatus
+	}
+	var gotResults []got
+	for _, r := range results {
+		gotResults = append(gotResults, got{r.Target, r.Line, r.Status})
+	}
+	want := []got{
+		{"felsenstein1981", 3, SweepStatusOK},
+		{"tavare1986", 4, SweepStatusIssue},
+		{"knuth84", 4, SweepStatusWarning}, // evidence is not quoted from the abstract
+		{"missing2020", 5, SweepStatusOK},  // no passages: manual review
+	}
+	if len(gotResults) != len(want) {
+		t.Fatalf("got %d results %v, want %v", len(gotResults), gotResults, want)
+	}
+	for i := range want {
+		if gotResults[i] != want[i] {
+			t.Errorf("result %d = %v, want %v", i, gotResults[i], want[i])
+		}
+	}
+
+	// The trailing citation fragment belongs to the sentence before it
+	if claim := results[1].Details["claim"]; claim != "Rates are constant across sites." {
+		t.Errorf("tavare1986 claim = %q", claim)
+	}
+	if passages := results[0].Details["passages"].([]string); len(passages) != 2 || passages[0] != "abstract" || passages[1] != "text:2" {
+		t.Errorf("felsenstein1981 passages = %v, want abstract and the matching paragraph", passages)
+	}
+	if results[3].Details["needs_review"] != true {
+		t.Errorf("missing2020 should need review: %v", results[3].Details)
+	}
+	if len(judge.claims) != 3 {
+		t.Errorf("judge called %d times, want 3", len(judge.claims))
+	}
+
+	// Without a judge, every pair is left for manual review
+	for _, r := range CheckClaimConsistency(doc, source, nil) {
+		if r.Status != SweepStatusOK || r.Details["needs_review"] != true {
+			t.Errorf("%s: got %s %v without a judge", r.Target, r.Status, r.Details)
+		}
+	}
+}
+
+func TestPaperStoreSource(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	text := filepath.Join(tmpDir, "paper.txt")
+	if err := os.WriteFile(text, []byte("Abstract: rates vary.\n\nMethods.\fResults differ."), 0644); err != nil {
+		t.Fatal(err)
+	}
+	storeDir := filepath.Join(tmpDir, "papers")
+	if _, err := papers.NewStore(storeDir).Add("10.1000/x", text); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	source, err := NewSnippetSource(storeDir)
+	if err != nil {
+		t.Fatalf("NewSnippetSource: %v", err)
+	}
+	snippets, err := source.Snippets("10.1000/x")
+	if err != nil {
+		t.Fatalf("Snippets: %v", err)
+	}
+	var got []string
+	for _, s := range snippets {
+		got = append(got, s.Source)
+	}
+	if want := "abstract|page 1, paragraph 2|page 2, paragraph 1"; strings.Join(got, "|") != want {
+		t.Errorf("sources = %q, want %q", got, want)
+	}
+	if snippets, err := source.Snippets("unknown"); snippets != nil || err != nil {
+		t.Errorf("unknown paper: %v, %v", snippets, err)
+	}
+}
+
+func TestQuotes(t *testing.T) {
+	passages := []string{"The pruning algorithm computes likelihoods in linear time."}
+	tests := []struct {
+		evidence string
+		want     bool
+	}{
+		{"computes likelihoods in linear time", true},
+		{`"The pruning  algorithm COMPUTES"`, true},
+		{"The pruning algorithm ... linear time", true},
+		{"The pruning algorithm … quadratic time", false},
+		{"computes likelihoods quickly", false},
+	}
+	for _, tt := range tests {
+		if got := quotes(tt.evidence, passages); got != tt.want {
+			t.Errorf("quotes(%q) = %v, want %v", tt.evidence, got, tt.want)
+		}
+	}
+}
@@ -3,30 +3,30 @@ package sweep
 import (
 	"regexp"
 	"time"
+
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/qmd"
 )
 
 // CheckCoverageGaps identifies potential coverage gaps in the content (FR-036).
 // This is a heuristic check that flags areas that might need more documentation.
-func CheckCoverageGaps(content string, file string) []SweepResult {
+func CheckCoverageGaps(doc *qmd.Document) []SweepResult {
 	var results []SweepResult
 
 	// Check for sections with few citations
-	results = append(results, checkCitationDensity(content, file)...)
+	results = append(results, checkCitationDensity(doc)...)
 
 	// Check for referenced but unexplained techniques
-	results = append(results, checkUnexplainedTechniques(content, file)...)
+	results = append(results, checkUnexplainedTechniques(doc)...)
 
 	return results
 }
 
-func checkCitationDensity(content string, file string) []SweepResult {
+func checkCitationDensity(doc *qmd.Document) []SweepResult {
 	var results []SweepResult
+	file := doc.Path
 
-	// Count citations
-	citations := len(citationPattern.FindAllString(content, -1))
-
-	// Count paragraphs (rough heuristic: double newlines)
-	paragraphs := len(paragraphPattern.FindAllString(content, -1)) + 1
+	citations := len(doc.Citations)
+	paragraphs := len(doc.Paragraphs)
 
 	if paragraphs > 5 && citations < paragraphs/3 {
 		results = append(results, SweepResult{
@@ -48,21 +48,23 @@ func checkCitationDensity(content string, file string) []SweepResult {
 	return results
 }
 
-func checkUnexplainedTechniques(content string, file string) []SweepResult {
+func checkUnexplainedTechniques(doc *qmd.Document) []SweepResult {
 	var results []SweepResult
 
-	// Look for technique mentions that might need explanation
+	// Look for technique mentions in prose that might need explanation
 	// This is a very rough heuristic
-	techniques := techniquePattern.FindAllString(content, -1)
-
-	for _, technique := range techniques {
-		// Check if the technique is explained (has "is" or "are" nearby)
-		if !isExplained(content, technique) {
+	for _, para := range doc.Paragraphs {
+		for _, technique := range techniquePattern.FindAllString(para.Text, -1) {
+			// Check if the technique is explained (has "is" or "are" nearby)
+			if isExplained(para.Text, technique) {
+				continue
+			}
 			results = append(results, SweepResult{
 				CheckType: CheckTypeCoverage,
 				Status:    SweepStatusWarning,
 				Target:    technique,
-				File:      file,
+				File:      doc.Path,
+				Line:      para.Span.Start.Line,
 				Message:   "Technique mentioned but may lack explanation",
 				Details: map[string]any{
 					"technique": technique,
@@ -82,8 +84,4 @@ func isExplained(content string, term string) bool {
 	return true // For now, assume all are explained
 }
 
-var (
-	citationPattern  = regexp.MustCompile(`@paper:[a-zA-Z0-9_-]+`)
-	paragraphPattern = regexp.MustCompile(`\n\n`)
-	techniquePattern = regexp.MustCompile(`(?i)\b(algorithm|method|technique|approach|strategy)\b`)
-)
+var techniquePattern = regexp.MustCompile(`(?i)\b(algorithm|method|technique|approach|strategy)\b`)
@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"os/exec"
 	"regexp"
+	"strings"
 	"time"
+
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/qmd"
 )
 
 // repoURLPattern matches GitHub repository URLs
@@ -26,21 +29,40 @@ func ExtractRepoURLs(content string) []string {
 	return urls
 }
 
+// repoURLsInDocument returns the distinct GitHub repository URLs linked from a
+// document, with the line of their first occurrence.
+func repoURLsInDocument(doc *qmd.Document) ([]string, map[string]int) {
+	var urls []string
+	lines := make(map[string]int)
+	for _, link := range doc.Links {
+		url := repoURLPattern.FindString(link.URL)
+		if url == "" || !strings.HasPrefix(link.URL, url) {
+			continue
+		}
+		if _, ok := lines[url]; !ok {
+			lines[url] = link.Span.Start.Line
+			urls = append(urls, url)
+		}
+	}
+	return urls, lines
+}
+
 // CheckRepoFreshness checks if referenced repos are still maintained (FR-034).
-// Repos not updated in > 2 years are flagged as stale.
-func CheckRepoFreshness(content string, file string) []SweepResult {
+// Repos not updated within staleThreshold are flagged as stale.
+func CheckRepoFreshness(doc *qmd.Document, staleThreshold time.Duration) []SweepResult {
 	var results []SweepResult
 
-	urls := ExtractRepoURLs(content)
+	urls, lines := repoURLsInDocument(doc)
 	for _, url := range urls {
-		result := checkSingleRepoFreshness(url, file)
+		result := checkSingleRepoFreshness(url, doc.Path, staleThreshold)
+		result.Line = lines[url]
 		results = append(results, result)
 	}
 
 	return results
 }
 
-func checkSingleRepoFreshness(url string, file string) SweepResult {
+func checkSingleRepoFreshness(url string, file string, staleThreshold time.Duration) SweepResult {
 	result := SweepResult{
 		CheckType: CheckTypeRepoFreshness,
 		Target:    url,
@@ -106,7 +128,7 @@ func checkSingleRepoFreshness(url string, file string) SweepResult {
 	}
 
 	age := time.Since(pushedAt)
-	if age > StaleThreshold {
+	if age > staleThreshold {
 		result.Status = SweepStatusIssue
 		result.Message = fmt.Sprintf("Repository has not been updated in %.1f years", age.Hours()/(24*365))
 		result.SuggestedFix = "Verify the code is still relevant or find an active alternative"
@@ -4,6 +4,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/llm"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/output"
 )
 
 // SweepReportBuilder builds a sweep report from results.
@@ -11,6 +13,7 @@ type SweepReportBuilder struct {
 	files     []string
 	checksRun []CheckType
 	results   []SweepResult
+	usage     *llm.Usage
 }
 
 // NewSweepReportBuilder creates a new report builder.
@@ -66,9 +69,15 @@ func (b *SweepReportBuilder) Build() SweepReport {
 		ChecksRun:    b.checksRun,
 		Summary:      summary,
 		Results:      b.results,
+		LLMUsage:     b.usage,
 	}
 }
 
+// SetLLMUsage records the LLM usage of the sweep.
+func (b *SweepReportBuilder) SetLLMUsage(usage llm.Usage) {
+	b.usage = &usage
+}
+
 // FilterByStatus returns results with the given status.
 func (r *SweepReport) FilterByStatus(status SweepResultStatus) []SweepResult {
 	var filtered []SweepResult
@@ -90,3 +99,36 @@ func (r *SweepReport) FilterByCheckType(checkType CheckType) []SweepResult {
 	}
 	return filtered
 }
+
+// Rules describes each sweep check type for CI report formats.
+func Rules() []output.Rule {
+	return []output.Rule{
+		{ID: string(CheckTypeClaimConsistency), Description: "Cited papers still support the claims that cite them"},
+		{ID: string(CheckTypeRepoFreshness), Description: "Referenced repositories are still maintained"},
+		{ID: string(CheckTypeCodeLinks), Description: "Code links still resolve at the repository head"},
+		{ID: string(CheckTypeCoverage), Description: "Content documents the techniques it relies on"},
+	}
+}
+
+// Findings converts the report into findings for CI report formats.
+func (r *SweepReport) Findings() output.FindingSet {
+	set := output.FindingSet{Tool: "scribe-sweep", Rules: Rules()}
+	for _, result := range r.Results {
+		level := output.LevelNone
+		switch result.Status {
+		case SweepStatusIssue:
+			level = output.LevelError
+		case SweepStatusWarning:
+			level = output.LevelWarning
+		}
+		set.Findings = append(set.Findings, output.Finding{
+			RuleID:  string(result.CheckType),
+			Level:   level,
+			File:    result.File,
+			Line:    result.Line,
+			Target:  result.Target,
+			Message: result.Message,
+		})
+	}
+	return set
+}
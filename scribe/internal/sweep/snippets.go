@@ -0,0 +1,217 @@
+package sweep
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/bibtex"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/papers"
+)
+
+// Snippet is a passage of a cited paper, such as its abstract or a
+// paragraph of its full text.
+type Snippet struct {
+	Text   string `json:"text"`
+	Source string `json:"source"` // "abstract", "text:N" for the Nth paragraph of the full text, or "page P, paragraph N"
+}
+
+// SnippetSource supplies passages of cited papers.
+// Snippets returns (nil, nil) if the source knows nothing about the paper.
+type SnippetSource interface {
+	Snippets(paperID string) ([]Snippet, error)
+}
+
+// NewSnippetSource opens the snippet sources at paths: directories are
+// paper stores or PDF text caches, and files are bipartite JSONL exports.
+func NewSnippetSource(paths ...string) (SnippetSource, error) {
+	var sources ChainSource
+	var exports []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("snippet source: %w", err)
+		}
+		switch {
+		case info.IsDir() && papers.IsStore(path):
+			sources = append(sources, NewPaperStoreSource(papers.NewStore(path)))
+		case info.IsDir():
+			sources = append(sources, NewTextCacheSource(path))
+		default:
+			exports = append(exports, path)
+		}
+	}
+	if len(exports) > 0 {
+		export, err := NewExportSource(exports...)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, export)
+	}
+	return sources, nil
+}
+
+// TextCacheSource reads passages from a directory of text extracted from
+// PDFs, with one <paper ID>.txt file per paper ("/" in IDs such as DOIs is
+// written as "_"). Paragraphs separated by blank lines are passages.
+type TextCacheSource struct {
+	Dir string
+}
+
+// NewTextCacheSource creates a TextCacheSource for dir.
+func NewTextCacheSource(dir string) *TextCacheSource {
+	return &TextCacheSource{Dir: dir}
+}
+
+// Snippets returns the paragraphs of the paper's cached text.
+func (s *TextCacheSource) Snippets(paperID string) ([]Snippet, error) {
+	name := strings.ReplaceAll(paperID, "/", "_") + ".txt"
+	data, err := os.ReadFile(filepath.Join(s.Dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read cached text: %w", err)
+	}
+
+	var snippets []Snippet
+	paragraph := 0
+	for _, block := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n\n") {
+		text := strings.Join(strings.Fields(block), " ")
+		if text == "" {
+			continue
+		}
+		paragraph++
+		source := fmt.Sprintf("text:%d", paragraph)
+		if paragraph == 1 && strings.HasPrefix(strings.ToLower(text), "abstract") {
+			source = "abstract"
+		}
+		snippets = append(snippets, Snippet{Text: text, Source: source})
+	}
+	return snippets, nil
+}
+
+// PaperStoreSource reads passages from the full texts in a paper store.
+type PaperStoreSource struct {
+	Store *papers.Store
+}
+
+// NewPaperStoreSource creates a PaperStoreSource for store.
+func NewPaperStoreSource(store *papers.Store) *PaperStoreSource {
+	return &PaperStoreSource{Store: store}
+}
+
+// Snippets returns the paragraphs of the paper's stored text. A first
+// paragraph starting with "Abstract" is the abstract.
+func (s *PaperStoreSource) Snippets(paperID string) ([]Snippet, error) {
+	paper, err := s.Store.Get(paperID)
+	if err != nil || paper == nil {
+		return nil, err
+	}
+	var snippets []Snippet
+	for _, page := range paper.Pages {
+		for i, text := range page.Paragraphs {
+			source := fmt.Sprintf("page %d, paragraph %d", page.Number, i+1)
+			if len(snippets) == 0 && strings.HasPrefix(strings.ToLower(text), "abstract") {
+				source = "abstract"
+			}
+			snippets = append(snippets, Snippet{Text: text, Source: source})
+		}
+	}
+	return snippets, nil
+}
+
+// exportEntry is a paper in a bipartite JSONL export.
+type exportEntry struct {
+	ID       string   `json:"id"`
+	S2ID     string   `json:"s2_id"`
+	DOI      string   `json:"doi"`
+	Aliases  []string `json:"aliases"`
+	Abstract string   `json:"abstract"`
+}
+
+// ExportSource supplies paper abstracts from bipartite JSONL exports.
+type ExportSource struct {
+	abstracts map[string]string // by ID, S2 ID, DOI, and alias
+}
+
+// NewExportSource loads the abstracts from the given export files.
+func NewExportSource(paths ...string) (*ExportSource, error) {
+	s := &ExportSource{abstracts: make(map[string]string)}
+	for _, path := range paths {
+		if err := s.load(path); err != nil {
+			return nil, fmt.Errorf("load %s: %w", path, err)
+		}
+	}
+	return s, nil
+}
+
+func (s *ExportSource) load(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var entry exportEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		abstract := strings.Join(strings.Fields(entry.Abstract), " ")
+		if abstract == "" {
+			continue
+		}
+		for _, id := range append([]string{entry.ID, entry.S2ID, bibtex.NormalizeDOI(entry.DOI)}, entry.Aliases...) {
+			if id != "" {
+				s.abstracts[id] = abstract
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// Snippets returns the paper's abstract.
+func (s *ExportSource) Snippets(paperID string) ([]Snippet, error) {
+	abstract, ok := s.abstracts[paperID]
+	if !ok {
+		abstract, ok = s.abstracts[bibtex.NormalizeDOI(paperID)]
+	}
+	if !ok {
+		return nil, nil
+	}
+	return []Snippet{{Text: abstract, Source: "abstract"}}, nil
+}
+
+// ChainSource combines the snippets of several sources.
+type ChainSource []SnippetSource
+
+// Snippets returns the snippets every source has for the paper. An error is
+// returned only if no source produced snippets.
+func (c ChainSource) Snippets(paperID string) ([]Snippet, error) {
+	var snippets []Snippet
+	var firstErr error
+	for _, source := range c {
+		found, err := source.Snippets(paperID)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		snippets = append(snippets, found...)
+	}
+	if len(snippets) == 0 {
+		return nil, firstErr
+	}
+	return snippets, nil
+}
@@ -1,34 +1,46 @@
 package sweep
 
 import (
-	"os"
+	"time"
+
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/llm"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/qmd"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/verify"
 )
 
 // Options configures sweep behavior.
 type Options struct {
-	Checks []CheckType // Which checks to run (empty = all)
+	Checks         []CheckType                   // Which checks to run (empty = all)
+	Mirror         *verify.RepoMirror            // Local repo mirror for code links (nil = verify.DefaultRepoCacheDir)
+	StaleThreshold time.Duration                 // Age after which a repo is stale (0 = StaleThreshold)
+	Severities     map[CheckType]verify.Severity // Per-type severity (missing = verify.SeverityError)
+	Snippets       SnippetSource                 // Passages of cited papers (nil = flag citations for manual review)
+	Judge          EvidenceJudge                 // Judges claims against passages (nil = flag citations for manual review)
 }
 
 // DefaultOptions returns the default sweep options.
 func DefaultOptions() Options {
 	return Options{
-		Checks: []CheckType{
-			CheckTypeRepoFreshness,
-			CheckTypeCodeLinks,
-			CheckTypeClaimConsistency,
-			CheckTypeCoverage,
-		},
+		Checks:         CheckTypes(),
+		StaleThreshold: StaleThreshold,
+	}
+}
+
+// applySeverity downgrades issues from check types configured as warnings.
+func (o Options) applySeverity(result SweepResult) SweepResult {
+	if result.Status == SweepStatusIssue && o.Severities[result.CheckType] == verify.SeverityWarning {
+		result.Status = SweepStatusWarning
 	}
+	return result
 }
 
 // SweepFile runs sweep checks on a single file.
 func SweepFile(filePath string, opts Options) ([]SweepResult, error) {
-	content, err := os.ReadFile(filePath)
+	doc, err := qmd.ParseFile(filePath)
 	if err != nil {
 		return nil, err
 	}
 
-	contentStr := string(content)
 	var results []SweepResult
 
 	checks := opts.Checks
@@ -36,19 +48,34 @@ func SweepFile(filePath string, opts Options) ([]SweepResult, error) {
 		checks = DefaultOptions().Checks
 	}
 
+	mirror := opts.Mirror
+	if mirror == nil {
+		mirror = verify.NewRepoMirror("")
+	}
+	staleThreshold := opts.StaleThreshold
+	if staleThreshold == 0 {
+		staleThreshold = StaleThreshold
+	}
+
 	for _, check := range checks {
+		if opts.Severities[check] == verify.SeverityOff {
+			continue
+		}
 		switch check {
 		case CheckTypeRepoFreshness:
-			results = append(results, CheckRepoFreshness(contentStr, filePath)...)
+			results = append(results, CheckRepoFreshness(doc, staleThreshold)...)
 		case CheckTypeCodeLinks:
-			results = append(results, CheckCodeLinks(contentStr, filePath)...)
+			results = append(results, CheckCodeLinks(mirror, doc)...)
 		case CheckTypeClaimConsistency:
-			results = append(results, CheckClaimConsistency(contentStr, filePath)...)
+			results = append(results, CheckClaimConsistency(doc, opts.Snippets, opts.Judge)...)
 		case CheckTypeCoverage:
-			results = append(results, CheckCoverageGaps(contentStr, filePath)...)
+			results = append(results, CheckCoverageGaps(doc)...)
 		}
 	}
 
+	for i := range results {
+		results[i] = opts.applySeverity(results[i])
+	}
 	return results, nil
 }
 
@@ -62,7 +89,9 @@ func SweepFiles(files []string, opts Options) (*SweepReport, error) {
 	}
 
 	for _, check := range checks {
-		builder.AddCheck(check)
+		if opts.Severities[check] != verify.SeverityOff {
+			builder.AddCheck(check)
+		}
 	}
 
 	for _, file := range files {
@@ -86,6 +115,12 @@ func SweepFiles(files []string, opts Options) (*SweepReport, error) {
 		}
 	}
 
+	if judge, ok := opts.Judge.(interface{ Usage() llm.Usage }); ok {
+		if usage := judge.Usage(); usage.Requests+usage.Cached > 0 {
+			builder.SetLLMUsage(usage)
+		}
+	}
+
 	report := builder.Build()
 	return &report, nil
 }
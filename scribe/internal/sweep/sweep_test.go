@@ -2,6 +2,8 @@ package sweep
 
 import (
 	"testing"
+
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/qmd"
 )
 
 func TestExtractRepoURLs(t *testing.T) {
@@ -122,7 +124,7 @@ This is paragraph five. Almost done.
 
 This is paragraph six. Final paragraph.`
 
-	results := CheckCoverageGaps(content, "test.qmd")
+	results := CheckCoverageGaps(qmd.Parse("test.qmd", []byte(content)))
 
 	// Should detect low citation density
 	hasWarning := false
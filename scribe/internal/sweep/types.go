@@ -1,7 +1,12 @@
 // Package sweep implements periodic verification sweep for the scribe CLI.
 package sweep
 
-import "time"
+import (
+	"fmt"
+	"time"
+
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/llm"
+)
 
 // CheckType represents the type of sweep check.
 type CheckType string
@@ -13,6 +18,26 @@ const (
 	CheckTypeCoverage         CheckType = "coverage"
 )
 
+// CheckTypes lists every sweep check type.
+func CheckTypes() []CheckType {
+	return []CheckType{
+		CheckTypeRepoFreshness,
+		CheckTypeCodeLinks,
+		CheckTypeClaimConsistency,
+		CheckTypeCoverage,
+	}
+}
+
+// ParseCheckType validates a check type name.
+func ParseCheckType(s string) (CheckType, error) {
+	for _, t := range CheckTypes() {
+		if string(t) == s {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unknown check type: %s", s)
+}
+
 // SweepResultStatus represents the status of a sweep check.
 type SweepResultStatus string
 
@@ -51,7 +76,8 @@ type SweepReport struct {
 	ChecksRun    []CheckType   `json:"checks_run"`
 	Summary      SweepSummary  `json:"summary"`
 	Results      []SweepResult `json:"results"`
+	LLMUsage     *llm.Usage    `json:"llm_usage,omitempty"` // LLM evidence check requests, tokens, and cost
 }
 
-// StaleThreshold is the age after which a repository is considered stale.
+// StaleThreshold is the default age after which a repository is considered stale.
 const StaleThreshold = 2 * 365 * 24 * time.Hour // 2 years
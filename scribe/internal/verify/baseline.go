@@ -0,0 +1,150 @@
+package verify
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultBaselinePath is the default location of the verify baseline file.
+const DefaultBaselinePath = ".scribe/baseline.json"
+
+// BaselineEntry records one accepted finding.
+type BaselineEntry struct {
+	Fingerprint string    `json:"fingerprint"`
+	CheckType   CheckType `json:"check_type"`
+	File        string    `json:"file"`
+	Line        int       `json:"line"` // informational; matching uses the fingerprint
+	Message     string    `json:"message"`
+}
+
+// Baseline is a set of known findings that no longer fail verification.
+type Baseline struct {
+	GeneratedAt time.Time       `json:"generated_at"`
+	Entries     []BaselineEntry `json:"entries"`
+
+	index map[string]bool
+}
+
+// Fingerprint identifies a finding independently of its line number, so
+// edits elsewhere in the file do not invalidate a baseline.
+// It hashes the check type, file, and the finding's subject (the URL, paper ID,
+// permalink, claim text, cross-reference, or identifier, falling back to the target line text).
+func Fingerprint(result VerificationResult) string {
+	subject := result.Target.Text
+	switch d := result.Details; {
+	case d.URL != nil:
+		subject = d.URL.URL
+	case d.Citation != nil:
+		subject = d.Citation.PaperID
+	case d.CodeLink != nil:
+		subject = d.CodeLink.Permalink
+	case d.Claim != nil:
+		subject = d.Claim.ClaimText
+	case d.CrossRef != nil:
+		subject = d.CrossRef.Problem + ":" + d.CrossRef.Label + d.CrossRef.Link
+	case d.Identifier != nil:
+		subject = string(d.Identifier.Kind) + ":" + d.Identifier.Value
+	}
+	subject = strings.Join(strings.Fields(subject), " ")
+
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		string(result.CheckType),
+		filepath.ToSlash(result.Target.File),
+		subject,
+	}, "\x00")))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// NewBaseline records every failing or warning result in a report.
+func NewBaseline(report *VerificationReport) *Baseline {
+	b := &Baseline{GeneratedAt: time.Now(), Entries: []BaselineEntry{}}
+	seen := make(map[string]bool)
+	for _, r := range report.Results {
+		if r.Status != CheckStatusFail && r.Status != CheckStatusWarn && r.Status != CheckStatusBaselined {
+			continue
+		}
+		fp := Fingerprint(r)
+		if seen[fp] {
+			continue
+		}
+		seen[fp] = true
+		b.Entries = append(b.Entries, BaselineEntry{
+			Fingerprint: fp,
+			CheckType:   r.CheckType,
+			File:        r.Target.File,
+			Line:        r.Target.Line,
+			Message:     r.Message,
+		})
+	}
+	sort.SliceStable(b.Entries, func(i, j int) bool {
+		if b.Entries[i].File != b.Entries[j].File {
+			return b.Entries[i].File < b.Entries[j].File
+		}
+		return b.Entries[i].Line < b.Entries[j].Line
+	})
+	return b
+}
+
+// LoadBaseline reads a baseline file. A missing file yields an empty baseline.
+func LoadBaseline(path string) (*Baseline, error) {
+	b := &Baseline{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return b, nil
+		}
+		return nil, fmt.Errorf("read baseline: %w", err)
+	}
+	if err := json.Unmarshal(data, b); err != nil {
+		return nil, fmt.Errorf("parse baseline %s: %w", path, err)
+	}
+	return b, nil
+}
+
+// Save writes the baseline to path.
+func (b *Baseline) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create baseline directory: %w", err)
+	}
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal baseline: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("write baseline: %w", err)
+	}
+	return nil
+}
+
+// Contains reports whether a fingerprint is in the baseline.
+func (b *Baseline) Contains(fingerprint string) bool {
+	if b == nil {
+		return false
+	}
+	if b.index == nil {
+		b.index = make(map[string]bool, len(b.Entries))
+		for _, e := range b.Entries {
+			b.index[e.Fingerprint] = true
+		}
+	}
+	return b.index[fingerprint]
+}
+
+// ApplyBaseline marks failing and warning results found in the baseline as
+// baselined and recomputes the summary and exit code.
+func (r *VerificationReport) ApplyBaseline(b *Baseline) {
+	for i := range r.Results {
+		result := &r.Results[i]
+		if (result.Status == CheckStatusFail || result.Status == CheckStatusWarn) && b.Contains(result.Fingerprint) {
+			result.Status = CheckStatusBaselined
+		}
+	}
+	r.rebuildSummary()
+}
@@ -0,0 +1,202 @@
+package verify
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/bibtex"
+)
+
+// BibEntry is the location of a BibTeX entry.
+type BibEntry struct {
+	Key  string `json:"key"`
+	Type string `json:"type"`
+	File string `json:"file"`
+	Line int    `json:"line"`
+
+	Entry *bibtex.Entry `json:"-"` // parsed fields
+}
+
+// Bibliography indexes the entries of one or more BibTeX files by citation key.
+type Bibliography struct {
+	Files   []string
+	Entries []BibEntry
+	byKey   map[string][]int
+	hash    string // SHA-256 of the files' paths and contents
+}
+
+// LoadBibliography reads BibTeX files and indexes their entries.
+func LoadBibliography(paths ...string) (*Bibliography, error) {
+	b := &Bibliography{byKey: make(map[string][]int)}
+	h := sha256.New()
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read bibliography %s: %w", path, err)
+		}
+		b.Files = append(b.Files, path)
+		b.add(path, string(data))
+		fmt.Fprintf(h, "%s\x00%d\x00", path, len(data))
+		h.Write(data)
+	}
+	b.hash = hex.EncodeToString(h.Sum(nil))
+	return b, nil
+}
+
+// Hash identifies the content of the bibliography files, so that results
+// depending on them are re-checked when they change. It is "" for nil.
+func (b *Bibliography) Hash() string {
+	if b == nil {
+		return ""
+	}
+	return b.hash
+}
+
+// add indexes the entries in a BibTeX file's content.
+func (b *Bibliography) add(path, content string) {
+	for _, entry := range bibtex.Parse(path, []byte(content)).Entries {
+		if entry.Key == "" {
+			continue
+		}
+		b.byKey[entry.Key] = append(b.byKey[entry.Key], len(b.Entries))
+		b.Entries = append(b.Entries, BibEntry{
+			Key:   entry.Key,
+			Type:  entry.Type,
+			File:  path,
+			Line:  entry.Line,
+			Entry: entry,
+		})
+	}
+}
+
+// Lookup returns the first entry with the given key.
+func (b *Bibliography) Lookup(key string) (BibEntry, bool) {
+	if b == nil {
+		return BibEntry{}, false
+	}
+	indexes, ok := b.byKey[key]
+	if !ok {
+		return BibEntry{}, false
+	}
+	return b.Entries[indexes[0]], true
+}
+
+// Duplicates returns every entry whose key was already defined by an earlier entry.
+func (b *Bibliography) Duplicates() []BibEntry {
+	var dups []BibEntry
+	for i, entry := range b.Entries {
+		if b.byKey[entry.Key][0] != i {
+			dups = append(dups, entry)
+		}
+	}
+	return dups
+}
+
+// Uncited returns the entries whose keys are not in cited, in file order.
+// Each key is reported once.
+func (b *Bibliography) Uncited(cited map[string]bool) []BibEntry {
+	var uncited []BibEntry
+	for i, entry := range b.Entries {
+		if b.byKey[entry.Key][0] == i && !cited[entry.Key] {
+			uncited = append(uncited, entry)
+		}
+	}
+	return uncited
+}
+
+// VerifyCitationKey checks that a Pandoc citation key is defined in a
+// bibliography file or known to the paper resolver.
+func VerifyCitationKey(bibs []*Bibliography, resolver CitationResolver, key string, file string, line int, text string) VerificationResult {
+	result := VerificationResult{
+		CheckID:   uuid.New().String(),
+		CheckType: CheckTypeCitation,
+		Target: VerificationTarget{
+			File: file,
+			Line: line,
+			Text: text,
+		},
+		CheckedAt: time.Now(),
+		Details: VerificationDetails{
+			Citation: &CitationDetails{PaperID: key},
+		},
+	}
+
+	for _, bib := range bibs {
+		if entry, ok := bib.Lookup(key); ok {
+			result.Status = CheckStatusPass
+			result.Message = fmt.Sprintf("Citation key %q found in %s", key, entry.File)
+			result.Details.Citation.Resolved = true
+			result.Details.Citation.Source = entry.File
+			return result
+		}
+	}
+
+	record, err := resolver.Resolve(key)
+	switch {
+	case err != nil:
+		result.Status = CheckStatusFail
+		result.Message = fmt.Sprintf("Citation key %q not found in bibliography, and %s lookup failed: %v", key, resolver.Name(), err)
+	case record == nil:
+		result.Status = CheckStatusFail
+		result.Message = fmt.Sprintf("Citation key %q not found in bibliography or %s", key, resolver.Name())
+	default:
+		result.Status = CheckStatusPass
+		result.Message = fmt.Sprintf("Citation key %q resolved successfully", key)
+		result.Details.Citation.Resolved = true
+		result.Details.Citation.Source = record.Source
+	}
+	return result
+}
+
+// bibliographyResults reports duplicate keys and uncited entries in a
+// bibliography. Uncited entries are skipped if cited is nil, meaning that
+// which entries are cited is not known.
+func bibliographyResults(bib *Bibliography, cited map[string]bool) []VerificationResult {
+	var results []VerificationResult
+
+	for _, dup := range bib.Duplicates() {
+		first, _ := bib.Lookup(dup.Key)
+		results = append(results, bibResult(dup, CheckStatusFail,
+			fmt.Sprintf("Duplicate BibTeX key %q (first defined at %s:%d)", dup.Key, first.File, first.Line)))
+	}
+
+	if cited == nil {
+		return results
+	}
+	uncited := bib.Uncited(cited)
+	sort.SliceStable(uncited, func(i, j int) bool { return uncited[i].File < uncited[j].File })
+	for _, entry := range uncited {
+		results = append(results, bibResult(entry, CheckStatusWarn,
+			fmt.Sprintf("BibTeX entry %q is never cited", entry.Key)))
+	}
+
+	return results
+}
+
+// bibResult creates a bibliography check result for an entry.
+func bibResult(entry BibEntry, status CheckStatus, message string) VerificationResult {
+	return VerificationResult{
+		CheckID:   uuid.New().String(),
+		CheckType: CheckTypeBibliography,
+		Target: VerificationTarget{
+			File: entry.File,
+			Line: entry.Line,
+			Text: fmt.Sprintf("@%s{%s,", entry.Type, entry.Key),
+		},
+		Status:  status,
+		Message: message,
+		Details: VerificationDetails{
+			Citation: &CitationDetails{
+				PaperID:  entry.Key,
+				Resolved: true,
+				Source:   entry.File,
+			},
+		},
+		CheckedAt: time.Now(),
+	}
+}
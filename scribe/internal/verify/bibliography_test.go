@@ -0,0 +1,186 @@
+package verify
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/quarto"
+)
+
+func TestLoadBibliography(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	bibPath := filepath.Join(tmpDir, "references.bib")
+	bib := `@string{jmb = "J. Mol. Biol."}
+@article{knuth84,
+  title = {Literate Programming},
+}
+
+@book{felsenstein2004,
+  title = {Inferring Phylogenies},
+}
+@misc{knuth84,
+  note = {duplicate},
+}
+`
+	if err := os.WriteFile(bibPath, []byte(bib), 0644); err != nil {
+		t.Fatalf("write bibliography: %v", err)
+	}
+
+	b, err := LoadBibliography(bibPath)
+	if err != nil {
+		t.Fatalf("LoadBibliography failed: %v", err)
+	}
+	if len(b.Entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(b.Entries))
+	}
+
+	entry, ok := b.Lookup("knuth84")
+	if !ok || entry.Type != "article" || entry.Line != 2 {
+		t.Errorf("Lookup(knuth84) = %+v, %v; want first article at line 2", entry, ok)
+	}
+	if _, ok := b.Lookup("jmb"); ok {
+		t.Error("@string definitions should not be indexed")
+	}
+
+	dups := b.Duplicates()
+	if len(dups) != 1 || dups[0].Line != 9 {
+		t.Errorf("Duplicates() = %+v, want knuth84 at line 9", dups)
+	}
+
+	uncited := b.Uncited(map[string]bool{"knuth84": true})
+	if len(uncited) != 1 || uncited[0].Key != "felsenstein2004" {
+		t.Errorf("Uncited() = %+v, want felsenstein2004", uncited)
+	}
+}
+
+func TestVerifyCitationKey(t *testing.T) {
+	b := &Bibliography{byKey: make(map[string][]int)}
+	b.add("refs.bib", "@article{knuth84,\n}\n")
+	resolver := &countingResolver{known: map[string]bool{"tavare1986": true}}
+	bibs := []*Bibliography{nil, b}
+
+	tests := []struct {
+		key    string
+		status CheckStatus
+		source string
+	}{
+		{"knuth84", CheckStatusPass, "refs.bib"},
+		{"tavare1986", CheckStatusPass, "counting"},
+		{"missing", CheckStatusFail, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			result := VerifyCitationKey(bibs, resolver, tt.key, "test.qmd", 1, "@"+tt.key)
+			if result.Status != tt.status {
+				t.Errorf("status = %s, want %s (%s)", result.Status, tt.status, result.Message)
+			}
+			if result.Details.Citation.Source != tt.source {
+				t.Errorf("source = %q, want %q", result.Details.Citation.Source, tt.source)
+			}
+		})
+	}
+
+	if resolver.calls != 2 {
+		t.Errorf("expected bibliography hits to skip the resolver, got %d resolver calls", resolver.calls)
+	}
+}
+
+func TestVerifyFiles_Bibliography(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	bibPath := filepath.Join(tmpDir, "references.bib")
+	if err := os.WriteFile(bibPath, []byte("@article{knuth84,\n}\n@book{unused,\n}\n@book{listed,\n}\n"), 0644); err != nil {
+		t.Fatalf("write bibliography: %v", err)
+	}
+	localBib := filepath.Join(tmpDir, "local.bib")
+	if err := os.WriteFile(localBib, []byte("@misc{local2020,\n}\n"), 0644); err != nil {
+		t.Fatalf("write bibliography: %v", err)
+	}
+
+	qmdPath := filepath.Join(tmpDir, "chapter.qmd")
+	content := `---
+title: Chapter
+bibliography: local.bib
+nocite: |
+  @listed
+---
+
+See @knuth84 [p. 3] and [-@local2020; @nobody]. A cross-reference to @sec-intro is not a citation.
+`
+	if err := os.WriteFile(qmdPath, []byte(content), 0644); err != nil {
+		t.Fatalf("write qmd: %v", err)
+	}
+
+	b, err := LoadBibliography(bibPath)
+	if err != nil {
+		t.Fatalf("LoadBibliography failed: %v", err)
+	}
+
+	verifyStatuses := func(project *quarto.Project) map[string]CheckStatus {
+		opts := Options{
+			UseLLM:       false,
+			Resolver:     &countingResolver{},
+			Mirror:       NewRepoMirror(filepath.Join(tmpDir, "repos")),
+			Bibliography: b,
+			Project:      project,
+		}
+		report, err := VerifyFiles([]string{qmdPath}, opts)
+		if err != nil {
+			t.Fatalf("VerifyFiles failed: %v", err)
+		}
+		statuses := make(map[string]CheckStatus)
+		for _, r := range report.Results {
+			if r.Details.Citation != nil {
+				statuses[string(r.CheckType)+":"+r.Details.Citation.PaperID] = r.Status
+			}
+		}
+		return statuses
+	}
+
+	// Entries may be cited in chapters that were not checked, so uncited
+	// entries are only reported for the whole project
+	tests := []struct {
+		name    string
+		project *quarto.Project
+		want    map[string]CheckStatus
+	}{
+		{"files", nil, map[string]CheckStatus{
+			"citation:knuth84":     CheckStatusPass,
+			"citation:local2020":   CheckStatusPass,
+			"citation:nobody":      CheckStatusFail,
+			"bibliography:unused":  "",
+			"citation:sec-intro":   "",
+			"bibliography:knuth84": "",
+		}},
+		{"project", &quarto.Project{Dir: tmpDir, Chapters: []string{qmdPath}}, map[string]CheckStatus{
+			"citation:knuth84":     CheckStatusPass,
+			"bibliography:unused":  CheckStatusWarn,
+			"bibliography:knuth84": "",
+			"bibliography:listed":  "",
+		}},
+		{"project nocite all", &quarto.Project{Dir: tmpDir, Chapters: []string{qmdPath}, Nocite: []string{"*"}}, map[string]CheckStatus{
+			"bibliography:unused": "",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statuses := verifyStatuses(tt.project)
+			for key, status := range tt.want {
+				if statuses[key] != status {
+					t.Errorf("%s: status = %q, want %q", key, statuses[key], status)
+				}
+			}
+		})
+	}
+}
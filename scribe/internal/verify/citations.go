@@ -1,11 +1,8 @@
 package verify
 
 import (
-	"bytes"
 	"fmt"
-	"os/exec"
 	"regexp"
-	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -28,8 +25,8 @@ func ExtractCitations(content string) []string {
 	return citations
 }
 
-// VerifyCitation checks if a paper ID exists in bipartite.
-func VerifyCitation(paperID string, file string, line int, text string) VerificationResult {
+// VerifyCitation checks if a paper ID resolves with the given resolver.
+func VerifyCitation(resolver CitationResolver, paperID string, file string, line int, text string) VerificationResult {
 	result := VerificationResult{
 		CheckID:   uuid.New().String(),
 		CheckType: CheckTypeCitation,
@@ -41,8 +38,7 @@ func VerifyCitation(paperID string, file string, line int, text string) Verifica
 		CheckedAt: time.Now(),
 	}
 
-	// Try to look up the paper in bipartite
-	resolved, err := lookupPaperInBipartite(paperID)
+	record, err := resolver.Resolve(paperID)
 	if err != nil {
 		result.Status = CheckStatusFail
 		result.Message = fmt.Sprintf("Failed to verify paper ID %q: %v", paperID, err)
@@ -55,12 +51,13 @@ func VerifyCitation(paperID string, file string, line int, text string) Verifica
 		return result
 	}
 
+	resolved := record != nil
 	if resolved {
 		result.Status = CheckStatusPass
 		result.Message = fmt.Sprintf("Paper ID %q resolved successfully", paperID)
 	} else {
 		result.Status = CheckStatusFail
-		result.Message = fmt.Sprintf("Paper ID %q not found in bipartite", paperID)
+		result.Message = fmt.Sprintf("Paper ID %q not found in %s", paperID, resolver.Name())
 	}
 
 	result.Details = VerificationDetails{
@@ -69,30 +66,16 @@ func VerifyCitation(paperID string, file string, line int, text string) Verifica
 			Resolved: resolved,
 		},
 	}
-
-	return result
-}
-
-// lookupPaperInBipartite checks if a paper exists in the bipartite library.
-func lookupPaperInBipartite(paperID string) (bool, error) {
-	// Check if bip CLI is available
-	if _, err := exec.LookPath("bip"); err != nil {
-		return false, fmt.Errorf("bip CLI not found: %w", err)
+	if resolved {
+		result.Details.Citation.Source = record.Source
 	}
 
-	// Try to get the paper from bipartite
-	cmd := exec.Command("bip", "s2", "get", paperID)
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
-		// Check if it's a "not found" error vs other error
-		stderrStr := stderr.String()
-		if strings.Contains(stderrStr, "not found") || strings.Contains(stderrStr, "no such") {
-			return false, nil
-		}
-		return false, fmt.Errorf("bip lookup failed: %s", stderrStr)
+	// Cite papers by their canonical ID rather than an alias or S2 ID
+	if resolved && record.ID != "" && record.ID != paperID {
+		result.Details.Citation.CanonicalID = record.ID
+		result.Message = fmt.Sprintf("Paper ID %q is an alias of %q", paperID, record.ID)
+		result.Fix = &Fix{Old: "@paper:" + paperID, New: "@paper:" + record.ID, Reason: "canonical paper ID"}
 	}
 
-	return true, nil
+	return result
 }
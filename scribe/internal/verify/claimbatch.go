@@ -0,0 +1,151 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/llm"
+)
+
+// DefaultClaimBatchSize is how many sentences are analyzed per LLM request.
+const DefaultClaimBatchSize = 20
+
+// claimBatchWorkers is how many batches are analyzed at once.
+const claimBatchWorkers = 4
+
+// ClaimAnalyzer asks an LLM whether sentences the heuristics are unsure
+// about need a citation. Sentences are analyzed in batches before the checks
+// run; sentences whose batch fails or leaves them out fall back to one
+// request each.
+type ClaimAnalyzer struct {
+	client    *llm.Client
+	batchSize int
+
+	mu      sync.Mutex
+	results map[string]claimAnalysis // by sentence
+}
+
+// claimAnalysis is the outcome of analyzing one sentence.
+type claimAnalysis struct {
+	result *llm.ClaimAnalysisResult
+	err    error
+}
+
+// NewClaimAnalyzer creates a ClaimAnalyzer. A batchSize of 0 uses
+// DefaultClaimBatchSize; 1 analyzes every sentence separately.
+func NewClaimAnalyzer(client *llm.Client, batchSize int) *ClaimAnalyzer {
+	if batchSize <= 0 {
+		batchSize = DefaultClaimBatchSize
+	}
+	return &ClaimAnalyzer{client: client, batchSize: batchSize, results: make(map[string]claimAnalysis)}
+}
+
+// identity names the provider, model, and prompts behind the analyses, so
+// that cached claim results are not reused after any of them changes. It is
+// "" for a nil analyzer, whose claims are judged by the heuristics alone.
+func (a *ClaimAnalyzer) identity() string {
+	if a == nil {
+		return ""
+	}
+	provider := a.client.Provider()
+	return strings.Join([]string{provider.Name(), provider.Model(), llm.ClaimPromptVersion, llm.ClaimBatchPromptVersion}, "\x00")
+}
+
+// Usage returns the LLM usage of the analyses so far.
+func (a *ClaimAnalyzer) Usage() llm.Usage {
+	return a.client.Usage()
+}
+
+// Prefetch analyzes sentences in batches, so that Analyze answers them
+// without another request. Item IDs are the sentences' document positions,
+// unique across batches, so verdicts are matched to the sentence they name.
+func (a *ClaimAnalyzer) Prefetch(items []llm.ClaimItem) {
+	var pending []llm.ClaimItem
+	seen := make(map[string]bool)
+	a.mu.Lock()
+	for _, item := range items {
+		if _, done := a.results[item.Sentence]; !done && !seen[item.Sentence] {
+			seen[item.Sentence] = true
+			pending = append(pending, item)
+		}
+	}
+	a.mu.Unlock()
+	if a.batchSize == 1 {
+		return
+	}
+
+	var batches [][]llm.ClaimItem
+	for start := 0; start < len(pending); start += a.batchSize {
+		batches = append(batches, pending[start:min(start+a.batchSize, len(pending))])
+	}
+
+	jobs := make(chan []llm.ClaimItem)
+	var wg sync.WaitGroup
+	for w := 0; w < claimBatchWorkers && w < len(batches); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range jobs {
+				a.analyzeBatch(batch)
+			}
+		}()
+	}
+	for _, batch := range batches {
+		jobs <- batch
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// analyzeBatch analyzes one batch, falling back to single requests for
+// sentences without a verdict. The fallback is only worth it when the batch
+// response could not be parsed: if the provider failed, every sentence
+// records that error instead of costing another request.
+func (a *ClaimAnalyzer) analyzeBatch(batch []llm.ClaimItem) {
+	results, err := a.client.AnalyzeClaims(context.Background(), batch)
+	if err != nil && !errors.Is(err, llm.ErrInvalidResponse) {
+		for _, item := range batch {
+			a.store(item.Sentence, claimAnalysis{err: err})
+		}
+		return
+	}
+	for _, item := range batch {
+		if result, ok := results[item.ID]; ok {
+			a.store(item.Sentence, claimAnalysis{result: result})
+			continue
+		}
+		result, err := a.client.AnalyzeClaim(context.Background(), item.Sentence)
+		a.store(item.Sentence, claimAnalysis{result: result, err: err})
+	}
+}
+
+func (a *ClaimAnalyzer) store(sentence string, analysis claimAnalysis) {
+	a.mu.Lock()
+	a.results[sentence] = analysis
+	a.mu.Unlock()
+}
+
+// Analyze returns the LLM's analysis of a sentence, prefetched or from a
+// new request.
+func (a *ClaimAnalyzer) Analyze(sentence string) (*llm.ClaimAnalysisResult, error) {
+	a.mu.Lock()
+	analysis, ok := a.results[sentence]
+	a.mu.Unlock()
+	if ok {
+		return analysis.result, analysis.err
+	}
+	result, err := a.client.AnalyzeClaim(context.Background(), sentence)
+	a.store(sentence, claimAnalysis{result: result, err: err})
+	return result, err
+}
+
+// defaultAnalyzer serves VerifyClaim, which has no options to carry one.
+var defaultAnalyzer = sync.OnceValue(func() *ClaimAnalyzer {
+	client, err := llm.NewClient()
+	if err != nil {
+		return nil
+	}
+	return NewClaimAnalyzer(client, 1)
+})
@@ -0,0 +1,208 @@
+package verify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/llm"
+)
+
+// batchIDs returns the sentence IDs listed in a batched claim prompt.
+var batchIDs = regexp.MustCompile(`(?m)^\[([^\]]+)\] Sentence: "([^"]*)"`)
+
+func TestVerifyFiles_BatchesClaims(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "ch.qmd")
+	content := `# Trees
+
+Rooted trees are drawn with the root at the top of the page.
+Most phylogenies in practice are inferred from aligned sequences.
+The branch lengths here are measured in expected substitutions.
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		batchReply   func(prompt string) string
+		wantRequests int // batch requests plus single fallbacks
+		wantVersion  string
+	}{
+		{
+			// The batch leaves out the last sentence, which is asked about on its own
+			name: "partial batch",
+			batchReply: func(prompt string) string {
+				var verdicts []map[string]any
+				for _, m := range batchIDs.FindAllStringSubmatch(prompt, -1) {
+					if strings.Contains(m[2], "branch lengths") {
+						continue
+					}
+					verdicts = append(verdicts, map[string]any{
+						"id": m[1], "needs_citation": strings.Contains(m[2], "Most phylogenies"),
+						"confidence": "medium", "reason": "r", "suggested_action": "add citation",
+					})
+				}
+				data, _ := json.Marshal(verdicts)
+				return "```json\n" + string(data) + "\n```"
+			},
+			wantRequests: 2,
+			wantVersion:  llm.ClaimBatchPromptVersion,
+		},
+		{
+			// Verdicts for IDs the batch did not ask about are ignored, not
+			// attributed to whichever sentence had that place in the batch
+			name: "unknown ids",
+			batchReply: func(string) string {
+				return `[{"id": "s1", "needs_citation": true, "confidence": "high", "reason": "r", "suggested_action": "add citation"}]`
+			},
+			wantRequests: 4,
+			wantVersion:  llm.ClaimPromptVersion,
+		},
+		{
+			name:         "unparseable batch",
+			batchReply:   func(string) string { return "I cannot answer that." },
+			wantRequests: 4,
+			wantVersion:  llm.ClaimPromptVersion,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &llm.Fake{Respond: func(req llm.Request) (string, error) {
+				if req.Schema.Type == "array" {
+					return tt.batchReply(req.Prompt), nil
+				}
+				needs := strings.Contains(req.Prompt, "Most phylogenies")
+				return fmt.Sprintf(`{"needs_citation": %t, "confidence": "high", "reason": "single", "suggested_action": "no action needed"}`, needs), nil
+			}}
+			client := llm.NewClientWithProvider(fake, llm.Config{Backoff: time.Millisecond})
+
+			opts := DefaultOptions()
+			opts.Checks = []CheckType{CheckTypeClaim}
+			opts.Claims = NewClaimAnalyzer(client, 10)
+			report, err := VerifyFiles([]string{path}, opts)
+			if err != nil {
+				t.Fatalf("VerifyFiles: %v", err)
+			}
+
+			if len(report.Results) != 1 || !strings.HasPrefix(report.Results[0].Target.Text, "Most phylogenies") {
+				t.Fatalf("expected one uncited claim, got %+v", report.Results)
+			}
+			if got := report.Results[0].Details.Claim.PromptVersion; got != tt.wantVersion {
+				t.Errorf("prompt version = %q, want %q", got, tt.wantVersion)
+			}
+			requests := fake.Requests()
+			if len(requests) != tt.wantRequests {
+				t.Errorf("got %d LLM requests, want %d", len(requests), tt.wantRequests)
+			}
+			if len(requests) > 0 && (!strings.Contains(requests[0].Prompt, "["+path+":5:1]") || !strings.Contains(requests[0].Prompt, "Paragraph:")) {
+				t.Errorf("first request should batch all sentences with context:\n%s", requests[0].Prompt)
+			}
+			if report.LLMUsage == nil || report.LLMUsage.Requests != tt.wantRequests {
+				t.Errorf("LLMUsage = %+v, want %d requests", report.LLMUsage, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestClaimAnalyzer_Analyze(t *testing.T) {
+	fake := llm.NewFake(`{"needs_citation": true, "confidence": "high", "reason": "numbers", "suggested_action": "add citation"}`)
+	analyzer := NewClaimAnalyzer(llm.NewClientWithProvider(fake, llm.Config{}), 1)
+
+	// Batch size 1 skips prefetching; each sentence is asked about once
+	analyzer.Prefetch([]llm.ClaimItem{{Sentence: "About 90 percent of trees are binary."}})
+	for range 2 {
+		result, err := analyzer.Analyze("About 90 percent of trees are binary.")
+		if err != nil || !result.NeedsCitation {
+			t.Fatalf("Analyze = %+v, %v", result, err)
+		}
+	}
+	if n := len(fake.Requests()); n != 1 {
+		t.Errorf("got %d requests, want 1", n)
+	}
+}
+
+func TestVerifyFiles_ClaimProviderFailure(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "ch.qmd")
+	content := `# Trees
+
+Rooted trees are drawn with the root at the top of the page.
+Most phylogenies in practice are inferred from aligned sequences.
+The branch lengths here are measured in expected substitutions.
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cachePath := filepath.Join(tmpDir, "results.json")
+	run := func(fake *llm.Fake) (VerificationReport, *ClaimAnalyzer) {
+		cache := NewResultCache(cachePath, nil)
+		opts := DefaultOptions()
+		opts.Checks = []CheckType{CheckTypeClaim}
+		opts.Cache = cache
+		opts.Claims = NewClaimAnalyzer(llm.NewClientWithProvider(fake, llm.Config{Retries: 1, Backoff: time.Millisecond}), 10)
+		report, err := VerifyFiles([]string{path}, opts)
+		if err != nil {
+			t.Fatalf("VerifyFiles: %v", err)
+		}
+		if err := cache.Flush(); err != nil {
+			t.Fatalf("Flush: %v", err)
+		}
+		return *report, opts.Claims
+	}
+
+	// A provider that is down costs the batch request and its retry, with
+	// no single-sentence fallbacks
+	down := &llm.Fake{Respond: func(llm.Request) (string, error) {
+		return "", &llm.HTTPError{StatusCode: 503, Body: "unavailable"}
+	}}
+	report, analyzer := run(down)
+	if _, err := analyzer.Analyze("Most phylogenies in practice are inferred from aligned sequences."); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("every sentence in the batch should record the provider error, got %v", err)
+	}
+	if n := len(down.Requests()); n != 2 {
+		t.Errorf("got %d LLM requests, want 2", n)
+	}
+
+	// The unchecked sentences are reported, not passed
+	if report.Summary.Warnings != 3 || report.Summary.Passed != 0 {
+		t.Errorf("summary = %+v, want 3 warnings", report.Summary)
+	}
+	for _, r := range report.Results {
+		if r.Status != CheckStatusWarn || !strings.Contains(r.Message, "not checked") || r.Details.Claim.LLMError == "" {
+			t.Errorf("unchecked claim = %s %q %+v", r.Status, r.Message, r.Details.Claim)
+		}
+	}
+
+	// Sentences that passed on heuristics alone were not cached
+	up := &llm.Fake{Respond: func(req llm.Request) (string, error) {
+		var items []string
+		for _, m := range batchIDs.FindAllStringSubmatch(req.Prompt, -1) {
+			items = append(items, fmt.Sprintf(`{"id": %q, "needs_citation": false, "confidence": "high", "reason": "ok", "suggested_action": "no action needed"}`, m[1]))
+		}
+		return "[" + strings.Join(items, ",") + "]", nil
+	}}
+	if report, _ = run(up); report.Summary.Warnings != 0 {
+		t.Errorf("summary after the outage = %+v, want no warnings", report.Summary)
+	}
+	if n := len(up.Requests()); n != 1 {
+		t.Errorf("got %d LLM requests after the outage, want 1", n)
+	}
+}
@@ -1,12 +1,12 @@
 package verify
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/matsen/phylogenetic-compendium/scribe/internal/llm"
 )
 
 // todoMarkerPattern matches TODO, FIXME, XXX, HACK markers
@@ -33,53 +33,89 @@ func VerifyTodoMarker(marker string, file string, line int, text string) Verific
 	}
 }
 
-// Pattern-based claim detection heuristics
-var (
-	// mustCitePatterns - high confidence claims that need citations
-	mustCitePatterns = []*regexp.Regexp{
+// ClaimPatterns are the regular expressions used to decide whether a sentence
+// is a factual claim that needs a citation.
+type ClaimPatterns struct {
+	MustCite   []*regexp.Regexp // high confidence claims that need citations
+	ShouldCite []*regexp.Regexp // medium confidence claims
+	Exempt     []*regexp.Regexp // sentences that don't need citations
+}
+
+// defaultClaimPatterns are the built-in claim detection heuristics.
+var defaultClaimPatterns = &ClaimPatterns{
+	MustCite: []*regexp.Regexp{
 		regexp.MustCompile(`(?i)\b(\d+%|\d+x|faster|slower|better|worse)\s+(than|compared to)`),
 		regexp.MustCompile(`(?i)\b(discovered|introduced|invented|developed)\s+by`),
 		regexp.MustCompile(`(?i)\b(as shown|as described|according to|as demonstrated)\s+(in|by)`),
 		regexp.MustCompile(`(?i)\bstudies\s+(have\s+)?(shown|demonstrated|found|revealed)`),
-	}
-
-	// shouldCitePatterns - medium confidence claims
-	shouldCitePatterns = []*regexp.Regexp{
+	},
+	ShouldCite: []*regexp.Regexp{
 		regexp.MustCompile(`(?i)\b(causes?|leads?\s+to|results?\s+in)\b`),
 		regexp.MustCompile(`(?i)\b(O\(n|O\(log|complexity\s+of)`),
 		regexp.MustCompile(`(?i)\b(historically|traditionally|originally)\b`),
-	}
-
-	// exemptPatterns - sentences that don't need citations
-	exemptPatterns = []*regexp.Regexp{
+	},
+	Exempt: []*regexp.Regexp{
 		regexp.MustCompile(`(?i)\b(is\s+defined\s+as|refers?\s+to|means?)\b`),
 		regexp.MustCompile(`(?i)\b(for\s+example|e\.g\.|such\s+as|i\.e\.)\b`),
 		regexp.MustCompile(`(?i)\b(in\s+this\s+(section|chapter)|we\s+now|let\s+us)\b`),
 		regexp.MustCompile("^```"), // Code block
+	},
+}
+
+// NewClaimPatterns compiles extra patterns and adds them to the built-in heuristics.
+func NewClaimPatterns(mustCite, shouldCite, exempt []string) (*ClaimPatterns, error) {
+	extend := func(base []*regexp.Regexp, exprs []string) ([]*regexp.Regexp, error) {
+		patterns := append([]*regexp.Regexp{}, base...)
+		for _, expr := range exprs {
+			p, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid claim pattern %q: %w", expr, err)
+			}
+			patterns = append(patterns, p)
+		}
+		return patterns, nil
 	}
-)
+
+	var p ClaimPatterns
+	var err error
+	if p.MustCite, err = extend(defaultClaimPatterns.MustCite, mustCite); err != nil {
+		return nil, err
+	}
+	if p.ShouldCite, err = extend(defaultClaimPatterns.ShouldCite, shouldCite); err != nil {
+		return nil, err
+	}
+	if p.Exempt, err = extend(defaultClaimPatterns.Exempt, exempt); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
 
 // AnalyzeClaimWithHeuristics uses pattern matching to detect claims.
 func AnalyzeClaimWithHeuristics(sentence string) (needsCitation bool, confidence string, reason string) {
+	return defaultClaimPatterns.Analyze(sentence)
+}
+
+// Analyze uses pattern matching to detect claims.
+func (p *ClaimPatterns) Analyze(sentence string) (needsCitation bool, confidence string, reason string) {
 	sentence = strings.TrimSpace(sentence)
 
 	// Check exempt patterns first
-	for _, p := range exemptPatterns {
-		if p.MatchString(sentence) {
+	for _, re := range p.Exempt {
+		if re.MatchString(sentence) {
 			return false, "high", "Sentence is a definition, example, or transitional prose"
 		}
 	}
 
 	// Check must-cite patterns
-	for _, p := range mustCitePatterns {
-		if p.MatchString(sentence) {
+	for _, re := range p.MustCite {
+		if re.MatchString(sentence) {
 			return true, "high", "Sentence contains comparison, attribution, or cites prior work"
 		}
 	}
 
 	// Check should-cite patterns
-	for _, p := range shouldCitePatterns {
-		if p.MatchString(sentence) {
+	for _, re := range p.ShouldCite {
+		if re.MatchString(sentence) {
 			return true, "medium", "Sentence contains causal claim or complexity statement"
 		}
 	}
@@ -89,6 +125,16 @@ func AnalyzeClaimWithHeuristics(sentence string) (needsCitation bool, confidence
 
 // VerifyClaim checks if a sentence is an uncited factual claim.
 func VerifyClaim(sentence string, file string, line int, hasCitation bool, useLLM bool) VerificationResult {
+	var analyzer *ClaimAnalyzer
+	if useLLM && !hasCitation {
+		analyzer = defaultAnalyzer()
+	}
+	return verifyClaim(sentence, file, line, hasCitation, defaultClaimPatterns, analyzer)
+}
+
+// verifyClaim checks a sentence with the given heuristics, asking the
+// analyzer, if any, when they are inconclusive.
+func verifyClaim(sentence string, file string, line int, hasCitation bool, patterns *ClaimPatterns, analyzer *ClaimAnalyzer) VerificationResult {
 	result := VerificationResult{
 		CheckID:   uuid.New().String(),
 		CheckType: CheckTypeClaim,
@@ -115,22 +161,35 @@ func VerifyClaim(sentence string, file string, line int, hasCitation bool, useLL
 	}
 
 	// First try pattern-based heuristics
-	needsCitation, confidence, reason := AnalyzeClaimWithHeuristics(sentence)
+	needsCitation, confidence, reason := patterns.Analyze(sentence)
 
 	// If heuristics are inconclusive and LLM is available, use it
-	if confidence == "low" && useLLM && llm.IsAvailable() {
-		client, err := llm.NewClient()
-		if err == nil {
-			analysis, err := client.AnalyzeClaim(sentence)
-			if err == nil {
-				needsCitation = analysis.NeedsCitation
-				confidence = analysis.Confidence
-				reason = analysis.Reason
-			}
+	var promptVersion, llmError string
+	if confidence == "low" && analyzer != nil {
+		if analysis, err := analyzer.Analyze(sentence); err == nil {
+			needsCitation = analysis.NeedsCitation
+			confidence = analysis.Confidence
+			reason = analysis.Reason
+			promptVersion = analysis.PromptVersion
+		} else {
+			llmError = err.Error()
 		}
 	}
 
-	if needsCitation {
+	switch {
+	case llmError != "":
+		// A failed provider must not pass the sentence as if it were checked
+		result.Status = CheckStatusWarn
+		result.Message = "Claim not checked: " + llmError
+		result.Details = VerificationDetails{
+			Claim: &ClaimDetails{
+				ClaimText:       sentence,
+				Confidence:      confidence,
+				SuggestedAction: "rerun with a working LLM provider",
+				LLMError:        llmError,
+			},
+		}
+	case needsCitation:
 		result.Status = CheckStatusFail
 		result.Message = "Uncited factual claim detected"
 		result.Details = VerificationDetails{
@@ -138,9 +197,10 @@ func VerifyClaim(sentence string, file string, line int, hasCitation bool, useLL
 				ClaimText:       sentence,
 				Confidence:      confidence,
 				SuggestedAction: "add citation",
+				PromptVersion:   promptVersion,
 			},
 		}
-	} else {
+	default:
 		result.Status = CheckStatusPass
 		result.Message = reason
 		result.Details = VerificationDetails{
@@ -148,6 +208,7 @@ func VerifyClaim(sentence string, file string, line int, hasCitation bool, useLL
 				ClaimText:       sentence,
 				Confidence:      confidence,
 				SuggestedAction: "no action needed",
+				PromptVersion:   promptVersion,
 			},
 		}
 	}
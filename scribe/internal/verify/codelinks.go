@@ -1,31 +1,29 @@
 package verify
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
-	"os/exec"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-// estimatedCharsPerLine is a rough estimate for calculating line count from file size.
-// This is unreliable (minified code may have 1000+ chars/line, sparse code 10-20).
-// Used only as a heuristic - line range validation is skipped when unreliable.
-const estimatedCharsPerLine = 30
-
 // githubPermalinkPattern matches GitHub permalink URLs with line numbers
 var githubPermalinkPattern = regexp.MustCompile(`https://github\.com/([^/]+)/([^/]+)/blob/([a-f0-9]+)/([^#]+)#L(\d+)(?:-L(\d+))?`)
 
+// githubBranchLinkPattern matches GitHub blob links with line numbers whose
+// ref may be a branch or tag rather than a commit SHA.
+var githubBranchLinkPattern = regexp.MustCompile(`^https://github\.com/([^/]+)/([^/]+)/blob/([^/]+)/([^#\s]+)#L(\d+)(?:-L(\d+))?$`)
+
 // CodeLinkMatch represents a parsed GitHub permalink.
 type CodeLinkMatch struct {
 	FullURL   string
 	Owner     string
 	Repo      string
 	CommitSHA string
+	Ref       string // branch or tag, for links not pinned to a commit
 	FilePath  string
 	StartLine int
 	EndLine   int
@@ -39,26 +37,93 @@ func ExtractCodeLinks(content string) []CodeLinkMatch {
 		if len(m) < 6 {
 			continue
 		}
-		startLine, _ := strconv.Atoi(m[5])
-		endLine := startLine
-		if len(m) > 6 && m[6] != "" {
-			endLine, _ = strconv.Atoi(m[6])
-		}
-		links = append(links, CodeLinkMatch{
-			FullURL:   m[0],
-			Owner:     m[1],
-			Repo:      m[2],
-			CommitSHA: m[3],
-			FilePath:  m[4],
-			StartLine: startLine,
-			EndLine:   endLine,
-		})
+		links = append(links, codeLinkFromMatch(m))
 	}
 	return links
 }
 
-// VerifyCodeLink checks if a GitHub permalink points to valid code.
-func VerifyCodeLink(link CodeLinkMatch, file string, line int, text string) VerificationResult {
+// codeLinkFromMatch builds a CodeLinkMatch from githubPermalinkPattern submatches.
+func codeLinkFromMatch(m []string) CodeLinkMatch {
+	startLine, _ := strconv.Atoi(m[5])
+	endLine := startLine
+	if len(m) > 6 && m[6] != "" {
+		endLine, _ = strconv.Atoi(m[6])
+	}
+	return CodeLinkMatch{
+		FullURL:   m[0],
+		Owner:     m[1],
+		Repo:      m[2],
+		CommitSHA: m[3],
+		FilePath:  m[4],
+		StartLine: startLine,
+		EndLine:   endLine,
+	}
+}
+
+// ParseCodeLink parses a single URL as a GitHub permalink.
+func ParseCodeLink(url string) (CodeLinkMatch, bool) {
+	m := githubPermalinkPattern.FindStringSubmatch(url)
+	if m == nil || m[0] != url {
+		return CodeLinkMatch{}, false
+	}
+	return codeLinkFromMatch(m), true
+}
+
+// ParseBranchLink parses a GitHub blob link that points at a branch or tag
+// (e.g. blob/main) instead of a commit SHA.
+func ParseBranchLink(url string) (CodeLinkMatch, bool) {
+	if _, ok := ParseCodeLink(url); ok {
+		return CodeLinkMatch{}, false
+	}
+	m := githubBranchLinkPattern.FindStringSubmatch(url)
+	if m == nil {
+		return CodeLinkMatch{}, false
+	}
+	link := codeLinkFromMatch(m)
+	link.Ref, link.CommitSHA = link.CommitSHA, ""
+	return link, true
+}
+
+// Pin returns the link rewritten to point at a commit instead of its branch or tag.
+func (l CodeLinkMatch) Pin(sha string) CodeLinkMatch {
+	pinned := l
+	pinned.FullURL = strings.Replace(l.FullURL, "/blob/"+l.Ref+"/", "/blob/"+sha+"/", 1)
+	pinned.CommitSHA = sha
+	pinned.Ref = ""
+	return pinned
+}
+
+// VerifyBranchLink checks a code link that names a branch or tag. The link
+// is resolved to the ref's current commit; if the code is there, the result
+// is a warning with a fix that pins the link to that commit.
+func VerifyBranchLink(mirror *RepoMirror, link CodeLinkMatch, file string, line int, text string) VerificationResult {
+	sha, err := mirror.ResolveRef(link.Owner, link.Repo, link.Ref)
+	if err != nil {
+		return VerificationResult{
+			CheckID:   uuid.New().String(),
+			CheckType: CheckTypeCodeLink,
+			Target:    VerificationTarget{File: file, Line: line, Text: text},
+			Status:    CheckStatusWarn,
+			Message:   fmt.Sprintf("Cannot pin code link %q: %v", link.FullURL, err),
+			Details:   VerificationDetails{CodeLink: &CodeLinkDetails{Permalink: link.FullURL}},
+			CheckedAt: time.Now(),
+		}
+	}
+
+	pinned := link.Pin(sha)
+	result := VerifyCodeLink(mirror, pinned, file, line, text)
+	result.Details.CodeLink.Permalink = link.FullURL
+	if result.Status == CheckStatusPass {
+		result.Status = CheckStatusWarn
+		result.Message = fmt.Sprintf("Code link %q points at %q, not a commit; pin it to %s", link.FullURL, link.Ref, shortSHA(sha))
+		result.Fix = &Fix{Old: link.FullURL, New: pinned.FullURL, Reason: "pin to commit " + shortSHA(sha)}
+	}
+	return result
+}
+
+// VerifyCodeLink checks if a GitHub permalink points to valid code (FR-003).
+// The commit, file, and line range are checked against a local mirror of the repository.
+func VerifyCodeLink(mirror *RepoMirror, link CodeLinkMatch, file string, line int, text string) VerificationResult {
 	result := VerificationResult{
 		CheckID:   uuid.New().String(),
 		CheckType: CheckTypeCodeLink,
@@ -70,109 +135,45 @@ func VerifyCodeLink(link CodeLinkMatch, file string, line int, text string) Veri
 		CheckedAt: time.Now(),
 	}
 
-	// Check if gh CLI is available
-	if _, err := exec.LookPath("gh"); err != nil {
-		result.Status = CheckStatusWarn
-		result.Message = fmt.Sprintf("Cannot verify code link %q: gh CLI not found", link.FullURL)
-		result.Details = VerificationDetails{
-			CodeLink: &CodeLinkDetails{
-				Permalink:      link.FullURL,
-				FileExists:     false,
-				LineRangeValid: false,
-			},
-		}
-		return result
-	}
+	details := &CodeLinkDetails{Permalink: link.FullURL}
+	result.Details = VerificationDetails{CodeLink: details}
 
-	// Check if the file exists at the given commit
-	fileExists, estimatedLineCount, isEstimated, err := checkFileExistsAtCommit(link.Owner, link.Repo, link.CommitSHA, link.FilePath)
+	res, err := mirror.ResolveCodeLink(link)
 	if err != nil {
-		result.Status = CheckStatusFail
-		result.Message = fmt.Sprintf("Failed to verify code link %q: %v", link.FullURL, err)
-		result.Details = VerificationDetails{
-			CodeLink: &CodeLinkDetails{
-				Permalink:      link.FullURL,
-				FileExists:     false,
-				LineRangeValid: false,
-			},
-		}
+		result.Status = CheckStatusWarn
+		result.Message = fmt.Sprintf("Cannot verify code link %q: %v", link.FullURL, err)
 		return result
 	}
 
-	// Determine line range validity
-	// If line count is estimated (unreliable), skip line range validation and warn
-	lineRangeValid := false
-	lineRangeSkipped := false
-	if fileExists {
-		if isEstimated {
-			// Can't reliably validate line ranges with estimated counts
-			lineRangeSkipped = true
-		} else if estimatedLineCount > 0 {
-			lineRangeValid = link.StartLine <= estimatedLineCount && link.EndLine <= estimatedLineCount
-		}
-	}
+	details.CommitExists = res.CommitExists
+	details.FileExists = res.FileExists
+	details.LineCount = res.LineCount
+	details.Snippet = res.Snippet
+	details.LineRangeValid = res.FileExists && link.StartLine >= 1 &&
+		link.StartLine <= link.EndLine && link.EndLine <= res.LineCount
 
-	if !fileExists {
+	switch {
+	case !res.CommitExists:
 		result.Status = CheckStatusFail
-		result.Message = fmt.Sprintf("File %q does not exist at commit %s", link.FilePath, link.CommitSHA[:8])
-	} else if lineRangeSkipped {
-		// File exists but we can't verify line range - warn instead of false positive
-		result.Status = CheckStatusWarn
-		result.Message = fmt.Sprintf("Code link %q: file exists but line range L%d-L%d could not be verified (line count estimated)", link.FullURL, link.StartLine, link.EndLine)
-		lineRangeValid = true // Mark as valid for details since we couldn't check
-	} else if !lineRangeValid {
+		result.Message = fmt.Sprintf("Commit %s does not exist in %s/%s", shortSHA(link.CommitSHA), link.Owner, link.Repo)
+	case !res.FileExists:
 		result.Status = CheckStatusFail
-		result.Message = fmt.Sprintf("Line range L%d-L%d exceeds file length (%d lines)", link.StartLine, link.EndLine, estimatedLineCount)
-	} else {
+		result.Message = fmt.Sprintf("File %q does not exist at commit %s", link.FilePath, shortSHA(link.CommitSHA))
+	case !details.LineRangeValid:
+		result.Status = CheckStatusFail
+		result.Message = fmt.Sprintf("Line range L%d-L%d exceeds file length (%d lines)", link.StartLine, link.EndLine, res.LineCount)
+	default:
 		result.Status = CheckStatusPass
 		result.Message = fmt.Sprintf("Code link %q is valid", link.FullURL)
 	}
 
-	result.Details = VerificationDetails{
-		CodeLink: &CodeLinkDetails{
-			Permalink:      link.FullURL,
-			FileExists:     fileExists,
-			LineRangeValid: lineRangeValid,
-		},
-	}
-
 	return result
 }
 
-// checkFileExistsAtCommit checks if a file exists at a specific commit.
-// Returns: exists, estimatedLineCount, isEstimated (true if line count is unreliable), error.
-// Line count is estimated from file size and is unreliable - callers should treat it as a hint only.
-func checkFileExistsAtCommit(owner, repo, sha, filePath string) (exists bool, lineCount int, isEstimated bool, err error) {
-	// Use gh api to get file content
-	apiPath := fmt.Sprintf("repos/%s/%s/contents/%s?ref=%s", owner, repo, filePath, sha)
-	cmd := exec.Command("gh", "api", apiPath)
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
-		// Check if it's a 404 (file not found)
-		if bytes.Contains(stderr.Bytes(), []byte("404")) || bytes.Contains(stderr.Bytes(), []byte("Not Found")) {
-			return false, 0, false, nil
-		}
-		return false, 0, false, fmt.Errorf("gh api error: %s", stderr.String())
+// shortSHA abbreviates a commit SHA for messages.
+func shortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
 	}
-
-	// Parse the response to get the file size (as a proxy for existence)
-	var response struct {
-		Size int `json:"size"`
-	}
-	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
-		return false, 0, false, fmt.Errorf("parse response: %w", err)
-	}
-
-	// File exists, estimate line count from size
-	// This is unreliable - minified files may have very few lines, sparse code many
-	estimatedLines := response.Size / estimatedCharsPerLine
-	if estimatedLines < 1 {
-		estimatedLines = 1
-	}
-
-	// Always mark as estimated since we're using a heuristic
-	return true, estimatedLines, true, nil
+	return sha
 }
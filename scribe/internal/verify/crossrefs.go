@@ -0,0 +1,201 @@
+package verify
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/qmd"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/quarto"
+)
+
+// labelSite is where a label is defined.
+type labelSite struct {
+	file string
+	line int
+}
+
+// crossRefIndex holds the labels, anchors, and references of a set of
+// documents, so that references can be checked across files.
+type crossRefIndex struct {
+	project    *quarto.Project            // nil when the files are not a whole project
+	labels     map[string][]labelSite     // label -> definitions
+	anchors    map[string]map[string]bool // cleaned file path -> anchor IDs
+	referenced map[string]bool            // labels used by @refs or #anchor links
+}
+
+// newCrossRefIndex indexes the labels and references in docs.
+func newCrossRefIndex(docs []*qmd.Document, project *quarto.Project) *crossRefIndex {
+	index := &crossRefIndex{
+		project:    project,
+		labels:     make(map[string][]labelSite),
+		anchors:    make(map[string]map[string]bool),
+		referenced: make(map[string]bool),
+	}
+	for _, doc := range docs {
+		index.anchors[filepath.Clean(doc.Path)] = doc.Anchors()
+		for _, label := range doc.Labels {
+			index.labels[label.ID] = append(index.labels[label.ID], labelSite{file: doc.Path, line: label.Span.Start.Line})
+		}
+		for _, ref := range doc.CrossRefs {
+			index.referenced[ref.Key] = true
+		}
+		for _, link := range doc.Links {
+			if _, anchor, ok := strings.Cut(link.URL, "#"); ok && isRelativeLink(link.URL) {
+				index.referenced[anchor] = true
+			}
+		}
+	}
+	return index
+}
+
+// anchorsOf returns the anchors of a document, parsing it if it was not indexed.
+func (x *crossRefIndex) anchorsOf(path string) (map[string]bool, error) {
+	path = filepath.Clean(path)
+	if anchors, ok := x.anchors[path]; ok {
+		return anchors, nil
+	}
+	doc, err := qmd.ParseFile(path)
+	if err != nil {
+		return nil, err
+	}
+	x.anchors[path] = doc.Anchors()
+	return x.anchors[path], nil
+}
+
+// results checks the labels, cross-references, and relative links of doc.
+// Dangling references and unreferenced labels are only reported when the
+// index covers a whole project; otherwise they may live in other files.
+func (x *crossRefIndex) results(doc *qmd.Document) []VerificationResult {
+	var results []VerificationResult
+	add := func(line int, status CheckStatus, details CrossRefDetails, message string) {
+		results = append(results, VerificationResult{
+			CheckID:   uuid.New().String(),
+			CheckType: CheckTypeCrossRef,
+			Target:    VerificationTarget{File: doc.Path, Line: line, Text: doc.Line(line)},
+			Status:    status,
+			Message:   message,
+			Details:   VerificationDetails{CrossRef: &details},
+			CheckedAt: time.Now(),
+		})
+	}
+
+	for _, label := range doc.Labels {
+		line := label.Span.Start.Line
+		if sites := x.labels[label.ID]; len(sites) > 1 {
+			var others []string
+			for _, site := range sites {
+				if site.file != doc.Path || site.line != line {
+					others = append(others, fmt.Sprintf("%s:%d", site.file, site.line))
+				}
+			}
+			add(line, CheckStatusFail, CrossRefDetails{Problem: "duplicate", Label: label.ID},
+				fmt.Sprintf("Label #%s is defined more than once (also at %s)", label.ID, strings.Join(others, ", ")))
+		}
+		if x.project != nil && qmd.IsCrossRef(label.ID) && !x.referenced[label.ID] {
+			add(line, CheckStatusWarn, CrossRefDetails{Problem: "unreferenced", Label: label.ID},
+				fmt.Sprintf("Label #%s is never referenced", label.ID))
+		}
+	}
+
+	if x.project != nil {
+		for _, ref := range doc.CrossRefs {
+			if len(x.labels[ref.Key]) == 0 {
+				add(ref.Span.Start.Line, CheckStatusFail, CrossRefDetails{Problem: "dangling", Label: ref.Key},
+					fmt.Sprintf("Cross-reference @%s is not defined in any chapter", ref.Key))
+			}
+		}
+	}
+
+	for _, link := range doc.Links {
+		if !isRelativeLink(link.URL) {
+			continue
+		}
+		if problem, msg := x.checkLink(doc, link.URL); problem != "" {
+			add(link.Span.Start.Line, CheckStatusFail, CrossRefDetails{Problem: problem, Link: link.URL}, msg)
+		}
+	}
+
+	return results
+}
+
+// checkLink checks that a relative link's file, and its #anchor if any,
+// exist. It returns the problem and a failure message, both empty if the link is valid.
+func (x *crossRefIndex) checkLink(doc *qmd.Document, link string) (string, string) {
+	rawPath, anchor, _ := strings.Cut(link, "#")
+	rawPath, _, _ = strings.Cut(rawPath, "?")
+	path, err := url.PathUnescape(rawPath)
+	if err != nil {
+		path = rawPath
+	}
+
+	target := doc.Path
+	switch {
+	case path == "":
+		// #anchor within this document
+	case strings.HasPrefix(path, "/"):
+		if x.project == nil {
+			return "", "" // project-relative; the root is unknown
+		}
+		target = filepath.Join(x.project.Dir, path)
+	default:
+		target = filepath.Join(filepath.Dir(doc.Path), path)
+	}
+
+	if path != "" {
+		resolved, ok := resolveLinkTarget(target)
+		if !ok {
+			return "missing-file", fmt.Sprintf("Link %q points at a missing file", link)
+		}
+		target = resolved
+	}
+
+	if anchor == "" || !isDocumentPath(target) {
+		return "", ""
+	}
+	anchors, err := x.anchorsOf(target)
+	if err != nil {
+		return "missing-file", fmt.Sprintf("Link %q: %v", link, err)
+	}
+	if !anchors[anchor] {
+		return "missing-anchor", fmt.Sprintf("Link %q points at a missing heading or label #%s", link, anchor)
+	}
+	return "", ""
+}
+
+// resolveLinkTarget finds the file a link refers to. Links to rendered
+// .html pages are matched to their .qmd or .md source.
+func resolveLinkTarget(path string) (string, bool) {
+	candidates := []string{path}
+	if base, ok := strings.CutSuffix(path, ".html"); ok {
+		candidates = append(candidates, base+".qmd", base+".md")
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// isRelativeLink reports whether a link points inside the project rather
+// than at a URL with a scheme such as https: or mailto:.
+func isRelativeLink(link string) bool {
+	if link == "" {
+		return false
+	}
+	if i := strings.IndexAny(link, ":/#?"); i >= 0 && link[i] == ':' {
+		return false
+	}
+	return true
+}
+
+// isDocumentPath reports whether path is a Markdown source with anchors.
+func isDocumentPath(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".qmd" || ext == ".md"
+}
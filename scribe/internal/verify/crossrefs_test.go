@@ -0,0 +1,138 @@
+package verify
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/quarto"
+)
+
+func TestVerifyFiles_CrossRefs(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	files := map[string]string{
+		"intro.qmd":  "# Introduction {#sec-intro}\n\nSee @sec-models.\n",
+		"models.qmd": "# Models {#sec-models}\n\nAs in @sec-intro and @fig-missing.\n",
+	}
+	var paths []string
+	for _, name := range []string{"intro.qmd", "models.qmd"} {
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte(files[name]), 0644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		paths = append(paths, path)
+	}
+
+	opts := Options{Checks: []CheckType{CheckTypeCrossRef}, Project: &quarto.Project{Dir: tmpDir}}
+	report, err := VerifyFiles(paths, opts)
+	if err != nil {
+		t.Fatalf("VerifyFiles failed: %v", err)
+	}
+	if len(report.Results) != 1 {
+		t.Fatalf("got %d results, want 1: %+v", len(report.Results), report.Results)
+	}
+	result := report.Results[0]
+	if result.Target.File != paths[1] || result.Target.Line != 3 || !strings.Contains(result.Message, "@fig-missing") {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	// Without a project, cross-file labels are unknown and not checked
+	report, err = VerifyFiles(paths[1:], Options{Checks: []CheckType{CheckTypeCrossRef}})
+	if err != nil {
+		t.Fatalf("VerifyFiles failed: %v", err)
+	}
+	if len(report.Results) != 0 {
+		t.Errorf("expected no cross-ref results outside a project, got %+v", report.Results)
+	}
+}
+
+func TestVerifyFiles_LabelsAndLinks(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	files := map[string]string{
+		"a.qmd": `# Alpha {#sec-alpha}
+
+## Rate Variation
+
+![Tree](tree.png){#fig-tree}
+
+See [rates](b.html#gamma-rates), [beta](b.qmd#sec-beta), [here](#rate-variation),
+[mail](mailto:me@example.com), and [docs](guide/missing.qmd).
+`,
+		"b.qmd": `# Beta {#sec-beta}
+
+## Gamma Rates
+
+Text [back](a.qmd#nowhere) and [up](#gamma-rates). Ref @sec-alpha.
+
+# Again {#sec-beta}
+`,
+		"tree.png": "",
+	}
+	var paths []string
+	for _, name := range []string{"a.qmd", "b.qmd", "tree.png"} {
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte(files[name]), 0644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		paths = append(paths, path)
+	}
+
+	opts := Options{Checks: []CheckType{CheckTypeCrossRef}, Project: &quarto.Project{Dir: tmpDir}}
+	report, err := VerifyFiles(paths[:2], opts)
+	if err != nil {
+		t.Fatalf("VerifyFiles failed: %v", err)
+	}
+
+	type finding struct {
+		file   string
+		line   int
+		status CheckStatus
+		text   string
+	}
+	want := []finding{
+		{"a.qmd", 5, CheckStatusWarn, "#fig-tree is never referenced"},
+		{"a.qmd", 8, CheckStatusFail, `"guide/missing.qmd" points at a missing file`},
+		{"b.qmd", 1, CheckStatusFail, "#sec-beta is defined more than once"},
+		{"b.qmd", 7, CheckStatusFail, "#sec-beta is defined more than once"},
+		{"b.qmd", 5, CheckStatusFail, "missing heading or label #nowhere"},
+	}
+	if len(report.Results) != len(want) {
+		t.Fatalf("got %d results, want %d: %+v", len(report.Results), len(want), report.Results)
+	}
+	for i, w := range want {
+		r := report.Results[i]
+		if filepath.Base(r.Target.File) != w.file || r.Target.Line != w.line || r.Status != w.status || !strings.Contains(r.Message, w.text) {
+			t.Errorf("result %d = %s:%d %s %q; want %+v", i, filepath.Base(r.Target.File), r.Target.Line, r.Status, r.Message, w)
+		}
+	}
+}
+
+func TestIsRelativeLink(t *testing.T) {
+	tests := []struct {
+		link     string
+		relative bool
+	}{
+		{"intro.qmd#sec-x", true},
+		{"#top", true},
+		{"../a/b.qmd", true},
+		{"https://example.com", false},
+		{"mailto:me@example.com", false},
+		{"docs/a:b.qmd", true},
+	}
+	for _, tt := range tests {
+		if got := isRelativeLink(tt.link); got != tt.relative {
+			t.Errorf("isRelativeLink(%q) = %v, want %v", tt.link, got, tt.relative)
+		}
+	}
+}
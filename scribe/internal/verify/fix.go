@@ -0,0 +1,186 @@
+package verify
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Fix is a safe, mechanical rewrite that resolves a finding: Old is replaced
+// by New on the result's target line and any lines it repeats on.
+type Fix struct {
+	Old     string `json:"old"`
+	New     string `json:"new"`
+	Reason  string `json:"reason"`
+	Applied bool   `json:"applied,omitempty"` // set once the rewrite has been written
+}
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// FileFix holds the rewrites planned for one file.
+type FileFix struct {
+	File     string
+	Original []string
+	Fixed    []string
+	results  []int // indexes into the report's results
+}
+
+// Changed reports whether the fixes alter the file.
+func (f *FileFix) Changed() bool {
+	for i := range f.Original {
+		if f.Original[i] != f.Fixed[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// PlanFixes applies every fix in the report to an in-memory copy of its file.
+// Suppressed findings are left alone. The report itself is not modified.
+func (r *VerificationReport) PlanFixes() ([]*FileFix, error) {
+	byFile := make(map[string]*FileFix)
+	var files []string
+	for i, result := range r.Results {
+		if result.Fix == nil || result.Fix.Applied || result.Status == CheckStatusSuppressed {
+			continue
+		}
+		ff, ok := byFile[result.Target.File]
+		if !ok {
+			data, err := os.ReadFile(result.Target.File)
+			if err != nil {
+				return nil, fmt.Errorf("read %s: %w", result.Target.File, err)
+			}
+			lines := strings.Split(string(data), "\n")
+			ff = &FileFix{File: result.Target.File, Original: lines, Fixed: append([]string(nil), lines...)}
+			byFile[result.Target.File] = ff
+			files = append(files, result.Target.File)
+		}
+
+		fixed := false
+		for _, lineNum := range append([]int{result.Target.Line}, result.Target.Repeats...) {
+			line := lineNum - 1
+			if line < 0 || line >= len(ff.Fixed) {
+				continue
+			}
+			var n int
+			ff.Fixed[line], n = replaceToken(ff.Fixed[line], result.Fix.Old, result.Fix.New)
+			// A repeated finding on the same line was already rewritten by the first fix
+			if n > 0 || strings.Contains(ff.Fixed[line], result.Fix.New) {
+				fixed = true
+			}
+		}
+		if fixed {
+			ff.results = append(ff.results, i)
+		}
+	}
+
+	sort.Strings(files)
+	fixes := make([]*FileFix, 0, len(files))
+	for _, file := range files {
+		fixes = append(fixes, byFile[file])
+	}
+	return fixes, nil
+}
+
+// ApplyFixes writes planned fixes to disk and records them in the report.
+// Fixed failures and warnings get status fixed; passing results stay passing.
+func (r *VerificationReport) ApplyFixes(fixes []*FileFix) error {
+	for _, ff := range fixes {
+		if ff.Changed() {
+			info, err := os.Stat(ff.File)
+			if err != nil {
+				return fmt.Errorf("stat %s: %w", ff.File, err)
+			}
+			if err := os.WriteFile(ff.File, []byte(strings.Join(ff.Fixed, "\n")), info.Mode().Perm()); err != nil {
+				return fmt.Errorf("write %s: %w", ff.File, err)
+			}
+		}
+		for _, i := range ff.results {
+			result := &r.Results[i]
+			// Deduplicated checks share one Fix; record each application separately
+			fix := *result.Fix
+			fix.Applied = true
+			result.Fix = &fix
+			if result.Status == CheckStatusFail || result.Status == CheckStatusWarn || result.Status == CheckStatusBaselined {
+				result.Status = CheckStatusFixed
+			}
+		}
+	}
+	r.rebuildSummary()
+	return nil
+}
+
+// Diff returns the planned changes as a unified diff.
+func (f *FileFix) Diff() string {
+	// Fixes rewrite within lines, so both sides always have the same line count
+	lines := len(f.Original)
+	if lines > 0 && f.Original[lines-1] == "" {
+		lines-- // trailing newline
+	}
+	var changed []int
+	for i := 0; i < lines; i++ {
+		if f.Original[i] != f.Fixed[i] {
+			changed = append(changed, i)
+		}
+	}
+	if len(changed) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", f.File, f.File)
+	for start := 0; start < len(changed); {
+		// Merge changes whose context would overlap into one hunk
+		end := start
+		for end+1 < len(changed) && changed[end+1]-changed[end] <= 2*diffContext {
+			end++
+		}
+		from := max(changed[start]-diffContext, 0)
+		to := min(changed[end]+diffContext, lines-1)
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", from+1, to-from+1, from+1, to-from+1)
+		for i := from; i <= to; i++ {
+			if f.Original[i] == f.Fixed[i] {
+				fmt.Fprintf(&b, " %s\n", f.Original[i])
+				continue
+			}
+			fmt.Fprintf(&b, "-%s\n+%s\n", f.Original[i], f.Fixed[i])
+		}
+		start = end + 1
+	}
+	return b.String()
+}
+
+// replaceToken replaces whole occurrences of old in line, so that fixing
+// @paper:abc leaves @paper:abcd alone. It returns the count replaced.
+func replaceToken(line, old, new string) (string, int) {
+	var b strings.Builder
+	count := 0
+	for {
+		i := strings.Index(line, old)
+		if i < 0 {
+			b.WriteString(line)
+			return b.String(), count
+		}
+		end := i + len(old)
+		b.WriteString(line[:i])
+		if end < len(line) && continuesToken(line[end]) {
+			b.WriteString(old)
+		} else {
+			b.WriteString(new)
+			count++
+		}
+		line = line[end:]
+	}
+}
+
+// continuesToken reports whether c can extend a paper ID or URL path.
+func continuesToken(c byte) bool {
+	return isIDByte(c) || c == '/'
+}
+
+// isIDByte reports whether c can appear in a paper ID.
+func isIDByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
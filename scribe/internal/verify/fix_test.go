@@ -0,0 +1,191 @@
+package verify
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReplaceToken(t *testing.T) {
+	tests := []struct {
+		line, old, new string
+		want           string
+		count          int
+	}{
+		{"See @paper:abc.", "@paper:abc", "@paper:xyz", "See @paper:xyz.", 1},
+		{"See @paper:abcd and @paper:abc", "@paper:abc", "@paper:xyz", "See @paper:abcd and @paper:xyz", 1},
+		{"<http://a.org/x> and http://a.org/x/y", "http://a.org/x", "https://a.org/x", "<https://a.org/x> and http://a.org/x/y", 1},
+		{"nothing here", "@paper:abc", "@paper:xyz", "nothing here", 0},
+	}
+
+	for _, tt := range tests {
+		got, count := replaceToken(tt.line, tt.old, tt.new)
+		if got != tt.want || count != tt.count {
+			t.Errorf("replaceToken(%q) = %q, %d; want %q, %d", tt.line, got, count, tt.want, tt.count)
+		}
+	}
+}
+
+func TestVerifyFiles_FixAlias(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	libPath := filepath.Join(tmpDir, "library.jsonl")
+	lib := `{"id": "tavare1986", "s2_id": "abc123", "aliases": ["Tavare86"]}` + "\n"
+	if err := os.WriteFile(libPath, []byte(lib), 0644); err != nil {
+		t.Fatalf("write library: %v", err)
+	}
+	resolver, err := NewLibraryResolver(libPath)
+	if err != nil {
+		t.Fatalf("NewLibraryResolver failed: %v", err)
+	}
+
+	qmdPath := filepath.Join(tmpDir, "chapter.qmd")
+	content := "# Models\n\nSee @paper:Tavare86.\n\nAlso @paper:abc123 and @paper:tavare1986.\n"
+	if err := os.WriteFile(qmdPath, []byte(content), 0644); err != nil {
+		t.Fatalf("write qmd: %v", err)
+	}
+
+	report, err := VerifyFiles([]string{qmdPath}, Options{Resolver: resolver, Checks: []CheckType{CheckTypeCitation}})
+	if err != nil {
+		t.Fatalf("VerifyFiles failed: %v", err)
+	}
+
+	fixes, err := report.PlanFixes()
+	if err != nil {
+		t.Fatalf("PlanFixes failed: %v", err)
+	}
+	if len(fixes) != 1 {
+		t.Fatalf("got %d file fixes, want 1", len(fixes))
+	}
+	diff := fixes[0].Diff()
+	for _, want := range []string{"-See @paper:Tavare86.", "+See @paper:tavare1986.", "+Also @paper:tavare1986 and @paper:tavare1986.", "@@ -1,5 +1,5 @@"} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("diff missing %q:\n%s", want, diff)
+		}
+	}
+
+	if err := report.ApplyFixes(fixes); err != nil {
+		t.Fatalf("ApplyFixes failed: %v", err)
+	}
+	data, err := os.ReadFile(qmdPath)
+	if err != nil {
+		t.Fatalf("read qmd: %v", err)
+	}
+	want := "# Models\n\nSee @paper:tavare1986.\n\nAlso @paper:tavare1986 and @paper:tavare1986.\n"
+	if string(data) != want {
+		t.Errorf("fixed file = %q, want %q", data, want)
+	}
+	if report.Summary.Fixed != 2 {
+		t.Errorf("Summary.Fixed = %d, want 2", report.Summary.Fixed)
+	}
+}
+
+func TestVerifyFiles_FixAliasEveryOccurrence(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	libPath := filepath.Join(tmpDir, "library.jsonl")
+	lib := `{"id": "smith2020", "aliases": ["oldsmith"]}` + "\n"
+	if err := os.WriteFile(libPath, []byte(lib), 0644); err != nil {
+		t.Fatalf("write library: %v", err)
+	}
+	resolver, err := NewLibraryResolver(libPath)
+	if err != nil {
+		t.Fatalf("NewLibraryResolver failed: %v", err)
+	}
+
+	qmdPath := filepath.Join(tmpDir, "chapter.qmd")
+	content := "# Trees\n\nFirst @paper:oldsmith.\n\nAgain @paper:oldsmith, twice @paper:oldsmith.\n"
+	if err := os.WriteFile(qmdPath, []byte(content), 0644); err != nil {
+		t.Fatalf("write qmd: %v", err)
+	}
+
+	report, err := VerifyFiles([]string{qmdPath}, Options{Resolver: resolver, Checks: []CheckType{CheckTypeCitation}})
+	if err != nil {
+		t.Fatalf("VerifyFiles failed: %v", err)
+	}
+	fixes, err := report.PlanFixes()
+	if err != nil {
+		t.Fatalf("PlanFixes failed: %v", err)
+	}
+	if err := report.ApplyFixes(fixes); err != nil {
+		t.Fatalf("ApplyFixes failed: %v", err)
+	}
+	data, err := os.ReadFile(qmdPath)
+	if err != nil {
+		t.Fatalf("read qmd: %v", err)
+	}
+	want := "# Trees\n\nFirst @paper:smith2020.\n\nAgain @paper:smith2020, twice @paper:smith2020.\n"
+	if string(data) != want {
+		t.Errorf("fixed file = %q, want %q", data, want)
+	}
+}
+
+func TestVerifyURL_PermanentRedirect(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/temporary", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusFound)
+	})
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	result := VerifyURL(server.URL+"/moved#intro", "ch.qmd", 1, "")
+	if result.Fix == nil || result.Fix.New != server.URL+"/new#intro" {
+		t.Errorf("expected fix to the new URL, got %+v", result.Fix)
+	}
+
+	result = VerifyURL(server.URL+"/temporary", "ch.qmd", 1, "")
+	if result.Status != CheckStatusPass || result.Fix != nil {
+		t.Errorf("temporary redirect should pass without a fix, got %s %+v", result.Status, result.Fix)
+	}
+}
+
+func TestVerifyBranchLink(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	repoDir, sha := newTestRemote(t, tmpDir)
+	out, err := exec.Command("git", "-C", repoDir, "rev-parse", "--abbrev-ref", "HEAD").Output()
+	if err != nil {
+		t.Fatalf("git rev-parse: %v", err)
+	}
+	branch := strings.TrimSpace(string(out))
+
+	mirror := NewRepoMirror(filepath.Join(tmpDir, "repos"))
+	mirror.remote = func(owner, repo string) string { return repoDir }
+
+	url := "https://github.com/owner/repo/blob/" + branch + "/src/main.go#L3-L4"
+	link, ok := ParseBranchLink(url)
+	if !ok {
+		t.Fatalf("ParseBranchLink(%q) failed", url)
+	}
+	result := VerifyBranchLink(mirror, link, "ch.qmd", 1, url)
+	if result.Status != CheckStatusWarn || result.Fix == nil {
+		t.Fatalf("expected a warning with a fix, got %s: %s", result.Status, result.Message)
+	}
+	if want := "https://github.com/owner/repo/blob/" + sha + "/src/main.go#L3-L4"; result.Fix.New != want {
+		t.Errorf("Fix.New = %q, want %q", result.Fix.New, want)
+	}
+
+	if _, ok := ParseBranchLink(result.Fix.New); ok {
+		t.Error("pinned permalink should not parse as a branch link")
+	}
+}
@@ -0,0 +1,703 @@
+package verify
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/bibtex"
+)
+
+// IdentifierKind is a kind of persistent paper identifier.
+type IdentifierKind string
+
+const (
+	IdentifierDOI   IdentifierKind = "doi"
+	IdentifierArXiv IdentifierKind = "arxiv"
+	IdentifierPMID  IdentifierKind = "pmid"
+)
+
+// Identifier is a normalized DOI, arXiv ID, or PubMed ID.
+// DOIs and arXiv IDs are lowercased, and arXiv versions are dropped.
+type Identifier struct {
+	Kind  IdentifierKind `json:"kind"`
+	Value string         `json:"value"`
+}
+
+// String returns the identifier as "kind:value", e.g. "doi:10.1093/sysbio/syy032".
+func (id Identifier) String() string {
+	return string(id.Kind) + ":" + id.Value
+}
+
+// label returns the identifier as written in prose, e.g. "arXiv 2101.00001".
+func (id Identifier) label() string {
+	switch id.Kind {
+	case IdentifierDOI:
+		return "DOI " + id.Value
+	case IdentifierArXiv:
+		return "arXiv " + id.Value
+	default:
+		return "PMID " + id.Value
+	}
+}
+
+var (
+	// doiPattern matches a DOI anywhere, including inside doi.org URLs.
+	doiPattern = regexp.MustCompile(`\b10\.\d{4,9}/[^\s"'<>{}\[\]]+`)
+	// arxivPattern matches "arXiv:2101.00001v2", "arXiv:q-bio/0601234", and arxiv.org abs/pdf URLs.
+	arxivPattern = regexp.MustCompile(`(?i)(?:\barxiv:\s*|arxiv\.org/(?:abs|pdf)/)(\d{4}\.\d{4,5}|[a-z-]+(?:\.[a-z]{2})?/\d{7})(?:v\d+)?`)
+	// pmidPattern matches "PMID: 12345" and pubmed.ncbi.nlm.nih.gov URLs.
+	pmidPattern = regexp.MustCompile(`(?i)(?:\bPMID:?\s*|pubmed\.ncbi\.nlm\.nih\.gov/)(\d{1,9})\b`)
+
+	doiValuePattern   = regexp.MustCompile(`^10\.\d{4,9}/\S+$`)
+	arxivValuePattern = regexp.MustCompile(`^(\d{4}\.\d{4,5}|[a-z-]+(\.[a-z]{2})?/\d{7})$`)
+	arxivVersion      = regexp.MustCompile(`v\d+$`)
+	pmidValuePattern  = regexp.MustCompile(`^\d{1,9}$`)
+)
+
+// ParseIdentifier normalizes an identifier of the given kind. It accepts
+// the usual prefixes, such as https://doi.org/, doi:, and arXiv:.
+func ParseIdentifier(kind IdentifierKind, value string) (Identifier, bool) {
+	value = strings.TrimSpace(value)
+	switch kind {
+	case IdentifierDOI:
+		value = NormalizeDOI(value)
+		return Identifier{Kind: kind, Value: value}, doiValuePattern.MatchString(value)
+	case IdentifierArXiv:
+		value = strings.ToLower(value)
+		value = strings.TrimPrefix(value, "arxiv:")
+		value = arxivVersion.ReplaceAllString(value, "")
+		return Identifier{Kind: kind, Value: value}, arxivValuePattern.MatchString(value)
+	case IdentifierPMID:
+		value = strings.TrimSpace(strings.TrimPrefix(strings.ToLower(value), "pmid:"))
+		return Identifier{Kind: kind, Value: value}, pmidValuePattern.MatchString(value)
+	}
+	return Identifier{}, false
+}
+
+// NormalizeDOI strips resolver and doi: prefixes from a DOI and lowercases it.
+func NormalizeDOI(doi string) string {
+	return bibtex.NormalizeDOI(doi)
+}
+
+// identifierMatch is an identifier found in text, with its byte range.
+type identifierMatch struct {
+	Identifier
+	start, end int
+}
+
+// findIdentifiers finds DOIs, arXiv IDs, and PubMed IDs in text, in order.
+func findIdentifiers(text string) []identifierMatch {
+	var matches []identifierMatch
+	for _, m := range doiPattern.FindAllStringIndex(text, -1) {
+		end := m[0] + len(trimDOI(text[m[0]:m[1]]))
+		if id, ok := ParseIdentifier(IdentifierDOI, text[m[0]:end]); ok {
+			matches = append(matches, identifierMatch{id, m[0], end})
+		}
+	}
+	for _, m := range arxivPattern.FindAllStringSubmatchIndex(text, -1) {
+		if id, ok := ParseIdentifier(IdentifierArXiv, text[m[2]:m[3]]); ok {
+			matches = append(matches, identifierMatch{id, m[0], m[1]})
+		}
+	}
+	for _, m := range pmidPattern.FindAllStringSubmatchIndex(text, -1) {
+		if id, ok := ParseIdentifier(IdentifierPMID, text[m[2]:m[3]]); ok {
+			matches = append(matches, identifierMatch{id, m[0], m[1]})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].start < matches[j].start })
+	return matches
+}
+
+// trimDOI drops trailing punctuation that ends the sentence or encloses the
+// DOI rather than belonging to it. Parentheses are kept when balanced, as
+// in 10.1016/0022-2836(86)90063-6.
+func trimDOI(doi string) string {
+	for doi != "" {
+		last := doi[len(doi)-1]
+		switch {
+		case strings.IndexByte(".,;:!?", last) >= 0:
+		case last == ')' && strings.Count(doi, "(") < strings.Count(doi, ")"):
+		default:
+			return doi
+		}
+		doi = doi[:len(doi)-1]
+	}
+	return doi
+}
+
+// ExtractIdentifiers returns the unique DOIs, arXiv IDs, and PubMed IDs in
+// text, in order of first appearance.
+func ExtractIdentifiers(text string) []Identifier {
+	var ids []Identifier
+	seen := make(map[Identifier]bool)
+	for _, m := range findIdentifiers(text) {
+		if !seen[m.Identifier] {
+			seen[m.Identifier] = true
+			ids = append(ids, m.Identifier)
+		}
+	}
+	return ids
+}
+
+// isIdentifierURL reports whether a URL is a DOI, arXiv, or PubMed link.
+// These are checked against metadata rather than fetched.
+func isIdentifierURL(url string) bool {
+	switch host := hostOf(url); host {
+	case "doi.org", "dx.doi.org", "arxiv.org", "www.arxiv.org", "pubmed.ncbi.nlm.nih.gov":
+		return len(findIdentifiers(url)) > 0
+	}
+	return false
+}
+
+// bibIdentifiers returns the identifiers recorded in a BibTeX entry: the
+// doi and pmid fields, and eprint when it is an arXiv ID.
+func bibIdentifiers(e *bibtex.Entry) []Identifier {
+	var ids []Identifier
+	if id, ok := ParseIdentifier(IdentifierDOI, e.Get("doi")); ok {
+		ids = append(ids, id)
+	}
+	archive := strings.ToLower(e.Get("archiveprefix") + e.Get("eprinttype"))
+	if eprint := e.Get("eprint"); eprint != "" && (archive == "" || archive == "arxiv") {
+		if id, ok := ParseIdentifier(IdentifierArXiv, eprint); ok {
+			ids = append(ids, id)
+		}
+	}
+	if id, ok := ParseIdentifier(IdentifierPMID, e.Get("pmid")); ok {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// PaperMetadata is the bibliographic metadata compared across sources.
+type PaperMetadata struct {
+	Title   string   `json:"title"`
+	Year    int      `json:"year,omitempty"`
+	Authors []string `json:"authors,omitempty"` // family names, in order
+	Source  string   `json:"source,omitempty"`  // where the metadata came from
+}
+
+// bibMetadata returns the metadata of a BibTeX entry.
+func bibMetadata(entry BibEntry) *PaperMetadata {
+	year, _ := strconv.Atoi(entry.Entry.Get("year"))
+	if year == 0 {
+		if date := entry.Entry.Get("date"); len(date) >= 4 {
+			year, _ = strconv.Atoi(date[:4])
+		}
+	}
+	return &PaperMetadata{
+		Title:   entry.Entry.Get("title"),
+		Year:    year,
+		Authors: entry.Entry.FamilyNames(),
+		Source:  fmt.Sprintf("BibTeX entry %s (%s:%d)", entry.Key, entry.File, entry.Line),
+	}
+}
+
+// recordMetadata returns the metadata of a resolved paper.
+func recordMetadata(record *PaperRecord) *PaperMetadata {
+	meta := &PaperMetadata{Title: record.Title, Year: record.Year, Source: "paper " + record.ID}
+	for _, name := range record.Authors {
+		meta.Authors = append(meta.Authors, bibtex.FamilyName(name))
+	}
+	return meta
+}
+
+// ErrLookupUnavailable marks a metadata lookup that failed for reasons that
+// say nothing about the identifier: a network error, rate limiting, or a
+// server error. Such failures are warnings, not failures.
+var ErrLookupUnavailable = errors.New("metadata service unavailable")
+
+// MetadataProvider looks up the metadata an identifier is registered with.
+// Lookup returns (nil, nil) when the identifier does not exist.
+type MetadataProvider interface {
+	Name() string
+	Lookup(id Identifier) (*PaperMetadata, error)
+}
+
+// Default metadata API endpoints.
+const (
+	DefaultCrossrefURL = "https://api.crossref.org"
+	DefaultArXivURL    = "https://export.arxiv.org"
+	DefaultPubMedURL   = "https://eutils.ncbi.nlm.nih.gov"
+)
+
+// WebMetadataProvider looks up DOIs with Crossref, arXiv IDs with the arXiv
+// API, and PubMed IDs with NCBI E-utilities.
+type WebMetadataProvider struct {
+	CrossrefURL string
+	ArXivURL    string
+	PubMedURL   string
+
+	client *http.Client
+}
+
+// NewWebMetadataProvider creates a provider for the public metadata APIs.
+func NewWebMetadataProvider(timeout time.Duration) *WebMetadataProvider {
+	return &WebMetadataProvider{
+		CrossrefURL: DefaultCrossrefURL,
+		ArXivURL:    DefaultArXivURL,
+		PubMedURL:   DefaultPubMedURL,
+		client:      &http.Client{Timeout: timeout},
+	}
+}
+
+// Name returns the provider name.
+func (p *WebMetadataProvider) Name() string {
+	return "web"
+}
+
+// host returns the API host for an identifier kind, for rate limiting.
+func (p *WebMetadataProvider) host(kind IdentifierKind) string {
+	switch kind {
+	case IdentifierDOI:
+		return hostOf(p.CrossrefURL)
+	case IdentifierArXiv:
+		return hostOf(p.ArXivURL)
+	default:
+		return hostOf(p.PubMedURL)
+	}
+}
+
+// Lookup fetches the metadata registered for an identifier.
+func (p *WebMetadataProvider) Lookup(id Identifier) (*PaperMetadata, error) {
+	switch id.Kind {
+	case IdentifierDOI:
+		return p.crossref(id)
+	case IdentifierArXiv:
+		return p.arxiv(id)
+	case IdentifierPMID:
+		return p.pubmed(id)
+	}
+	return nil, fmt.Errorf("unsupported identifier kind %q", id.Kind)
+}
+
+// get fetches a URL. It returns nil if the server reports 404.
+func (p *WebMetadataProvider) get(url string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "scribe/1.0 (citation verification)")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrLookupUnavailable, err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		err := fmt.Errorf("GET %s: HTTP %d", url, resp.StatusCode)
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			err = fmt.Errorf("%w: %w", ErrLookupUnavailable, err)
+		}
+		return nil, err
+	}
+	return resp, nil
+}
+
+// htmlTag matches markup such as <i> that Crossref leaves in titles.
+var htmlTag = regexp.MustCompile(`<[^>]+>`)
+
+func (p *WebMetadataProvider) crossref(id Identifier) (*PaperMetadata, error) {
+	resp, err := p.get(p.CrossrefURL + "/works/" + neturl.PathEscape(id.Value))
+	if resp == nil || err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Message struct {
+			Title  []string `json:"title"`
+			Author []struct {
+				Family string `json:"family"`
+				Name   string `json:"name"` // organizations
+			} `json:"author"`
+			Issued struct {
+				DateParts [][]int `json:"date-parts"`
+			} `json:"issued"`
+		} `json:"message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("parse Crossref response for %s: %w", id.Value, err)
+	}
+	msg := body.Message
+	meta := &PaperMetadata{Source: "Crossref"}
+	if len(msg.Title) > 0 {
+		meta.Title = strings.Join(strings.Fields(htmlTag.ReplaceAllString(msg.Title[0], "")), " ")
+	}
+	if len(msg.Issued.DateParts) > 0 && len(msg.Issued.DateParts[0]) > 0 {
+		meta.Year = msg.Issued.DateParts[0][0]
+	}
+	for _, a := range msg.Author {
+		if a.Family != "" {
+			meta.Authors = append(meta.Authors, a.Family)
+		} else if a.Name != "" {
+			meta.Authors = append(meta.Authors, a.Name)
+		}
+	}
+	return meta, nil
+}
+
+func (p *WebMetadataProvider) arxiv(id Identifier) (*PaperMetadata, error) {
+	resp, err := p.get(p.ArXivURL + "/api/query?id_list=" + neturl.QueryEscape(id.Value))
+	if resp == nil || err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var feed struct {
+		Entries []struct {
+			ID        string `xml:"id"`
+			Title     string `xml:"title"`
+			Published string `xml:"published"`
+			Authors   []struct {
+				Name string `xml:"name"`
+			} `xml:"author"`
+		} `xml:"entry"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&feed); err != nil {
+		return nil, fmt.Errorf("parse arXiv response for %s: %w", id.Value, err)
+	}
+	// Unknown IDs come back as an empty feed or a single error entry
+	if len(feed.Entries) == 0 || strings.Contains(feed.Entries[0].ID, "/api/errors") {
+		return nil, nil
+	}
+	entry := feed.Entries[0]
+	meta := &PaperMetadata{Title: strings.Join(strings.Fields(entry.Title), " "), Source: "arXiv"}
+	if len(entry.Published) >= 4 {
+		meta.Year, _ = strconv.Atoi(entry.Published[:4])
+	}
+	for _, a := range entry.Authors {
+		meta.Authors = append(meta.Authors, bibtex.FamilyName(a.Name))
+	}
+	return meta, nil
+}
+
+func (p *WebMetadataProvider) pubmed(id Identifier) (*PaperMetadata, error) {
+	resp, err := p.get(p.PubMedURL + "/entrez/eutils/esummary.fcgi?db=pubmed&retmode=json&id=" + id.Value)
+	if resp == nil || err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Result map[string]json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("parse PubMed response for %s: %w", id.Value, err)
+	}
+	raw, ok := body.Result[id.Value]
+	if !ok {
+		return nil, nil
+	}
+	var summary struct {
+		Title   string `json:"title"`
+		PubDate string `json:"pubdate"` // e.g. "1986 Jan"
+		Error   string `json:"error"`
+		Authors []struct {
+			Name string `json:"name"` // e.g. "Tavaré S"
+		} `json:"authors"`
+	}
+	if err := json.Unmarshal(raw, &summary); err != nil {
+		return nil, fmt.Errorf("parse PubMed response for %s: %w", id.Value, err)
+	}
+	if summary.Error != "" || summary.Title == "" {
+		return nil, nil
+	}
+	meta := &PaperMetadata{Title: summary.Title, Source: "PubMed"}
+	if len(summary.PubDate) >= 4 {
+		meta.Year, _ = strconv.Atoi(summary.PubDate[:4])
+	}
+	for _, a := range summary.Authors {
+		meta.Authors = append(meta.Authors, pubmedFamilyName(a.Name))
+	}
+	return meta, nil
+}
+
+// pubmedFamilyName drops the trailing initials from a PubMed author name.
+func pubmedFamilyName(name string) string {
+	words := strings.Fields(name)
+	if len(words) > 1 && strings.ToUpper(words[len(words)-1]) == words[len(words)-1] {
+		words = words[:len(words)-1]
+	}
+	return strings.Join(words, " ")
+}
+
+// FixtureMetadataProvider serves metadata from a JSON file that maps
+// identifiers such as "doi:10.1093/sysbio/syy032" to records. It stands in
+// for the web APIs in tests and offline runs.
+type FixtureMetadataProvider struct {
+	records map[Identifier]*PaperMetadata
+}
+
+// NewFixtureMetadataProvider loads a metadata fixture file.
+func NewFixtureMetadataProvider(path string) (*FixtureMetadataProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read metadata fixture %s: %w", path, err)
+	}
+	var raw map[string]*PaperMetadata
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse metadata fixture %s: %w", path, err)
+	}
+	p := &FixtureMetadataProvider{records: make(map[Identifier]*PaperMetadata)}
+	for key, meta := range raw {
+		kind, value, _ := strings.Cut(key, ":")
+		id, ok := ParseIdentifier(IdentifierKind(strings.ToLower(kind)), value)
+		if !ok {
+			return nil, fmt.Errorf("metadata fixture %s: invalid identifier %q", path, key)
+		}
+		if meta.Source == "" {
+			meta.Source = "fixture"
+		}
+		p.records[id] = meta
+	}
+	return p, nil
+}
+
+// Name returns the provider name.
+func (p *FixtureMetadataProvider) Name() string {
+	return "fixture"
+}
+
+// Lookup returns the fixture record for an identifier.
+func (p *FixtureMetadataProvider) Lookup(id Identifier) (*PaperMetadata, error) {
+	return p.records[id], nil
+}
+
+// metadataHost returns the rate-limit bucket for looking up an identifier.
+// Only the web provider is rate limited.
+func metadataHost(provider MetadataProvider, kind IdentifierKind) string {
+	if web, ok := provider.(*WebMetadataProvider); ok {
+		return web.host(kind)
+	}
+	return ""
+}
+
+// VerifyIdentifier checks that an identifier resolves and, if expected is
+// set, that its title, year, and first author match the expected metadata.
+func VerifyIdentifier(provider MetadataProvider, id Identifier, expected *PaperMetadata, file string, line int, text string) VerificationResult {
+	details := &IdentifierDetails{Kind: id.Kind, Value: id.Value}
+	if expected != nil {
+		details.Expected = expected.Source
+	}
+	result := VerificationResult{
+		CheckID:   uuid.New().String(),
+		CheckType: CheckTypeIdentifier,
+		Target: VerificationTarget{
+			File: file,
+			Line: line,
+			Text: text,
+		},
+		CheckedAt: time.Now(),
+		Details:   VerificationDetails{Identifier: details},
+	}
+
+	actual, err := provider.Lookup(id)
+	switch {
+	case err != nil:
+		result.Status = CheckStatusFail
+		result.Message = fmt.Sprintf("%s lookup failed: %v", id.label(), err)
+		if errors.Is(err, ErrResolverUnavailable) || errors.Is(err, ErrLookupUnavailable) {
+			result.Status = CheckStatusWarn
+		}
+		return result
+	case actual == nil:
+		result.Status = CheckStatusFail
+		result.Message = fmt.Sprintf("%s does not resolve (%s)", id.label(), provider.Name())
+		return result
+	}
+
+	details.Resolved = true
+	details.Title = actual.Title
+	details.Year = actual.Year
+	if len(actual.Authors) > 0 {
+		details.FirstAuthor = actual.Authors[0]
+	}
+	if expected != nil {
+		details.Mismatches = compareMetadata(expected, actual, id.Kind)
+	}
+	if len(details.Mismatches) > 0 {
+		result.Status = CheckStatusFail
+		result.Message = fmt.Sprintf("%s belongs to %q (%s), which does not match the %s of %s",
+			id.label(), actual.Title, describeMetadata(actual), strings.Join(details.Mismatches, ", "), expected.Source)
+		return result
+	}
+	result.Status = CheckStatusPass
+	result.Message = fmt.Sprintf("%s resolves to %q", id.label(), actual.Title)
+	return result
+}
+
+// describeMetadata summarizes the first author and year, e.g. "Tavaré, 1986".
+func describeMetadata(meta *PaperMetadata) string {
+	var parts []string
+	if len(meta.Authors) > 0 {
+		parts = append(parts, meta.Authors[0])
+	}
+	if meta.Year != 0 {
+		parts = append(parts, strconv.Itoa(meta.Year))
+	}
+	if len(parts) == 0 {
+		return meta.Source
+	}
+	return strings.Join(parts, ", ")
+}
+
+// compareMetadata returns the fields that differ: "title", "year", or
+// "first author". Fields missing on either side are not compared. Preprint
+// years may trail the published year, so arXiv years may be off by one.
+func compareMetadata(expected, actual *PaperMetadata, kind IdentifierKind) []string {
+	var mismatches []string
+	if expected.Title != "" && actual.Title != "" && !similarTitles(expected.Title, actual.Title) {
+		mismatches = append(mismatches, "title")
+	}
+	if expected.Year != 0 && actual.Year != 0 {
+		slack := 0
+		if kind == IdentifierArXiv {
+			slack = 1
+		}
+		if diff := expected.Year - actual.Year; diff > slack || diff < -slack {
+			mismatches = append(mismatches, "year")
+		}
+	}
+	if len(expected.Authors) > 0 && len(actual.Authors) > 0 && !sameFamilyName(expected.Authors[0], actual.Authors[0]) {
+		mismatches = append(mismatches, "first author")
+	}
+	return mismatches
+}
+
+// similarTitles compares titles word by word, ignoring case, accents, and
+// punctuation. A title matches if one is a prefix of the other (a dropped
+// subtitle) or if the titles share at least 80% of their words.
+func similarTitles(a, b string) bool {
+	wa, wb := titleWords(a), titleWords(b)
+	if len(wa) == 0 || len(wb) == 0 {
+		return true
+	}
+	if len(wa) > len(wb) {
+		wa, wb = wb, wa
+	}
+	if strings.Join(wb[:len(wa)], " ") == strings.Join(wa, " ") {
+		return true
+	}
+	counts := make(map[string]int)
+	for _, w := range wa {
+		counts[w]++
+	}
+	shared := 0
+	for _, w := range wb {
+		if counts[w] > 0 {
+			counts[w]--
+			shared++
+		}
+	}
+	return float64(2*shared)/float64(len(wa)+len(wb)) >= 0.8
+}
+
+// titleWords splits a title into folded lowercase words.
+func titleWords(title string) []string {
+	return strings.Fields(bibtex.NormalizeTitle(title))
+}
+
+// sameFamilyName compares family names, ignoring case, accents, spacing, and
+// particles: "van der Vaart" matches "Vaart" and "Tavaré" matches "Tavare".
+func sameFamilyName(a, b string) bool {
+	wa, wb := titleWords(a), titleWords(b)
+	if len(wa) == 0 || len(wb) == 0 {
+		return true
+	}
+	return strings.Join(wa, "") == strings.Join(wb, "") || wa[len(wa)-1] == wb[len(wb)-1]
+}
+
+// bibIdentifierChecks plans a metadata comparison for every identifier
+// recorded in a bibliography's entries.
+func bibIdentifierChecks(bib *Bibliography, opts Options) []check {
+	var checks []check
+	for _, entry := range bib.Entries {
+		if entry.Entry == nil {
+			continue
+		}
+		for _, id := range bibIdentifiers(entry.Entry) {
+			key := fmt.Sprintf("identifier:%s@%s:%s", id, entry.File, entry.Key)
+			target := VerificationTarget{File: entry.File, Line: entry.Line, Text: fmt.Sprintf("@%s{%s,", entry.Type, entry.Key)}
+			checks = append(checks, check{
+				key:      key,
+				cacheKey: ResultCacheKey(key, ContentHash([]byte(fmt.Sprint(entry.Entry.Fields)))+"\x00"+opts.Metadata.Name()),
+				host:     metadataHost(opts.Metadata, id.Kind),
+				target:   target,
+				run: func() VerificationResult {
+					return VerifyIdentifier(opts.Metadata, id, bibMetadata(entry), target.File, target.Line, target.Text)
+				},
+			})
+		}
+	}
+	return checks
+}
+
+// expectedMetadata returns the metadata an identifier found in prose should
+// match: that of the bibliography entry recording it, or else of a cited
+// paper with the same DOI. It returns nil if neither is known.
+func expectedMetadata(id Identifier, bibs []*Bibliography, resolver CitationResolver, paperIDs []string) *PaperMetadata {
+	for _, bib := range bibs {
+		if bib == nil {
+			continue
+		}
+		for _, entry := range bib.Entries {
+			if entry.Entry != nil && slices.Contains(bibIdentifiers(entry.Entry), id) {
+				return bibMetadata(entry)
+			}
+		}
+	}
+	if id.Kind != IdentifierDOI || resolver == nil {
+		return nil
+	}
+	for _, paperID := range paperIDs {
+		record, err := resolver.Resolve(paperID)
+		if err != nil || record == nil {
+			continue
+		}
+		if doi, ok := ParseIdentifier(IdentifierDOI, record.DOI); ok && doi == id {
+			return recordMetadata(record)
+		}
+	}
+	return nil
+}
+
+// verifyPaperIdentifier compares a cited paper's DOI with the paper's
+// metadata. A paper without a DOI has nothing to compare and passes without
+// details. A paper that does not resolve is left to the citation check; its
+// result is uncacheable, since the paper may resolve on the next run.
+func verifyPaperIdentifier(resolver CitationResolver, provider MetadataProvider, paperID string, file string, line int, text string) VerificationResult {
+	result := VerificationResult{
+		CheckID:   uuid.New().String(),
+		CheckType: CheckTypeIdentifier,
+		Target:    VerificationTarget{File: file, Line: line, Text: text},
+		Status:    CheckStatusPass,
+		CheckedAt: time.Now(),
+	}
+	record, err := resolver.Resolve(paperID)
+	if err != nil || record == nil {
+		result.Message = fmt.Sprintf("Paper ID %q did not resolve; its DOI was not checked", paperID)
+		result.uncacheable = true
+		return result
+	}
+	id, ok := ParseIdentifier(IdentifierDOI, record.DOI)
+	if !ok {
+		result.Message = fmt.Sprintf("Paper ID %q has no DOI to check", paperID)
+		return result
+	}
+	return VerifyIdentifier(provider, id, recordMetadata(record), file, line, text)
+}
@@ -0,0 +1,335 @@
+package verify
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractIdentifiers(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"See doi:10.1093/SysBio/syy032.", []string{"doi:10.1093/sysbio/syy032"}},
+		{"[paper](https://doi.org/10.1016/0022-2836(86)90063-6)", []string{"doi:10.1016/0022-2836(86)90063-6"}},
+		{"(preprint, arXiv:2101.00001v2)", []string{"arxiv:2101.00001"}},
+		{"<https://arxiv.org/abs/q-bio/0601234>", []string{"arxiv:q-bio/0601234"}},
+		{"PMID: 3528966 and https://pubmed.ncbi.nlm.nih.gov/3528966/", []string{"pmid:3528966"}},
+		{"Version 10.2 of the tool, 2101.00001 alone, and PMID-less text", nil},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, id := range ExtractIdentifiers(tt.text) {
+			got = append(got, id.String())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ExtractIdentifiers(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCompareMetadata(t *testing.T) {
+	expected := &PaperMetadata{Title: "Inferring Phylogenies", Year: 2004, Authors: []string{"Felsenstein"}}
+	tests := []struct {
+		name   string
+		actual PaperMetadata
+		kind   IdentifierKind
+		want   []string
+	}{
+		{"match", PaperMetadata{Title: "Inferring phylogenies.", Year: 2004, Authors: []string{"Felsenstein"}}, IdentifierDOI, nil},
+		{"subtitle", PaperMetadata{Title: "Inferring Phylogenies: A Guide", Year: 2004}, IdentifierDOI, nil},
+		{"wrong paper", PaperMetadata{Title: "A Different Paper Entirely", Year: 1986, Authors: []string{"Tavaré"}}, IdentifierDOI, []string{"title", "year", "first author"}},
+		{"preprint year", PaperMetadata{Title: "Inferring Phylogenies", Year: 2003}, IdentifierArXiv, nil},
+		{"published year", PaperMetadata{Title: "Inferring Phylogenies", Year: 2003}, IdentifierDOI, []string{"year"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compareMetadata(expected, &tt.actual, tt.kind); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("compareMetadata() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyFiles_Identifiers(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	fixturePath := filepath.Join(tmpDir, "metadata.json")
+	fixture := `{
+  "doi:10.1016/0040-5809(86)90063-6": {"title": "Some probabilistic and statistical problems in the analysis of DNA sequences", "year": 1986, "authors": ["Tavaré"]},
+  "doi:10.1093/sysbio/syy032": {"title": "Bayesian Phylogenetic Analysis", "year": 2018, "authors": ["Smith"]},
+  "arxiv:2101.00001": {"title": "A Preprint", "year": 2021, "authors": ["Doe"]}
+}`
+	if err := os.WriteFile(fixturePath, []byte(fixture), 0644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	metadata, err := NewFixtureMetadataProvider(fixturePath)
+	if err != nil {
+		t.Fatalf("NewFixtureMetadataProvider failed: %v", err)
+	}
+
+	bibPath := filepath.Join(tmpDir, "references.bib")
+	bib := `@article{tavare1986,
+  author = {Tavar\'e, Simon},
+  title = {Some Probabilistic and Statistical Problems in the Analysis of {DNA} Sequences},
+  year = 1986,
+  doi = {10.1016/0040-5809(86)90063-6},
+}
+
+@article{felsenstein1981,
+  author = {Felsenstein, Joseph},
+  title = {Evolutionary Trees from {DNA} Sequences},
+  year = 1981,
+  doi = {https://doi.org/10.1093/sysbio/syy032},
+}
+`
+	if err := os.WriteFile(bibPath, []byte(bib), 0644); err != nil {
+		t.Fatalf("write bibliography: %v", err)
+	}
+	bibliography, err := LoadBibliography(bibPath)
+	if err != nil {
+		t.Fatalf("LoadBibliography failed: %v", err)
+	}
+
+	libPath := filepath.Join(tmpDir, "library.jsonl")
+	lib := `{"id": "doe2021", "doi": "10.1093/sysbio/syy032", "title": "A Preprint", "authors": ["Jane Doe"], "year": 2021}` + "\n"
+	if err := os.WriteFile(libPath, []byte(lib), 0644); err != nil {
+		t.Fatalf("write library: %v", err)
+	}
+	resolver, err := NewLibraryResolver(libPath)
+	if err != nil {
+		t.Fatalf("NewLibraryResolver failed: %v", err)
+	}
+
+	qmdPath := filepath.Join(tmpDir, "chapter.qmd")
+	content := "# Models\n\nSee [the preprint](https://arxiv.org/abs/2101.00001v1) and @paper:doe2021.\n\nAlso doi:10.9999/does-not-exist.\n"
+	if err := os.WriteFile(qmdPath, []byte(content), 0644); err != nil {
+		t.Fatalf("write qmd: %v", err)
+	}
+
+	report, err := VerifyFiles([]string{qmdPath}, Options{
+		Resolver:     resolver,
+		Bibliography: bibliography,
+		Metadata:     metadata,
+		Checks:       []CheckType{CheckTypeIdentifier, CheckTypeURL},
+	})
+	if err != nil {
+		t.Fatalf("VerifyFiles failed: %v", err)
+	}
+
+	if urls := report.FilterByType(CheckTypeURL); len(urls) != 0 {
+		t.Errorf("arXiv links should not be fetched as URLs, got %+v", urls)
+	}
+	got := make(map[string]VerificationResult)
+	for _, r := range report.FilterByType(CheckTypeIdentifier) {
+		got[string(r.Details.Identifier.Kind)+" "+r.Details.Identifier.Value+" "+r.Details.Identifier.Expected] = r
+	}
+	tests := []struct {
+		key    string
+		status CheckStatus
+	}{
+		{"arxiv 2101.00001 ", CheckStatusPass},
+		{"doi 10.9999/does-not-exist ", CheckStatusFail},
+		{"doi 10.1093/sysbio/syy032 paper doe2021", CheckStatusFail},
+		{"doi 10.1016/0040-5809(86)90063-6 BibTeX entry tavare1986 (" + bibPath + ":1)", CheckStatusPass},
+		{"doi 10.1093/sysbio/syy032 BibTeX entry felsenstein1981 (" + bibPath + ":8)", CheckStatusFail},
+	}
+	if len(got) != len(tests) {
+		t.Errorf("got %d identifier results, want %d: %v", len(got), len(tests), got)
+	}
+	for _, tt := range tests {
+		r, ok := got[tt.key]
+		if !ok {
+			t.Errorf("missing result for %q", tt.key)
+			continue
+		}
+		if r.Status != tt.status {
+			t.Errorf("%s: status = %s, want %s (%s)", tt.key, r.Status, tt.status, r.Message)
+		}
+	}
+
+	wrong := got["doi 10.1093/sysbio/syy032 BibTeX entry felsenstein1981 ("+bibPath+":8)"]
+	if want := []string{"title", "year", "first author"}; !reflect.DeepEqual(wrong.Details.Identifier.Mismatches, want) {
+		t.Errorf("Mismatches = %q, want %q", wrong.Details.Identifier.Mismatches, want)
+	}
+	if !strings.Contains(wrong.Message, "Bayesian Phylogenetic Analysis") {
+		t.Errorf("message should name the paper the DOI belongs to: %s", wrong.Message)
+	}
+}
+
+func TestWebMetadataProvider(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/works/", func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/10.1093/sysbio/syy032") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"message": {"title": ["Bayesian <i>Phylogenetic</i> Analysis"], "author": [{"family": "Smith", "given": "A."}], "issued": {"date-parts": [[2018, 5]]}}}`))
+	})
+	mux.HandleFunc("/api/query", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("id_list") != "2101.00001" {
+			w.Write([]byte(`<feed xmlns="http://www.w3.org/2005/Atom"><entry><id>http://arxiv.org/api/errors#incorrect_id_format</id></entry></feed>`))
+			return
+		}
+		w.Write([]byte(`<feed xmlns="http://www.w3.org/2005/Atom"><entry><id>http://arxiv.org/abs/2101.00001v1</id>
+<title>A
+  Preprint</title><published>2021-01-01T00:00:00Z</published><author><name>Jane Doe</name></author></entry></feed>`))
+	})
+	mux.HandleFunc("/entrez/eutils/esummary.fcgi", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"result": {"uids": ["3528966"], "3528966": {"title": "Some probabilistic problems.", "pubdate": "1986 Apr", "authors": [{"name": "Tavaré S"}]}}}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	p := NewWebMetadataProvider(DefaultURLTimeout)
+	p.CrossrefURL, p.ArXivURL, p.PubMedURL = server.URL, server.URL, server.URL
+
+	tests := []struct {
+		id   Identifier
+		want *PaperMetadata
+	}{
+		{Identifier{IdentifierDOI, "10.1093/sysbio/syy032"}, &PaperMetadata{Title: "Bayesian Phylogenetic Analysis", Year: 2018, Authors: []string{"Smith"}, Source: "Crossref"}},
+		{Identifier{IdentifierDOI, "10.9999/missing"}, nil},
+		{Identifier{IdentifierArXiv, "2101.00001"}, &PaperMetadata{Title: "A Preprint", Year: 2021, Authors: []string{"Doe"}, Source: "arXiv"}},
+		{Identifier{IdentifierArXiv, "2101.99999"}, nil},
+		{Identifier{IdentifierPMID, "3528966"}, &PaperMetadata{Title: "Some probabilistic problems.", Year: 1986, Authors: []string{"Tavaré"}, Source: "PubMed"}},
+		{Identifier{IdentifierPMID, "1"}, nil},
+	}
+
+	for _, tt := range tests {
+		got, err := p.Lookup(tt.id)
+		if err != nil {
+			t.Errorf("Lookup(%s) failed: %v", tt.id, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Lookup(%s) = %+v, want %+v", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyFiles_ProseIdentifiers(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	fixturePath := filepath.Join(tmpDir, "metadata.json")
+	fixture := `{
+  "doi:10.1016/0040-5809(86)90063-6": {"title": "Some probabilistic and statistical problems in the analysis of DNA sequences", "year": 1986, "authors": ["Tavaré"]},
+  "doi:10.1093/sysbio/syy032": {"title": "Bayesian Phylogenetic Analysis", "year": 2018, "authors": ["Smith"]},
+  "doi:10.1093/molbev/msw001": {"title": "Unrelated", "year": 2016, "authors": ["Roe"]}
+}`
+	if err := os.WriteFile(fixturePath, []byte(fixture), 0644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	metadata, err := NewFixtureMetadataProvider(fixturePath)
+	if err != nil {
+		t.Fatalf("NewFixtureMetadataProvider failed: %v", err)
+	}
+
+	bibPath := filepath.Join(tmpDir, "references.bib")
+	bib := "@article{felsenstein1981,\n  author = {Felsenstein, Joseph},\n  title = {Evolutionary Trees from {DNA} Sequences},\n  year = 1981,\n  doi = {10.1093/sysbio/syy032},\n}\n"
+	if err := os.WriteFile(bibPath, []byte(bib), 0644); err != nil {
+		t.Fatalf("write bibliography: %v", err)
+	}
+	bibliography, err := LoadBibliography(bibPath)
+	if err != nil {
+		t.Fatalf("LoadBibliography failed: %v", err)
+	}
+	libPath := filepath.Join(tmpDir, "library.jsonl")
+	lib := `{"id": "tavare1986", "doi": "10.1016/0040-5809(86)90063-6", "title": "Some Probabilistic and Statistical Problems in the Analysis of DNA Sequences", "authors": ["Simon Tavaré"], "year": 1986}` + "\n"
+	if err := os.WriteFile(libPath, []byte(lib), 0644); err != nil {
+		t.Fatalf("write library: %v", err)
+	}
+	resolver, err := NewLibraryResolver(libPath)
+	if err != nil {
+		t.Fatalf("NewLibraryResolver failed: %v", err)
+	}
+
+	qmdPath := filepath.Join(tmpDir, "chapter.qmd")
+	content := "# Models\n\nAs shown in doi:10.1093/sysbio/syy032, doi:10.1016/0040-5809(86)90063-6, and doi:10.1093/molbev/msw001.\n\nSee @paper:tavare1986.\n"
+	if err := os.WriteFile(qmdPath, []byte(content), 0644); err != nil {
+		t.Fatalf("write qmd: %v", err)
+	}
+
+	report, err := VerifyFiles([]string{qmdPath}, Options{
+		Resolver:     resolver,
+		Bibliography: bibliography,
+		Metadata:     metadata,
+		Checks:       []CheckType{CheckTypeIdentifier},
+	})
+	if err != nil {
+		t.Fatalf("VerifyFiles failed: %v", err)
+	}
+
+	got := make(map[string]VerificationResult)
+	for _, r := range report.FilterByType(CheckTypeIdentifier) {
+		if r.Target.File == qmdPath && r.Target.Line == 3 {
+			got[r.Details.Identifier.Value] = r
+		}
+	}
+	tests := []struct {
+		doi      string
+		expected string
+		status   CheckStatus
+	}{
+		{"10.1093/sysbio/syy032", "BibTeX entry felsenstein1981 (" + bibPath + ":1)", CheckStatusFail},
+		{"10.1016/0040-5809(86)90063-6", "paper tavare1986", CheckStatusPass},
+		{"10.1093/molbev/msw001", "", CheckStatusPass},
+	}
+	for _, tt := range tests {
+		r, ok := got[tt.doi]
+		if !ok {
+			t.Errorf("missing result for %s", tt.doi)
+			continue
+		}
+		if r.Details.Identifier.Expected != tt.expected || r.Status != tt.status {
+			t.Errorf("%s: expected %q, status %s; want %q, %s (%s)", tt.doi, r.Details.Identifier.Expected, r.Status, tt.expected, tt.status, r.Message)
+		}
+	}
+}
+
+func TestVerifyIdentifier_ServiceErrors(t *testing.T) {
+	mux := http.NewServeMux()
+	status := map[string]int{"/works/10.1/unavailable": 503, "/works/10.1/limited": 429, "/works/10.1/bad": 400}
+	mux.HandleFunc("/works/", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "error", status[r.URL.Path])
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	closed := httptest.NewServer(mux)
+	closed.Close()
+
+	tests := []struct {
+		url    string
+		doi    string
+		status CheckStatus
+	}{
+		{server.URL, "10.1/unavailable", CheckStatusWarn},
+		{server.URL, "10.1/limited", CheckStatusWarn},
+		{closed.URL, "10.1/unreachable", CheckStatusWarn},
+		{server.URL, "10.1/bad", CheckStatusFail},
+	}
+	for _, tt := range tests {
+		p := NewWebMetadataProvider(DefaultURLTimeout)
+		p.CrossrefURL = tt.url
+		r := VerifyIdentifier(p, Identifier{IdentifierDOI, tt.doi}, nil, "ch.qmd", 1, "")
+		if r.Status != tt.status {
+			t.Errorf("%s: status = %s, want %s (%s)", tt.doi, r.Status, tt.status, r.Message)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package verify
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// DefaultRepoCacheDir is the default directory for bare clones of referenced repos.
+const DefaultRepoCacheDir = ".scribe/repos"
+
+// maxSnippetLines caps the snippet text stored for a code link.
+const maxSnippetLines = 50
+
+// githubNamePattern matches the characters GitHub allows in owner and
+// repository names.
+var githubNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// validRepoName reports whether owner and repo are GitHub names that are
+// safe to use as path components under the mirror directory.
+func validRepoName(owner, repo string) bool {
+	for _, name := range []string{owner, repo} {
+		if name == "." || name == ".." || !githubNamePattern.MatchString(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// CodeLinkResolution describes what a permalink points to in the mirrored repo.
+type CodeLinkResolution struct {
+	CommitExists bool
+	FileExists   bool
+	LineCount    int
+	Snippet      string
+}
+
+// RepoMirror keeps bare clones of GitHub repositories under a cache directory
+// and answers code link questions with git plumbing commands.
+type RepoMirror struct {
+	dir    string
+	remote func(owner, repo string) string
+
+	mu      sync.Mutex
+	locks   map[string]*sync.Mutex
+	fetched map[string]bool // repos whose branches were fetched this run
+}
+
+// NewRepoMirror creates a RepoMirror rooted at dir.
+// If dir is empty, DefaultRepoCacheDir is used.
+func NewRepoMirror(dir string) *RepoMirror {
+	if dir == "" {
+		dir = DefaultRepoCacheDir
+	}
+	return &RepoMirror{
+		dir: dir,
+		remote: func(owner, repo string) string {
+			return fmt.Sprintf("https://github.com/%s/%s.git", owner, repo)
+		},
+		locks:   make(map[string]*sync.Mutex),
+		fetched: make(map[string]bool),
+	}
+}
+
+// ResolveCodeLink checks a permalink against the mirrored repository at its pinned SHA.
+// The repository is cloned on first use and fetched if the SHA is not yet known locally.
+func (m *RepoMirror) ResolveCodeLink(link CodeLinkMatch) (*CodeLinkResolution, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("git not found: %w", err)
+	}
+
+	unlock := m.lock(link.Owner, link.Repo)
+	defer unlock()
+
+	gitDir, err := m.ensureClone(link.Owner, link.Repo)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &CodeLinkResolution{}
+	res.CommitExists = gitSucceeds(gitDir, "cat-file", "-e", link.CommitSHA+"^{commit}")
+	if !res.CommitExists {
+		// The SHA may be newer than our clone; GitHub allows fetching by SHA
+		if _, err := runGit(gitDir, "fetch", "--quiet", "origin", link.CommitSHA); err == nil {
+			res.CommitExists = gitSucceeds(gitDir, "cat-file", "-e", link.CommitSHA+"^{commit}")
+		}
+	}
+	if !res.CommitExists {
+		return res, nil
+	}
+
+	object := link.CommitSHA + ":" + link.FilePath
+	objectType, err := runGit(gitDir, "cat-file", "-t", object)
+	if err != nil || strings.TrimSpace(objectType) != "blob" {
+		return res, nil
+	}
+	res.FileExists = true
+
+	content, err := runGit(gitDir, "cat-file", "blob", object)
+	if err != nil {
+		return nil, fmt.Errorf("read %s at %s: %w", link.FilePath, link.CommitSHA, err)
+	}
+
+	lines := splitLines(content)
+	res.LineCount = len(lines)
+	if link.StartLine >= 1 && link.StartLine <= link.EndLine && link.EndLine <= len(lines) {
+		end := link.EndLine
+		if end-link.StartLine+1 > maxSnippetLines {
+			end = link.StartLine + maxSnippetLines - 1
+		}
+		res.Snippet = strings.Join(lines[link.StartLine-1:end], "\n")
+	}
+
+	return res, nil
+}
+
+// ResolveRef returns the commit a branch or tag currently points to.
+// Branches and tags are fetched from the remote once per mirror.
+func (m *RepoMirror) ResolveRef(owner, repo, ref string) (string, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return "", fmt.Errorf("git not found: %w", err)
+	}
+
+	unlock := m.lock(owner, repo)
+	defer unlock()
+
+	gitDir, err := m.ensureClone(owner, repo)
+	if err != nil {
+		return "", err
+	}
+
+	key := owner + "/" + repo
+	m.mu.Lock()
+	fetched := m.fetched[key]
+	m.mu.Unlock()
+	if !fetched {
+		if _, err := runGit(gitDir, "fetch", "--quiet", "--tags", "origin", "+refs/heads/*:refs/heads/*"); err != nil {
+			return "", err
+		}
+		m.mu.Lock()
+		m.fetched[key] = true
+		m.mu.Unlock()
+	}
+
+	sha, err := runGit(gitDir, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("no branch or tag %q in %s/%s", ref, owner, repo)
+	}
+	return strings.TrimSpace(sha), nil
+}
+
+// lock serializes clone and fetch operations per repository.
+func (m *RepoMirror) lock(owner, repo string) func() {
+	key := owner + "/" + repo
+	m.mu.Lock()
+	l, ok := m.locks[key]
+	if !ok {
+		l = &sync.Mutex{}
+		m.locks[key] = l
+	}
+	m.mu.Unlock()
+	l.Lock()
+	return l.Unlock
+}
+
+// ensureClone returns the path of the bare clone, cloning it if needed.
+func (m *RepoMirror) ensureClone(owner, repo string) (string, error) {
+	if !validRepoName(owner, repo) {
+		return "", fmt.Errorf("invalid GitHub repository %q", owner+"/"+repo)
+	}
+	gitDir := filepath.Join(m.dir, owner, repo+".git")
+	if _, err := os.Stat(filepath.Join(gitDir, "HEAD")); err == nil {
+		return gitDir, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(gitDir), 0755); err != nil {
+		return "", fmt.Errorf("create repo cache directory: %w", err)
+	}
+
+	cmd := exec.Command("git", "clone", "--bare", "--quiet", m.remote(owner, repo), gitDir)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		os.RemoveAll(gitDir)
+		return "", fmt.Errorf("clone %s/%s: %s", owner, repo, strings.TrimSpace(stderr.String()))
+	}
+
+	return gitDir, nil
+}
+
+// runGit runs a git command against a bare repository and returns stdout.
+func runGit(gitDir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"--git-dir", gitDir}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// gitSucceeds reports whether a git command exits successfully.
+func gitSucceeds(gitDir string, args ...string) bool {
+	_, err := runGit(gitDir, args...)
+	return err == nil
+}
+
+// splitLines splits file content into lines the way an editor numbers them.
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	content = strings.TrimSuffix(content, "\n")
+	return strings.Split(content, "\n")
+}
@@ -0,0 +1,159 @@
+package verify
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestRemote creates a git repository with a single committed file
+// and returns its directory and commit SHA.
+func newTestRemote(t *testing.T, root string) (string, string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	repoDir := filepath.Join(root, "remote", "owner", "repo")
+	if err := os.MkdirAll(filepath.Join(repoDir, "src"), 0755); err != nil {
+		t.Fatalf("create remote: %v", err)
+	}
+	content := "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n"
+	if err := os.WriteFile(filepath.Join(repoDir, "src", "main.go"), []byte(content), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	run := func(args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoDir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	run("init", "--quiet")
+	run("add", ".")
+	run("commit", "--quiet", "-m", "initial")
+	return repoDir, run("rev-parse", "HEAD")
+}
+
+func TestRepoMirror_ResolveCodeLink(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	repoDir, sha := newTestRemote(t, tmpDir)
+	mirror := NewRepoMirror(filepath.Join(tmpDir, "repos"))
+	mirror.remote = func(owner, repo string) string { return repoDir }
+
+	tests := []struct {
+		name         string
+		link         CodeLinkMatch
+		commitExists bool
+		fileExists   bool
+		status       CheckStatus
+		snippet      string
+	}{
+		{
+			name:         "valid range",
+			link:         CodeLinkMatch{CommitSHA: sha, FilePath: "src/main.go", StartLine: 3, EndLine: 4},
+			commitExists: true,
+			fileExists:   true,
+			status:       CheckStatusPass,
+			snippet:      "func main() {\n\tprintln(\"hi\")",
+		},
+		{
+			name:         "range past end of file",
+			link:         CodeLinkMatch{CommitSHA: sha, FilePath: "src/main.go", StartLine: 4, EndLine: 6},
+			commitExists: true,
+			fileExists:   true,
+			status:       CheckStatusFail,
+		},
+		{
+			name:         "missing file",
+			link:         CodeLinkMatch{CommitSHA: sha, FilePath: "src/missing.go", StartLine: 1, EndLine: 1},
+			commitExists: true,
+			status:       CheckStatusFail,
+		},
+		{
+			name:         "directory is not a file",
+			link:         CodeLinkMatch{CommitSHA: sha, FilePath: "src", StartLine: 1, EndLine: 1},
+			commitExists: true,
+			status:       CheckStatusFail,
+		},
+		{
+			name:   "missing commit",
+			link:   CodeLinkMatch{CommitSHA: strings.Repeat("ab", 20), FilePath: "src/main.go", StartLine: 1, EndLine: 1},
+			status: CheckStatusFail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.link.Owner, tt.link.Repo = "owner", "repo"
+			result := VerifyCodeLink(mirror, tt.link, "test.qmd", 1, "")
+			details := result.Details.CodeLink
+			if result.Status != tt.status {
+				t.Errorf("status: got %s, want %s (%s)", result.Status, tt.status, result.Message)
+			}
+			if details.CommitExists != tt.commitExists {
+				t.Errorf("commit exists: got %v, want %v", details.CommitExists, tt.commitExists)
+			}
+			if details.FileExists != tt.fileExists {
+				t.Errorf("file exists: got %v, want %v", details.FileExists, tt.fileExists)
+			}
+			if tt.fileExists && details.LineCount != 5 {
+				t.Errorf("line count: got %d, want 5", details.LineCount)
+			}
+			if details.Snippet != tt.snippet {
+				t.Errorf("snippet: got %q, want %q", details.Snippet, tt.snippet)
+			}
+		})
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "repos", "owner", "repo.git", "HEAD")); err != nil {
+		t.Errorf("expected bare clone in cache dir: %v", err)
+	}
+}
+
+func TestRepoMirror_RejectsUnsafeNames(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	mirror := NewRepoMirror(filepath.Join(tmpDir, "repos"))
+	mirror.remote = func(owner, repo string) string {
+		t.Errorf("unsafe repository %s/%s reached git", owner, repo)
+		return filepath.Join(tmpDir, "none")
+	}
+
+	tests := []struct{ owner, repo string }{
+		{"..", "repo"},
+		{"owner", ".."},
+		{".", "repo"},
+		{"owner/../..", "repo"},
+		{"owner", "repo name"},
+		{"", "repo"},
+	}
+	for _, tt := range tests {
+		if _, err := mirror.ensureClone(tt.owner, tt.repo); err == nil || !strings.Contains(err.Error(), "invalid GitHub repository") {
+			t.Errorf("ensureClone(%q, %q) = %v, want an invalid repository error", tt.owner, tt.repo, err)
+		}
+	}
+	if entries, _ := os.ReadDir(tmpDir); len(entries) != 0 {
+		t.Errorf("unsafe names created %d entries outside the mirror", len(entries))
+	}
+	if ok := validRepoName("matsen", "phylo.tools-2_x"); !ok {
+		t.Error("valid GitHub names were rejected")
+	}
+}
@@ -4,12 +4,15 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/llm"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/output"
 )
 
 // ReportBuilder builds a verification report from results.
 type ReportBuilder struct {
 	files   []string
 	results []VerificationResult
+	usage   *llm.Usage
 }
 
 // NewReportBuilder creates a new report builder.
@@ -44,6 +47,13 @@ func (b *ReportBuilder) Build() VerificationReport {
 			summary.Failed++
 		case CheckStatusWarn:
 			summary.Warnings++
+		case CheckStatusSuppressed:
+			summary.Suppressed++
+		case CheckStatusBaselined:
+			summary.Baselined++
+		}
+		if r.Fix != nil && r.Fix.Applied {
+			summary.Fixed++
 		}
 	}
 
@@ -59,9 +69,15 @@ func (b *ReportBuilder) Build() VerificationReport {
 		Summary:      summary,
 		Results:      b.results,
 		ExitCode:     exitCode,
+		LLMUsage:     b.usage,
 	}
 }
 
+// SetLLMUsage records the LLM usage of the verification run.
+func (b *ReportBuilder) SetLLMUsage(usage llm.Usage) {
+	b.usage = &usage
+}
+
 // FilterByStatus returns results with the given status.
 func (r *VerificationReport) FilterByStatus(status CheckStatus) []VerificationResult {
 	var filtered []VerificationResult
@@ -83,3 +99,49 @@ func (r *VerificationReport) FilterByType(checkType CheckType) []VerificationRes
 	}
 	return filtered
 }
+
+// Rules describes each verify check type for CI report formats.
+func Rules() []output.Rule {
+	return []output.Rule{
+		{ID: string(CheckTypeCitation), Description: "Citations resolve to a known paper or bibliography entry"},
+		{ID: string(CheckTypeURL), Description: "URLs are reachable"},
+		{ID: string(CheckTypeCodeLink), Description: "Code links point to an existing file and line range at a pinned commit"},
+		{ID: string(CheckTypeClaim), Description: "Factual claims are backed by a citation"},
+		{ID: string(CheckTypeTodoMarker), Description: "Content has no TODO, FIXME, or XXX markers"},
+		{ID: string(CheckTypeBibliography), Description: "Bibliography entries are cited and have unique keys"},
+		{ID: string(CheckTypeDirective), Description: "scribe-ignore directives name known check types"},
+		{ID: string(CheckTypeCrossRef), Description: "Labels are unique and referenced; cross-references and relative links resolve"},
+		{ID: string(CheckTypeIdentifier), Description: "DOIs, arXiv IDs, and PubMed IDs resolve to the paper they are attached to"},
+	}
+}
+
+// Findings converts the report into findings for CI report formats.
+func (r *VerificationReport) Findings() output.FindingSet {
+	set := output.FindingSet{Tool: "scribe-verify", Rules: Rules()}
+	for _, result := range r.Results {
+		level := output.LevelNone
+		switch result.Status {
+		case CheckStatusFail:
+			level = output.LevelError
+		case CheckStatusWarn:
+			level = output.LevelWarning
+		}
+		set.Findings = append(set.Findings, output.Finding{
+			RuleID:  string(result.CheckType),
+			Level:   level,
+			File:    result.Target.File,
+			Line:    result.Target.Line,
+			Target:  result.Target.Text,
+			Message: result.Message,
+		})
+	}
+	return set
+}
+
+// rebuildSummary recomputes the summary and exit code after results change status.
+func (r *VerificationReport) rebuildSummary() {
+	builder := &ReportBuilder{files: r.ContentFiles, results: r.Results}
+	rebuilt := builder.Build()
+	r.Summary = rebuilt.Summary
+	r.ExitCode = rebuilt.ExitCode
+}
@@ -0,0 +1,451 @@
+package verify
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/bibtex"
+)
+
+// ErrResolverUnavailable is returned when a resolver backend cannot be used
+// in the current environment (e.g. the bip CLI is not installed).
+var ErrResolverUnavailable = errors.New("resolver unavailable")
+
+// DefaultPaperCachePath is the default path for the resolved paper cache.
+const DefaultPaperCachePath = ".scribe/cache/papers.json"
+
+// DefaultPaperCacheTTL is how long a cached paper lookup stays valid.
+const DefaultPaperCacheTTL = 7 * 24 * time.Hour
+
+// PaperRecord holds the metadata known about a resolved paper.
+type PaperRecord struct {
+	ID      string   `json:"id"`
+	S2ID    string   `json:"s2_id,omitempty"`
+	DOI     string   `json:"doi,omitempty"`
+	Title   string   `json:"title,omitempty"`
+	Authors []string `json:"authors,omitempty"`
+	Year    int      `json:"year,omitempty"`
+	Source  string   `json:"source"`
+}
+
+// CitationResolver looks up paper IDs in a paper library.
+// Resolve returns (nil, nil) when the backend is usable but the paper is unknown.
+type CitationResolver interface {
+	Name() string
+	Resolve(paperID string) (*PaperRecord, error)
+}
+
+// BipartiteResolver resolves paper IDs with the bip CLI. Lookups go to the
+// Semantic Scholar API, so they are spaced out by its rate limit; papers
+// found in a library or the paper cache never reach this resolver.
+type BipartiteResolver struct {
+	limiter *hostLimiter
+}
+
+// NewBipartiteResolver creates a new BipartiteResolver.
+func NewBipartiteResolver() *BipartiteResolver {
+	return &BipartiteResolver{
+		limiter: &hostLimiter{interval: DefaultRateLimits()[hostSemanticScholar]},
+	}
+}
+
+// Name returns the resolver name.
+func (r *BipartiteResolver) Name() string {
+	return "bipartite"
+}
+
+// Resolve looks up a paper with `bip s2 get`.
+func (r *BipartiteResolver) Resolve(paperID string) (*PaperRecord, error) {
+	if _, err := exec.LookPath("bip"); err != nil {
+		return nil, fmt.Errorf("bip CLI not found: %w", ErrResolverUnavailable)
+	}
+
+	if r.limiter != nil {
+		r.limiter.wait()
+	}
+	cmd := exec.Command("bip", "s2", "get", paperID)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		// Check if it's a "not found" error vs other error
+		stderrStr := stderr.String()
+		if strings.Contains(stderrStr, "not found") || strings.Contains(stderrStr, "no such") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("bip lookup failed: %s", stderrStr)
+	}
+
+	record := &PaperRecord{ID: paperID, Source: r.Name()}
+	// bip prints JSON metadata; use whatever fields are present
+	var meta libraryEntry
+	if err := json.Unmarshal(stdout.Bytes(), &meta); err == nil {
+		if meta.ID != "" {
+			record.ID = meta.ID
+		}
+		record.S2ID = meta.S2ID
+		record.DOI = meta.DOI
+		record.Title = meta.Title
+		record.Authors = meta.Authors
+		record.Year = meta.Year
+	}
+	return record, nil
+}
+
+// libraryEntry is a single paper in a JSONL library export.
+type libraryEntry struct {
+	ID      string   `json:"id"`
+	S2ID    string   `json:"s2_id"`
+	DOI     string   `json:"doi"`
+	Title   string   `json:"title"`
+	Authors []string `json:"authors"`
+	Year    int      `json:"year"`
+	Aliases []string `json:"aliases"` // other IDs the paper has been cited as
+}
+
+// LibraryResolver resolves paper IDs against local library files.
+// Supported formats are JSONL exports (one paper object per line) and BibTeX files.
+type LibraryResolver struct {
+	papers map[string]*PaperRecord
+}
+
+// NewLibraryResolver loads the given library files into memory.
+func NewLibraryResolver(paths ...string) (*LibraryResolver, error) {
+	r := &LibraryResolver{
+		papers: make(map[string]*PaperRecord),
+	}
+	for _, path := range paths {
+		var err error
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".bib":
+			err = r.loadBibTeX(path)
+		case ".jsonl", ".json":
+			err = r.loadJSONL(path)
+		default:
+			err = fmt.Errorf("unsupported library format: %s", path)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Name returns the resolver name.
+func (r *LibraryResolver) Name() string {
+	return "library"
+}
+
+// Resolve looks up a paper by ID, S2 ID, or alias in the loaded libraries.
+func (r *LibraryResolver) Resolve(paperID string) (*PaperRecord, error) {
+	if record, ok := r.papers[paperID]; ok {
+		return record, nil
+	}
+	return nil, nil
+}
+
+// Len returns the number of papers loaded.
+func (r *LibraryResolver) Len() int {
+	return len(r.papers)
+}
+
+func (r *LibraryResolver) loadJSONL(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open library %s: %w", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var entry libraryEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return fmt.Errorf("parse line %d in %s: %w", lineNum, path, err)
+		}
+		if entry.ID == "" && entry.S2ID == "" {
+			continue
+		}
+		record := &PaperRecord{
+			ID:      entry.ID,
+			S2ID:    entry.S2ID,
+			DOI:     entry.DOI,
+			Title:   entry.Title,
+			Authors: entry.Authors,
+			Year:    entry.Year,
+			Source:  path,
+		}
+		if entry.ID != "" {
+			r.papers[entry.ID] = record
+		}
+		if entry.S2ID != "" {
+			r.papers[entry.S2ID] = record
+		}
+		for _, alias := range entry.Aliases {
+			r.papers[alias] = record
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read library %s: %w", path, err)
+	}
+	return nil
+}
+
+func (r *LibraryResolver) loadBibTeX(path string) error {
+	file, err := bibtex.ParseFile(path)
+	if err != nil {
+		return fmt.Errorf("open library %s: %w", path, err)
+	}
+	for _, entry := range file.Entries {
+		if entry.Key == "" {
+			continue
+		}
+		year, _ := strconv.Atoi(entry.Get("year"))
+		r.papers[entry.Key] = &PaperRecord{
+			ID:      entry.Key,
+			DOI:     NormalizeDOI(entry.Get("doi")),
+			Title:   entry.Get("title"),
+			Authors: entry.Names(),
+			Year:    year,
+			Source:  path,
+		}
+	}
+	return nil
+}
+
+// ChainResolver tries several resolvers in order.
+// Unavailable backends are skipped; the first backend that knows the paper wins.
+type ChainResolver struct {
+	resolvers []CitationResolver
+}
+
+// NewChainResolver creates a resolver that consults each backend in order.
+func NewChainResolver(resolvers ...CitationResolver) *ChainResolver {
+	return &ChainResolver{resolvers: resolvers}
+}
+
+// Name returns the resolver name.
+func (r *ChainResolver) Name() string {
+	names := make([]string, len(r.resolvers))
+	for i, resolver := range r.resolvers {
+		names[i] = resolver.Name()
+	}
+	return strings.Join(names, "+")
+}
+
+// Resolve returns the first match across all backends.
+func (r *ChainResolver) Resolve(paperID string) (*PaperRecord, error) {
+	available := 0
+	var lastErr error
+	for _, resolver := range r.resolvers {
+		record, err := resolver.Resolve(paperID)
+		if err != nil {
+			if !errors.Is(err, ErrResolverUnavailable) {
+				lastErr = err
+				available++
+			}
+			continue
+		}
+		available++
+		if record != nil {
+			return record, nil
+		}
+	}
+	if available == 0 {
+		return nil, fmt.Errorf("no citation resolver available: %w", ErrResolverUnavailable)
+	}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return nil, nil
+}
+
+// paperCacheEntry is a single cached lookup result.
+type paperCacheEntry struct {
+	Found    bool         `json:"found"`
+	Resolver string       `json:"resolver"`
+	Record   *PaperRecord `json:"record,omitempty"`
+	CachedAt time.Time    `json:"cached_at"`
+}
+
+// CachedResolver wraps a resolver with a persistent on-disk cache keyed by paper ID.
+// Both hits and definitive misses are cached; backend errors are not.
+// A cached miss is only trusted if it came from the same resolver chain,
+// so adding a library or going back online re-checks previously unknown papers.
+type CachedResolver struct {
+	inner   CitationResolver
+	path    string
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]paperCacheEntry
+	loaded  bool
+	dirty   bool
+}
+
+// NewCachedResolver creates a caching wrapper around inner.
+// If path is empty or ttl is zero, defaults are used.
+func NewCachedResolver(inner CitationResolver, path string, ttl time.Duration) *CachedResolver {
+	if path == "" {
+		path = DefaultPaperCachePath
+	}
+	if ttl == 0 {
+		ttl = DefaultPaperCacheTTL
+	}
+	return &CachedResolver{
+		inner:   inner,
+		path:    path,
+		ttl:     ttl,
+		entries: make(map[string]paperCacheEntry),
+	}
+}
+
+// Name returns the resolver name.
+func (r *CachedResolver) Name() string {
+	return r.inner.Name()
+}
+
+// Resolve returns a cached lookup if it is still fresh, otherwise asks the inner resolver.
+func (r *CachedResolver) Resolve(paperID string) (*PaperRecord, error) {
+	r.mu.Lock()
+	if err := r.load(); err != nil {
+		r.mu.Unlock()
+		return nil, err
+	}
+	entry, ok := r.entries[paperID]
+	r.mu.Unlock()
+
+	if ok && time.Since(entry.CachedAt) < r.ttl {
+		if entry.Found {
+			return entry.Record, nil
+		}
+		if entry.Resolver == r.inner.Name() {
+			return nil, nil
+		}
+	}
+
+	record, err := r.inner.Resolve(paperID)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.entries[paperID] = paperCacheEntry{
+		Found:    record != nil,
+		Resolver: r.inner.Name(),
+		Record:   record,
+		CachedAt: time.Now(),
+	}
+	r.dirty = true
+	r.mu.Unlock()
+
+	return record, nil
+}
+
+// Flush writes the cache to disk if it has changed.
+func (r *CachedResolver) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.dirty {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return fmt.Errorf("create cache directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(r.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal paper cache: %w", err)
+	}
+
+	if err := os.WriteFile(r.path, data, 0644); err != nil {
+		return fmt.Errorf("write paper cache: %w", err)
+	}
+
+	r.dirty = false
+	return nil
+}
+
+// load reads the cache file once. Callers must hold r.mu.
+func (r *CachedResolver) load() error {
+	if r.loaded {
+		return nil
+	}
+	r.loaded = true
+
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("read paper cache: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &r.entries); err != nil {
+		// A corrupt cache is not fatal; start over
+		r.entries = make(map[string]paperCacheEntry)
+	}
+	return nil
+}
+
+// memoResolver remembers lookups for the duration of one run, so the
+// checks that need the same paper resolve it once even when run concurrently.
+type memoResolver struct {
+	inner CitationResolver
+	mu    sync.Mutex
+	calls map[string]*memoCall
+}
+
+// memoCall is a single lookup shared by every caller asking for the same paper.
+type memoCall struct {
+	once   sync.Once
+	record *PaperRecord
+	err    error
+}
+
+// newMemoResolver wraps inner unless it is already memoized.
+func newMemoResolver(inner CitationResolver) CitationResolver {
+	if _, ok := inner.(*memoResolver); ok {
+		return inner
+	}
+	return &memoResolver{inner: inner, calls: make(map[string]*memoCall)}
+}
+
+// Name returns the resolver name.
+func (r *memoResolver) Name() string {
+	return r.inner.Name()
+}
+
+// Resolve looks up a paper, reusing an earlier lookup of the same ID.
+func (r *memoResolver) Resolve(paperID string) (*PaperRecord, error) {
+	r.mu.Lock()
+	call, ok := r.calls[paperID]
+	if !ok {
+		call = &memoCall{}
+		r.calls[paperID] = call
+	}
+	r.mu.Unlock()
+
+	call.once.Do(func() {
+		call.record, call.err = r.inner.Resolve(paperID)
+	})
+	return call.record, call.err
+}
@@ -0,0 +1,179 @@
+package verify
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingResolver records how often it is asked to resolve a paper.
+type countingResolver struct {
+	known map[string]bool
+	mu    sync.Mutex
+	calls int
+}
+
+func (r *countingResolver) Name() string { return "counting" }
+
+func (r *countingResolver) Resolve(paperID string) (*PaperRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	if r.known[paperID] {
+		return &PaperRecord{ID: paperID, Source: "counting"}, nil
+	}
+	return nil, nil
+}
+
+// unavailableResolver simulates a backend that is not installed.
+type unavailableResolver struct{}
+
+func (unavailableResolver) Name() string { return "unavailable" }
+
+func (unavailableResolver) Resolve(paperID string) (*PaperRecord, error) {
+	return nil, ErrResolverUnavailable
+}
+
+func TestLibraryResolver(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	jsonlPath := filepath.Join(tmpDir, "library.jsonl")
+	jsonl := `{"id": "tavare1986", "s2_id": "S2:abc123", "title": "Some probabilistic and statistical problems", "year": 1986}
+
+{"id": "felsenstein1981"}
+`
+	if err := os.WriteFile(jsonlPath, []byte(jsonl), 0644); err != nil {
+		t.Fatalf("write library: %v", err)
+	}
+
+	bibPath := filepath.Join(tmpDir, "references.bib")
+	bib := `@comment{ignored,}
+@article{knuth84,
+  title = {Literate Programming},
+}
+@Book{ felsenstein2004 ,
+  title = {Inferring Phylogenies},
+}
+`
+	if err := os.WriteFile(bibPath, []byte(bib), 0644); err != nil {
+		t.Fatalf("write bib: %v", err)
+	}
+
+	resolver, err := NewLibraryResolver(jsonlPath, bibPath)
+	if err != nil {
+		t.Fatalf("NewLibraryResolver: %v", err)
+	}
+
+	for _, id := range []string{"tavare1986", "S2:abc123", "felsenstein1981", "knuth84", "felsenstein2004"} {
+		record, err := resolver.Resolve(id)
+		if err != nil {
+			t.Fatalf("Resolve(%q): %v", id, err)
+		}
+		if record == nil {
+			t.Errorf("expected %q to resolve", id)
+		}
+	}
+
+	record, _ := resolver.Resolve("S2:abc123")
+	if record != nil && record.Year != 1986 {
+		t.Errorf("got year %d, want 1986", record.Year)
+	}
+
+	for _, id := range []string{"ignored", "missing"} {
+		record, err := resolver.Resolve(id)
+		if err != nil {
+			t.Fatalf("Resolve(%q): %v", id, err)
+		}
+		if record != nil {
+			t.Errorf("expected %q not to resolve", id)
+		}
+	}
+
+	if _, err := NewLibraryResolver(filepath.Join(tmpDir, "library.csv")); err == nil {
+		t.Error("expected error for unsupported library format")
+	}
+}
+
+func TestChainResolver(t *testing.T) {
+	first := &countingResolver{known: map[string]bool{"a": true}}
+	second := &countingResolver{known: map[string]bool{"b": true}}
+	chain := NewChainResolver(unavailableResolver{}, first, second)
+
+	if record, err := chain.Resolve("b"); err != nil || record == nil {
+		t.Errorf("expected b to resolve via second backend, got %v, %v", record, err)
+	}
+	if record, err := chain.Resolve("c"); err != nil || record != nil {
+		t.Errorf("expected c to be unknown, got %v, %v", record, err)
+	}
+
+	// A chain with no usable backends is an error, not a miss
+	empty := NewChainResolver(unavailableResolver{})
+	if _, err := empty.Resolve("a"); err == nil {
+		t.Error("expected error when no resolver is available")
+	}
+}
+
+func TestCachedResolver(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	cachePath := filepath.Join(tmpDir, "papers.json")
+	inner := &countingResolver{known: map[string]bool{"known": true}}
+
+	cached := NewCachedResolver(inner, cachePath, time.Hour)
+	cached.Resolve("known")
+	cached.Resolve("known")
+	cached.Resolve("unknown")
+	cached.Resolve("unknown")
+	if inner.calls != 2 {
+		t.Errorf("got %d backend calls, want 2", inner.calls)
+	}
+	if err := cached.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	// A fresh resolver reuses the persisted cache
+	reloaded := NewCachedResolver(inner, cachePath, time.Hour)
+	record, err := reloaded.Resolve("known")
+	if err != nil || record == nil {
+		t.Fatalf("expected cached hit, got %v, %v", record, err)
+	}
+	if inner.calls != 2 {
+		t.Errorf("got %d backend calls after reload, want 2", inner.calls)
+	}
+
+	// Expired entries are looked up again
+	expired := NewCachedResolver(inner, cachePath, time.Nanosecond)
+	expired.Resolve("known")
+	if inner.calls != 3 {
+		t.Errorf("got %d backend calls after expiry, want 3", inner.calls)
+	}
+}
+
+func TestVerifyCitationWithResolver(t *testing.T) {
+	resolver := &countingResolver{known: map[string]bool{"tavare1986": true}}
+
+	result := VerifyCitation(resolver, "tavare1986", "test.qmd", 1, "")
+	if result.Status != CheckStatusPass {
+		t.Errorf("got status %s, want pass", result.Status)
+	}
+
+	result = VerifyCitation(resolver, "missing", "test.qmd", 1, "")
+	if result.Status != CheckStatusFail {
+		t.Errorf("got status %s, want fail", result.Status)
+	}
+
+	result = VerifyCitation(NewChainResolver(unavailableResolver{}), "tavare1986", "test.qmd", 1, "")
+	if result.Status != CheckStatusFail {
+		t.Errorf("got status %s, want fail when no resolver is available", result.Status)
+	}
+}
@@ -0,0 +1,230 @@
+package verify
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultResultCachePath is the default location of the verification result cache.
+const DefaultResultCachePath = ".scribe/cache/results.json"
+
+// NoExpiry is a result TTL for checks whose outcome cannot change,
+// such as code links pinned to a commit SHA.
+const NoExpiry time.Duration = -1
+
+// DefaultResultTTLs returns how long passing results stay valid per check type.
+// Check types without an entry are never cached.
+func DefaultResultTTLs() map[CheckType]time.Duration {
+	return map[CheckType]time.Duration{
+		CheckTypeCodeLink:   NoExpiry,
+		CheckTypeURL:        24 * time.Hour,
+		CheckTypeCitation:   DefaultPaperCacheTTL,
+		CheckTypeClaim:      DefaultPaperCacheTTL,
+		CheckTypeIdentifier: DefaultPaperCacheTTL,
+	}
+}
+
+// resultCacheEntry is a single cached verification result.
+type resultCacheEntry struct {
+	Result   VerificationResult `json:"result"`
+	CachedAt time.Time          `json:"cached_at"`
+}
+
+// ResultCacheStats summarizes the contents of a result cache.
+type ResultCacheStats struct {
+	Path      string            `json:"path"`
+	SizeBytes int64             `json:"size_bytes"`
+	Entries   int               `json:"entries"`
+	Expired   int               `json:"expired"`
+	ByType    map[CheckType]int `json:"by_type"`
+}
+
+// ResultCache persists passing verification results between runs.
+// Entries are keyed by check type, target and the hash of the file the
+// target appears in, so editing a file re-checks everything in it.
+// Failures are never cached so they are re-checked on every run.
+type ResultCache struct {
+	path    string
+	ttls    map[CheckType]time.Duration
+	mu      sync.Mutex
+	entries map[string]resultCacheEntry
+	loaded  bool
+	dirty   bool
+}
+
+// NewResultCache creates a result cache backed by path.
+// If path is empty or ttls is nil, defaults are used.
+func NewResultCache(path string, ttls map[CheckType]time.Duration) *ResultCache {
+	if path == "" {
+		path = DefaultResultCachePath
+	}
+	if ttls == nil {
+		ttls = DefaultResultTTLs()
+	}
+	return &ResultCache{
+		path:    path,
+		ttls:    ttls,
+		entries: make(map[string]resultCacheEntry),
+	}
+}
+
+// ResultCacheKey derives a cache key from a check target and the hash of its file.
+func ResultCacheKey(target, contentHash string) string {
+	sum := sha256.Sum256([]byte(target + "\x00" + contentHash))
+	return hex.EncodeToString(sum[:])
+}
+
+// ContentHash returns the hex SHA-256 of file content.
+func ContentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Get returns a fresh cached result for key.
+func (c *ResultCache) Get(key string) (VerificationResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.load(); err != nil {
+		return VerificationResult{}, false
+	}
+	entry, ok := c.entries[key]
+	if !ok || !c.fresh(entry, time.Now()) {
+		return VerificationResult{}, false
+	}
+	return entry.Result, true
+}
+
+// Put stores a result if its check type is cacheable and it passed. A claim
+// that passed only because the LLM failed is not stored.
+func (c *ResultCache) Put(key string, result VerificationResult) {
+	if result.Status != CheckStatusPass || result.uncacheable || c.ttls[result.CheckType] == 0 {
+		return
+	}
+	if claim := result.Details.Claim; claim != nil && claim.LLMError != "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.load(); err != nil {
+		return
+	}
+	c.entries[key] = resultCacheEntry{Result: result, CachedAt: time.Now()}
+	c.dirty = true
+}
+
+// Prune removes expired entries, or every entry if all is set.
+// It returns the number of entries removed and writes the cache back to disk.
+func (c *ResultCache) Prune(all bool) (int, error) {
+	c.mu.Lock()
+	if err := c.load(); err != nil {
+		c.mu.Unlock()
+		return 0, err
+	}
+	now := time.Now()
+	removed := 0
+	for key, entry := range c.entries {
+		if all || !c.fresh(entry, now) {
+			delete(c.entries, key)
+			removed++
+		}
+	}
+	if removed > 0 {
+		c.dirty = true
+	}
+	c.mu.Unlock()
+
+	return removed, c.Flush()
+}
+
+// Stats reports the number of entries in the cache by check type.
+func (c *ResultCache) Stats() (*ResultCacheStats, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+
+	stats := &ResultCacheStats{
+		Path:    c.path,
+		Entries: len(c.entries),
+		ByType:  make(map[CheckType]int),
+	}
+	if info, err := os.Stat(c.path); err == nil {
+		stats.SizeBytes = info.Size()
+	}
+	now := time.Now()
+	for _, entry := range c.entries {
+		stats.ByType[entry.Result.CheckType]++
+		if !c.fresh(entry, now) {
+			stats.Expired++
+		}
+	}
+	return stats, nil
+}
+
+// Flush writes the cache to disk if it has changed.
+func (c *ResultCache) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.dirty {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("create cache directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal result cache: %w", err)
+	}
+
+	if err := os.WriteFile(c.path, data, 0644); err != nil {
+		return fmt.Errorf("write result cache: %w", err)
+	}
+
+	c.dirty = false
+	return nil
+}
+
+// fresh reports whether an entry is still within its check type's TTL.
+func (c *ResultCache) fresh(entry resultCacheEntry, now time.Time) bool {
+	ttl := c.ttls[entry.Result.CheckType]
+	switch {
+	case ttl == 0:
+		return false
+	case ttl < 0:
+		return true
+	default:
+		return now.Sub(entry.CachedAt) < ttl
+	}
+}
+
+// load reads the cache file once. Callers must hold c.mu.
+func (c *ResultCache) load() error {
+	if c.loaded {
+		return nil
+	}
+	c.loaded = true
+
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("read result cache: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		// A corrupt cache is not fatal; start over
+		c.entries = make(map[string]resultCacheEntry)
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package verify
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/llm"
+)
+
+func TestResultCache(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "results.json")
+	ttls := map[CheckType]time.Duration{
+		CheckTypeCodeLink: NoExpiry,
+		CheckTypeURL:      time.Hour,
+	}
+	cache := NewResultCache(path, ttls)
+
+	cache.Put("link", VerificationResult{CheckType: CheckTypeCodeLink, Status: CheckStatusPass})
+	cache.Put("url", VerificationResult{CheckType: CheckTypeURL, Status: CheckStatusPass})
+	cache.Put("broken", VerificationResult{CheckType: CheckTypeURL, Status: CheckStatusFail})
+	cache.Put("claim", VerificationResult{CheckType: CheckTypeClaim, Status: CheckStatusPass})
+
+	if _, ok := cache.Get("broken"); ok {
+		t.Error("failed results should not be cached")
+	}
+	if _, ok := cache.Get("claim"); ok {
+		t.Error("check types without a TTL should not be cached")
+	}
+	if err := cache.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	// Age the URL entry past its TTL
+	cache.entries["url"] = resultCacheEntry{
+		Result:   cache.entries["url"].Result,
+		CachedAt: time.Now().Add(-2 * time.Hour),
+	}
+	if _, ok := cache.Get("url"); ok {
+		t.Error("expired URL result should not be returned")
+	}
+	cache.dirty = true
+	if err := cache.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	reloaded := NewResultCache(path, ttls)
+	if _, ok := reloaded.Get("link"); !ok {
+		t.Error("code link result should survive reload and never expire")
+	}
+
+	stats, err := reloaded.Stats()
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.Entries != 2 || stats.Expired != 1 || stats.ByType[CheckTypeURL] != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	removed, err := reloaded.Prune(false)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("Prune removed %d entries, want 1", removed)
+	}
+	removed, err = NewResultCache(path, ttls).Prune(true)
+	if err != nil {
+		t.Fatalf("Prune(all) failed: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("Prune(all) removed %d entries, want 1", removed)
+	}
+}
+
+func TestResultCache_PaperIdentifier(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	cache := NewResultCache(filepath.Join(tmpDir, "results.json"), nil)
+
+	// A paper that does not resolve may resolve next time
+	unresolved := verifyPaperIdentifier(unavailableResolver{}, nil, "smith2020", "ch.qmd", 3, "")
+	if !unresolved.uncacheable || unresolved.CheckType != CheckTypeIdentifier || unresolved.Details.Identifier != nil {
+		t.Errorf("unresolved paper = %+v, want an uncacheable result without details", unresolved)
+	}
+	cache.Put("unresolved", unresolved)
+	if _, ok := cache.Get("unresolved"); ok {
+		t.Error("identifier check of an unresolved paper was cached")
+	}
+
+	// A paper without a DOI will not gain one until its record changes
+	noDOI := verifyPaperIdentifier(&countingResolver{known: map[string]bool{"smith2020": true}}, nil, "smith2020", "ch.qmd", 3, "")
+	if noDOI.uncacheable || noDOI.Status != CheckStatusPass {
+		t.Errorf("paper without a DOI = %+v, want a cacheable pass", noDOI)
+	}
+	cache.Put("no-doi", noDOI)
+	if _, ok := cache.Get("no-doi"); !ok {
+		t.Error("identifier check of a paper without a DOI was not cached")
+	}
+}
+
+func TestVerifyFiles_ResultCache(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	qmdPath := filepath.Join(tmpDir, "chapter.qmd")
+	if err := os.WriteFile(qmdPath, []byte("See @paper:tavare1986.\n"), 0644); err != nil {
+		t.Fatalf("write qmd: %v", err)
+	}
+
+	resolver := &countingResolver{known: map[string]bool{"tavare1986": true}}
+	cachePath := filepath.Join(tmpDir, "results.json")
+	run := func() *VerificationReport {
+		cache := NewResultCache(cachePath, nil)
+		opts := Options{
+			Resolver: resolver,
+			Mirror:   NewRepoMirror(filepath.Join(tmpDir, "repos")),
+			Cache:    cache,
+		}
+		report, err := VerifyFiles([]string{qmdPath}, opts)
+		if err != nil {
+			t.Fatalf("VerifyFiles failed: %v", err)
+		}
+		if err := cache.Flush(); err != nil {
+			t.Fatalf("Flush failed: %v", err)
+		}
+		return report
+	}
+
+	run()
+	report := run()
+	if resolver.calls != 1 {
+		t.Errorf("expected second run to use the cache, got %d resolver calls", resolver.calls)
+	}
+	if len(report.Results) != 1 || !report.Results[0].Cached {
+		t.Errorf("expected one cached result, got %+v", report.Results)
+	}
+
+	// Editing the file invalidates its cached results
+	if err := os.WriteFile(qmdPath, []byte("Edited. See @paper:tavare1986.\n"), 0644); err != nil {
+		t.Fatalf("write qmd: %v", err)
+	}
+	run()
+	if resolver.calls != 2 {
+		t.Errorf("expected edit to invalidate the cache, got %d resolver calls", resolver.calls)
+	}
+}
+
+func TestVerifyFiles_ResultCacheDependencies(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	qmdPath := filepath.Join(tmpDir, "chapter.qmd")
+	if err := os.WriteFile(qmdPath, []byte("See @felsenstein1981.\n"), 0644); err != nil {
+		t.Fatalf("write qmd: %v", err)
+	}
+	bibPath := filepath.Join(tmpDir, "references.bib")
+	writeBib := func(content string) {
+		if err := os.WriteFile(bibPath, []byte(content), 0644); err != nil {
+			t.Fatalf("write bib: %v", err)
+		}
+	}
+	cachePath := filepath.Join(tmpDir, "results.json")
+	run := func() VerificationResult {
+		bib, err := LoadBibliography(bibPath)
+		if err != nil {
+			t.Fatalf("LoadBibliography: %v", err)
+		}
+		cache := NewResultCache(cachePath, nil)
+		opts := Options{
+			Resolver:     &countingResolver{},
+			Bibliography: bib,
+			Cache:        cache,
+			Checks:       []CheckType{CheckTypeCitation},
+		}
+		report, err := VerifyFiles([]string{qmdPath}, opts)
+		if err != nil {
+			t.Fatalf("VerifyFiles failed: %v", err)
+		}
+		if err := cache.Flush(); err != nil {
+			t.Fatalf("Flush failed: %v", err)
+		}
+		if len(report.Results) != 1 {
+			t.Fatalf("got %d results, want 1: %+v", len(report.Results), report.Results)
+		}
+		return report.Results[0]
+	}
+
+	writeBib("@article{felsenstein1981, title={Evolutionary trees from DNA sequences}, year={1981}}\n")
+	run()
+	if r := run(); !r.Cached || r.Status != CheckStatusPass {
+		t.Errorf("expected a cached pass, got %+v", r)
+	}
+
+	// Deleting the entry invalidates the cached pass
+	writeBib("@article{other2000, title={Other}, year={2000}}\n")
+	if r := run(); r.Cached || r.Status == CheckStatusPass {
+		t.Errorf("expected the citation to be re-checked and fail, got %+v", r)
+	}
+}
+
+func TestCacheDependencies(t *testing.T) {
+	analyzer := NewClaimAnalyzer(llm.NewClientWithProvider(llm.NewFake(), llm.Config{}), 1)
+	opts := Options{Resolver: &countingResolver{}, Metadata: &FixtureMetadataProvider{}}
+	withLLM := opts
+	withLLM.Claims = analyzer
+
+	claim := "claim:false:Trees are binary."
+	if cacheDependencies(claim, opts, nil) == cacheDependencies(claim, withLLM, nil) {
+		t.Error("claim results with and without the LLM share a cache key")
+	}
+	if !strings.Contains(cacheDependencies(claim, withLLM, nil), llm.ClaimBatchPromptVersion) {
+		t.Error("claim cache key does not include the prompt version")
+	}
+
+	other := opts
+	other.Resolver = unavailableResolver{}
+	if cacheDependencies("citation:abc", opts, nil) == cacheDependencies("citation:abc", other, nil) {
+		t.Error("citation results from different resolvers share a cache key")
+	}
+	if cacheDependencies("url:https://example.org", opts, nil) != "" {
+		t.Error("URL results should depend only on the file")
+	}
+}
@@ -0,0 +1,219 @@
+package verify
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/llm"
+)
+
+// DefaultWorkers is the default number of checks run in parallel.
+const DefaultWorkers = 8
+
+// Rate-limit buckets for checks that do not talk to a URL directly.
+const (
+	hostGitHub          = "github.com"
+	hostSemanticScholar = "api.semanticscholar.org"
+	hostLLM             = "llm"
+)
+
+// DefaultRateLimits returns the minimum interval between requests per host.
+// A host matches its own entry and any subdomain of it.
+func DefaultRateLimits() map[string]time.Duration {
+	return map[string]time.Duration{
+		hostGitHub:          100 * time.Millisecond,
+		"doi.org":           200 * time.Millisecond,
+		hostSemanticScholar: time.Second, // public S2 API allows ~1 request/second
+	}
+}
+
+// check is a single planned verification check.
+type check struct {
+	key      string // dedup key; checks with the same non-empty key run once
+	cacheKey string // result cache key; empty means never cached
+	host     string // rate-limit bucket; empty means unlimited
+	target   VerificationTarget
+	run      func() VerificationResult
+	keep     func(VerificationResult) bool // nil keeps every result
+	claim    *llm.ClaimItem                // sentence to analyze with the LLM before the checks run
+}
+
+// hostLimiter spaces out requests to a single host.
+type hostLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// wait blocks until the next request slot for this host.
+func (l *hostLimiter) wait() {
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	time.Sleep(time.Until(slot))
+}
+
+// scheduler runs checks on a bounded worker pool with per-host rate limits.
+type scheduler struct {
+	workers  int
+	limiters map[string]*hostLimiter
+	cache    *ResultCache   // nil disables result caching
+	claims   *ClaimAnalyzer // batches LLM claim analysis; nil disables it
+}
+
+// newScheduler creates a scheduler from verification options.
+func newScheduler(opts Options) *scheduler {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	limits := opts.RateLimits
+	if limits == nil {
+		limits = DefaultRateLimits()
+	}
+	s := &scheduler{
+		workers:  workers,
+		limiters: make(map[string]*hostLimiter, len(limits)),
+		cache:    opts.Cache,
+		claims:   opts.Claims,
+	}
+	for host, interval := range limits {
+		if interval > 0 {
+			s.limiters[strings.ToLower(host)] = &hostLimiter{interval: interval}
+		}
+	}
+	return s
+}
+
+// limiterFor returns the limiter for a host, matching parent domains.
+func (s *scheduler) limiterFor(host string) *hostLimiter {
+	host = strings.ToLower(host)
+	for host != "" {
+		if l, ok := s.limiters[host]; ok {
+			return l
+		}
+		i := strings.IndexByte(host, '.')
+		if i < 0 {
+			break
+		}
+		host = host[i+1:]
+	}
+	return nil
+}
+
+// run executes checks and returns their results in plan order.
+// Checks sharing a key are executed once and the result is reused for each
+// occurrence with its own target and check ID.
+func (s *scheduler) run(checks []check) []VerificationResult {
+	// Collect the unique checks to execute
+	firstByKey := make(map[string]int)
+	owner := make([]int, len(checks)) // index of the check whose result is reused
+	var unique []int
+	for i, c := range checks {
+		if c.key != "" {
+			if first, ok := firstByKey[c.key]; ok {
+				owner[i] = first
+				continue
+			}
+			firstByKey[c.key] = i
+		}
+		owner[i] = i
+		unique = append(unique, i)
+	}
+
+	s.prefetchClaims(checks, unique)
+
+	executed := make([]VerificationResult, len(checks))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < s.workers && w < len(unique); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				executed[i] = s.execute(checks[i])
+			}
+		}()
+	}
+	for _, i := range unique {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var results []VerificationResult
+	for i, c := range checks {
+		result := executed[owner[i]]
+		if owner[i] != i {
+			result.CheckID = uuid.New().String()
+		}
+		result.Target = c.target // including lines the target repeats on
+		if c.keep != nil && !c.keep(result) {
+			continue
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// prefetchClaims sends the sentences of uncached claim checks to the LLM
+// in batches.
+func (s *scheduler) prefetchClaims(checks []check, unique []int) {
+	if s.claims == nil {
+		return
+	}
+	var items []llm.ClaimItem
+	for _, i := range unique {
+		c := checks[i]
+		if c.claim == nil {
+			continue
+		}
+		if s.cache != nil && c.cacheKey != "" {
+			if _, ok := s.cache.Get(c.cacheKey); ok {
+				continue
+			}
+		}
+		items = append(items, *c.claim)
+	}
+	s.claims.Prefetch(items)
+}
+
+// execute runs a single check, reusing a cached result when one is available.
+func (s *scheduler) execute(c check) VerificationResult {
+	if s.cache != nil && c.cacheKey != "" {
+		if result, ok := s.cache.Get(c.cacheKey); ok {
+			result.CheckID = uuid.New().String()
+			result.Target = c.target
+			result.Cached = true
+			return result
+		}
+	}
+
+	if l := s.limiterFor(c.host); l != nil {
+		l.wait()
+	}
+	result := c.run()
+
+	if s.cache != nil && c.cacheKey != "" {
+		s.cache.Put(c.cacheKey, result)
+	}
+	return result
+}
+
+// hostOf returns the lowercased host of a URL, or "" if it cannot be parsed.
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
@@ -0,0 +1,166 @@
+package verify
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScheduler_DedupAndOrder(t *testing.T) {
+	var calls atomic.Int32
+	makeCheck := func(key string, line int) check {
+		target := VerificationTarget{File: "test.qmd", Line: line}
+		return check{
+			key:    key,
+			target: target,
+			run: func() VerificationResult {
+				calls.Add(1)
+				// Finish out of order to exercise result ordering
+				time.Sleep(time.Duration(10-line) * time.Millisecond)
+				return VerificationResult{CheckID: fmt.Sprint(line), Target: target, Status: CheckStatusPass, Message: key}
+			},
+		}
+	}
+
+	checks := []check{
+		makeCheck("a", 1),
+		makeCheck("b", 2),
+		makeCheck("a", 3),
+		makeCheck("", 4),
+		makeCheck("", 5),
+	}
+
+	s := newScheduler(Options{Workers: 4})
+	results := s.run(checks)
+
+	if got := calls.Load(); got != 4 {
+		t.Errorf("got %d executions, want 4", got)
+	}
+	if len(results) != 5 {
+		t.Fatalf("got %d results, want 5", len(results))
+	}
+	for i, r := range results {
+		if r.Target.Line != i+1 {
+			t.Errorf("result %d: got line %d, want %d", i, r.Target.Line, i+1)
+		}
+	}
+	if results[2].Message != "a" || results[2].CheckID == results[0].CheckID {
+		t.Errorf("expected shared result with its own check ID, got %+v", results[2])
+	}
+}
+
+func TestScheduler_Keep(t *testing.T) {
+	checks := []check{
+		{run: func() VerificationResult { return VerificationResult{Status: CheckStatusPass} }},
+		{run: func() VerificationResult { return VerificationResult{Status: CheckStatusFail} }},
+	}
+	for i := range checks {
+		checks[i].keep = func(r VerificationResult) bool { return r.Status == CheckStatusFail }
+	}
+
+	results := newScheduler(Options{}).run(checks)
+	if len(results) != 1 || results[0].Status != CheckStatusFail {
+		t.Errorf("expected only the failed result, got %+v", results)
+	}
+}
+
+func TestScheduler_RateLimit(t *testing.T) {
+	s := newScheduler(Options{
+		Workers:    4,
+		RateLimits: map[string]time.Duration{"example.org": 20 * time.Millisecond},
+	})
+
+	if s.limiterFor("api.example.org") == nil {
+		t.Error("expected subdomain to share the parent host limiter")
+	}
+	if s.limiterFor("example.com") != nil {
+		t.Error("expected no limiter for unrelated host")
+	}
+
+	var checks []check
+	for i := 0; i < 4; i++ {
+		checks = append(checks, check{
+			host: "example.org",
+			run:  func() VerificationResult { return VerificationResult{} },
+		})
+	}
+
+	start := time.Now()
+	s.run(checks)
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("4 rate-limited checks took %v, want at least 60ms", elapsed)
+	}
+}
+
+func TestVerifyFiles_DedupAcrossFiles(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	var files []string
+	for i := 0; i < 3; i++ {
+		path := filepath.Join(tmpDir, fmt.Sprintf("ch%d.qmd", i))
+		content := fmt.Sprintf("# Chapter %d\n\nSee @paper:shared and @paper:only%d.\n", i, i)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("write file: %v", err)
+		}
+		files = append(files, path)
+	}
+
+	resolver := &countingResolver{known: map[string]bool{"shared": true}}
+	opts := DefaultOptions()
+	opts.UseLLM = false
+	opts.Resolver = resolver
+	opts.RateLimits = map[string]time.Duration{}
+
+	report, err := VerifyFiles(files, opts)
+	if err != nil {
+		t.Fatalf("VerifyFiles: %v", err)
+	}
+
+	citations := report.FilterByType(CheckTypeCitation)
+	if len(citations) != 6 {
+		t.Fatalf("got %d citation results, want 6", len(citations))
+	}
+	if resolver.calls != 4 {
+		t.Errorf("got %d resolver calls, want 4 (shared citation checked once)", resolver.calls)
+	}
+	for i, r := range citations {
+		if want := files[i/2]; r.Target.File != want {
+			t.Errorf("result %d: got file %s, want %s", i, r.Target.File, want)
+		}
+	}
+}
+
+func TestVerifyFile_LocalCitationsNotRateLimited(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "ch.qmd")
+	content := "# Chapter\n\nSee @paper:a, @paper:b, @paper:c, @paper:d, and @unknown2020.\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	// Default rate limits, but every paper resolves without the network
+	opts := DefaultOptions()
+	opts.UseLLM = false
+	opts.Checks = []CheckType{CheckTypeCitation}
+	opts.Resolver = &countingResolver{known: map[string]bool{"a": true, "b": true, "c": true, "d": true}}
+
+	start := time.Now()
+	if _, err := VerifyFile(path, opts); err != nil {
+		t.Fatalf("VerifyFile: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("local citation checks took %v; they should not wait for the S2 rate limit", elapsed)
+	}
+}
@@ -0,0 +1,140 @@
+package verify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/qmd"
+)
+
+// Suppression directives, written as HTML comments in QMD files:
+//
+//	<!-- scribe-ignore claim -->            in a paragraph: ignore claim findings in that paragraph
+//	<!-- scribe-ignore-next-line url -->    ignore url findings on the next non-blank line
+//	<!-- scribe-ignore -- reason -->        no check types: ignore every check type
+//
+// Text after "--" is recorded as the reason.
+const (
+	directiveIgnore         = "scribe-ignore"
+	directiveIgnoreNextLine = "scribe-ignore-next-line"
+)
+
+// suppression silences findings on a range of lines.
+type suppression struct {
+	startLine int
+	endLine   int
+	types     map[CheckType]bool // nil matches every check type
+	reason    string
+}
+
+// suppressions are the directives found in one file.
+type suppressions []suppression
+
+// parseSuppressions collects scribe-ignore directives from a document.
+// A directive naming an unknown check type is reported as a failed result.
+func parseSuppressions(doc *qmd.Document) (suppressions, []VerificationResult) {
+	var sups suppressions
+	var problems []VerificationResult
+
+	for _, comment := range doc.Comments {
+		text := comment.Text
+		var reason string
+		if i := strings.Index(text, "--"); i >= 0 {
+			text, reason = text[:i], strings.TrimSpace(text[i+2:])
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 || !isDirective(fields[0]) {
+			continue
+		}
+
+		sup := suppression{reason: reason}
+		if len(fields) > 1 {
+			sup.types = make(map[CheckType]bool)
+		}
+		for _, name := range fields[1:] {
+			checkType, err := ParseCheckType(strings.TrimSuffix(name, ","))
+			if err != nil {
+				line := comment.Span.Start.Line
+				problems = append(problems, VerificationResult{
+					CheckID:   uuid.New().String(),
+					CheckType: CheckTypeDirective,
+					Target:    VerificationTarget{File: doc.Path, Line: line, Text: doc.Line(line)},
+					Status:    CheckStatusFail,
+					Message:   fmt.Sprintf("Invalid %s directive: %v", fields[0], err),
+					CheckedAt: time.Now(),
+				})
+				continue
+			}
+			sup.types[checkType] = true
+		}
+		if sup.types != nil && len(sup.types) == 0 {
+			continue // every named check type was invalid
+		}
+
+		if fields[0] == directiveIgnoreNextLine {
+			next := nextContentLine(doc, comment.Span.End.Line+1)
+			sup.startLine, sup.endLine = next, next
+		} else {
+			sup.startLine, sup.endLine = comment.Span.Start.Line, comment.Span.End.Line
+			for _, para := range doc.Paragraphs {
+				if para.Span.Start.Line <= comment.Span.Start.Line && comment.Span.Start.Line <= para.Span.End.Line {
+					sup.startLine, sup.endLine = para.Span.Start.Line, para.Span.End.Line
+					break
+				}
+			}
+		}
+		sups = append(sups, sup)
+	}
+
+	return sups, problems
+}
+
+// isDirective reports whether word names a scribe directive.
+func isDirective(word string) bool {
+	return word == directiveIgnore || word == directiveIgnoreNextLine
+}
+
+// directiveTexts maps line numbers to the text of scribe directives starting there,
+// so other checks can skip them (e.g. "scribe-ignore todo-marker" is not a TODO).
+func directiveTexts(doc *qmd.Document) map[int][]string {
+	texts := make(map[int][]string)
+	for _, comment := range doc.Comments {
+		if fields := strings.Fields(comment.Text); len(fields) > 0 && isDirective(fields[0]) {
+			texts[comment.Span.Start.Line] = append(texts[comment.Span.Start.Line], comment.Text)
+		}
+	}
+	return texts
+}
+
+// nextContentLine returns the first line at or after line that is not blank or a comment.
+func nextContentLine(doc *qmd.Document, line int) int {
+	for ; line <= len(doc.Lines); line++ {
+		if kind := doc.Kind(line); kind != qmd.LineBlank && kind != qmd.LineComment {
+			return line
+		}
+	}
+	return line
+}
+
+// apply marks a non-passing result as suppressed if a directive covers it.
+func (s suppressions) apply(result VerificationResult) VerificationResult {
+	if result.Status == CheckStatusPass {
+		return result
+	}
+	for _, sup := range s {
+		if result.Target.Line < sup.startLine || result.Target.Line > sup.endLine {
+			continue
+		}
+		if sup.types != nil && !sup.types[result.CheckType] {
+			continue
+		}
+		result.Status = CheckStatusSuppressed
+		if sup.reason != "" {
+			result.Message += " (suppressed: " + sup.reason + ")"
+		}
+		return result
+	}
+	return result
+}
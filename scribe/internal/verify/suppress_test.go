@@ -0,0 +1,128 @@
+package verify
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/qmd"
+)
+
+func TestParseSuppressions(t *testing.T) {
+	content := `# Intro
+
+This is a broad claim. <!-- scribe-ignore claim -- discussed in ch. 3 -->
+It continues here.
+
+<!-- scribe-ignore-next-line url -->
+
+See https://example.com/dead for details.
+See https://example.com/other too.
+
+<!-- scribe-ignore spelling -->
+`
+	doc := qmd.Parse("ch.qmd", []byte(content))
+	sups, problems := parseSuppressions(doc)
+
+	if len(sups) != 2 {
+		t.Fatalf("got %d suppressions, want 2", len(sups))
+	}
+	if len(problems) != 1 || problems[0].CheckType != CheckTypeDirective || problems[0].Target.Line != 11 {
+		t.Errorf("expected one directive problem on line 11, got %+v", problems)
+	}
+
+	tests := []struct {
+		name      string
+		checkType CheckType
+		line      int
+		status    CheckStatus
+	}{
+		{"claim in paragraph", CheckTypeClaim, 4, CheckStatusSuppressed},
+		{"other type in paragraph", CheckTypeURL, 3, CheckStatusFail},
+		{"url on next line", CheckTypeURL, 8, CheckStatusSuppressed},
+		{"url on following line", CheckTypeURL, 9, CheckStatusFail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := sups.apply(VerificationResult{
+				CheckType: tt.checkType,
+				Target:    VerificationTarget{File: "ch.qmd", Line: tt.line},
+				Status:    CheckStatusFail,
+				Message:   "problem",
+			})
+			if result.Status != tt.status {
+				t.Errorf("status = %s, want %s", result.Status, tt.status)
+			}
+		})
+	}
+
+	result := sups.apply(VerificationResult{CheckType: CheckTypeClaim, Target: VerificationTarget{Line: 3}, Status: CheckStatusWarn, Message: "uncited"})
+	if !strings.HasSuffix(result.Message, "(suppressed: discussed in ch. 3)") {
+		t.Errorf("reason not recorded: %q", result.Message)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	a := VerificationResult{
+		CheckType: CheckTypeURL,
+		Target:    VerificationTarget{File: "ch.qmd", Line: 10, Text: "See https://example.com"},
+		Details:   VerificationDetails{URL: &URLDetails{URL: "https://example.com"}},
+	}
+	b := a
+	b.Target.Line = 42
+	b.Target.Text = "Moved: see https://example.com"
+	if Fingerprint(a) != Fingerprint(b) {
+		t.Error("fingerprint should not depend on line number or surrounding text")
+	}
+
+	c := a
+	c.Target.File = "other.qmd"
+	if Fingerprint(a) == Fingerprint(c) {
+		t.Error("fingerprint should depend on file")
+	}
+}
+
+func TestBaseline(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "scribe-test-*")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	builder := NewReportBuilder()
+	builder.AddFile("ch.qmd")
+	builder.AddResult(VerificationResult{CheckType: CheckTypeTodoMarker, Target: VerificationTarget{File: "ch.qmd", Line: 1, Text: "TODO: old"}, Status: CheckStatusFail})
+	report := builder.Build()
+	for i := range report.Results {
+		report.Results[i].Fingerprint = Fingerprint(report.Results[i])
+	}
+
+	path := filepath.Join(tmpDir, "baseline.json")
+	if err := NewBaseline(&report).Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	baseline, err := LoadBaseline(path)
+	if err != nil {
+		t.Fatalf("LoadBaseline failed: %v", err)
+	}
+	if len(baseline.Entries) != 1 {
+		t.Fatalf("got %d baseline entries, want 1", len(baseline.Entries))
+	}
+
+	// A new finding still fails; the known one is baselined
+	builder.AddResult(VerificationResult{CheckType: CheckTypeTodoMarker, Target: VerificationTarget{File: "ch.qmd", Line: 5, Text: "TODO: new"}, Status: CheckStatusFail})
+	report = builder.Build()
+	for i := range report.Results {
+		report.Results[i].Fingerprint = Fingerprint(report.Results[i])
+	}
+	report.ApplyBaseline(baseline)
+	if report.Summary.Baselined != 1 || report.Summary.Failed != 1 || report.ExitCode != 1 {
+		t.Errorf("unexpected summary %+v, exit %d", report.Summary, report.ExitCode)
+	}
+
+	if empty, err := LoadBaseline(filepath.Join(tmpDir, "missing.json")); err != nil || len(empty.Entries) != 0 {
+		t.Errorf("missing baseline should be empty, got %+v, %v", empty, err)
+	}
+}
@@ -1,7 +1,12 @@
 // Package verify implements content verification for the scribe CLI.
 package verify
 
-import "time"
+import (
+	"fmt"
+	"time"
+
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/llm"
+)
 
 // CodeLocation represents a specific location in a codebase.
 type CodeLocation struct {
@@ -25,13 +30,61 @@ func (c *CodeLocation) GeneratePermalink() string {
 type CheckType string
 
 const (
-	CheckTypeCitation   CheckType = "citation"
-	CheckTypeURL        CheckType = "url"
-	CheckTypeCodeLink   CheckType = "code-link"
-	CheckTypeClaim      CheckType = "claim"
-	CheckTypeTodoMarker CheckType = "todo-marker"
+	CheckTypeCitation     CheckType = "citation"
+	CheckTypeURL          CheckType = "url"
+	CheckTypeCodeLink     CheckType = "code-link"
+	CheckTypeClaim        CheckType = "claim"
+	CheckTypeTodoMarker   CheckType = "todo-marker"
+	CheckTypeBibliography CheckType = "bibliography"
+	CheckTypeDirective    CheckType = "directive"
+	CheckTypeCrossRef     CheckType = "cross-ref"
+	CheckTypeIdentifier   CheckType = "identifier"
 )
 
+// CheckTypes lists every verify check type.
+func CheckTypes() []CheckType {
+	return []CheckType{
+		CheckTypeCitation,
+		CheckTypeURL,
+		CheckTypeCodeLink,
+		CheckTypeClaim,
+		CheckTypeTodoMarker,
+		CheckTypeBibliography,
+		CheckTypeDirective,
+		CheckTypeCrossRef,
+		CheckTypeIdentifier,
+	}
+}
+
+// ParseCheckType validates a check type name.
+func ParseCheckType(s string) (CheckType, error) {
+	for _, t := range CheckTypes() {
+		if string(t) == s {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("unknown check type: %s", s)
+}
+
+// Severity controls how failures of a check type are reported.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"   // failures fail the run (default)
+	SeverityWarning Severity = "warning" // failures are reported as warnings
+	SeverityOff     Severity = "off"     // the check is not run
+)
+
+// ParseSeverity validates a severity name.
+func ParseSeverity(s string) (Severity, error) {
+	switch sev := Severity(s); sev {
+	case SeverityError, SeverityWarning, SeverityOff:
+		return sev, nil
+	default:
+		return "", fmt.Errorf("unknown severity %q (want error, warning, or off)", s)
+	}
+}
+
 // CheckStatus represents the outcome of a verification check.
 type CheckStatus string
 
@@ -39,6 +92,10 @@ const (
 	CheckStatusPass CheckStatus = "pass"
 	CheckStatusFail CheckStatus = "fail"
 	CheckStatusWarn CheckStatus = "warn"
+
+	CheckStatusSuppressed CheckStatus = "suppressed" // silenced by a scribe-ignore directive
+	CheckStatusBaselined  CheckStatus = "baselined"  // already recorded in the baseline file
+	CheckStatusFixed      CheckStatus = "fixed"      // rewritten in place by verify --fix
 )
 
 // VerificationTarget identifies the source of a verification issue.
@@ -46,12 +103,18 @@ type VerificationTarget struct {
 	File string `json:"file"`
 	Line int    `json:"line"`
 	Text string `json:"text"`
+	// Repeats lists later lines where the same target occurs; a fix
+	// rewrites them along with Line
+	Repeats []int `json:"repeats,omitempty"`
 }
 
 // CitationDetails contains details specific to citation checks.
 type CitationDetails struct {
 	PaperID  string `json:"paper_id"`
 	Resolved bool   `json:"resolved"`
+	Source   string `json:"source,omitempty"` // resolver backend that found the paper
+	// CanonicalID is set when PaperID is an alias of another paper ID
+	CanonicalID string `json:"canonical_id,omitempty"`
 }
 
 // URLDetails contains details specific to URL checks.
@@ -59,13 +122,18 @@ type URLDetails struct {
 	URL        string  `json:"url"`
 	HTTPStatus *int    `json:"http_status,omitempty"`
 	Error      *string `json:"error,omitempty"`
+	// RedirectedTo is the final URL when every redirect was permanent
+	RedirectedTo string `json:"redirected_to,omitempty"`
 }
 
 // CodeLinkDetails contains details specific to code link checks.
 type CodeLinkDetails struct {
 	Permalink      string `json:"permalink"`
+	CommitExists   bool   `json:"commit_exists"`
 	FileExists     bool   `json:"file_exists"`
 	LineRangeValid bool   `json:"line_range_valid"`
+	LineCount      int    `json:"line_count,omitempty"`
+	Snippet        string `json:"snippet,omitempty"`
 }
 
 // ClaimDetails contains details specific to claim checks.
@@ -73,25 +141,55 @@ type ClaimDetails struct {
 	ClaimText       string `json:"claim_text"`
 	Confidence      string `json:"confidence"` // high, medium, low
 	SuggestedAction string `json:"suggested_action"`
+	PromptVersion   string `json:"prompt_version,omitempty"` // LLM prompt behind the verdict, if any
+	LLMError        string `json:"llm_error,omitempty"`      // why the LLM gave no verdict, leaving the claim unchecked
+}
+
+// CrossRefDetails contains details specific to cross-reference checks.
+type CrossRefDetails struct {
+	Problem string `json:"problem"`         // dangling, duplicate, unreferenced, missing-file, or missing-anchor
+	Label   string `json:"label,omitempty"` // label or reference key, without @ or #
+	Link    string `json:"link,omitempty"`  // relative link as written
+}
+
+// IdentifierDetails contains details specific to DOI, arXiv, and PubMed ID checks.
+type IdentifierDetails struct {
+	Kind        IdentifierKind `json:"kind"`
+	Value       string         `json:"value"`
+	Resolved    bool           `json:"resolved"`
+	Title       string         `json:"title,omitempty"` // metadata the identifier is registered with
+	Year        int            `json:"year,omitempty"`
+	FirstAuthor string         `json:"first_author,omitempty"`
+	Expected    string         `json:"expected,omitempty"`   // what the metadata was compared with, e.g. a BibTeX entry
+	Mismatches  []string       `json:"mismatches,omitempty"` // title, year, or first author
 }
 
 // VerificationDetails contains type-specific check details.
 type VerificationDetails struct {
-	Citation *CitationDetails `json:"citation,omitempty"`
-	URL      *URLDetails      `json:"url,omitempty"`
-	CodeLink *CodeLinkDetails `json:"code_link,omitempty"`
-	Claim    *ClaimDetails    `json:"claim,omitempty"`
+	Citation   *CitationDetails   `json:"citation,omitempty"`
+	URL        *URLDetails        `json:"url,omitempty"`
+	CodeLink   *CodeLinkDetails   `json:"code_link,omitempty"`
+	Claim      *ClaimDetails      `json:"claim,omitempty"`
+	CrossRef   *CrossRefDetails   `json:"cross_ref,omitempty"`
+	Identifier *IdentifierDetails `json:"identifier,omitempty"`
 }
 
 // VerificationResult represents the outcome of a single verification check.
 type VerificationResult struct {
-	CheckID   string              `json:"check_id"`
-	CheckType CheckType           `json:"check_type"`
-	Target    VerificationTarget  `json:"target"`
-	Status    CheckStatus         `json:"status"`
-	Message   string              `json:"message"`
-	Details   VerificationDetails `json:"details"`
-	CheckedAt time.Time           `json:"checked_at"`
+	CheckID     string              `json:"check_id"`
+	CheckType   CheckType           `json:"check_type"`
+	Target      VerificationTarget  `json:"target"`
+	Status      CheckStatus         `json:"status"`
+	Message     string              `json:"message"`
+	Details     VerificationDetails `json:"details"`
+	CheckedAt   time.Time           `json:"checked_at"`
+	Cached      bool                `json:"cached,omitempty"`      // reused from the result cache
+	Fingerprint string              `json:"fingerprint,omitempty"` // stable ID of a finding, set for non-passing results
+	Fix         *Fix                `json:"fix,omitempty"`         // safe rewrite of the target line, if any
+
+	// uncacheable marks a result that may change while its cache key does
+	// not, e.g. because a lookup it depends on failed
+	uncacheable bool
 }
 
 // ReportSummary contains aggregated verification statistics.
@@ -100,6 +198,9 @@ type ReportSummary struct {
 	Passed      int `json:"passed"`
 	Failed      int `json:"failed"`
 	Warnings    int `json:"warnings"`
+	Suppressed  int `json:"suppressed"`
+	Baselined   int `json:"baselined"`
+	Fixed       int `json:"fixed"`
 }
 
 // VerificationReport is the aggregated output of verify-content.
@@ -110,4 +211,5 @@ type VerificationReport struct {
 	Summary      ReportSummary        `json:"summary"`
 	Results      []VerificationResult `json:"results"`
 	ExitCode     int                  `json:"exit_code"`
+	LLMUsage     *llm.Usage           `json:"llm_usage,omitempty"` // LLM claim analysis requests, tokens, and cost
 }
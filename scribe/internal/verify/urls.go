@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	neturl "net/url"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,8 +20,22 @@ func ExtractURLs(content string) []string {
 	return urlPattern.FindAllString(content, -1)
 }
 
+// isWebURL reports whether a link is an HTTP(S) URL.
+func isWebURL(link string) bool {
+	return strings.HasPrefix(link, "http://") || strings.HasPrefix(link, "https://")
+}
+
+// DefaultURLTimeout bounds the time spent checking a single URL.
+const DefaultURLTimeout = 10 * time.Second
+
 // VerifyURL checks if a URL is accessible.
 func VerifyURL(url string, file string, line int, text string) VerificationResult {
+	return VerifyURLWithTimeout(url, DefaultURLTimeout, file, line, text)
+}
+
+// VerifyURLWithTimeout checks if a URL is accessible within timeout,
+// including any fallback from HEAD to GET.
+func VerifyURLWithTimeout(url string, timeout time.Duration, file string, line int, text string) VerificationResult {
 	result := VerificationResult{
 		CheckID:   uuid.New().String(),
 		CheckType: CheckTypeURL,
@@ -32,7 +48,7 @@ func VerifyURL(url string, file string, line int, text string) VerificationResul
 	}
 
 	// Check URL accessibility with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
@@ -50,13 +66,18 @@ func VerifyURL(url string, file string, line int, text string) VerificationResul
 	}
 
 	// Use a custom client with reasonable timeouts
+	redirected, permanent := false, true
 	client := &http.Client{
-		Timeout: 15 * time.Second,
+		Timeout: timeout,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			// Allow up to 10 redirects
 			if len(via) >= 10 {
 				return fmt.Errorf("too many redirects")
 			}
+			redirected = true
+			if code := req.Response.StatusCode; code != http.StatusMovedPermanently && code != http.StatusPermanentRedirect {
+				permanent = false
+			}
 			return nil
 		},
 	}
@@ -64,6 +85,7 @@ func VerifyURL(url string, file string, line int, text string) VerificationResul
 	resp, err := client.Do(req)
 	if err != nil {
 		// Try GET if HEAD fails (some servers don't support HEAD)
+		redirected, permanent = false, true
 		req, _ = http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 		resp, err = client.Do(req)
 		if err != nil {
@@ -101,5 +123,24 @@ func VerifyURL(url string, file string, line int, text string) VerificationResul
 		},
 	}
 
+	// A URL that permanently moved can safely be rewritten to its new home
+	if final := redirectTarget(url, resp.Request.URL); redirected && permanent && final != url && result.Status == CheckStatusPass {
+		result.Details.URL.RedirectedTo = final
+		result.Message = fmt.Sprintf("URL %q permanently redirects to %q (HTTP %d)", url, final, statusCode)
+		result.Fix = &Fix{Old: url, New: final, Reason: "permanent redirect"}
+	}
+
 	return result
 }
+
+// redirectTarget returns the final URL of a request, keeping the original
+// fragment since servers never see it.
+func redirectTarget(original string, final *neturl.URL) string {
+	u := *final
+	if u.Fragment == "" {
+		if parsed, err := neturl.Parse(original); err == nil {
+			u.Fragment = parsed.Fragment
+		}
+	}
+	return u.String()
+}
@@ -1,143 +1,492 @@
 package verify
 
 import (
-	"bufio"
-	"os"
+	"fmt"
 	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/llm"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/qmd"
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/quarto"
 )
 
 // Options configures verification behavior.
 type Options struct {
-	UseLLM bool // Whether to use LLM for claim detection
+	UseLLM        bool                     // Whether to use LLM for claim detection
+	Resolver      CitationResolver         // Paper ID resolver (nil = bipartite CLI)
+	Mirror        *RepoMirror              // Local repo mirror for code links (nil = DefaultRepoCacheDir)
+	Workers       int                      // Number of checks run in parallel (0 = DefaultWorkers)
+	RateLimits    map[string]time.Duration // Minimum interval between requests per host (nil = DefaultRateLimits)
+	Bibliography  *Bibliography            // Project bibliography for Pandoc citation keys (nil = none)
+	Cache         *ResultCache             // Persistent result cache (nil = always re-check)
+	Checks        []CheckType              // Check types to run (nil = all)
+	Severities    map[CheckType]Severity   // Per-type severity (missing = SeverityError)
+	URLTimeout    time.Duration            // Timeout per URL check (0 = DefaultURLTimeout)
+	ClaimPatterns *ClaimPatterns           // Claim heuristics (nil = built-in patterns)
+	LLM           llm.Config               // LLM provider for claim analysis
+	Claims        *ClaimAnalyzer           // LLM claim analysis (nil = built from LLM if UseLLM)
+	ClaimBatch    int                      // Sentences per LLM request (0 = DefaultClaimBatchSize)
+	Project       *quarto.Project          // Project the files make up (nil = unrelated files); enables cross-file checks
+	Metadata      MetadataProvider         // DOI, arXiv, and PubMed metadata lookups (nil = web APIs)
+
+	crossRefs *crossRefIndex // labels and references across all files, set by VerifyFiles
 }
 
 // DefaultOptions returns the default verification options.
 func DefaultOptions() Options {
 	return Options{
-		UseLLM: true,
+		UseLLM:     true,
+		Workers:    DefaultWorkers,
+		RateLimits: DefaultRateLimits(),
+	}
+}
+
+// withDefaults fills in backends that were not configured.
+func (o Options) withDefaults() Options {
+	if o.Resolver == nil {
+		o.Resolver = NewBipartiteResolver()
+	}
+	o.Resolver = newMemoResolver(o.Resolver)
+	if o.Mirror == nil {
+		o.Mirror = NewRepoMirror("")
+	}
+	if o.URLTimeout == 0 {
+		o.URLTimeout = DefaultURLTimeout
+	}
+	if o.ClaimPatterns == nil {
+		o.ClaimPatterns = defaultClaimPatterns
 	}
+	if o.Metadata == nil {
+		o.Metadata = NewWebMetadataProvider(o.URLTimeout)
+	}
+	if o.UseLLM && o.Claims == nil {
+		if client, err := llm.NewClientFromConfig(o.LLM); err == nil {
+			o.Claims = NewClaimAnalyzer(client, o.ClaimBatch)
+		}
+	}
+	if !o.UseLLM {
+		o.Claims = nil
+	}
+	return o
+}
+
+// enabled reports whether checks of the given type should run.
+func (o Options) enabled(checkType CheckType) bool {
+	if o.Severities[checkType] == SeverityOff {
+		return false
+	}
+	if len(o.Checks) == 0 {
+		return true
+	}
+	for _, t := range o.Checks {
+		if t == checkType {
+			return true
+		}
+	}
+	return false
+}
+
+// finalize applies severities and suppression directives to a result and
+// fingerprints it if it did not pass.
+func (o Options) finalize(result VerificationResult, sups suppressions) VerificationResult {
+	result = sups.apply(o.applySeverity(result))
+	if result.Status != CheckStatusPass {
+		result.Fingerprint = Fingerprint(result)
+	}
+	return result
+}
+
+// applySeverity downgrades failures of check types configured as warnings.
+func (o Options) applySeverity(result VerificationResult) VerificationResult {
+	if result.Status == CheckStatusFail && o.Severities[result.CheckType] == SeverityWarning {
+		result.Status = CheckStatusWarn
+	}
+	return result
 }
 
 // VerifyFile runs all verification checks on a single file.
 func VerifyFile(filePath string, opts Options) ([]VerificationResult, error) {
-	content, err := os.ReadFile(filePath)
+	opts = opts.withDefaults()
+	doc, checks, err := planFile(filePath, opts)
 	if err != nil {
 		return nil, err
 	}
+	sups, _ := parseSuppressions(doc)
+	results := newScheduler(opts).run(checks)
+	for i := range results {
+		results[i] = opts.finalize(results[i], sups)
+	}
+	return results, nil
+}
 
-	contentStr := string(content)
-	var results []VerificationResult
-
-	// Read file line by line for line-specific checks
-	file, err := os.Open(filePath)
+// planFile parses a file and plans every verification check for it.
+// Network-bound checks are deferred so they can run concurrently.
+func planFile(filePath string, opts Options) (*qmd.Document, []check, error) {
+	doc, err := qmd.ParseFile(filePath)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	defer file.Close()
+	return doc, planDocument(doc, opts), nil
+}
+
+// planDocument plans verification checks for a parsed document.
+// Code chunks and front matter are never scanned for claims or links.
+func planDocument(doc *qmd.Document, opts Options) []check {
+	filePath := doc.Path
+	var checks []check
 
-	scanner := bufio.NewScanner(file)
-	lineNum := 0
-	var lines []string
+	// Check for TODO markers in everything except code chunks and scribe directives
+	directives := directiveTexts(doc)
+	for i, line := range doc.Lines {
+		lineNum := i + 1
+		if !opts.enabled(CheckTypeTodoMarker) || doc.Kind(lineNum) == qmd.LineCode {
+			continue
+		}
+		for _, text := range directives[lineNum] {
+			line = strings.Replace(line, text, "", 1)
+		}
+		for _, marker := range todoMarkerPattern.FindAllString(line, -1) {
+			result := VerifyTodoMarker(marker, filePath, lineNum, line)
+			checks = append(checks, check{
+				target: result.Target,
+				run:    func() VerificationResult { return result },
+			})
+		}
+	}
 
-	for scanner.Scan() {
-		lineNum++
-		line := scanner.Text()
-		lines = append(lines, line)
+	// Report malformed scribe-ignore directives
+	if opts.enabled(CheckTypeDirective) {
+		_, problems := parseSuppressions(doc)
+		for _, result := range problems {
+			checks = append(checks, check{
+				target: result.Target,
+				run:    func() VerificationResult { return result },
+			})
+		}
+	}
 
-		// Check for TODO markers on each line
-		if todoMarkerPattern.MatchString(line) {
-			markers := todoMarkerPattern.FindAllString(line, -1)
-			for _, marker := range markers {
-				results = append(results, VerifyTodoMarker(marker, filePath, lineNum, line))
-			}
+	// Labels, cross-references, and relative links
+	if opts.enabled(CheckTypeCrossRef) {
+		index := opts.crossRefs
+		if index == nil {
+			index = newCrossRefIndex([]*qmd.Document{doc}, nil)
+		}
+		for _, result := range index.results(doc) {
+			checks = append(checks, check{
+				target: result.Target,
+				run:    func() VerificationResult { return result },
+			})
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
+	// Bibliographies for Pandoc citation keys: the project's, then the document's own
+	bibs := []*Bibliography{opts.Bibliography}
+	bibKey := "citekey:"
+	if doc.FrontMatter != nil && opts.enabled(CheckTypeCitation) {
+		docBib, err := loadFrontMatterBibliography(doc)
+		if err != nil {
+			result := bibliographyError(doc, err)
+			checks = append(checks, check{
+				target: result.Target,
+				run:    func() VerificationResult { return result },
+			})
+		} else if docBib != nil {
+			bibs = append(bibs, docBib)
+			bibKey = "citekey:" + filePath + ":"
+			if opts.enabled(CheckTypeIdentifier) {
+				checks = append(checks, bibIdentifierChecks(docBib, opts)...)
+			}
+		}
 	}
 
-	// Extract and verify citations from full content
-	citations := ExtractCitations(contentStr)
-	for _, citation := range citations {
-		// Find line number for this citation
-		lineNum := findLineNumber(lines, "@paper:"+citation)
-		lineText := ""
-		if lineNum > 0 && lineNum <= len(lines) {
-			lineText = lines[lineNum-1]
+	// Verify each cited paper once, at its first occurrence, remembering
+	// the others so a fix rewrites every one
+	repeats := make(map[string][]int)
+	for _, citation := range doc.Citations {
+		lines, line := repeats[citation.Key], citation.Span.Start.Line
+		if len(lines) == 0 || lines[len(lines)-1] != line {
+			repeats[citation.Key] = append(lines, line)
 		}
-		results = append(results, VerifyCitation(citation, filePath, lineNum, lineText))
 	}
+	seen := make(map[string]bool)
+	for _, citation := range doc.Citations {
+		if seen[citation.Key] {
+			continue
+		}
+		seen[citation.Key] = true
+		lineNum := citation.Span.Start.Line
+		target := VerificationTarget{File: filePath, Line: lineNum, Text: doc.Line(lineNum), Repeats: repeats[citation.Key][1:]}
 
-	// Extract and verify URLs
-	urls := ExtractURLs(contentStr)
-	for _, url := range urls {
-		// Skip GitHub blob URLs (handled by code link checker)
-		if strings.Contains(url, "github.com") && strings.Contains(url, "/blob/") {
+		paperID, ok := strings.CutPrefix(citation.Key, "paper:")
+		if ok && opts.enabled(CheckTypeIdentifier) {
+			// Compare the paper's DOI with its metadata, if it has one
+			checks = append(checks, check{
+				key:    "identifier:paper:" + paperID,
+				host:   metadataHost(opts.Metadata, IdentifierDOI),
+				target: target,
+				run: func() VerificationResult {
+					return verifyPaperIdentifier(opts.Resolver, opts.Metadata, paperID, target.File, target.Line, target.Text)
+				},
+				// Papers without a DOI, or that do not resolve, have nothing to report
+				keep: func(r VerificationResult) bool { return r.Details.Identifier != nil },
+			})
+		}
+		if !opts.enabled(CheckTypeCitation) {
 			continue
 		}
-		lineNum := findLineNumber(lines, url)
-		lineText := ""
-		if lineNum > 0 && lineNum <= len(lines) {
-			lineText = lines[lineNum-1]
+		if !ok {
+			// Plain Pandoc key: a bibliography hit needs no lookup
+			key := citation.Key
+			checks = append(checks, check{
+				key:    bibKey + key,
+				target: target,
+				run: func() VerificationResult {
+					return VerifyCitationKey(bibs, opts.Resolver, key, target.File, target.Line, target.Text)
+				},
+			})
+			continue
 		}
-		results = append(results, VerifyURL(url, filePath, lineNum, lineText))
+		// The resolver rate-limits its own network lookups
+		checks = append(checks, check{
+			key:    "citation:" + paperID,
+			target: target,
+			run: func() VerificationResult {
+				return VerifyCitation(opts.Resolver, paperID, target.File, target.Line, target.Text)
+			},
+		})
 	}
 
-	// Extract and verify code links
-	codeLinks := ExtractCodeLinks(contentStr)
-	for _, link := range codeLinks {
-		lineNum := findLineNumber(lines, link.FullURL)
-		lineText := ""
-		if lineNum > 0 && lineNum <= len(lines) {
-			lineText = lines[lineNum-1]
+	// Verify that DOIs, arXiv IDs, and PubMed IDs in prose resolve and match
+	// the bibliography entry or cited paper that carries them
+	if opts.enabled(CheckTypeIdentifier) {
+		var paperIDs []string
+		for _, citation := range doc.Citations {
+			if paperID, ok := strings.CutPrefix(citation.Key, "paper:"); ok {
+				paperIDs = append(paperIDs, paperID)
+			}
+		}
+		seenIDs := make(map[Identifier]bool)
+		for i := range doc.Paragraphs {
+			para := &doc.Paragraphs[i]
+			for _, m := range findIdentifiers(para.Text) {
+				if seenIDs[m.Identifier] {
+					continue
+				}
+				seenIDs[m.Identifier] = true
+				id := m.Identifier
+				lineNum := para.PositionOf(m.start).Line
+				target := VerificationTarget{File: filePath, Line: lineNum, Text: doc.Line(lineNum)}
+				// Keyed by file, since what the identifier should match
+				// depends on the file's bibliography and citations
+				checks = append(checks, check{
+					key:    "identifier:" + id.String() + "@" + filePath,
+					host:   metadataHost(opts.Metadata, id.Kind),
+					target: target,
+					run: func() VerificationResult {
+						expected := expectedMetadata(id, bibs, opts.Resolver, paperIDs)
+						return VerifyIdentifier(opts.Metadata, id, expected, target.File, target.Line, target.Text)
+					},
+				})
+			}
 		}
-		results = append(results, VerifyCodeLink(link, filePath, lineNum, lineText))
 	}
 
-	// Check for uncited claims (sentence by sentence)
-	for i, line := range lines {
-		lineNum := i + 1
-		// Skip YAML frontmatter, code blocks, headers, and empty lines
-		if strings.HasPrefix(line, "---") || strings.HasPrefix(line, "```") ||
-			strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+	// Verify URLs and code links
+	for _, link := range doc.Links {
+		url := link.URL
+		lineNum := link.Span.Start.Line
+		target := VerificationTarget{File: filePath, Line: lineNum, Text: doc.Line(lineNum)}
+
+		if codeLink, ok := ParseCodeLink(url); ok {
+			if !opts.enabled(CheckTypeCodeLink) {
+				continue
+			}
+			checks = append(checks, check{
+				key:    "code-link:" + url,
+				host:   hostGitHub,
+				target: target,
+				run: func() VerificationResult {
+					return VerifyCodeLink(opts.Mirror, codeLink, target.File, target.Line, target.Text)
+				},
+			})
 			continue
 		}
-
-		// Split line into sentences
-		sentences := splitSentences(line)
-		for _, sentence := range sentences {
-			sentence = strings.TrimSpace(sentence)
-			if len(sentence) < 20 { // Skip very short fragments
+		if branchLink, ok := ParseBranchLink(url); ok {
+			if !opts.enabled(CheckTypeCodeLink) {
 				continue
 			}
+			checks = append(checks, check{
+				key:    "code-link:" + url,
+				host:   hostGitHub,
+				target: target,
+				run: func() VerificationResult {
+					return VerifyBranchLink(opts.Mirror, branchLink, target.File, target.Line, target.Text)
+				},
+			})
+			continue
+		}
+		// Relative links are cross-ref checks; skip other GitHub blob URLs (not pinned permalinks)
+		if !opts.enabled(CheckTypeURL) || !isWebURL(url) || strings.Contains(url, "github.com") && strings.Contains(url, "/blob/") {
+			continue
+		}
+		// DOI, arXiv, and PubMed links are identifier checks
+		if opts.enabled(CheckTypeIdentifier) && isIdentifierURL(url) {
+			continue
+		}
+		checks = append(checks, check{
+			key:    "url:" + url,
+			host:   hostOf(url),
+			target: target,
+			run: func() VerificationResult {
+				return VerifyURLWithTimeout(url, opts.URLTimeout, target.File, target.Line, target.Text)
+			},
+		})
+	}
 
-			// Check if sentence has a citation nearby
-			hasCitation := citationPattern.MatchString(sentence) ||
-				(lineNum > 0 && lineNum <= len(lines) && citationPattern.MatchString(lines[lineNum-1]))
+	// Check for uncited claims sentence by sentence. Callouts are asides and
+	// are not held to the citation requirement.
+	for i := range doc.Paragraphs {
+		para := &doc.Paragraphs[i]
+		if !opts.enabled(CheckTypeClaim) || para.Callout != "" {
+			continue
+		}
+		for _, sent := range para.Sentences() {
+			if len(sent.Text) < 20 { // Skip very short fragments
+				continue
+			}
+			start := para.PositionOf(sent.Start)
+			startLine := start.Line
+			endLine := para.PositionOf(sent.End).Line
+			hasCitation := citedBetween(doc, startLine, endLine)
 
-			result := VerifyClaim(sentence, filePath, lineNum, hasCitation, opts.UseLLM)
-			// Only add failed claim checks to avoid noise
-			if result.Status == CheckStatusFail {
-				results = append(results, result)
+			target := VerificationTarget{File: filePath, Line: startLine, Text: sent.Text}
+			c := check{
+				key:    fmt.Sprintf("claim:%t:%s", hasCitation, sent.Text),
+				host:   hostLLM,
+				target: target,
+				run: func() VerificationResult {
+					return verifyClaim(target.Text, target.File, target.Line, hasCitation, opts.ClaimPatterns, opts.Claims)
+				},
+				// Only keep failed and unchecked claims to avoid noise
+				keep: func(r VerificationResult) bool { return r.Status != CheckStatusPass },
+			}
+			// Sentences the heuristics are unsure about go to the LLM in batches
+			if opts.Claims != nil && !hasCitation {
+				if _, confidence, _ := opts.ClaimPatterns.Analyze(sent.Text); confidence == "low" {
+					// Identified by position, so a verdict cannot be
+					// attributed to a sentence from another batch
+					id := fmt.Sprintf("%s:%d:%d", filePath, start.Line, start.Col)
+					c.claim = &llm.ClaimItem{ID: id, Sentence: sent.Text, Context: para.Text}
+				}
 			}
+			checks = append(checks, c)
 		}
 	}
 
-	return results, nil
+	// Cache network-bound checks against the current file content and
+	// whatever else their results depend on
+	contentHash := ContentHash([]byte(strings.Join(doc.Lines, "\n")))
+	for i := range checks {
+		if checks[i].key != "" && checks[i].cacheKey == "" {
+			checks[i].cacheKey = ResultCacheKey(checks[i].key, contentHash+cacheDependencies(checks[i].key, opts, bibs))
+		}
+	}
+
+	return checks
+}
+
+// cacheDependencies describes what a check's result depends on besides the
+// file content: the bibliographies and resolver for citations, also the
+// metadata provider for identifiers, and the LLM for claims.
+func cacheDependencies(key string, opts Options, bibs []*Bibliography) string {
+	var bibHashes []string
+	for _, bib := range bibs {
+		bibHashes = append(bibHashes, bib.Hash())
+	}
+	var resolver, metadata string
+	if opts.Resolver != nil {
+		resolver = opts.Resolver.Name()
+	}
+	if opts.Metadata != nil {
+		metadata = opts.Metadata.Name()
+	}
+	citation := "\x00resolver=" + resolver + "\x00bib=" + strings.Join(bibHashes, ",")
+	switch {
+	case strings.HasPrefix(key, "citekey:"), strings.HasPrefix(key, "citation:"):
+		return citation
+	case strings.HasPrefix(key, "identifier:"):
+		return citation + "\x00metadata=" + metadata
+	case strings.HasPrefix(key, "claim:"):
+		return "\x00llm=" + opts.Claims.identity()
+	}
+	return ""
+}
+
+// loadFrontMatterBibliography loads the bibliography files named in a
+// document's front matter. It returns nil if the document names none.
+func loadFrontMatterBibliography(doc *qmd.Document) (*Bibliography, error) {
+	paths, err := quarto.FrontMatterBibliography(doc.Path, doc.FrontMatter.Raw)
+	if err != nil || len(paths) == 0 {
+		return nil, err
+	}
+	return LoadBibliography(paths...)
+}
+
+// bibliographyError reports a document bibliography that could not be loaded.
+func bibliographyError(doc *qmd.Document, err error) VerificationResult {
+	line := doc.FrontMatter.Span.Start.Line
+	return VerificationResult{
+		CheckID:   uuid.New().String(),
+		CheckType: CheckTypeBibliography,
+		Target:    VerificationTarget{File: doc.Path, Line: line, Text: doc.Line(line)},
+		Status:    CheckStatusFail,
+		Message:   err.Error(),
+		CheckedAt: time.Now(),
+	}
+}
+
+// citedBetween reports whether any citation appears on lines [start, end].
+func citedBetween(doc *qmd.Document, start, end int) bool {
+	for _, c := range doc.Citations {
+		if c.Span.Start.Line >= start && c.Span.Start.Line <= end {
+			return true
+		}
+	}
+	return false
 }
 
 // VerifyFiles runs verification on multiple files.
+// Checks from all files are scheduled together so identical targets are only
+// checked once; results are reported in file order.
 func VerifyFiles(files []string, opts Options) (*VerificationReport, error) {
+	opts = opts.withDefaults()
 	builder := NewReportBuilder()
 
-	for _, file := range files {
+	// Parse everything first so checks can see labels from other files
+	docs := make([]*qmd.Document, len(files))
+	parseErrs := make([]error, len(files))
+	for i, file := range files {
+		docs[i], parseErrs[i] = qmd.ParseFile(file)
+	}
+	var parsed []*qmd.Document
+	for _, doc := range docs {
+		if doc != nil {
+			parsed = append(parsed, doc)
+		}
+	}
+	opts.crossRefs = newCrossRefIndex(parsed, opts.Project)
+
+	var checks []check
+	cited := make(map[string]bool)
+	supsByFile := make(map[string]suppressions)
+	for i, file := range files {
 		builder.AddFile(file)
 
-		results, err := VerifyFile(file, opts)
+		doc, err := docs[i], parseErrs[i]
 		if err != nil {
 			// Create a failed result for the file read error
-			builder.AddResult(VerificationResult{
+			result := VerificationResult{
 				CheckType: CheckTypeCitation,
 				Target: VerificationTarget{
 					File: file,
@@ -146,46 +495,57 @@ func VerifyFiles(files []string, opts Options) (*VerificationReport, error) {
 				},
 				Status:  CheckStatusFail,
 				Message: err.Error(),
+			}
+			checks = append(checks, check{
+				target: result.Target,
+				run:    func() VerificationResult { return result },
 			})
 			continue
 		}
-
-		for _, result := range results {
-			builder.AddResult(result)
+		checks = append(checks, planDocument(doc, opts)...)
+		supsByFile[file], _ = parseSuppressions(doc)
+		for _, citation := range doc.Citations {
+			cited[citation.Key] = true
+		}
+		if doc.FrontMatter != nil {
+			keys, _ := quarto.FrontMatterNocite(doc.Path, doc.FrontMatter.Raw)
+			for _, key := range keys {
+				cited[key] = true
+			}
 		}
 	}
 
-	report := builder.Build()
-	return &report, nil
-}
+	// Identifiers in the project bibliography are compared with their entries
+	if opts.Bibliography != nil && opts.enabled(CheckTypeIdentifier) {
+		checks = append(checks, bibIdentifierChecks(opts.Bibliography, opts)...)
+	}
 
-// findLineNumber finds the line number containing a substring.
-func findLineNumber(lines []string, substr string) int {
-	for i, line := range lines {
-		if strings.Contains(line, substr) {
-			return i + 1
+	for _, result := range newScheduler(opts).run(checks) {
+		builder.AddResult(opts.finalize(result, supsByFile[result.Target.File]))
+	}
+	if opts.Claims != nil {
+		if usage := opts.Claims.Usage(); usage.Requests+usage.Cached > 0 {
+			builder.SetLLMUsage(usage)
 		}
 	}
-	return 0
-}
 
-// splitSentences splits text into sentences (simple heuristic).
-func splitSentences(text string) []string {
-	// Simple sentence splitting on . ? !
-	var sentences []string
-	current := ""
-	for _, r := range text {
-		current += string(r)
-		if r == '.' || r == '?' || r == '!' {
-			s := strings.TrimSpace(current)
-			if len(s) > 0 {
-				sentences = append(sentences, s)
+	// Duplicate entries only make sense across the whole file set, and
+	// uncited ones across the whole project: an entry may be cited only in
+	// chapters that were not checked
+	if opts.Bibliography != nil && opts.enabled(CheckTypeBibliography) {
+		if opts.Project != nil {
+			for _, key := range opts.Project.Nocite {
+				cited[key] = true
 			}
-			current = ""
+		}
+		if opts.Project == nil || cited["*"] {
+			cited = nil
+		}
+		for _, result := range bibliographyResults(opts.Bibliography, cited) {
+			builder.AddResult(opts.finalize(result, nil))
 		}
 	}
-	if s := strings.TrimSpace(current); len(s) > 0 {
-		sentences = append(sentences, s)
-	}
-	return sentences
+
+	report := builder.Build()
+	return &report, nil
 }
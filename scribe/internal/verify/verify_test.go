@@ -1,7 +1,10 @@
 package verify
 
 import (
+	"strings"
 	"testing"
+
+	"github.com/matsen/phylogenetic-compendium/scribe/internal/qmd"
 )
 
 func TestExtractCitations(t *testing.T) {
@@ -324,3 +327,40 @@ func TestReportFilterByType(t *testing.T) {
 		t.Errorf("got %d urls, want 1", len(urls))
 	}
 }
+
+func TestPlanDocument_SkipsNonProse(t *testing.T) {
+	content := `---
+url: https://front.matter
+---
+
+Visit https://prose.example for more. Method A is 40% faster than method B.
+
+<!-- https://comment.example Method C is 10% faster than method D. -->
+
+` + "```{python}" + `
+# https://code.example Method E is 5% faster than method F.
+` + "```" + `
+
+::: {.callout-note}
+Method G is 2x faster than method H.
+:::
+`
+	checks := planDocument(qmd.Parse("test.qmd", []byte(content)), DefaultOptions())
+
+	var urls, claims []string
+	for _, c := range checks {
+		switch {
+		case strings.HasPrefix(c.key, "url:"):
+			urls = append(urls, strings.TrimPrefix(c.key, "url:"))
+		case strings.HasPrefix(c.key, "claim:"):
+			claims = append(claims, c.target.Text)
+		}
+	}
+
+	if len(urls) != 1 || urls[0] != "https://prose.example" {
+		t.Errorf("got URLs %v, want only https://prose.example", urls)
+	}
+	if len(claims) != 2 || claims[1] != "Method A is 40% faster than method B." {
+		t.Errorf("got claims %q, want the two prose sentences", claims)
+	}
+}